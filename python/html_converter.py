@@ -17,6 +17,8 @@ import uuid
 import sys
 import time
 from datetime import datetime
+from vlp2ss_progress import ProgressTracker
+from vlp2ss_clean import prepare_output_dir, OutputDirError
 
 # --- Constants ---
 APP_VERSION = "1.0.3" # Initial version for HTML converter
@@ -39,16 +41,7 @@ class ProgressLogger:
     def __init__(self, verbose: bool = False):
         self.verbose = verbose
         self.setup_logging()
-        self.start_time = time.time()
-        self.total_manuals = 0
-        self.total_chapters = 0
-        self.total_articles = 0
-        self.total_images = 0
-        self.current_manual = 0
-        self.current_chapter = 0
-        self.current_article = 0
-        self.processed_articles = 0
-        self.processed_images = 0
+        self.tracker = ProgressTracker()  # Thread-safe progress counters
     
     def setup_logging(self):
         """Configure logging with file and console handlers"""
@@ -123,50 +116,19 @@ class ProgressLogger:
     
     def set_totals(self, manuals: int = 1, chapters: int = 0, articles: int = 0, images: int = 0):
         """Set total counts for progress tracking"""
-        self.total_manuals = manuals
-        self.total_chapters = chapters
-        self.total_articles = articles
-        self.total_images = images
+        self.tracker.set_totals(manuals=manuals, chapters=chapters, articles=articles, images=images)
     
     def get_progress_string(self) -> str:
         """Generate progress percentage string"""
-        manual_pct = (self.current_manual / self.total_manuals * 100) if self.total_manuals > 0 else 0
-        chapter_pct = (self.current_chapter / self.total_chapters * 100) if self.total_chapters > 0 else 0
-        article_pct = (self.current_article / self.total_articles * 100) if self.total_articles > 0 else 0
-        return f"[ Manual: {manual_pct:.0f}%, Chapter: {chapter_pct:.0f}%, Article: {article_pct:.0f}% ]"
+        return self.tracker.progress_string()
     
     def estimate_time_remaining(self) -> str:
         """Estimate remaining time based on progress"""
-        if self.processed_articles == 0:
-            return "Calculating..."
-        
-        elapsed = time.time() - self.start_time
-        
-        # Use weighted formula: articles are primary driver, images add time
-        # Based on user data: ~10-15 seconds per article + ~2 seconds per image
-        avg_time_per_article = 12.5  # seconds
-        avg_time_per_image = 2.0     # seconds
-        
-        # Calculate estimated total time
-        estimated_total = (self.total_articles * avg_time_per_article) + (self.total_images * avg_time_per_image)
-        
-        # Calculate progress-based estimate
-        articles_remaining = self.total_articles - self.processed_articles
-        images_remaining = self.total_images - self.processed_images
-        
-        estimated_remaining = (articles_remaining * avg_time_per_article) + (images_remaining * avg_time_per_image)
-        
-        # Format time
-        minutes, seconds = divmod(int(estimated_remaining), 60)
-        if minutes > 0:
-            return f"~{minutes}m {seconds}s"
-        else:
-            return f"~{seconds}s"
+        return self.tracker.estimate_time_remaining()
     
     def progress(self, message: str):
         """Print a progress message with percentages and time estimate"""
-        progress_str = self.get_progress_string()
-        time_est = self.estimate_time_remaining()
+        progress_str, time_est = self.tracker.status()
         print(f"{Colors.OKBLUE}{progress_str} {message} {Colors.OKCYAN}[ETA: {time_est}]{Colors.ENDC}")
         logging.info(f"{progress_str} {message} [ETA: {time_est}]")
 
@@ -190,8 +152,7 @@ class HTMLConverter:
         self.logger = logger
         
     def setup_directories(self):
-        if self.output_dir.exists():
-            shutil.rmtree(self.output_dir)
+        # main() has already emptied the output directory if allowed
         self.output_dir.mkdir(parents=True, exist_ok=True)
         self.images_dir.mkdir(exist_ok=True)
         self.articles_dir.mkdir(exist_ok=True)
@@ -490,6 +451,10 @@ def main():
                        help='Enable verbose logging')
     parser.add_argument('--no-cleanup', action='store_true',
                        help='Keep temporary files after conversion (N/A for direct HTML conversion)')
+    parser.add_argument('--no-clean-output', action='store_true',
+                       help='Keep what is already in the output directory instead of emptying it first')
+    parser.add_argument('--force', action='store_true',
+                       help='Empty the output directory even if it was not created by the converter')
     parser.add_argument('--version', action='version',
                        version=f'html_converter v{APP_VERSION}')
     parser.add_argument('--examples', action='store_true',
@@ -523,15 +488,9 @@ def main():
             print(f"{Colors.FAIL}Error: Input path does not exist: {input_path}{Colors.ENDC}")
             return 1
         
-        # Clean logs and output directories at startup
-        logs_dir = Path("logs")
-        if logs_dir.exists():
-            shutil.rmtree(logs_dir)
-        logs_dir.mkdir(exist_ok=True)
-        
-        if output_dir.exists():
-            shutil.rmtree(output_dir)
-        output_dir.mkdir(parents=True, exist_ok=True)
+        # Clean the output directory at startup, if a converter created it (logs are kept)
+        prepare_output_dir(output_dir, clean=not args.no_clean_output, force=args.force,
+                           protected=[input_path, Path("logs")])
         
         converter_instance = HTMLConverter(input_path, output_dir, logger=ProgressLogger(verbose=args.verbose))
         converter_instance.convert(cleanup=not args.no_cleanup)
@@ -545,6 +504,9 @@ def main():
         
         return 0
         
+    except OutputDirError as e:
+        print(f"{Colors.FAIL}Error: {e}{Colors.ENDC}")
+        return 1
     except Exception as e:
         print(f"{Colors.FAIL}Error: {e}{Colors.ENDC}")
         logging.exception("Conversion failed")
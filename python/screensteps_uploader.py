@@ -10,19 +10,87 @@ Version: 1.0.3
 import sys
 import os
 import json
+import csv
 import argparse
 import logging
 import time
 from pathlib import Path
 from datetime import datetime
-from typing import Dict, List, Optional
+from typing import Dict, List, Optional, Tuple
 import requests
-from requests.auth import HTTPBasicAuth
 import uuid
 import re
+import getpass
+import contextlib
+import threading
+import hashlib
+import tempfile
+from urllib.parse import quote, unquote
+from concurrent.futures import ThreadPoolExecutor
 from bs4 import BeautifulSoup
 from PIL import Image
-from html import unescape
+from html import escape, unescape
+from vlp2ss_version import APP_VERSION, VersionAction, build_info
+from vlp2ss_api import (ScreenStepsClient, ScreenStepsBackend, RateLimiter, RequestScheduler, RetryPolicy,
+                        API_REQUEST_RATE, FILE_UPLOAD_RATE, BACKEND_METHODS)
+from vlp2ss_images import (ImageIndex, IMAGE_EXTENSIONS, ImageFormatError, apply_format_policy,
+                           load_format_policies, optimize_image)
+from vlp2ss_report import write_qa_report, format_structure
+from vlp2ss_notify import notify_webhook, email_report, smtp_settings
+from vlp2ss_metrics import DEFAULT_METRICS_FILE, record_run
+from vlp2ss_progress import ProgressTracker
+from vlp2ss_secrets import load_scanner
+from vlp2ss_overrides import load_overrides, apply_overrides, OverridesError
+from vlp2ss_filters import add_filter_arguments, filter_from_args, select_articles, FilterError
+from vlp2ss_diff import block_text, outline_block, remote_steps, diff_manuals, format_diff, has_differences
+from vlp2ss_exitcodes import EXIT_OK, EXIT_ERROR, EXIT_WARNINGS, EXIT_AUTH, exit_status, result_exit_code
+from vlp2ss_logs import DEFAULT_LOG_DIR, DEFAULT_LOG_KEEP, new_log_file
+from vlp2ss_config import (add_config_arguments, apply_profile, ConfigError, get_keychain_token,
+                           store_keychain_token, delete_keychain_token)
+from vlp2ss_cassette import CassetteError, open_cassette, use_cassette
+from vlp2ss_oplog import (OPERATION_LOG_FILE, OperationLogError, open_operation_log, load_operations,
+                          select_replay, replay_operations)
+
+# Stores the VLP -> ScreenSteps ID mapping of the last upload inside the content directory
+ID_MAP_FILE = 'screensteps_ids.json'
+# The same mapping as one row per manual/chapter/article/step/image (for spreadsheets and redirects)
+ID_MAP_CSV_FILE = 'screensteps_ids.csv'
+ID_MAP_CSV_COLUMNS = ['type', 'vlp_id', 'converted_id', 'title', 'screensteps_id',
+                      'parent_screensteps_id', 'anchor', 'url']
+# Journal of every ScreenSteps object created by the current/last upload (used for rollback)
+JOURNAL_FILE = 'upload_journal.json'
+# End-of-run report shared with the converter (the upload results go in its 'upload' section)
+SUMMARY_FILE = 'summary.json'
+# Article content read back from ScreenSteps, reused while the article is unchanged
+READBACK_CACHE_FILE = 'readback_cache.json'
+# JSON files in a content directory that are not the TOC
+STATE_FILES = (ID_MAP_FILE, JOURNAL_FILE, SUMMARY_FILE, READBACK_CACHE_FILE)
+# Index of a multi-language conversion, next to its per-language content directories (converter --lang)
+LANGUAGES_FILE = 'languages.json'
+
+# Links to other articles/steps marked by the converter (resolved once the target article exists)
+INTERNAL_LINK_REGEX = re.compile(r'href="#([^"]*)" data-ss-article="([^"]+)"')
+
+# Splits step HTML into embeds, styled blocks and images (everything else is text)
+BLOCK_REGEX = re.compile(r'(<div class="html-embed">.*?</div>|<div class="screensteps-styled-block".*?>.*?</div>|<img[^>]+src="[^"]+"[^>]*>)', re.DOTALL)
+# Styled blocks the uploader folds into a collapsed sub-step: foldable Lab Credentials and quiz answers
+FOLDABLE_BLOCK_REGEX = re.compile(r'<div class="screensteps-styled-block"[^>]*\sdata-(?:credentials="foldable"|foldable="true")')
+# List tags; a list is never split across content blocks
+LIST_TAG_REGEX = re.compile(r'<(/?)(?:ol|ul)\b[^>]*>', re.IGNORECASE)
+IMG_TAG_REGEX = re.compile(r'<img\b[^>]*>')
+# Shown in place of an image that could not be uploaded
+IMAGE_ERROR_TEXT = 'ERROR IMPORTING IMAGE - PLEASE RE-CREATE SCREENSHOT'
+# Alert block left at the position of an image that could not be uploaded (--image-placeholder);
+# {file} and {alt} are replaced with the image's file name and alt text
+DEFAULT_IMAGE_PLACEHOLDER = IMAGE_ERROR_TEXT + ' ({file})'
+# Shown in place of a video from the export that could not be uploaded
+VIDEO_ERROR_TEXT = 'ERROR IMPORTING VIDEO - PLEASE RE-ADD VIDEO'
+# Shown next to the text of an attachment link whose file could not be uploaded
+ATTACHMENT_ERROR_TEXT = 'ATTACHMENT NOT IMPORTED'
+# Relative links to a file (a name with an extension); uploaded as attachments when the converter copied the file
+LOCAL_FILE_LINK_REGEX = re.compile(r'<a\b([^>]*?)\bhref="(?![a-z][a-z0-9+.-]*:|//|#)([^"#?]+\.\w+)"([^>]*)>(.*?)</a>',
+                                   re.IGNORECASE | re.DOTALL)
+LOCAL_VIDEO_REGEX = re.compile(r'<video\b[^>]*\bsrc="(?![a-z][a-z0-9+.-]*:|//)([^"]+)"', re.IGNORECASE)
 
 # ANSI color codes for terminal output
 class Colors:
@@ -48,6 +116,61 @@ def slugify(text):
     text = re.sub(r'[-\s]+', '-', text)
     return text.strip('-')
 
+def step_anchor(step: Dict) -> str:
+    """Anchor of a step: the converter's stable anchor (--stable-anchors) or the title slug"""
+    return step.get('anchor') or slugify(step['title'])
+
+def list_spans(html_content: str) -> List[Tuple[int, int]]:
+    """(start, end) offsets of every top-level <ol>/<ul> in step HTML"""
+    spans = []
+    depth = 0
+    start = 0
+    for match in LIST_TAG_REGEX.finditer(html_content):
+        if not match.group(1):
+            if depth == 0:
+                start = match.start()
+            depth += 1
+        elif depth:
+            depth -= 1
+            if depth == 0:
+                spans.append((start, match.end()))
+    return spans
+
+def outline_step_html(html_content: str) -> List[Dict]:
+    """Comparable outline (vlp2ss_diff) of the content blocks generate_content_blocks makes from step HTML"""
+    blocks = []
+    lists = list_spans(html_content)
+    last_index = 0
+
+    def add_text(html: str, style: Optional[str] = None):
+        if re.sub(r'<[^>]+>', '', html).strip():
+            blocks.append(outline_block('text', block_text(html), style))
+
+    for match in BLOCK_REGEX.finditer(html_content):
+        block_html = match.group(0)
+        if block_html.startswith('<img') and 'inline-icon' in block_html or \
+                any(list_start <= match.start() < list_end for list_start, list_end in lists):
+            continue
+        add_text(html_content[last_index:match.start()])
+        last_index = match.end()
+        if block_html.startswith('<img'):
+            src = re.search(r'src="([^"]+)"', block_html).group(1)
+            blocks.append(outline_block('image', unescape(src).split('/')[-1].split('?')[0]))
+        elif block_html.startswith('<div class="html-embed"'):
+            blocks.append(outline_block('text', block_text(block_html), 'html-embed'))
+        else:
+            style_match = re.search(r'data-style="([^"]+)"[^>]*>(.*)</div>', block_html, re.DOTALL)
+            if not style_match:
+                continue
+            inner_body = style_match.group(2)
+            title_match = re.match(r'<p><strong>([^<]*)</strong></p>', inner_body)
+            if FOLDABLE_BLOCK_REGEX.match(block_html) and title_match:
+                blocks.append(outline_block('fold', unescape(title_match.group(1))))
+                inner_body = inner_body[title_match.end():]
+            blocks.append(outline_block('text', block_text(inner_body), style_match.group(1)))
+    add_text(html_content[last_index:])
+    return blocks
+
 def extract_images_from_html(html_content):
     """Extract image references from HTML"""
     if not html_content:
@@ -178,145 +301,248 @@ def remove_style_divs(html_content):
         div.unwrap()
     return str(soup)
 
-class ScreenStepsAPI:
-    """ScreenSteps API client"""
+# Article metadata available to article_snippets templates as {name} (HTML-escaped) or {name|url} (URL-encoded);
+# {{name}} is accepted as well
+SNIPPET_FIELDS = ('manual', 'chapter', 'title', 'position', 'duration_minutes', 'vlp_id', 'article_id',
+                  'manual_vlp_id', 'sku', 'data_format', 'export_date', 'date')
+SNIPPET_FIELD_REGEX = re.compile(r'\{(?P<brace>\{)?(?P<field>\w+)(?P<url>\|url)?\}(?(brace)\})')
+SNIPPET_STYLES = ('info', 'tip', 'alert', 'warning', 'introduction')
+
+def load_article_snippets(section: Optional[Dict]) -> Dict[str, List[Dict]]:
+    """Validate a profile's article_snippets section: head/foot HTML blocks with an optional style
     
-    def __init__(self, account: str, user: str, token: str, logger):
-        self.account = account
-        self.user = user
-        self.token = token
-        self.logger = logger
-        self.base_url = f"https://{account}.screenstepslive.com/api/v2"
-        self.auth = HTTPBasicAuth(user, token)
-        self.session = requests.Session()
-        self.session.auth = self.auth
-    
-    def _request(self, method: str, endpoint: str, **kwargs) -> requests.Response:
-        """Make API request with rate limiting and retry logic"""
-        url = f"{self.base_url}/{endpoint}"
-        
-        # Log request details in verbose mode
-        if hasattr(self, 'verbose') and self.verbose:
-            self.logger.info("=" * 70)
-            self.logger.info("API REQUEST DETAILS:")
-            self.logger.info(f"  Endpoint: {method} {url}")
-            self.logger.info(f"  Username: {self.user}")
-            if 'json' in kwargs:
-                self.logger.info(f"  JSON Data: {json.dumps(kwargs['json'], indent=2)}")
-            if 'data' in kwargs:
-                self.logger.info(f"  Form Data: {kwargs['data']}")
-            if 'files' in kwargs:
-                self.logger.info(f"  Files: {list(kwargs['files'].keys())}")
-            self.logger.info("=" * 70)
-        
-        while True:
-            try:
-                response = self.session.request(method, url, **kwargs)
-                
-                # Log response details in verbose mode
-                if hasattr(self, 'verbose') and self.verbose:
-                    self.logger.info("API RESPONSE:")
-                    self.logger.info(f"  Status Code: {response.status_code}")
-                    self.logger.info(f"  Headers: {dict(response.headers)}")
-                    try:
-                        self.logger.info(f"  Body: {response.json()}")
-                    except:
-                        self.logger.info(f"  Body: {response.text[:500]}")
-                    self.logger.info("=" * 70)
-                
-                if response.status_code == 200 or response.status_code == 201:
-                    # Add delay between successful API calls to avoid rate limiting
-                    time.sleep(0.25)
-                    return response
-                elif response.status_code == 429:
-                    # Rate limit exceeded - check for retry_in value
-                    try:
-                        retry_info = response.json()
-                        retry_in = retry_info.get('retry_in', 60)
-                        self.logger.warning(f"Rate limit exceeded. Retrying in {retry_in} seconds...")
-                        time.sleep(retry_in)                    
-                    except ValueError:
-                        self.logger.warning("Rate limit exceeded. Retrying in 60 seconds...")
-                        time.sleep(60)
-                else:
-                    self.logger.error("=" * 70)
-                    self.logger.error("API REQUEST FAILED:")
-                    self.logger.error(f"  Endpoint: {method} {url}")
-                    self.logger.error(f"  Username: {self.user}")
-                    self.logger.error(f"  Status Code: {response.status_code}")
-                    if 'json' in kwargs:
-                        self.logger.error(f"  Request JSON: {json.dumps(kwargs['json'], indent=2)}")
-                    self.logger.error(f"  Response: {response.text}")
-                    self.logger.error("=" * 70)
-                    response.raise_for_status()
-                    
-            except requests.exceptions.RequestException as e:
-                self.logger.error("=" * 70)
-                self.logger.error("REQUEST EXCEPTION:")
-                self.logger.error(f"  Endpoint: {method} {url}")
-                self.logger.error(f"  Username: {self.user}")
-                if 'json' in kwargs:
-                    self.logger.error(f"  Request JSON: {json.dumps(kwargs['json'], indent=2)}")
-                self.logger.error(f"  Error: {e}")
-                self.logger.error("=" * 70)
-                raise
-    
-    def get_sites(self) -> List[Dict]:
-        """Get all sites"""
-        response = self._request('GET', 'sites')
-        return response.json().get('sites', [])
-    
-    def get_site(self, site_id: str) -> Dict:
-        """Get site details"""
-        response = self._request('GET', f'sites/{site_id}')
-        return response.json().get('site', {})
-    
-    def create_manual(self, site_id: str, title: str, chapters: List[Dict] = None, 
-                     published: bool = True) -> Dict:
-        """Create a new manual with chapters"""
-        data = {
-            'manual': {
-                'title': title,
-                'published': published
-            }
-        }
-        
-        # Add chapters array if provided
-        if chapters:
-            data['manual']['chapters'] = chapters
-        
-        response = self._request('POST', f'sites/{site_id}/manuals', json=data)
-        return response.json().get('manual', {})
-    
-    def create_chapter(self, site_id: str, manual_id: str, title: str, 
-                      position: int, description: str = "") -> Dict:
-        """Create a new chapter"""
-        data = {
-            'chapter': {
-                'position': position,
-                'title': title,
-                'published': True,
-                'manual_id': int(manual_id)
-            }
-        }
-        response = self._request('POST', f'sites/{site_id}/chapters', 
-                                json=data)
-        return response.json().get('chapter', {})
-    
-    def create_article(self, site_id: str, chapter_id: str, title: str, 
-                      position: int) -> Dict:
-        """Create a new article (placeholder - content added separately)"""
-        data = {
-            'article': {
-                'position': position,
-                'title': title,
-                'published': True,
-                'chapter_id': int(chapter_id)
-            }
+    Each of head and foot is an HTML string, a mapping with 'html' and
+    'style' (info, tip, alert, warning or introduction; plain text if unset),
+    or a list of these for several blocks in that order.
+    """
+    snippets = {}
+    if not section:
+        return snippets
+    if not isinstance(section, dict):
+        raise ConfigError("article_snippets must map head and/or foot to an HTML snippet")
+    unknown = set(section) - {'head', 'foot'}
+    if unknown:
+        raise ConfigError(f"Unknown article_snippets keys: {', '.join(sorted(unknown))} (supported: head, foot)")
+    for position, blocks in section.items():
+        snippets[position] = []
+        for index, snippet in enumerate(blocks if isinstance(blocks, list) else [blocks]):
+            name = f"{position}.{index}" if isinstance(blocks, list) else position
+            if isinstance(snippet, str):
+                snippet = {'html': snippet}
+            if not isinstance(snippet, dict) or not isinstance(snippet.get('html'), str) \
+                    or set(snippet) - {'html', 'style'}:
+                raise ConfigError(f"article_snippets.{name} must be an HTML string or a mapping with html and style")
+            if snippet.get('style') not in (None,) + SNIPPET_STYLES:
+                raise ConfigError(f"article_snippets.{name}.style must be one of: {', '.join(SNIPPET_STYLES)}")
+            fields = {match.group('field') for match in SNIPPET_FIELD_REGEX.finditer(snippet['html'])}
+            if fields - set(SNIPPET_FIELDS):
+                raise ConfigError(f"Unknown fields in article_snippets.{name}: {', '.join(sorted(fields - set(SNIPPET_FIELDS)))} "
+                                  f"(available: {', '.join(SNIPPET_FIELDS)})")
+            snippets[position].append({'html': snippet['html'], 'style': snippet.get('style')})
+    return snippets
+
+def metadata_tags(manual_info: Dict) -> List[str]:
+    """Tags tracing an article back to its VLP lab (--metadata-tags): SKU, manual ID, data format, export day"""
+    metadata = manual_info.get('metadata') or {}
+    tags = [metadata.get('sku')]
+    if manual_info.get('vlp_id'):
+        tags.append(f"vlp-manual:{manual_info['vlp_id']}")
+    if metadata.get('data_format'):
+        tags.append(f"vlp-format:{metadata['data_format']}")
+    if metadata.get('export_date'):
+        tags.append(f"vlp-export:{metadata['export_date'][:10]}")
+    return [tag for tag in tags if tag]
+
+def load_tag_rules(section: Optional[Dict]) -> List[Tuple[re.Pattern, List[str]]]:
+    """Validate a profile's article_tags section: title pattern -> tag or list of tags
+    
+    Patterns are case-insensitive regular expressions searched in the manual,
+    chapter and article titles.
+    """
+    if not section:
+        return []
+    if not isinstance(section, dict):
+        raise ConfigError("article_tags must map title patterns to a tag or a list of tags")
+    rules = []
+    for pattern, tags in section.items():
+        tags = [tags] if isinstance(tags, str) else tags
+        if not isinstance(tags, list) or not tags or not all(isinstance(tag, str) and tag.strip() for tag in tags):
+            raise ConfigError(f"article_tags.{pattern} must be a tag or a list of tags")
+        try:
+            rules.append((re.compile(str(pattern), re.IGNORECASE), [tag.strip() for tag in tags]))
+        except re.error as e:
+            raise ConfigError(f"Invalid pattern in article_tags: {pattern}: {e}")
+    return rules
+
+def title_tags(rules: List[Tuple[re.Pattern, List[str]]], titles: List[str]) -> List[str]:
+    """Tags of the article_tags rules whose pattern is found in any of the titles"""
+    tags = []
+    for pattern, rule_tags in rules:
+        if any(pattern.search(title or '') for title in titles):
+            tags.extend(rule_tags)
+    return tags
+
+def render_snippet(html: str, values: Dict) -> str:
+    """Fill the {field}, {{field}} and {field|url} placeholders of a snippet; other braces are left alone"""
+    def replace(match):
+        field = match.group('field')
+        if field not in values:
+            return match.group(0)
+        value = '' if values[field] is None else str(values[field])
+        return quote(value, safe='') if match.group('url') else escape(value)
+    return SNIPPET_FIELD_REGEX.sub(replace, html)
+
+class UploadJournal:
+    """Persistent record of the ScreenSteps objects created during an upload
+    
+    Every created manual/chapter/article/file ID is appended and flushed to disk
+    immediately, so a crashed or interrupted run can still be rolled back.
+    """
+    
+    def __init__(self, path: Path, site_id: str):
+        self.path = path
+        self.lock = threading.Lock()
+        self.data = {
+            'site_id': str(site_id),
+            'manual_id': None,
+            'status': 'in_progress',
+            'started_at': datetime.now().isoformat(),
+            'generator': build_info(),
+            'created': []
         }
-        response = self._request('POST', f'sites/{site_id}/articles', 
-                                json=data)
-        return response.json().get('article', {})
+    
+    @classmethod
+    def load(cls, path: Path) -> 'UploadJournal':
+        """Load an existing journal file"""
+        with open(path, 'r', encoding='utf-8') as f:
+            data = json.load(f)
+        journal = cls(path, data.get('site_id', ''))
+        journal.data.update(data)
+        return journal
+    
+    @property
+    def manual_id(self) -> Optional[str]:
+        return self.data.get('manual_id')
+    
+    @property
+    def entries(self) -> List[Dict]:
+        return self.data['created']
+    
+    def record(self, kind: str, object_id, title: str = ''):
+        """Record a created object ('manual', 'chapter', 'article' or 'file')"""
+        with self.lock:
+            if kind == 'manual':
+                self.data['manual_id'] = str(object_id)
+            self.entries.append({'type': kind, 'id': str(object_id), 'title': title})
+            self.save()
+    
+    def set_status(self, status: str):
+        self.data['status'] = status
+        self.data['updated_at'] = datetime.now().isoformat()
+        self.save()
+    
+    def save(self):
+        with open(self.path, 'w', encoding='utf-8') as f:
+            json.dump(self.data, f, indent=2)
+
+class ReadBackCache:
+    """Local cache of article content read back from ScreenSteps
+    
+    Entries are keyed by article ID and reused only while the article's
+    updated_at is unchanged, so repeated verification runs against a large
+    site download just the articles that changed since the last run.
+    """
+    
+    def __init__(self, path: Path, site_id: str):
+        self.path = path
+        self.site_id = str(site_id)
+        self.lock = threading.Lock()
+        self.articles: Dict[str, Dict] = {}
+        self.hits = 0
+        self.misses = 0
+        self.changed = False
+    
+    @classmethod
+    def load(cls, path: Path, site_id: str) -> 'ReadBackCache':
+        """Load the cache file (an unreadable file or one for another site starts empty)"""
+        cache = cls(path, site_id)
+        try:
+            with open(path, 'r', encoding='utf-8') as f:
+                data = json.load(f)
+            if str(data.get('site_id')) == cache.site_id:
+                cache.articles = data.get('articles', {})
+        except (OSError, ValueError):
+            pass
+        return cache
+    
+    def get(self, article_id: str, updated_at: Optional[str]) -> Optional[Dict]:
+        """Cached article, if it was fetched at the given updated_at"""
+        with self.lock:
+            entry = self.articles.get(str(article_id))
+            if updated_at and entry and entry.get('updated_at') == updated_at:
+                self.hits += 1
+                return entry['article']
+            self.misses += 1
+            return None
+    
+    def put(self, article: Dict):
+        """Store a fetched article (articles without updated_at are not cached)"""
+        if not article.get('id') or not article.get('updated_at'):
+            return
+        with self.lock:
+            self.articles[str(article['id'])] = {'updated_at': article['updated_at'], 'article': article}
+            self.changed = True
+    
+    def save(self):
+        if not self.changed:
+            return
+        with self.lock:
+            with open(self.path, 'w', encoding='utf-8') as f:
+                json.dump({'site_id': self.site_id, 'generator': build_info(),
+                           'articles': self.articles}, f)
+            self.changed = False
+
+class ScreenStepsAPI(ScreenStepsClient):
+    """ScreenSteps API client of the uploader: image preprocessing, deduplication and read-back caching
+    
+    With a backend, every ScreenStepsBackend operation (including those the
+    image code here calls) goes to it instead of over HTTP; what its
+    create_* and upload_file return is still recorded in the journal.
+    """
+    
+    def __init__(self, account: str, user: str, token: str, logger, base_url: Optional[str] = None,
+                 backend: Optional[ScreenStepsBackend] = None):
+        super().__init__(account, user, token, logger, base_url)
+        self.backend = backend
+        if backend is not None:
+            for name in BACKEND_METHODS:
+                setattr(self, name, getattr(backend, name))
+            for kind in ('manual', 'chapter', 'article', 'file'):
+                name = 'upload_file' if kind == 'file' else f'create_{kind}'
+                setattr(self, name, self._journaled(kind, getattr(backend, name)))
+        self.upload_concurrency = 1  # Parallel image uploads per article
+        self.image_index = None  # ImageIndex of the content's images directory, if built
+        self.image_formats = None  # Per-format image policies (GIF size limit, SVG rasterization)
+        self.image_optimization = {}  # max_width / png_compress / jpeg_quality applied before upload
+        self.optimized_images = 0  # Images resized or recompressed before upload
+        self.bytes_saved = 0  # Upload size saved by optimization
+        self._optimization_lock = threading.Lock()
+        self._counter_lock = threading.Lock()  # Counters shared by concurrently uploading articles
+        self.image_placeholder = DEFAULT_IMAGE_PLACEHOLDER  # Text of the alert left for a failed image
+        self.readback_cache = None  # ReadBackCache of fetched article content, if enabled
+        self.image_assets = []  # Image assets uploaded by the current run (ID mapping)
+        self.uploaded_hashes = {}  # Image content hash -> upload response, so repeated screenshots upload once
+        self.deduplicated_images = 0  # Image references served by an earlier upload of the same content
+    
+    def _journaled(self, kind: str, create):
+        """A backend's create_<kind> (or upload_file) that also records what it creates in the journal"""
+        def journaled(*args, **kwargs):
+            obj = create(*args, **kwargs)
+            self._record(kind, obj.get('file', {}) if kind == 'file' else obj)
+            for chapter in obj.get('chapters', []) if kind == 'manual' else []:
+                self._record('chapter', chapter)
+            return obj
+        return journaled
     
     def upload_image(self, site_id: str, article_id: str, 
                    image_path: Path) -> Dict:
@@ -330,36 +556,102 @@ class ScreenStepsAPI:
              -F "type=ImageAsset" \
              -F "file=@image.png"
         """
-        with open(image_path, 'rb') as f:
-            # Prepare multipart form data (equivalent to curl -F flags)
-            files = {
-                'type': (None, 'ImageAsset'),  # -F "type=ImageAsset"
-                'file': (image_path.name, f, 'image/png')  # -F "file=@image.png"
-            }
-            
-            # Use the _request method which handles rate limiting
-            response = self._request('POST', f'sites/{site_id}/files', 
-                                   files=files)
-            
-            # Add delay after successful upload
-            # ScreenSteps rate limit: 8 files per 10 seconds for image uploads
-            time.sleep(1.25)
-            return response.json()
-    
-    def update_article_contents(self, site_id: str, article_id: str, 
-                               title: str, content_blocks: List[Dict], 
-                               publish: bool = True) -> Dict:
-        """Update article contents with content blocks"""
-        data = {
-            'article': {
-                'title': title,
-                'content_blocks': content_blocks,
-                'publish': publish
-            }
-        }
-        response = self._request('POST', f'sites/{site_id}/articles/{article_id}/contents', 
-                                json=data)
-        return response.json().get('article', {})
+        with tempfile.TemporaryDirectory(prefix='vlp2ss-image-') as temp_dir:
+            # GIFs over the size limit raise ImageFormatError; SVGs may be rasterized to a PNG
+            image_path = apply_format_policy(image_path, self.image_formats, Path(temp_dir))
+            if self.image_optimization:
+                optimized = optimize_image(image_path, Path(temp_dir), **self.image_optimization)
+                if optimized != image_path:
+                    with self._optimization_lock:
+                        self.optimized_images += 1
+                        self.bytes_saved += max(0, image_path.stat().st_size - optimized.stat().st_size)
+                    image_path = optimized
+            return self.upload_file(site_id, image_path, 'ImageAsset')
+    
+    def get_article_cached(self, site_id: str, article_id: str, updated_at: Optional[str] = None) -> Dict:
+        """Get an article from the read-back cache if unchanged since updated_at, else fetch it"""
+        cache = self.readback_cache
+        if cache is not None:
+            article = cache.get(article_id, updated_at)
+            if article is not None:
+                return article
+        article = self.get_article(site_id, article_id)
+        if cache is not None:
+            cache.put(article)
+        return article
+    
+    def find_image(self, article_images_dir: Path, filename: str) -> Path:
+        """Resolve an article image through the image index (the returned path may not exist)"""
+        if self.image_index is not None:
+            found = self.image_index.lookup(filename, within=article_images_dir.name)
+            if found:
+                return found
+        return article_images_dir / filename
+    
+    def upload_article_images(self, site_id: str, article_id: str, article_data: Dict,
+                              article_images_dir: Path) -> Dict[Path, object]:
+        """Upload every image referenced by an article's steps
+        
+        Uses a bounded pool of upload_concurrency workers; all workers share the
+        file upload rate limiter. Returns a map of image path to the API response,
+        or to the exception raised while uploading it.
+        """
+        image_paths = []
+        for step in article_data.get('steps', []):
+            for match in BLOCK_REGEX.finditer(step.get('content', '')):
+                block_html = match.group(0)
+                if not block_html.startswith('<img') or 'inline-icon' in block_html:
+                    continue
+                img_match = re.search(r'<img[^>]+src="([^"]+)"', block_html)
+                if img_match:
+                    filename = unescape(img_match.group(1)).split('/')[-1].split('?')[0]
+                    image_path = self.find_image(article_images_dir, filename)
+                    if image_path.exists() and image_path not in image_paths:
+                        image_paths.append(image_path)
+        
+        # Identical screenshots are uploaded once per manual and share the asset
+        paths_by_hash = {}
+        for image_path in image_paths:
+            paths_by_hash.setdefault(self.image_hash(image_path), []).append(image_path)
+        pending = [digest for digest in paths_by_hash if digest not in self.uploaded_hashes]
+        
+        def upload_one(digest: str):
+            try:
+                return self.upload_image(site_id, article_id, paths_by_hash[digest][0])
+            except Exception as e:
+                return e
+        
+        workers = max(1, min(self.upload_concurrency, len(pending)))
+        if workers <= 1:
+            responses = [upload_one(digest) for digest in pending]
+        else:
+            with ThreadPoolExecutor(max_workers=workers) as pool:
+                responses = list(pool.map(upload_one, pending))
+        
+        results = {digest: self.uploaded_hashes.get(digest) for digest in paths_by_hash}
+        for digest, response in zip(pending, responses):
+            results[digest] = response
+            if isinstance(response, dict) and response.get('file', {}).get('id'):
+                self.uploaded_hashes[digest] = response
+        with self._counter_lock:
+            self.deduplicated_images += len(image_paths) - len(pending)
+        return {path: results[digest] for digest, paths in paths_by_hash.items() for path in paths}
+    
+    @staticmethod
+    def image_hash(image_path: Path) -> str:
+        """SHA-256 of an image's content (its path if unreadable, so it is never merged)"""
+        digest = hashlib.sha256()
+        try:
+            with open(image_path, 'rb') as f:
+                for chunk in iter(lambda: f.read(1024 * 1024), b''):
+                    digest.update(chunk)
+        except OSError:
+            return f"path:{image_path}"
+        return digest.hexdigest()
+    
+    def image_placeholder_text(self, filename: str, alt: str = '') -> str:
+        """HTML text of the placeholder for an image that could not be uploaded"""
+        return escape(self.image_placeholder).replace('{file}', escape(filename)).replace('{alt}', escape(alt))
     
     def generate_content_blocks(self, article_data: Dict, images_dir: Path, 
                                site_id: str, article_id: str, article_vlp_id: str,
@@ -377,6 +669,10 @@ class ScreenStepsAPI:
         # Images are stored in article-specific subdirectories
         article_images_dir = images_dir / article_vlp_id
         
+        # Upload all of the article's images up front (in parallel when enabled)
+        upload_results = self.upload_article_images(site_id, article_id, article_data, article_images_dir)
+        attachment_urls = {}  # Attachment path -> uploaded URL (or None), so repeated links upload once
+        
         for step in article_data.get('steps', []):
             # Create StepContent block
             step_uuid = generate_uuid()
@@ -387,7 +683,7 @@ class ScreenStepsAPI:
                 'depth': 0,
                 'sort_order': sort_order,
                 'content_block_ids': [],
-                'anchor_name': slugify(step['title']),
+                'anchor_name': step_anchor(step),
                 'auto_numbered': False,
                 'foldable': False
             }
@@ -397,15 +693,82 @@ class ScreenStepsAPI:
             # New sequential parsing logic to preserve content order
             html_content = step.get('content', '')
             
-            block_regex = re.compile(r'(<div class="html-embed">.*?</div>|<div class="screensteps-styled-block".*?>.*?</div>|<img[^>]+src="[^"]+"[^>]*>)', re.DOTALL)
+            def upload_attachment(match):
+                """Upload a linked attachment and point the link at the uploaded file"""
+                file_path = article_images_dir / unescape(match.group(2)).split('/')[-1]
+                if file_path.suffix.lower() in IMAGE_EXTENSIONS or not file_path.is_file():
+                    return match.group(0)  # Not a file the converter copied
+                if file_path not in attachment_urls:
+                    try:
+                        attachment_urls[file_path] = self.upload_file(site_id, file_path)['file']['url']
+                        self.logger.substep(f"Uploaded attachment: {file_path.name}")
+                    except Exception as e:
+                        self.logger.warning(f"Failed to upload attachment {file_path}: {e}")
+                        attachment_urls[file_path] = None
+                        skipped_images.append({
+                            'image_path': str(file_path), 'chapter_title': chapter_title,
+                            'article_title': article_data.get('title', 'Unknown'), 'step_title': step.get('title', 'Unknown'),
+                            'article_id': article_vlp_id
+                        })
+                if not attachment_urls[file_path]:
+                    return f'{match.group(4)} <strong>({ATTACHMENT_ERROR_TEXT}: {escape(file_path.name)})</strong>'
+                return f'<a{match.group(1)}href="{escape(attachment_urls[file_path])}"{match.group(3)}>{match.group(4)}</a>'
+            
+            if '<a' in html_content:
+                html_content = LOCAL_FILE_LINK_REGEX.sub(upload_attachment, html_content)
+            lists = list_spans(html_content)
+            
+            def inline_list_images(text: str) -> str:
+                """Point images kept inside a list at their uploaded asset"""
+                def replace(match):
+                    img_tag = match.group(0)
+                    src_match = re.search(r'src="([^"]+)"', img_tag)
+                    if 'inline-icon' in img_tag or not src_match or \
+                            src_match.group(1).startswith(('http://', 'https://', 'data:')):
+                        return img_tag
+                    filename = unescape(src_match.group(1)).split('/')[-1].split('?')[0]
+                    image_path = self.find_image(article_images_dir, filename)
+                    image_response = upload_results.get(image_path)
+                    if isinstance(image_response, dict) and image_response.get('file', {}).get('url'):
+                        with self._counter_lock:
+                            uploaded_images_count[0] += 1
+                            self.image_assets.append({
+                                'step_id': step.get('id'), 'step_vlp_id': step.get('vlp_id'),
+                                'file_name': filename, 'screensteps_id': str(image_response['file'].get('id')),
+                                'screensteps_article_id': str(article_id)
+                            })
+                        img_tag = img_tag.replace(src_match.group(0), f'src="{escape(image_response["file"]["url"])}"')
+                        # Images in lists stay inline, so a clickable screenshot gets its anchor back
+                        link_match = re.search(r'\sdata-href="([^"]*)"', img_tag)
+                        if link_match:
+                            img_tag = f'<a href="{link_match.group(1)}">{img_tag.replace(link_match.group(0), "")}</a>'
+                        return img_tag
+                    self.logger.warning(f"Image in list could not be uploaded, skipping: {image_path}")
+                    skipped_images.append({
+                        'image_path': str(image_path), 'chapter_title': chapter_title,
+                        'article_title': article_data.get('title', 'Unknown'), 'step_title': step.get('title', 'Unknown'),
+                        'article_id': article_vlp_id
+                    })
+                    alt_match = re.search(r'\balt="([^"]*)"', img_tag)
+                    alt = unescape(alt_match.group(1)).strip() if alt_match else ''
+                    return f'<strong>{self.image_placeholder_text(filename, alt)}</strong>'
+                return IMG_TAG_REGEX.sub(replace, text) if '<img' in text else text
             
             last_index = 0
             
-            for match in block_regex.finditer(html_content):
+            for match in BLOCK_REGEX.finditer(html_content):
+                # Inline icons stay part of the surrounding text block
+                if 'inline-icon' in match.group(0) and match.group(0).startswith('<img'):
+                    continue
+                
                 start, end = match.span()
                 
+                # Lists are never split: images and blocks inside one stay in its text block
+                if any(list_start <= start < list_end for list_start, list_end in lists):
+                    continue
+                
                 # 1. Process text before the special block
-                text_before = html_content[last_index:start]
+                text_before = inline_list_images(html_content[last_index:start])
                 plain_text_before = re.sub(r'<[^>]+>', '', text_before).strip()
                 if plain_text_before:
                     text_uuid = generate_uuid()
@@ -425,12 +788,18 @@ class ScreenStepsAPI:
                     if img_match:
                         src = unescape(img_match.group(1))
                         filename = src.split('/')[-1].split('?')[0]
-                        image_path = article_images_dir / filename
+                        alt_match = re.search(r'\balt="([^"]*)"', block_html)
+                        alt_tag = unescape(alt_match.group(1)).strip() if alt_match else ""
+                        # Link of a clickable screenshot (set by the converter), else the full-size image
+                        link_match = re.search(r'\bdata-href="([^"]*)"', block_html)
+                        image_path = self.find_image(article_images_dir, filename)
                         
                         image_processed = False
                         if image_path.exists():
                             try:
-                                image_response = self.upload_image(site_id, article_id, image_path)
+                                image_response = upload_results.get(image_path)
+                                if isinstance(image_response, Exception):
+                                    raise image_response
                                 if image_response and 'file' in image_response and 'id' in image_response['file']:
                                     # ... (code to create image_block) ...
                                     image_asset_id = image_response['file']['id']
@@ -439,13 +808,20 @@ class ScreenStepsAPI:
                                         'uuid': image_uuid, 'type': 'ImageContentBlock', 'asset_file_name': filename,
                                         'image_asset_id': image_asset_id, 'width': image_response['file'].get('width', 800),
                                         'height': image_response['file'].get('height', 600), 'depth': 1, 'sort_order': sort_order,
-                                        'alt_tag': "", 'url': image_response['file'].get('url', '')
+                                        'alt_tag': alt_tag,
+                                        'url': unescape(link_match.group(1)) if link_match else image_response['file'].get('url', '')
                                     }
                                     content_blocks.append(image_block)
                                     step_block['content_block_ids'].append(image_uuid)
                                     sort_order += 1
-                                    uploaded_images_count[0] += 1
                                     image_processed = True
+                                    with self._counter_lock:
+                                        uploaded_images_count[0] += 1
+                                        self.image_assets.append({
+                                            'step_id': step.get('id'), 'step_vlp_id': step.get('vlp_id'),
+                                            'file_name': filename, 'screensteps_id': str(image_asset_id),
+                                            'screensteps_article_id': str(article_id)
+                                        })
                                 else:
                                     self.logger.warning(f"Invalid API response for image {filename}")
                             except Exception as e:
@@ -454,14 +830,16 @@ class ScreenStepsAPI:
                              self.logger.warning(f"Image not found, skipping: {image_path}")
 
                         if not image_processed:
-                            # Add placeholder alert block if image failed to process
+                            # Alert block at the image's position, so authors can find and fix the gap in place
                             skipped_images.append({
                                 'image_path': str(image_path), 'chapter_title': chapter_title, 
-                                'article_title': article_data.get('title', 'Unknown'), 'step_title': step.get('title', 'Unknown')
+                                'article_title': article_data.get('title', 'Unknown'), 'step_title': step.get('title', 'Unknown'),
+                                'article_id': article_vlp_id
                             })
                             placeholder_uuid = generate_uuid()
                             placeholder_block = {
-                                'uuid': placeholder_uuid, 'type': 'TextContent', 'body': '<p>ERROR IMPORTING IMAGE - PLEASE RE-CREATE SCREENSHOT</p>',
+                                'uuid': placeholder_uuid, 'type': 'TextContent',
+                                'body': f'<p>{self.image_placeholder_text(filename, alt_tag)}</p>',
                                 'style': 'alert', 'depth': 1, 'sort_order': sort_order, 'anchor_name': '', 
                                 'auto_numbered': False, 'foldable': False
                             }
@@ -470,10 +848,29 @@ class ScreenStepsAPI:
                             sort_order += 1
 
                 elif block_html.startswith('<div class="html-embed"'):
+                    embed_style = 'html-embed'
+                    video_match = LOCAL_VIDEO_REGEX.search(block_html)
+                    if video_match:
+                        # A video from the export: upload it and point the player at the asset
+                        video_name = unescape(video_match.group(1)).split('/')[-1]
+                        video_path = article_images_dir / video_name
+                        try:
+                            video_url = self.upload_file(site_id, video_path)['file']['url']
+                            block_html = block_html.replace(video_match.group(0),
+                                                            video_match.group(0).replace(video_match.group(1), escape(video_url)))
+                        except Exception as e:
+                            self.logger.warning(f"Failed to upload video {video_path}: {e}")
+                            skipped_images.append({
+                                'image_path': str(video_path), 'chapter_title': chapter_title,
+                                'article_title': article_data.get('title', 'Unknown'), 'step_title': step.get('title', 'Unknown'),
+                                'article_id': article_vlp_id
+                            })
+                            block_html = f'<p>{VIDEO_ERROR_TEXT} ({escape(video_name)})</p>'
+                            embed_style = 'alert'
                     embed_uuid = generate_uuid()
                     embed_block = {
                         'uuid': embed_uuid, 'type': 'TextContent', 'body': block_html, 'depth': 1,
-                        'sort_order': sort_order, 'style': 'html-embed', 'show_copy_clipboard': False
+                        'sort_order': sort_order, 'style': embed_style, 'show_copy_clipboard': False
                     }
                     content_blocks.append(embed_block)
                     step_block['content_block_ids'].append(embed_uuid)
@@ -484,19 +881,38 @@ class ScreenStepsAPI:
                     if style_match:
                         style = style_match.group(1)
                         inner_body = style_match.group(2)
-                        block_uuid = generate_uuid()
-                        block = {
-                            'uuid': block_uuid, 'type': 'TextContent', 'body': inner_body, 'depth': 1,
-                            'sort_order': sort_order, 'style': style, 'show_copy_clipboard': False
-                        }
-                        content_blocks.append(block)
-                        step_block['content_block_ids'].append(block_uuid)
-                        sort_order += 1
+                        title_match = re.match(r'<p><strong>([^<]*)</strong></p>', inner_body)
+                        if FOLDABLE_BLOCK_REGEX.match(block_html) and title_match:
+                            # Lab credentials or a quiz answer folded into a collapsed sub-step titled by the block
+                            fold_uuid = generate_uuid()
+                            content_blocks.append({
+                                'uuid': fold_uuid, 'type': 'StepContent', 'title': unescape(title_match.group(1)),
+                                'depth': 1, 'sort_order': sort_order, 'content_block_ids': [],
+                                'anchor_name': '', 'auto_numbered': False, 'foldable': True
+                            })
+                            step_block['content_block_ids'].append(fold_uuid)
+                            sort_order += 1
+                            block_uuid = generate_uuid()
+                            content_blocks.append({
+                                'uuid': block_uuid, 'type': 'TextContent', 'body': inner_body[title_match.end():],
+                                'depth': 2, 'sort_order': sort_order, 'style': style, 'show_copy_clipboard': False
+                            })
+                            content_blocks[-2]['content_block_ids'].append(block_uuid)
+                            sort_order += 1
+                        else:
+                            block_uuid = generate_uuid()
+                            block = {
+                                'uuid': block_uuid, 'type': 'TextContent', 'body': inner_body, 'depth': 1,
+                                'sort_order': sort_order, 'style': style, 'show_copy_clipboard': style == 'code'
+                            }
+                            content_blocks.append(block)
+                            step_block['content_block_ids'].append(block_uuid)
+                            sort_order += 1
 
                 last_index = end
 
             # 3. Process any remaining text after the last special block
-            remaining_text = html_content[last_index:]
+            remaining_text = inline_list_images(html_content[last_index:])
             plain_remaining_text = re.sub(r'<[^>]+>', '', remaining_text).strip()
             if plain_remaining_text:
                 text_uuid = generate_uuid()
@@ -511,35 +927,69 @@ class ScreenStepsAPI:
         return content_blocks
 
 class ScreenStepsUploader:
-    """Upload converted content to ScreenSteps"""
+    """Upload converted content to ScreenSteps
+    
+    backend replaces the HTTP client for ScreenSteps calls, e.g. with another
+    service or a test fake (any ScreenStepsBackend).
+    """
     
-    def __init__(self, account: str, user: str, token: str, verbose: bool = False, suffix: bool = False):
+    _shared_log_file = None  # Log file configured by the first uploader in this process
+    
+    def __init__(self, account: str, user: str, token: str, verbose: bool = False, suffix: bool = False,
+                 options: Optional[Dict] = None, backend: Optional[ScreenStepsBackend] = None):
         self.verbose = verbose
+        self.options = options or {}
         self.setup_logging(verbose)
         self.logger = logging.getLogger(__name__)
-        self.api = ScreenStepsAPI(account, user, token, self)
+        self.api = ScreenStepsAPI(account, user, token, self, base_url=self.options.get('base_url'), backend=backend)
         self.api.verbose = verbose  # Pass verbose flag to API client
+        self.api.upload_concurrency = self.options.get('upload_concurrency', 1)
+        if self.options.get('file_rate_limiter'):
+            self.api.file_rate_limiter = self.options['file_rate_limiter']
+        if self.options.get('request_rate_limiter'):
+            self.api.request_rate_limiter = self.options['request_rate_limiter']
+        # Image workers of every concurrent article plus one slot so structural calls
+        # never wait behind a full pool
+        self.api.scheduler = self.options.get('request_scheduler') or \
+            RequestScheduler(self.api.upload_concurrency * self.options.get('article_concurrency', 1) + 1)
+        if self.options.get('retry_policy'):
+            self.api.retry_policy = self.options['retry_policy']
+        # --operation-log: every call that changes content, of any command (uploads log next to their content)
+        if self.options.get('operation_log'):
+            self.api.operation_log = open_operation_log(Path(self.options['operation_log']), account)
+        # --record-cassette / --replay-cassette: HTTP exchanges to or from a cassette file
+        if self.options.get('cassette'):
+            use_cassette(self.api, Path(self.options['cassette']), self.options['cassette_mode'])
+        self.api.compress_requests = self.options.get('compress_requests', False)
+        self.api.image_formats = self.options.get('image_formats')
+        self.api.image_optimization = self.options.get('image_optimization') or {}
+        self.api.image_placeholder = self.options.get('image_placeholder') or DEFAULT_IMAGE_PLACEHOLDER
+        self.api.configure_transport(
+            proxy=self.options.get('proxy'),
+            ca_bundle=self.options.get('ca_bundle'),
+            insecure=self.options.get('insecure_skip_verify', False)
+        )
         self.image_map = {}  # Map old image paths to new URLs
-        # Progress tracking
-        self.start_time = time.time()
-        self.total_manuals = 0
-        self.total_chapters = 0
-        self.total_articles = 0
-        self.total_images = 0
-        self.current_manual = 0
-        self.current_chapter = 0
-        self.current_article = 0
-        self.processed_articles = 0
-        self.processed_images = 0
+        self.tracker = ProgressTracker()  # Thread-safe progress counters
         self.suffix = suffix
+        self.label = self.options.get('label', '')  # Prefix for console output in batch uploads
+        # immediate: publish as created, after-verify: publish once verified, never: leave drafts
+        self.publish_strategy = self.options.get('publish_strategy', 'after-verify')
+        self.article_snippets = self.options.get('article_snippets') or {}  # head/foot HTML added to every article
+        self.metadata_tags = self.options.get('metadata_tags', False)  # Tag articles with their lab's VLP metadata
+        self.tags = self.options.get('tags') or []  # --tag: fixed tags for every article
+        self.tag_rules = self.options.get('tag_rules') or []  # article_tags: (title pattern, tags) rules
+        self.publish_on_create = self.publish_strategy == 'immediate'
+        self.run_report = {'articles': [], 'skipped_images': []}  # Results of the current upload (summary.json)
     
     def setup_logging(self, verbose: bool):
-        """Configure logging"""
-        log_dir = Path("logs")
-        log_dir.mkdir(exist_ok=True)
+        """Configure logging (once per process; later uploaders share the log file)"""
+        if ScreenStepsUploader._shared_log_file:
+            self.log_file = ScreenStepsUploader._shared_log_file
+            return
         
-        timestamp = datetime.now().strftime("%Y%m%d_%H%M%S")
-        log_file = log_dir / f"screensteps_upload_{timestamp}.log"
+        log_file = new_log_file(Path(self.options.get('log_dir', DEFAULT_LOG_DIR)), "screensteps_upload",
+                                self.options.get('log_keep', DEFAULT_LOG_KEEP))
         
         # File handler
         file_handler = logging.FileHandler(log_file)
@@ -550,9 +1000,12 @@ class ScreenStepsUploader:
         )
         file_handler.setFormatter(file_formatter)
         
-        # Console handler
+        # Console handler (errors only with --quiet; stdout is silenced by main)
         console_handler = logging.StreamHandler()
-        console_handler.setLevel(logging.INFO if not verbose else logging.DEBUG)
+        if self.options.get('quiet'):
+            console_handler.setLevel(logging.ERROR)
+        else:
+            console_handler.setLevel(logging.INFO if not verbose else logging.DEBUG)
         console_formatter = logging.Formatter('%(message)s')
         console_handler.setFormatter(console_formatter)
         
@@ -563,6 +1016,11 @@ class ScreenStepsUploader:
         logger.addHandler(console_handler)
         
         self.log_file = log_file
+        ScreenStepsUploader._shared_log_file = log_file
+    
+    def _labeled(self, message: str) -> str:
+        """Prefix a message with the manual label when uploading several manuals at once"""
+        return f"[{self.label}] {message}" if self.label else message
     
     def header(self, message: str):
         """Print header"""
@@ -573,32 +1031,38 @@ class ScreenStepsUploader:
     
     def success(self, message: str):
         """Print success"""
+        message = self._labeled(message)
         print(f"{Colors.OKGREEN}✓ {message}{Colors.ENDC}")
         self.logger.info(f"SUCCESS: {message}")
     
     def info(self, message: str):
         """Print info"""
+        message = self._labeled(message)
         if self.verbose:
             print(f"{Colors.OKCYAN}ℹ {message}{Colors.ENDC}")
         self.logger.info(message)
     
     def warning(self, message: str):
         """Print warning"""
+        message = self._labeled(message)
         print(f"{Colors.WARNING}⚠ {message}{Colors.ENDC}")
         self.logger.warning(message)
     
     def error(self, message: str):
         """Print error"""
+        message = self._labeled(message)
         print(f"{Colors.FAIL}✗ {message}{Colors.ENDC}")
         self.logger.error(message)
     
     def step(self, step_num: int, total_steps: int, message: str):
         """Print step"""
+        message = self._labeled(message)
         print(f"{Colors.OKBLUE}[{step_num}/{total_steps}] {message}{Colors.ENDC}")
         self.logger.info(f"STEP [{step_num}/{total_steps}]: {message}")
     
     def substep(self, message: str, indent: int = 1):
         """Print substep"""
+        message = self._labeled(message)
         if self.verbose:
             indent_str = "  " * indent
             print(f"{indent_str}→ {message}")
@@ -606,50 +1070,20 @@ class ScreenStepsUploader:
     
     def set_totals(self, manuals: int = 1, chapters: int = 0, articles: int = 0, images: int = 0):
         """Set total counts for progress tracking"""
-        self.total_manuals = manuals
-        self.total_chapters = chapters
-        self.total_articles = articles
-        self.total_images = images
+        self.tracker.set_totals(manuals=manuals, chapters=chapters, articles=articles, images=images)
     
     def get_progress_string(self) -> str:
         """Generate progress percentage string"""
-        manual_pct = (self.current_manual / self.total_manuals * 100) if self.total_manuals > 0 else 0
-        chapter_pct = (self.current_chapter / self.total_chapters * 100) if self.total_chapters > 0 else 0
-        article_pct = (self.current_article / self.total_articles * 100) if self.total_articles > 0 else 0
-        return f"[ Manual: {manual_pct:.0f}%, Chapter: {chapter_pct:.0f}%, Article: {article_pct:.0f}% ]"
+        return self.tracker.progress_string()
     
     def estimate_time_remaining(self) -> str:
         """Estimate remaining time based on progress"""
-        if self.processed_articles == 0:
-            return "Calculating..."
-        
-        elapsed = time.time() - self.start_time
-        
-        # Use weighted formula: articles are primary driver, images add time
-        # Based on user data: ~10-15 seconds per article + ~2 seconds per image
-        avg_time_per_article = 12.5  # seconds
-        avg_time_per_image = 2.0     # seconds
-        
-        # Calculate estimated total time
-        estimated_total = (self.total_articles * avg_time_per_article) + (self.total_images * avg_time_per_image)
-        
-        # Calculate progress-based estimate
-        articles_remaining = self.total_articles - self.processed_articles
-        images_remaining = self.total_images - self.processed_images
-        
-        estimated_remaining = (articles_remaining * avg_time_per_article) + (images_remaining * avg_time_per_image)
-        
-        # Format time
-        minutes, seconds = divmod(int(estimated_remaining), 60)
-        if minutes > 0:
-            return f"~{minutes}m {seconds}s"
-        else:
-            return f"~{seconds}s"
+        return self.tracker.estimate_time_remaining()
     
     def progress(self, message: str):
         """Print a progress message with percentages and time estimate"""
-        progress_str = self.get_progress_string()
-        time_est = self.estimate_time_remaining()
+        message = self._labeled(message)
+        progress_str, time_est = self.tracker.status()
         print(f"{Colors.OKBLUE}{progress_str} {message} {Colors.OKCYAN}[ETA: {time_est}]{Colors.ENDC}")
         self.logger.info(f"{progress_str} {message} [ETA: {time_est}]")
     
@@ -712,10 +1146,455 @@ class ScreenStepsUploader:
     
     def upload(self, content_dir: Path, site_id: str, 
                create_new: bool = True) -> Dict:
+        """Upload content to ScreenSteps, journaling every created object
+        
+        With the 'atomic' option, a failed run rolls back everything it created.
+        """
+        journal = UploadJournal(content_dir / JOURNAL_FILE, site_id)
+        self.api.journal = journal
+        if self.api.operation_log is None and self.options.get('record_operations', True):
+            self.api.operation_log = open_operation_log(content_dir / OPERATION_LOG_FILE, self.api.account)
+        self.run_report = {
+            'site_id': str(site_id),
+            'started_at': datetime.now().isoformat(),
+            'manual_id': None,
+            'articles': [],
+            'skipped_images': [],
+            'unresolved_links': []
+        }
+        started = time.time()
+        try:
+            result = self._upload(content_dir, site_id, create_new)
+        except BaseException as e:
+            self._write_summary(content_dir, 'failed', started, error=str(e))
+            journal.set_status('failed')
+            if self.options.get('atomic') and journal.entries:
+                self.error("Upload failed - rolling back created content (--atomic)")
+                self.api.journal = None
+                self.rollback(site_id, journal.entries)
+                journal.set_status('rolled_back')
+            else:
+                self.warning(f"Upload failed - created objects are listed in {journal.path}")
+            raise
+        finally:
+            self.api.journal = None
+        
+        journal.set_status('complete')
+        self._write_summary(content_dir, 'complete', started, result=result)
+        return result
+    
+    def _write_summary(self, content_dir: Path, status: str, started: float,
+                       result: Optional[Dict] = None, error: Optional[str] = None):
+        """Add the results of this upload to summary.json in the content directory"""
+        summary_file = content_dir / SUMMARY_FILE
+        summary = {}
+        if summary_file.exists():
+            try:
+                with open(summary_file, 'r', encoding='utf-8') as f:
+                    summary = json.load(f)
+            except (OSError, ValueError) as e:
+                self.warning(f"Replacing unreadable {summary_file.name}: {e}")
+        
+        report = dict(self.run_report)
+        articles = report['articles']
+        manual_id = report.get('manual_id')
+        report.update({
+            'status': status,
+            'finished_at': datetime.now().isoformat(),
+            'duration_seconds': round(time.time() - started, 1),
+            'manual_url': self.api.manual_url(manual_id) if manual_id else None,
+            'counts': {
+                'chapters': len(report.get('chapters', {})),
+                'articles': sum(1 for a in articles if a['status'] != 'failed'),
+                'failed_articles': sum(1 for a in articles if a['status'] == 'failed'),
+                'images_uploaded': (result or {}).get('images_uploaded', 0),
+                'images_skipped': len(report['skipped_images']),
+                'images_deduplicated': (result or {}).get('images_deduplicated', 0),
+                'images_optimized': (result or {}).get('images_optimized', 0),
+                'unresolved_links': len(report.get('unresolved_links', [])),
+            },
+        })
+        if error:
+            report['error'] = error
+        
+        summary['generator'] = build_info()
+        summary['upload'] = report
+        try:
+            with open(summary_file, 'w', encoding='utf-8') as f:
+                json.dump(summary, f, indent=2, ensure_ascii=False)
+            self.substep(f"Wrote summary report: {summary_file}")
+        except OSError as e:
+            self.warning(f"Could not write {summary_file}: {e}")
+        
+        # Refresh the conversion's QA report with the upload results
+        if self.options.get('qa_report', True):
+            try:
+                report_file = write_qa_report(content_dir)
+                if report_file:
+                    self.substep(f"Updated QA report: {report_file}")
+            except OSError as e:
+                self.warning(f"Could not write QA report: {e}")
+    
+    def rollback(self, site_id: str, entries: List[Dict]) -> int:
+        """Delete the ScreenSteps objects listed in journal entries
+        
+        Uploaded files are deleted individually. A created manual is deleted as a
+        whole (which removes its chapters and articles); otherwise articles and
+        chapters are deleted in reverse creation order. Returns the number of
+        objects deleted.
+        """
+        self.header("Rolling Back Upload")
+        
+        deleters = {
+            'file': self.api.delete_file,
+            'article': self.api.delete_article,
+            'chapter': self.api.delete_chapter,
+            'manual': self.api.delete_manual,
+        }
+        
+        manual_created = any(e['type'] == 'manual' for e in entries)
+        order = ['file', 'manual'] if manual_created else ['file', 'article', 'chapter']
+        
+        deleted = 0
+        for kind in order:
+            for entry in reversed([e for e in entries if e['type'] == kind]):
+                try:
+                    deleters[kind](site_id, entry['id'])
+                    deleted += 1
+                    self.substep(f"Deleted {kind} {entry['id']} {entry.get('title', '')}".rstrip())
+                except requests.exceptions.HTTPError as e:
+                    if e.response is not None and e.response.status_code == 404:
+                        self.substep(f"{kind.capitalize()} {entry['id']} already deleted")
+                    else:
+                        self.warning(f"Failed to delete {kind} {entry['id']}: {e}")
+        
+        self.success(f"Rollback complete: deleted {deleted} objects")
+        return deleted
+    
+    def manage(self, site_id: str, action: str, kind: str, ids: List[str]) -> Dict:
+        """Delete or unpublish ScreenSteps objects by ID (--delete-article, --unpublish-article, --unpublish-manual)
+        
+        Objects that no longer exist are reported and skipped. Returns the
+        number of objects changed and the IDs that failed.
+        """
+        done = {'delete': 'Deleted', 'unpublish': 'Unpublished'}[action]
+        self.header(f"{action.capitalize()} {kind}s")
+        getters = {'article': self.api.get_article, 'manual': self.api.get_manual}
+        deleters = {'article': self.api.delete_article, 'manual': self.api.delete_manual}
+        updaters = {'article': self.api.update_article, 'manual': self.api.update_manual}
+        
+        changed = 0
+        failed = []
+        for object_id in ids:
+            try:
+                title = getters[kind](site_id, object_id).get('title', '')
+                if action == 'delete':
+                    deleters[kind](site_id, object_id)
+                else:
+                    updaters[kind](site_id, object_id, published=False)
+                changed += 1
+                self.substep(f"{done} {kind} {object_id} '{title}'")
+            except requests.exceptions.HTTPError as e:
+                if e.response is not None and e.response.status_code == 404:
+                    self.warning(f"{kind.capitalize()} {object_id} not found")
+                else:
+                    self.warning(f"Failed to {action} {kind} {object_id}: {e}")
+                failed.append(object_id)
+        
+        if failed:
+            self.warning(f"{done} {changed} {kind}(s), {len(failed)} failed")
+        else:
+            self.success(f"{done} {changed} {kind}(s)")
+        return {'changed': changed, 'failed': failed}
+    
+    def publish_manual(self, site_id: str, manual_id: str, content_dir: Optional[Path] = None) -> Dict:
+        """Publish a manual with all its chapters and articles (--publish-manual), e.g. after a --draft upload
+        
+        With the content directory it was uploaded from, the chapters and
+        articles the converter marked restricted stay unpublished.
+        """
+        self.header("Publishing Manual")
+        restricted = set()
+        if content_dir:
+            id_map = self._load_id_map(content_dir, site_id)
+            toc_file = self._find_toc_file(content_dir)
+            if str(id_map.get('manual_id')) == str(manual_id) and toc_file:
+                with open(toc_file, 'r', encoding='utf-8') as f:
+                    manual_info = json.load(f)['manual']
+                restricted = {str(object_id) for object_id in self._restricted_ids(
+                    manual_info, id_map.get('chapters', {}), id_map.get('articles', {}))}
+            else:
+                self.warning(f"{content_dir} was not uploaded to manual {manual_id}; restricted content is not known")
+        
+        chapter_ids, article_ids = [], []
+        for chapter in self.api.list_chapters(site_id, manual_id):
+            if str(chapter['id']) in restricted:
+                continue
+            chapter_ids.append(str(chapter['id']))
+            article_ids.extend(str(article['id']) for article in self.api.list_articles(site_id, str(chapter['id']))
+                               if str(article['id']) not in restricted)
+        if restricted:
+            self.info(f"Leaving {len(restricted)} restricted chapters/articles unpublished")
+        self._publish_all(site_id, manual_id, chapter_ids, article_ids)
+        return {'chapters': len(chapter_ids), 'articles': len(article_ids)}
+    
+    def retry_failed(self, content_dir: Path, site_id: str, report_path: Optional[Path] = None) -> Optional[Dict]:
+        """Re-upload the articles a previous upload failed or skipped images in (--retry-failed)
+        
+        Reads the upload section of summary.json (or report_path) and re-pushes
+        exactly those articles into the manual they were uploaded to, like
+        --only-article. Returns the upload result, or None when the report
+        lists nothing to retry.
+        """
+        report_path = report_path or content_dir / SUMMARY_FILE
+        with open(report_path, 'r', encoding='utf-8') as f:
+            report = json.load(f).get('upload')
+        if not report:
+            raise ValueError(f"{report_path} holds no upload results")
+        if str(report.get('site_id')) != str(site_id):
+            raise ValueError(f"{report_path} is an upload to site {report.get('site_id')}, not {site_id}")
+        if report.get('status') != 'complete':
+            raise ValueError(f"The upload in {report_path} did not finish ({report.get('error', report.get('status'))}); "
+                             f"resume it with --start-from or --sync instead")
+        id_map = self._load_id_map(content_dir, site_id)
+        if str(id_map.get('manual_id')) != str(report.get('manual_id')):
+            raise ValueError(f"{content_dir} is no longer mapped to manual {report.get('manual_id')}; "
+                             f"re-push the articles with --only-article instead")
+        
+        # Reports from before article IDs were recorded name articles by title
+        targets = [article['id'] for article in report.get('articles', []) if article['status'] == 'failed']
+        targets += [image.get('article_id') or image['article_title'] for image in report.get('skipped_images', [])]
+        targets = list(dict.fromkeys(targets))
+        if not targets:
+            self.success(f"Nothing to retry: {report_path} lists no failed articles or skipped images")
+            return None
+        self.info(f"Retrying {len(targets)} articles with failed uploads or skipped images "
+                  f"in manual {report['manual_id']}")
+        self.options = dict(self.options, update=True, sync=False, only_articles=targets, start_from=None)
+        return self.upload(content_dir, site_id)
+    
+    def _fetch_manual(self, site_id: str, manual_id: str) -> Dict:
+        """A ScreenSteps manual with its chapters in position order, each with its full articles
+        
+        Articles unchanged since the read-back cache (if set) stored them are
+        not downloaded again.
+        """
+        manual = self.api.get_manual(site_id, manual_id)
+        chapters = sorted(manual.get('chapters', []), key=lambda c: c.get('position') or 0)
+        for chapter in chapters:
+            listed = self.api.get_chapter(site_id, str(chapter['id'])).get('articles', [])
+            chapter['articles'] = [self.api.get_article_cached(site_id, str(a['id']), a.get('updated_at'))
+                                   for a in sorted(listed, key=lambda a: a.get('position') or 0)]
+            self.substep(f"Downloaded chapter: {chapter.get('title', chapter['id'])} ({len(listed)} articles)")
+        manual['chapters'] = chapters
+        return manual
+    
+    def download_manual(self, site_id: str, manual_id: str, output_dir: Path) -> Dict:
+        """Save a ScreenSteps manual in the converter's output layout, for backups and as a diff/sync baseline
+        
+        Writes output_dir/<manual title>/ with the table of contents,
+        articles/<id>.json and the image assets under images/<article id>/.
+        Content blocks become step HTML that uploads back to the same blocks.
+        The ID mapping is written too, so --diff, --sync and --update match
+        the backup to the manual it came from.
+        """
+        self.header("Downloading Manual")
+        manual = self._fetch_manual(site_id, manual_id)
+        title = manual.get('title') or f"manual-{manual_id}"
+        content_dir = output_dir / re.sub(r'[\\/:*?"<>|]', '-', title).strip()
+        articles_dir = content_dir / "articles"
+        images_dir = content_dir / "images"
+        articles_dir.mkdir(parents=True, exist_ok=True)
+        images_dir.mkdir(exist_ok=True)
+        self.info(f"Writing {content_dir}")
+        
+        manual_info = {'id': f"ss-manual-{manual_id}", 'vlp_id': None, 'title': title,
+                       'description': manual.get('description') or '', 'chapters': []}
+        chapter_map = {}
+        article_map = {}
+        images = [0, 0]  # Downloaded, failed
+        for chapter_pos, chapter in enumerate(manual['chapters'], 1):
+            chapter_data = {'id': f"ss-chapter-{chapter['id']}", 'vlp_id': None, 'title': chapter.get('title', ''),
+                            'order': chapter_pos, 'description': chapter.get('description') or '', 'articles': []}
+            chapter_map[chapter_data['id']] = str(chapter['id'])
+            for position, article in enumerate(chapter['articles'], 1):
+                article_data = {
+                    'id': f"ss-article-{article['id']}", 'vlp_id': None, 'title': article.get('title', ''),
+                    'description': '', 'position': position, 'vlp_order': None, 'duration_minutes': None,
+                    'restricted': False
+                }
+                article_images_dir = images_dir / article_data['id']
+                article_images_dir.mkdir(exist_ok=True)
+                article_data['steps'] = self._blocks_to_steps(article, article_data['id'], article_images_dir, images)
+                article_map[article_data['id']] = str(article['id'])
+                chapter_data['articles'].append(article_data)
+                with open(articles_dir / f"{article_data['id']}.json", 'w', encoding='utf-8') as f:
+                    json.dump(article_data, f, indent=2, ensure_ascii=False)
+            manual_info['chapters'].append(chapter_data)
+        
+        toc = {
+            'generator': build_info(),
+            'source': {'account': self.api.account, 'site_id': str(site_id), 'manual_id': str(manual_id),
+                       'downloaded_at': datetime.now().isoformat()},
+            'manual': manual_info
+        }
+        with open(content_dir / f"{manual_info['id']}.json", 'w', encoding='utf-8') as f:
+            json.dump(toc, f, indent=2, ensure_ascii=False)
+        self.api.image_assets = []
+        self._save_id_map(content_dir, site_id, manual_id, chapter_map, article_map, manual_info)
+        
+        articles = len(article_map)
+        self.success(f"Downloaded {len(chapter_map)} chapters, {articles} articles and {images[0]} images")
+        if images[1]:
+            self.warning(f"{images[1]} images could not be downloaded and keep their ScreenSteps URL")
+        return {'manual_id': str(manual_id), 'output': str(content_dir), 'chapters': len(chapter_map),
+                'articles': articles, 'images_downloaded': images[0], 'images_skipped': images[1]}
+    
+    def _blocks_to_steps(self, article: Dict, article_id: str, article_images_dir: Path, images: List[int]) -> List[Dict]:
+        """Converted steps (as the converter writes them) from a ScreenSteps article's content blocks
+        
+        Images are downloaded into article_images_dir and referenced by file
+        name; images[0]/images[1] count downloaded and failed ones.
+        """
+        steps = []
+        fold = None  # Folded sub-step (credentials block) collecting the blocks below it
+        names = {}  # Image URL -> local file name
+        
+        def local_image(url: str, name: str) -> Optional[str]:
+            if url not in names:
+                name = re.sub(r'[^\w.-]', '_', unquote(name or url.split('/')[-1].split('?')[0])) or 'image'
+                if (article_images_dir / name).exists():
+                    name = f"{len(names) + 1}-{name}"
+                try:
+                    names[url] = name if self.api.download_file(url, article_images_dir / name) else None
+                except requests.exceptions.RequestException as e:
+                    self.logger.warning(f"Failed to download image {url}: {e}")
+                    names[url] = None
+                images[0 if names[url] else 1] += 1
+                if names[url]:
+                    steps[-1]['images'].append({'src': names[url], 'filename': names[url], 'width': '', 'height': ''})
+            return names[url]
+        
+        def inline_image(match):
+            url = unescape(match.group(2))
+            name = local_image(url, '') if url.startswith(('http://', 'https://')) else None
+            return f'{match.group(1)}{escape(name)}{match.group(3)}' if name else match.group(0)
+        
+        for block in sorted(article.get('content_blocks', []), key=lambda b: b.get('sort_order') or 0):
+            block_type = block.get('type')
+            depth = block.get('depth') or 0
+            if block_type == 'StepContent' and depth == 0:
+                fold = None
+                steps.append({'id': f"{article_id}-step-{len(steps) + 1}", 'vlp_id': None,
+                              'title': block.get('title') or '', 'order': len(steps) + 1,
+                              'anchor': block.get('anchor_name') or '', 'content': '', 'images': []})
+                continue
+            if not steps:
+                steps.append({'id': f"{article_id}-step-1", 'vlp_id': None, 'title': '', 'order': 1,
+                              'anchor': '', 'content': '', 'images': []})
+            if fold is not None and depth <= fold['depth']:
+                fold = None
+            if block_type == 'StepContent':
+                fold = {'depth': depth, 'title': block.get('title') or ''}
+                continue
+            if block_type == 'ImageContentBlock':
+                url = block.get('url') or ''
+                name = local_image(url, block.get('asset_file_name')) if url else None
+                alt = escape(block.get('alt_tag') or '')
+                html = f'<img src="{escape(name or url)}" alt="{alt}">'
+            else:
+                body = IMG_TAG_REGEX.sub(lambda m: re.sub(r'(src=")([^"]+)(")', inline_image, m.group(0)),
+                                         block.get('body') or '')
+                style = block.get('style')
+                if fold is not None:
+                    html = (f'<div class="screensteps-styled-block" data-style="{style or "warning"}" data-credentials="foldable">'
+                            f'<p><strong>{escape(fold["title"])}</strong></p>{body}</div>')
+                elif style == 'html-embed':
+                    html = body if body.startswith('<div class="html-embed"') else f'<div class="html-embed">{body}</div>'
+                elif style:
+                    html = f'<div class="screensteps-styled-block" data-style="{style}">{body}</div>'
+                else:
+                    html = body
+            steps[-1]['content'] += html
+        for step in steps:
+            if not step['anchor']:
+                del step['anchor']
+        return steps
+    
+    def diff_manual(self, content_dir: Path, site_id: str, manual_id: Optional[str] = None) -> Dict:
+        """Compare the converted content with a ScreenSteps manual without changing anything
+        
+        Without manual_id, the manual of the stored ID mapping is compared.
+        Text is compared as the upload would send it (overrides, masked
+        secrets and article snippets applied); images by file name. Returns
+        the vlp2ss_diff result.
+        """
+        self.header("Comparing With ScreenSteps")
+        toc_file = self._find_toc_file(content_dir)
+        if not toc_file:
+            raise FileNotFoundError("No TOC file found in content directory")
+        with open(toc_file, 'r', encoding='utf-8') as f:
+            manual_info = json.load(f)['manual']
+        if self.options.get('overrides'):
+            self._apply_overrides(manual_info)
+        if self.options.get('mask_secrets'):
+            self._scan_secrets(manual_info)
+        
+        id_map = self._load_id_map(content_dir, site_id)
+        manual_id = str(manual_id or id_map.get('manual_id') or '')
+        if not manual_id:
+            raise ValueError(f"No manual to compare with: pass --manual-id, or upload once to create {ID_MAP_FILE}")
+        if str(id_map.get('manual_id')) != manual_id:
+            id_map = {}  # The mapping describes another manual; match by title only
+        
+        self.info(f"Downloading manual {manual_id}")
+        cache = ReadBackCache.load(content_dir / READBACK_CACHE_FILE, site_id) \
+            if self.options.get('readback_cache', True) else None
+        self.api.readback_cache = cache
+        try:
+            remote = self._fetch_manual(site_id, manual_id)
+        finally:
+            self.api.readback_cache = None
+            if cache is not None:
+                try:
+                    cache.save()
+                except OSError as e:
+                    self.warning(f"Could not save read-back cache {cache.path}: {e}")
+        
+        local_chapters = []
+        for chapter_data in manual_info['chapters']:
+            articles = []
+            for article_data in chapter_data['articles']:
+                steps = [{'title': step['title'], 'blocks': outline_step_html(step.get('content') or '')}
+                         for step in article_data.get('steps', [])]
+                if steps and self.article_snippets:
+                    values = self._snippet_values(manual_info, chapter_data, article_data,
+                                                  id_map.get('articles', {}).get(article_data['id'], ''))
+                    # {date} as of the last upload, not today
+                    values['date'] = (id_map.get('updated_at') or values['date'])[:10]
+                    for position, snippets in self.article_snippets.items():
+                        blocks = [outline_block('text', block_text(render_snippet(snippet['html'], values)), snippet['style'])
+                                  for snippet in snippets]
+                        if position == 'head':
+                            steps[0]['blocks'][:0] = blocks
+                        else:
+                            steps[-1]['blocks'].extend(blocks)
+                articles.append({'id': article_data['id'], 'title': article_data['title'], 'steps': steps})
+            local_chapters.append({'id': chapter_data['id'], 'title': chapter_data['title'], 'articles': articles})
+        local = {'title': manual_info['title'] + ("-python" if self.suffix else ""), 'chapters': local_chapters}
+        remote = {'id': manual_id, 'title': remote.get('title', ''), 'chapters': [
+            {'id': chapter['id'], 'title': chapter.get('title', ''), 'articles': [
+                {'id': article.get('id'), 'title': article.get('title', ''), 'steps': remote_steps(article)}
+                for article in chapter['articles']]}
+            for chapter in remote['chapters']]}
+        return diff_manuals(local, remote, id_map.get('chapters'), id_map.get('articles'))
+    
+    def _upload(self, content_dir: Path, site_id: str, 
+                create_new: bool = True) -> Dict:
         """Upload content to ScreenSteps"""
         
         # Track skipped images
-        skipped_images = []
+        skipped_images = self.run_report['skipped_images']
         
         # Track uploaded images
         uploaded_images_count = [0]  # Use list to allow modification in nested function
@@ -746,9 +1625,26 @@ class ScreenStepsUploader:
             manual_data = json.load(f)
         
         manual_info = manual_data['manual']
+        
+        # Editorial overrides (--override) apply before anything is scanned or created
+        if self.options.get('overrides'):
+            self._apply_overrides(manual_info)
+        
+        # --include-*/--exclude-*: upload a part of the manual; the ID mapping keeps the rest
+        full_manual_info = manual_info
+        if self.options.get('content_filter'):
+            manual_info = self._apply_filters(manual_info)
+        # --only-article/--start-from: re-push single articles into the existing manual
+        if self.options.get('only_articles') or self.options.get('start_from'):
+            manual_info = self._select_articles(manual_info, content_dir, site_id)
+        
         self.substep(f"Manual: {manual_info['title']}")
         self.substep(f"Chapters: {len(manual_info['chapters'])}")
         
+        # Look for credentials before anything reaches ScreenSteps
+        if self.options.get('secret_scan', True):
+            self._scan_secrets(manual_info)
+        
         # Count totals for progress tracking
         total_chapters = len(manual_info['chapters'])
         total_articles = sum(len(ch['articles']) for ch in manual_info['chapters'])
@@ -759,32 +1655,61 @@ class ScreenStepsUploader:
                     total_images += len(step.get('images', []))
         
         self.set_totals(manuals=1, chapters=total_chapters, articles=total_articles, images=total_images)
-        self.current_manual = 1
+        self.tracker.enter('manual', 1)
         
         # Step 3: Create manual with chapters
         self.step(3, 5, "Creating manual with chapters in ScreenSteps")
         chapter_map = {}
-        
-        if create_new:
+        article_map = {}  # VLP article ID -> existing ScreenSteps article ID (update mode)
+
+        manual_title = manual_info['title']
+        if self.suffix:
+            manual_title += "-python"
+
+        existing = None
+        if self.options.get('chapter_id'):
+            # --chapter-id: every converted article goes into one existing chapter, after its own articles
+            manual_id, chapter_map, article_map, first_position = self._resolve_target_chapter(
+                site_id, manual_info, content_dir)
+            articles = [article for chapter_data in manual_info['chapters'] for article in chapter_data['articles']]
+            positions = {article['id']: first_position + index for index, article in enumerate(articles)}
+            manual_info = dict(manual_info, chapters=[
+                dict(chapter_data, articles=[dict(article, position=positions[article['id']])
+                                             for article in chapter_data['articles']])
+                for chapter_data in manual_info['chapters']])
+            existing = (manual_id, chapter_map, article_map)
+        elif self.options.get('manual_id'):
+            existing = self._resolve_existing_manual(site_id, manual_title, manual_info, content_dir,
+                                                     target_id=self.options['manual_id'])
+        elif self.options.get('update'):
+            existing = self._resolve_existing_manual(site_id, manual_title, manual_info, content_dir)
+        if not existing and (self.options.get('only_articles') or self.options.get('start_from')):
+            raise ValueError("--only-article/--start-from re-upload into an existing manual, but none was found; "
+                             "upload the whole manual first")
+
+        if existing:
+            manual_id, chapter_map, article_map = existing
+        elif create_new:
             # Prepare chapters array for manual creation
             chapters_array = []
             for idx, chapter_data in enumerate(manual_info['chapters'], 1):
-                chapters_array.append({
+                chapter = {
                     'position': chapter_data.get('order', idx),
                     'title': chapter_data['title'],
-                    'published': True
-                })
+                    'published': self._publish_on_create(chapter_data)
+                }
+                if chapter_data.get('description'):
+                    chapter['description'] = chapter_data['description']
+                chapters_array.append(chapter)
             
             # Create manual with all chapters in one call
-            # Manual is unpublished, but chapters remain published
-            manual_title = manual_info['title']
-            if self.suffix:
-                manual_title += "-python"
+            # Unless publishing immediately, everything starts unpublished
             manual = self.api.create_manual(
                 site_id,
                 manual_title,
                 chapters=chapters_array,
-                published=False  # Manual unpublished for review
+                published=self.publish_on_create,
+                description=manual_info.get('description', '')
             )
             manual_id = str(manual['id'])
             self.success(f"Created manual: {manual['title']} (ID: {manual_id})")
@@ -808,88 +1733,180 @@ class ScreenStepsUploader:
                     manual_id,
                     chapter_data['title'],
                     position=chapter_data.get('order', idx),
-                    description=chapter_data.get('description', '')
+                    description=chapter_data.get('description', ''),
+                    published=self._publish_on_create(chapter_data)
                 )
                 chapter_map[chapter_data['id']] = str(chapter['id'])
                 self.substep(f"Created: {chapter['title']}")
         
+        self.run_report['manual_id'] = manual_id
+        self.run_report['chapters'] = chapter_map
+        
         # Step 4: Create articles and add content
         self.step(4, 5, "Creating articles and adding content")
+        expected_blocks = {}  # ScreenSteps article ID -> number of content blocks sent
+        failed_articles = []
+        
         images_dir = content_dir / "images"  # Images are in content_dir/images/article_id/
+        self.api.image_assets = []
+        self.api.uploaded_hashes = {}
+        self.api.deduplicated_images = 0
+        self.api.optimized_images = 0
+        self.api.bytes_saved = 0
+        
+        # Index the images once instead of checking the filesystem per reference
+        image_index = ImageIndex(images_dir, self.options.get('image_ignore', []))
+        self.api.image_index = image_index
+        self.substep(f"Indexed {len(image_index)} images ({image_index.ignored} ignored)")
         
-        for chapter_idx, chapter_data in enumerate(manual_info['chapters'], 1):
-            self.current_chapter = chapter_idx
+        # Create every article placeholder first so the whole structure shows up in
+        # ScreenSteps for early review; images and contents follow article by article
+        existing_articles = set(article_map)
+        placeholders = []  # (chapter ID, article data, position) of the articles to create
+        for chapter_data in manual_info['chapters']:
             chapter_id = chapter_map.get(chapter_data['id'])
             if not chapter_id:
                 continue
-            
-            for article_data in chapter_data['articles']:
-                self.current_article += 1
-                article_vlp_id = article_data['id']  # VLP article ID for finding images
-                
-                # Show progress
-                self.progress(f"Creating article: {article_data['title']}")
-                
-                # Create article placeholder
-                article = self.api.create_article(
-                    site_id,
-                    chapter_id,
-                    article_data['title'],
-                    position=article_data.get('position', self.current_article)
-                )
-                article_id_new = str(article['id'])
-                
-                # Generate content blocks (uploads images internally)
-                content_blocks = self.api.generate_content_blocks(
-                    article_data,
-                    images_dir,
-                    site_id,
-                    article_id_new,
-                    article_vlp_id,  # Pass VLP ID to find images
-                    chapter_title=chapter_data.get('title', 'Unknown'),
-                    skipped_images=skipped_images,
-                    uploaded_images_count=uploaded_images_count
-                )
-                
-                # Update article contents
-                if content_blocks:
-                    try:
-                        self.api.update_article_contents(
-                            site_id,
-                            article_id_new,
-                            article_data['title'],
-                            content_blocks,
-                            publish=True
-                        )
-                        if self.verbose:
-                            self.substep(f"  Updated content with {len(content_blocks)} blocks")
-                    except Exception as e:
-                        self.warning(f"Failed to update article contents: {e}")
-                
-                # Track processed articles and images
-                self.processed_articles += 1
-                # Count images in this article
-                for step in article_data.get('steps', []):
-                    self.processed_images += len(step.get('images', []))
+            for article_position, article_data in enumerate(chapter_data['articles'], 1):
+                if article_data['id'] not in article_map:
+                    placeholders.append((chapter_id, article_data, article_data.get('position', article_position)))
+        
+        def create_placeholder(placeholder) -> Dict:
+            chapter_id, article_data, position = placeholder
+            return self.api.create_article(site_id, chapter_id, article_data['title'], position=position,
+                                           published=self._publish_on_create(article_data))
+        
+        article_workers = max(1, min(self.options.get('article_concurrency', 1), len(placeholders)))
+        if article_workers <= 1:
+            articles = [create_placeholder(placeholder) for placeholder in placeholders]
+        else:
+            with ThreadPoolExecutor(max_workers=article_workers) as pool:
+                articles = list(pool.map(create_placeholder, placeholders))
+        for (_, article_data, _), article in zip(placeholders, articles):
+            article_map[article_data['id']] = str(article['id'])
+        created = len(placeholders)
+        self.substep(f"Created {created} article placeholders")
+        if article_workers > 1:
+            # Placeholders created out of order may have been shifted by ScreenSteps
+            self._restore_article_order(site_id, placeholders, article_map)
+        
+        # Tags of every article; article_tags rules add more per article
+        manual_tags = list(dict.fromkeys((metadata_tags(manual_info) if self.metadata_tags else []) + self.tags))
+        if manual_tags:
+            self.substep(f"Tagging articles with: {', '.join(manual_tags)}")
+        
+        # --sync: content hashes of the last upload to this manual, to skip articles that did not change
+        previous_hashes = {}
+        if self.options.get('sync'):
+            id_map = self._load_id_map(content_dir, site_id)
+            if str(id_map.get('manual_id')) == str(manual_id):
+                previous_hashes = id_map.get('content_hashes', {})
+            else:
+                self.info("No content hashes from a previous upload of this manual - uploading every article")
+        content_hashes = {}
+        unchanged_articles = 0
+        
+        tasks = [(chapter_idx, chapter_data, article_data)
+                 for chapter_idx, chapter_data in enumerate(manual_info['chapters'], 1)
+                 if chapter_map.get(chapter_data['id']) for article_data in chapter_data['articles']]
+        
+        def upload_task(task) -> Dict:
+            chapter_idx, chapter_data, article_data = task
+            if article_data is chapter_data['articles'][0]:
+                self.tracker.enter('chapter', chapter_idx)
+            return self._upload_article(site_id, manual_info, chapter_data, article_data, article_map, images_dir,
+                                        existing_articles, previous_hashes, manual_tags, skipped_images,
+                                        uploaded_images_count)
+        
+        # --article-concurrency: several articles upload at once; results are collected in manual order
+        article_workers = max(1, min(self.options.get('article_concurrency', 1), len(tasks)))
+        if article_workers <= 1:
+            results = [upload_task(task) for task in tasks]
+        else:
+            self.substep(f"Uploading {article_workers} articles at a time")
+            with ThreadPoolExecutor(max_workers=article_workers) as pool:
+                results = list(pool.map(upload_task, tasks))
+        for result in results:
+            record = result['record']
+            self.run_report['articles'].append(record)
+            if record['status'] == 'unchanged':
+                unchanged_articles += 1
+            elif record['status'] == 'failed':
+                failed_articles.append(record['title'])
+            elif record['content_blocks']:
+                expected_blocks[record['screensteps_id']] = record['content_blocks']
+            if result['hash']:
+                content_hashes[record['id']] = result['hash']
+        
+        if self.options.get('sync'):
+            self.success(f"Sync: {unchanged_articles} unchanged articles skipped, "
+                         f"{self.tracker.processed('articles') - unchanged_articles} uploaded")
+        
+        unresolved_links = self.run_report.get('unresolved_links', [])
+        if unresolved_links:
+            self.warning(f"{len(unresolved_links)} links point to articles that were not uploaded "
+                         f"(listed under unresolved_links in {SUMMARY_FILE})")
+        
+        # Report images that were only found by fallback matching
+        self.run_report['fuzzy_image_matches'] = image_index.fuzzy_matches
+        for match in image_index.fuzzy_matches:
+            self.warning(f"Image {match['reference']} matched {match['matched']} by {match['rule']}")
         
         # Final progress update
         self.progress("Upload complete!")
         
+        # Step 5: Publish according to the publish strategy
+        verification_problems = 0
+        if self.publish_strategy == 'after-verify':
+            self.step(5, 5, "Verifying uploaded content before publishing")
+            problems = self._verify_upload(site_id, content_dir, list(dict.fromkeys(chapter_map.values())),
+                                           expected_blocks, failed_articles)
+            verification_problems = len(problems)
+            if problems:
+                self.warning(f"Verification found {len(problems)} problems - content left unpublished")
+                for problem in problems:
+                    self.substep(problem)
+            else:
+                # Restricted chapters and articles (lab credentials) stay drafts for editors only
+                restricted = self._restricted_ids(manual_info, chapter_map, article_map)
+                self._publish_all(site_id, manual_id, [c for c in dict.fromkeys(chapter_map.values()) if c not in restricted],
+                                  [a for a in expected_blocks if a not in restricted])
+        elif self.publish_strategy == 'never':
+            self.info("Publish strategy 'never': manual, chapters and articles left unpublished")
+
+        # Remember which ScreenSteps objects were created so --update can reuse them
+        if manual_info is not full_manual_info:
+            # A filtered upload to the mapped manual keeps the mapping of the content it left out
+            previous = self._load_id_map(content_dir, site_id)
+            if str(previous.get('manual_id')) == str(manual_id):
+                chapter_map = dict(previous.get('chapters', {}), **chapter_map)
+                article_map = dict(previous.get('articles', {}), **article_map)
+                content_hashes = dict(previous.get('content_hashes', {}), **content_hashes)
+        self._save_id_map(content_dir, site_id, manual_id, chapter_map, article_map, full_manual_info, content_hashes)
+
         self.header("Upload Complete!")
         self.success(f"Manual: {manual_info['title']}")
-        self.success(f"Manual created with {self.processed_articles} articles")
+        self.success(f"Manual created with {self.tracker.processed('articles')} articles")
         self.success(f"Images uploaded: {uploaded_images_count[0]}")
+        if self.api.deduplicated_images:
+            self.info(f"Identical screenshots reused instead of uploaded again: {self.api.deduplicated_images}")
+        if self.api.optimized_images:
+            self.info(f"Images resized or recompressed before upload: {self.api.optimized_images} "
+                      f"({self.api.bytes_saved / (1024 * 1024):.1f} MB saved)")
         if skipped_images:
             self.warning(f"Images skipped: {len(skipped_images)}")
         else:
             self.success("Images skipped: 0")
+        if failed_articles:
+            self.warning(f"Articles failed: {len(failed_articles)} - once fixed, re-push them with --retry-failed, "
+                         f"or one by one with --only-article \"{failed_articles[0]}\"")
         self.info(f"Log file: {self.log_file}")
         
         # Display skipped images summary
         if skipped_images:
             self.header("Skipped Images Summary")
             self.warning(f"Total images skipped: {len(skipped_images)}")
-            self.info("Images were replaced with alert: ERROR IMPORTING IMAGE - PLEASE RE-CREATE SCREENSHOT")
+            self.info(f"Images were replaced with alert: {self.api.image_placeholder}")
             print()
             
             # Group by chapter
@@ -916,17 +1933,647 @@ class ScreenStepsUploader:
         
         return {
             'manual_id': manual_id,
+            'manual_url': self.api.manual_url(manual_id),
             'chapters': len(chapter_map),
-            'articles': self.processed_articles
+            'articles': self.tracker.processed('articles'),
+            'images_uploaded': uploaded_images_count[0],
+            'images_skipped': len(skipped_images),
+            'images_deduplicated': self.api.deduplicated_images,
+            'images_optimized': self.api.optimized_images,
+            'articles_unchanged': unchanged_articles,
+            'failed_articles': len(failed_articles),
+            'verification_problems': verification_problems,
+            'api_retries': self.api.retries
+        }
+    
+    def _upload_article(self, site_id: str, manual_info: Dict, chapter_data: Dict, article_data: Dict,
+                        article_map: Dict, images_dir: Path, existing_articles: set, previous_hashes: Dict,
+                        manual_tags: List[str], skipped_images: List, uploaded_images_count: List[int]) -> Dict:
+        """Upload the images and contents of one article placeholder
+        
+        Safe to run for several articles at once (--article-concurrency).
+        Returns the article's run report record and, unless its upload failed,
+        the content hash to store in the ID map.
+        """
+        self.tracker.enter('article')
+        article_vlp_id = article_data['id']  # VLP article ID for finding images
+        article_id_new = article_map[article_vlp_id]
+        record = {
+            'id': article_vlp_id,
+            'vlp_id': article_data.get('vlp_id'),
+            'title': article_data['title'],
+            'chapter': chapter_data.get('title', ''),
+            'screensteps_id': article_id_new,
+            'content_blocks': 0,
+            'status': 'uploaded'
+        }
+        
+        snippet_values = self._snippet_values(manual_info, chapter_data, article_data, article_id_new)
+        tags = list(dict.fromkeys(manual_tags + title_tags(self.tag_rules, [manual_info.get('title'),
+                                                                           chapter_data.get('title'),
+                                                                           article_data['title']])))
+        content_hash = self._article_hash(article_data, images_dir / article_vlp_id, article_map, snippet_values, tags)
+        if article_vlp_id in existing_articles and previous_hashes.get(article_vlp_id) == content_hash:
+            self.substep(f"Unchanged since the last upload, skipped: {article_data['title']}")
+            record['status'] = 'unchanged'
+        else:
+            # Show progress
+            self.progress(f"Adding content to article: {article_data['title']}")
+            if article_vlp_id in existing_articles:
+                # Reuse the existing article - its contents are replaced below
+                self.substep(f"Updating existing article (ID: {article_id_new})")
+            
+            # Generate content blocks (uploads images internally)
+            content_blocks = self.api.generate_content_blocks(
+                article_data,
+                images_dir,
+                site_id,
+                article_id_new,
+                article_vlp_id,  # Pass VLP ID to find images
+                chapter_title=chapter_data.get('title', 'Unknown'),
+                skipped_images=skipped_images,
+                uploaded_images_count=uploaded_images_count
+            )
+            
+            if self.article_snippets:
+                self._add_article_snippets(content_blocks, snippet_values)
+            
+            # Every article placeholder exists by now, so links resolve in one pass
+            self._resolve_internal_links(content_blocks, article_map, article_data['title'])
+            
+            # Update article contents
+            record['content_blocks'] = len(content_blocks)
+            if content_blocks:
+                try:
+                    self.api.update_article_contents(
+                        site_id,
+                        article_id_new,
+                        article_data['title'],
+                        content_blocks,
+                        publish=self._publish_on_create(article_data)
+                    )
+                    if self.verbose:
+                        self.substep(f"  Updated content with {len(content_blocks)} blocks")
+                except Exception as e:
+                    self.warning(f"Failed to update article contents: {e}")
+                    record['status'] = 'failed'
+            if tags and record['status'] == 'uploaded':
+                try:
+                    self.api.update_article(site_id, article_id_new, tags=tags)
+                except Exception as e:
+                    self.warning(f"Failed to set tags on '{article_data['title']}': {e}")
+        
+        # Track processed articles and images
+        self.tracker.advance('articles')
+        self.tracker.advance('images', sum(len(step.get('images', [])) for step in article_data.get('steps', [])))
+        return {'record': record, 'hash': content_hash if record['status'] != 'failed' else None}
+    
+    def _restore_article_order(self, site_id: str, placeholders: List[Tuple], article_map: Dict):
+        """Move articles created concurrently back to their intended positions
+        
+        placeholders are the (chapter ID, article data, position) tuples that
+        were created. Each chapter is read back once and only articles listed
+        out of order are moved.
+        """
+        wanted = {}  # chapter ID -> [(position, ScreenSteps article ID)]
+        for chapter_id, article_data, position in placeholders:
+            wanted.setdefault(chapter_id, []).append((position, article_map[article_data['id']]))
+        moved = 0
+        for chapter_id, articles in wanted.items():
+            try:
+                listed = [str(a['id']) for a in self.api.get_chapter(site_id, chapter_id).get('articles', [])]
+            except Exception as e:
+                self.warning(f"Could not check the article order of chapter {chapter_id}: {e}")
+                continue
+            ours = set(article_id for _, article_id in articles)
+            expected = [article_id for _, article_id in sorted(articles)]
+            if [article_id for article_id in listed if article_id in ours] == expected:
+                continue
+            for position, article_id in sorted(articles):
+                self.api.update_article(site_id, article_id, position=position)
+                moved += 1
+        if moved:
+            self.substep(f"Restored the order of {moved} articles created concurrently")
+    
+    def _apply_overrides(self, manual_info: Dict):
+        """Rename, drop or reposition chapters and articles from the --override file"""
+        path = Path(self.options['overrides'])
+        changes = apply_overrides(manual_info, load_overrides(path))
+        self.run_report['overrides'] = changes
+        self.info(f"Applying overrides from {path}")
+        for change in changes:
+            if change['change'] == 'unmatched':
+                self.warning(f"Override for {change['type']} '{change['key']}' matched nothing")
+            else:
+                self.substep(f"{change['type'].capitalize()} '{change['title']}': {change['change']}")
+    
+    def _apply_filters(self, manual_info: Dict) -> Dict:
+        """The manual with only the chapters and articles selected by --include-*/--exclude-*"""
+        chapters, dropped = self.options['content_filter'].apply(manual_info['chapters'])
+        self.run_report['filtered'] = dropped
+        self.info(f"Filters left out {sum(1 for d in dropped if d['type'] == 'chapter')} chapters and "
+                  f"{sum(1 for d in dropped if d['type'] == 'article')} articles")
+        for item in dropped:
+            self.substep(f"Left out {item['type']} '{item['title']}' ({item['reason']})")
+        if not chapters:
+            raise ValueError("The --include/--exclude filters left no chapters to upload")
+        return dict(manual_info, chapters=chapters)
+    
+    def _select_articles(self, manual_info: Dict, content_dir: Path, site_id: str) -> Dict:
+        """The manual with only the articles named by --only-article, or from --start-from on"""
+        id_map = self._load_id_map(content_dir, site_id)
+        try:
+            chapters, dropped = select_articles(manual_info['chapters'], self.options.get('only_articles'),
+                                                self.options.get('start_from'), id_map.get('articles', {}))
+        except FilterError as e:
+            raise ValueError(str(e))
+        self.run_report['filtered'] = self.run_report.get('filtered', []) + dropped
+        selected = [article['title'] for chapter in chapters for article in chapter['articles']]
+        self.info(f"Re-uploading {len(selected)} of {len(selected) + len(dropped)} articles")
+        for title in selected:
+            self.substep(f"Selected: {title}")
+        return dict(manual_info, chapters=chapters)
+    
+    def _scan_secrets(self, manual_info: Dict):
+        """Report (and with --mask-secrets, mask) likely credentials in the step HTML
+        
+        With --fail-on-secrets, unmasked findings abort the upload before
+        anything is created.
+        """
+        patterns = self.options.get('secret_patterns')
+        scanner = load_scanner(Path(patterns) if patterns else None)
+        mask = self.options.get('mask_secrets', False)
+        
+        findings = []
+        for chapter_data in manual_info['chapters']:
+            for article_data in chapter_data['articles']:
+                for step in article_data.get('steps', []):
+                    content = step.get('content') or ''
+                    for finding in scanner.scan(content):
+                        findings.append({'article': article_data['title'], 'step': step.get('title', ''), **finding})
+                    if mask:
+                        step['content'], _ = scanner.mask_html(content)
+        
+        self.run_report['secrets'] = findings
+        if not findings:
+            self.substep("Secrets scan: no likely credentials found")
+            return
+        
+        articles = len({f['article'] for f in findings})
+        action = "masked" if mask else "left as is"
+        self.warning(f"Secrets scan: {len(findings)} likely credentials in {articles} articles ({action})")
+        for finding in findings:
+            self.substep(f"{finding['pattern']}: {finding['value']} - {finding['article']} / {finding['step']}")
+        if not mask and self.options.get('fail_on_secrets'):
+            raise ValueError(f"{len(findings)} likely credentials found - re-run with --mask-secrets, "
+                             f"or allow known-safe values in a --secret-patterns file")
+    
+    def _article_hash(self, article_data: Dict, article_images_dir: Path, article_map: Dict,
+                      snippet_values: Dict, tags: List[str]) -> str:
+        """Hash of everything an article's upload depends on (--sync skips articles whose hash is unchanged)
+        
+        Covers the converted article, its image files, the ScreenSteps IDs its
+        internal links resolve to, and the options that change uploaded content.
+        """
+        content = ''.join(step.get('content') or '' for step in article_data.get('steps', []))
+        link_targets = sorted({(target, article_map.get(target)) for _, target in INTERNAL_LINK_REGEX.findall(content)},
+                              key=str)
+        images = sorted((path.name, self.api.image_hash(path)) for path in article_images_dir.iterdir()
+                        if path.is_file()) if article_images_dir.is_dir() else []
+        options = {
+            # The upload date alone does not make an article changed
+            'snippets': self.article_snippets, 'tags': tags,
+            'snippet_values': {field: value for field, value in snippet_values.items() if field != 'date'},
+            'image_placeholder': self.api.image_placeholder, 'image_formats': self.api.image_formats,
+            'image_optimization': self.api.image_optimization, 'image_ignore': self.options.get('image_ignore'),
+            'published': self._publish_on_create(article_data)
         }
+        data = json.dumps([article_data, link_targets, images, options], sort_keys=True, default=str)
+        return hashlib.sha256(data.encode('utf-8')).hexdigest()
+    
+    @staticmethod
+    def _snippet_values(manual_info: Dict, chapter_data: Dict, article_data: Dict, article_id: str) -> Dict:
+        """Values of the article snippet fields (SNIPPET_FIELDS) for an article"""
+        return {
+            'manual': manual_info['title'], 'chapter': chapter_data.get('title', ''),
+            'title': article_data['title'], 'position': article_data.get('position'),
+            'duration_minutes': article_data.get('duration_minutes'),
+            'vlp_id': article_data.get('vlp_id'), 'article_id': article_id,
+            'manual_vlp_id': manual_info.get('vlp_id'), **(manual_info.get('metadata') or {}),
+            'date': datetime.now().strftime('%Y-%m-%d')}
+    
+    def _add_article_snippets(self, content_blocks: List[Dict], values: Dict):
+        """Add the profile's head/foot snippets as the first blocks of the first step and the last blocks of the last step"""
+        steps = [block for block in content_blocks if block['type'] == 'StepContent' and block.get('depth', 0) == 0]
+        if not steps:
+            return
+        for position, snippets in self.article_snippets.items():
+            blocks = [{
+                'uuid': generate_uuid(), 'type': 'TextContent', 'body': render_snippet(snippet['html'], values),
+                'depth': 1, 'style': snippet['style'], 'show_copy_clipboard': False
+            } for snippet in snippets]
+            if position == 'head':
+                index = content_blocks.index(steps[0]) + 1
+                content_blocks[index:index] = blocks
+                steps[0]['content_block_ids'][:0] = [block['uuid'] for block in blocks]
+            else:
+                content_blocks.extend(blocks)
+                steps[-1]['content_block_ids'].extend(block['uuid'] for block in blocks)
+        for sort_order, block in enumerate(content_blocks, 1):
+            block['sort_order'] = sort_order
+    
+    def _resolve_internal_links(self, content_blocks: List[Dict], article_map: Dict, article_title: str) -> int:
+        """Point converter-marked links to other articles/steps at their ScreenSteps URLs
+        
+        article_map is the post-upload ID map (converted article ID ->
+        ScreenSteps ID). Links to articles that were not uploaded (dropped by
+        --override, or in a chapter that failed) are reduced to plain in-page
+        anchors and recorded in the run report. Returns their number.
+        """
+        unresolved = 0
+        
+        def replace(match):
+            nonlocal unresolved
+            anchor, target = match.group(1), match.group(2)
+            fragment = f"#{anchor}" if anchor else ""
+            if target in article_map:
+                return f'href="{self.api.article_url(article_map[target])}{fragment}"'
+            unresolved += 1
+            self.run_report.setdefault('unresolved_links', []).append(
+                {'article': article_title, 'target_article_id': target, 'anchor': anchor})
+            self.substep(f"Link in '{article_title}' to article {target} not uploaded, leaving in-page anchor")
+            return f'href="{fragment or "#"}"'
+        
+        for block in content_blocks:
+            if block.get('type') == 'TextContent' and 'data-ss-article' in (block.get('body') or ''):
+                block['body'] = INTERNAL_LINK_REGEX.sub(replace, block['body'])
+        return unresolved
+    
+    def _verify_upload(self, site_id: str, content_dir: Path, chapter_ids: List[str],
+                       expected_blocks: Dict[str, int], failed_articles: List[str]) -> List[str]:
+        """Read back every uploaded article and report missing/incomplete content
+        
+        Articles whose updated_at (from the chapter listings) matches the
+        read-back cache are not downloaded again.
+        """
+        problems = [f"Content update failed for article: {title}" for title in failed_articles]
+        
+        cache = None
+        updated_at = {}
+        if self.options.get('readback_cache', True):
+            cache = ReadBackCache.load(content_dir / READBACK_CACHE_FILE, site_id)
+            for chapter_id in chapter_ids:
+                try:
+                    chapter = self.api.get_chapter(site_id, chapter_id)
+                except requests.exceptions.RequestException as e:
+                    self.warning(f"Could not list chapter {chapter_id} for the read-back cache: {e}")
+                    continue
+                for listed in chapter.get('articles', []):
+                    updated_at[str(listed.get('id'))] = listed.get('updated_at')
+        self.api.readback_cache = cache
+        
+        try:
+            for article_id, block_count in expected_blocks.items():
+                try:
+                    article = self.api.get_article_cached(site_id, article_id, updated_at.get(str(article_id)))
+                except requests.exceptions.RequestException as e:
+                    problems.append(f"Article {article_id} could not be read back: {e}")
+                    continue
+                remote_blocks = len(article.get('content_blocks', []))
+                if 'content_blocks' in article and remote_blocks < block_count:
+                    problems.append(f"Article {article.get('title', article_id)} has {remote_blocks} "
+                                    f"of {block_count} content blocks")
+        finally:
+            self.api.readback_cache = None
+            if cache is not None:
+                try:
+                    cache.save()
+                except OSError as e:
+                    self.warning(f"Could not save read-back cache {cache.path}: {e}")
+                self.substep(f"Read-back cache: {cache.hits} unchanged, {cache.misses} downloaded")
+        
+        if not problems:
+            self.success(f"Verified {len(expected_blocks)} articles")
+        return problems
+    
+    def _publish_on_create(self, item: Dict) -> bool:
+        """Whether a chapter/article is published as created (never when marked restricted)"""
+        return self.publish_on_create and not item.get('restricted')
+    
+    @staticmethod
+    def _restricted_ids(manual_info: Dict, chapter_map: Dict, article_map: Dict) -> set:
+        """ScreenSteps IDs of the chapters and articles the converter marked restricted"""
+        restricted = set()
+        for chapter_data in manual_info['chapters']:
+            if chapter_data.get('restricted') and chapter_data['id'] in chapter_map:
+                restricted.add(chapter_map[chapter_data['id']])
+            restricted.update(article_map[a['id']] for a in chapter_data['articles']
+                              if a.get('restricted') and a['id'] in article_map)
+        return restricted
     
-    def _find_toc_file(self, content_dir: Path) -> Optional[Path]:
+    def _publish_all(self, site_id: str, manual_id: str, chapter_ids: List[str], article_ids: List[str]):
+        """Publish articles, then chapters, then the manual in one final batch"""
+        for article_id in article_ids:
+            self.api.update_article(site_id, article_id, published=True)
+        for chapter_id in chapter_ids:
+            self.api.update_chapter(site_id, chapter_id, published=True)
+        self.api.update_manual(site_id, manual_id, published=True)
+        self.success(f"Published manual {manual_id} with {len(chapter_ids)} chapters and {len(article_ids)} articles")
+    
+    @staticmethod
+    def _find_toc_file(content_dir: Path) -> Optional[Path]:
         """Find the TOC JSON file"""
         for file in content_dir.glob('*.json'):
-            if file.stem != 'manifest':  # Exclude manifest files
+            if file.stem != 'manifest' and file.name not in STATE_FILES:  # Exclude manifest/state files
                 return file
         return None
 
+    def _load_id_map(self, content_dir: Path, site_id: str) -> Dict:
+        """Load the stored VLP -> ScreenSteps ID mapping from a previous upload"""
+        map_file = content_dir / ID_MAP_FILE
+        if not map_file.exists():
+            return {}
+        try:
+            with open(map_file, 'r', encoding='utf-8') as f:
+                id_map = json.load(f)
+        except (OSError, ValueError) as e:
+            self.warning(f"Ignoring unreadable ID mapping {map_file.name}: {e}")
+            return {}
+        if str(id_map.get('site_id')) != str(site_id):
+            self.info(f"ID mapping {map_file.name} belongs to site {id_map.get('site_id')}, ignoring")
+            return {}
+        return id_map
+
+    def _save_id_map(self, content_dir: Path, site_id: str, manual_id: str,
+                     chapter_map: Dict, article_map: Dict, manual_info: Dict,
+                     content_hashes: Optional[Dict] = None):
+        """Persist the VLP -> ScreenSteps ID mapping next to the converted content
+        
+        The chapters/articles maps (converted ID -> ScreenSteps ID) are what
+        --update reads back, and content_hashes (converted ID -> hash of the
+        uploaded article) what --sync compares with. The entries list adds the
+        VLP ContentNode IDs, steps (article + anchor) and uploaded image assets,
+        and is also written as CSV for auditing and redirects.
+        """
+        id_map = {
+            'account': self.api.account,
+            'site_id': str(site_id),
+            'manual_id': str(manual_id),
+            'chapters': chapter_map,
+            'articles': article_map,
+            'content_hashes': content_hashes or {},
+            'entries': self._id_map_entries(manual_id, chapter_map, article_map, manual_info),
+            'updated_at': datetime.now().isoformat(),
+            'generator': build_info()
+        }
+        map_file = content_dir / ID_MAP_FILE
+        csv_file = content_dir / ID_MAP_CSV_FILE
+        try:
+            with open(map_file, 'w', encoding='utf-8') as f:
+                json.dump(id_map, f, indent=2)
+            with open(csv_file, 'w', encoding='utf-8', newline='') as f:
+                writer = csv.DictWriter(f, fieldnames=ID_MAP_CSV_COLUMNS, extrasaction='ignore')
+                writer.writeheader()
+                writer.writerows(id_map['entries'])
+            self.substep(f"Saved ID mapping: {map_file} ({csv_file.name})")
+        except OSError as e:
+            self.warning(f"Could not save ID mapping {map_file}: {e}")
+    
+    def _id_map_entries(self, manual_id: str, chapter_map: Dict, article_map: Dict,
+                        manual_info: Dict) -> List[Dict]:
+        """One mapping row per manual, chapter, article, step and uploaded image asset"""
+        manual_id = str(manual_id)
+        entries = [{
+            'type': 'manual', 'vlp_id': manual_info.get('vlp_id'), 'converted_id': manual_info.get('id'),
+            'title': manual_info['title'], 'screensteps_id': manual_id, 'parent_screensteps_id': None,
+            'anchor': None, 'url': self.api.manual_url(manual_id)
+        }]
+        for chapter_data in manual_info['chapters']:
+            chapter_id = chapter_map.get(chapter_data['id'])
+            if not chapter_id:
+                continue
+            entries.append({
+                'type': 'chapter', 'vlp_id': chapter_data.get('vlp_id'), 'converted_id': chapter_data['id'],
+                'title': chapter_data['title'], 'screensteps_id': chapter_id,
+                'parent_screensteps_id': manual_id, 'anchor': None, 'url': None
+            })
+            for article_data in chapter_data['articles']:
+                article_id = article_map.get(article_data['id'])
+                if not article_id:
+                    continue
+                article_url = self.api.article_url(article_id)
+                entries.append({
+                    'type': 'article', 'vlp_id': article_data.get('vlp_id'), 'converted_id': article_data['id'],
+                    'title': article_data['title'], 'screensteps_id': article_id,
+                    'parent_screensteps_id': chapter_id, 'anchor': None, 'url': article_url
+                })
+                for step in article_data.get('steps', []):
+                    anchor = step_anchor(step)
+                    entries.append({
+                        'type': 'step', 'vlp_id': step.get('vlp_id'), 'converted_id': step.get('id'),
+                        'title': step['title'], 'screensteps_id': article_id,
+                        'parent_screensteps_id': chapter_id, 'anchor': anchor, 'url': f"{article_url}#{anchor}"
+                    })
+        for asset in self.api.image_assets:
+            entries.append({
+                'type': 'image', 'vlp_id': asset['step_vlp_id'], 'converted_id': asset['step_id'],
+                'title': asset['file_name'], 'screensteps_id': asset['screensteps_id'],
+                'parent_screensteps_id': asset['screensteps_article_id'], 'anchor': None, 'url': None
+            })
+        return entries
+
+    def _resolve_existing_manual(self, site_id: str, manual_title: str, manual_info: Dict,
+                                 content_dir: Path, target_id: Optional[str] = None):
+        """Locate an existing manual for --update and map its chapters/articles
+
+        The stored ID mapping from a previous run takes precedence; otherwise the
+        manual is looked up by title and chapters/articles are matched by title.
+        Chapters missing from the remote manual are created. Returns
+        (manual_id, chapter_map, article_map), or None if no manual was found.
+
+        With target_id (--manual-id) the converted chapters are merged into
+        that manual instead: new chapters go after its own chapters, and
+        chapters are only reused with --update.
+        """
+        id_map = self._load_id_map(content_dir, site_id)
+
+        manual = None
+        if target_id:
+            try:
+                manual = self.api.get_manual(site_id, str(target_id))
+            except requests.exceptions.HTTPError:
+                raise ValueError(f"Manual {target_id} not found in site {site_id}")
+            if str(id_map.get('manual_id')) != str(target_id):
+                id_map = {}  # The mapping of an upload elsewhere does not apply
+        elif id_map.get('manual_id'):
+            try:
+                manual = self.api.get_manual(site_id, id_map['manual_id'])
+            except requests.exceptions.HTTPError:
+                self.warning(f"Mapped manual {id_map['manual_id']} no longer exists, looking up by title")
+                id_map = {}
+        if not manual:
+            found = self.api.find_manual_by_title(site_id, manual_title)
+            if found:
+                manual = self.api.get_manual(site_id, str(found['id']))
+        if not manual:
+            self.info(f"No existing manual titled '{manual_title}', creating a new one")
+            return None
+
+        manual_id = str(manual.get('id', target_id))
+        if target_id:
+            self.success(f"Merging into manual: {manual.get('title', manual_id)} (ID: {manual_id})")
+        else:
+            self.success(f"Updating existing manual: {manual.get('title', manual_title)} (ID: {manual_id})")
+            self._update_description('manual', site_id, manual_id, manual, manual_info)
+
+        remote_chapters = {str(ch['id']): ch for ch in manual.get('chapters', [])}
+        mapped_chapters = id_map.get('chapters', {})
+        mapped_articles = id_map.get('articles', {})
+        reuse = self.options.get('update') or not target_id
+        appended = 0  # Chapters created after the target manual's own chapters
+
+        chapter_map = {}
+        article_map = {}
+        for idx, chapter_data in enumerate(manual_info['chapters'], 1):
+            chapter_id = mapped_chapters.get(chapter_data['id']) if reuse else None
+            if reuse and chapter_id not in remote_chapters:
+                chapter_id = next((cid for cid, ch in remote_chapters.items()
+                                   if ch.get('title') == chapter_data['title']
+                                   and cid not in chapter_map.values()), None)
+            if not chapter_id:
+                appended += 1
+                chapter = self.api.create_chapter(
+                    site_id,
+                    manual_id,
+                    chapter_data['title'],
+                    position=len(remote_chapters) + appended if target_id else chapter_data.get('order', idx),
+                    description=chapter_data.get('description', ''),
+                    published=self._publish_on_create(chapter_data)
+                )
+                chapter_map[chapter_data['id']] = str(chapter['id'])
+                self.substep(f"Created chapter: {chapter_data['title']}")
+                continue
+
+            chapter_map[chapter_data['id']] = chapter_id
+            self.substep(f"Reusing chapter: {chapter_data['title']} (ID: {chapter_id})")
+            self._update_description('chapter', site_id, chapter_id, remote_chapters[chapter_id], chapter_data)
+
+            remote_articles = self.api.get_chapter(site_id, chapter_id).get('articles', [])
+            self._match_articles(chapter_data['articles'], remote_articles, mapped_articles, article_map)
+
+        self.substep(f"Matched {len(article_map)} existing articles")
+        return manual_id, chapter_map, article_map
+
+    def _update_description(self, kind: str, site_id: str, object_id: str, remote: Dict, converted: Dict):
+        """Send the converted description of a reused manual/chapter when it differs (empty ones are not sent)"""
+        description = converted.get('description') or ''
+        if not description or description == (remote.get('description') or ''):
+            return
+        updaters = {'manual': self.api.update_manual, 'chapter': self.api.update_chapter}
+        try:
+            updaters[kind](site_id, object_id, description=description)
+            self.substep(f"Updated {kind} description")
+        except requests.exceptions.RequestException as e:
+            self.warning(f"Failed to update the description of {kind} {object_id}: {e}")
+
+    @staticmethod
+    def _match_articles(articles: List[Dict], remote_articles: List[Dict], mapped_articles: Dict, article_map: Dict):
+        """Add the remote article of each converted article to article_map, by stored ID first, then by title"""
+        remote_ids = {str(a['id']) for a in remote_articles}
+        for article_data in articles:
+            article_id = mapped_articles.get(article_data['id'])
+            if article_id not in remote_ids:
+                article_id = next((str(a['id']) for a in remote_articles
+                                   if a.get('title') == article_data['title']
+                                   and str(a['id']) not in article_map.values()), None)
+            if article_id:
+                article_map[article_data['id']] = article_id
+
+    def _resolve_target_chapter(self, site_id: str, manual_info: Dict, content_dir: Path):
+        """Map every converted chapter to the existing chapter of --chapter-id
+
+        The converted articles are appended after the chapter's own articles,
+        in manual order; with --update, articles uploaded there before (ID
+        mapping, else title) are reused. Returns (manual_id, chapter_map,
+        article_map, position of the first appended article).
+        """
+        chapter_id = str(self.options['chapter_id'])
+        try:
+            chapter = self.api.get_chapter(site_id, chapter_id)
+        except requests.exceptions.HTTPError:
+            raise ValueError(f"Chapter {chapter_id} not found in site {site_id}")
+        manual_id = str(self.options.get('manual_id') or chapter.get('manual_id') or '')
+        if not manual_id:
+            raise ValueError(f"Cannot tell which manual chapter {chapter_id} belongs to; add --manual-id")
+        self.success(f"Merging into chapter: {chapter.get('title', chapter_id)} (ID: {chapter_id}, manual {manual_id})")
+
+        remote_articles = chapter.get('articles', [])
+        chapter_map = {chapter_data['id']: chapter_id for chapter_data in manual_info['chapters']}
+        article_map = {}
+        if self.options.get('update'):
+            id_map = self._load_id_map(content_dir, site_id)
+            mapped_articles = id_map.get('articles', {}) if str(id_map.get('manual_id')) == manual_id else {}
+            articles = [article for chapter_data in manual_info['chapters'] for article in chapter_data['articles']]
+            self._match_articles(articles, remote_articles, mapped_articles, article_map)
+            self.substep(f"Matched {len(article_map)} existing articles")
+        return manual_id, chapter_map, article_map, len(remote_articles) + 1
+
+class ManualUploadOrchestrator:
+    """Upload several converted manuals concurrently
+    
+    Each manual gets its own ScreenStepsUploader (progress, journal and ID
+    mapping stay isolated in its content directory), while all uploaders share
+    one file-upload limiter and one request limiter so the account-wide
+    ScreenSteps rate limits are respected as their API calls interleave.
+    """
+    
+    def __init__(self, account: str, user: str, token: str, verbose: bool = False,
+                 suffix: bool = False, options: Optional[Dict] = None, max_parallel: int = 2,
+                 backend: Optional[ScreenStepsBackend] = None):
+        self.account = account
+        self.user = user
+        self.token = token
+        self.verbose = verbose
+        self.suffix = suffix
+        self.options = dict(options or {})
+        self.max_parallel = max(1, max_parallel)
+        self.backend = backend
+        self.options['file_rate_limiter'] = RateLimiter(*FILE_UPLOAD_RATE)
+        self.options['request_rate_limiter'] = RateLimiter(*API_REQUEST_RATE)
+        self.options['request_scheduler'] = RequestScheduler(API_REQUEST_RATE[0])
+    
+    @staticmethod
+    def find_content_dirs(batch_dir: Path) -> List[Path]:
+        """Find converted manual directories (those containing a TOC file) under batch_dir"""
+        content_dirs = []
+        for candidate in sorted(p for p in batch_dir.iterdir() if p.is_dir()):
+            if (candidate / LANGUAGES_FILE).is_file():
+                content_dirs.extend(language_content_dirs(candidate, ['all']))
+            elif (candidate / 'articles').is_dir() and any(
+                    f.name not in STATE_FILES for f in candidate.glob('*.json')):
+                content_dirs.append(candidate)
+        return content_dirs
+    
+    def upload_all(self, content_dirs: List[Path], site_id: str, create_new: bool = True) -> List[Dict]:
+        """Upload every content directory; failures are reported per manual"""
+        def upload_one(content_dir: Path) -> Dict:
+            options = dict(self.options, label=content_dir.name)
+            uploader = ScreenStepsUploader(self.account, self.user, self.token,
+                                           verbose=self.verbose, suffix=self.suffix, options=options,
+                                           backend=self.backend)
+            started = time.time()
+            try:
+                result = uploader.upload(content_dir, site_id, create_new=create_new)
+                result.update({'content_dir': str(content_dir), 'status': 'success'})
+            except Exception as e:
+                logging.exception(f"Upload of {content_dir} failed")
+                result = {'content_dir': str(content_dir), 'status': 'failed', 'error': str(e)}
+            result['duration_seconds'] = round(time.time() - started, 1)
+            return result
+        
+        with ThreadPoolExecutor(max_workers=min(self.max_parallel, len(content_dirs)) or 1) as pool:
+            return list(pool.map(upload_one, content_dirs))
+
 def print_usage_examples():
     """Print detailed usage examples"""
     examples = """
@@ -951,7 +2598,34 @@ def print_usage_examples():
        --site 12345 \\
        --verbose
 
-3. Use existing manual (don't create new):
+3. Re-run an upload, updating the previously uploaded manual in place:
+   python screensteps_uploader.py \\
+       --content output/HOL-2601-03-VCF-L \\
+       --account myaccount \\
+       --user admin \\
+       --token abc123xyz \\
+       --site 12345 \\
+       --update
+
+4. Roll back a failed upload (deletes the manual, chapters, articles and images it created):
+   python screensteps_uploader.py \\
+       --content output/HOL-2601-03-VCF-L \\
+       --account myaccount \\
+       --user admin \\
+       --token abc123xyz \\
+       --site 12345 \\
+       --rollback --manual-id 67890
+
+5. Upload every manual of a batch conversion, three at a time:
+   python screensteps_uploader.py \\
+       --batch output/ \\
+       --parallel-manuals 3 \\
+       --account myaccount \\
+       --user admin \\
+       --token abc123xyz \\
+       --site 12345
+
+6. Use existing manual (don't create new):
    python screensteps_uploader.py \\
        --content output/HOL-2601-03-VCF-L \\
        --account myaccount \\
@@ -960,6 +2634,50 @@ def print_usage_examples():
        --site 12345 \\
        --no-create
 
+7. Use a named profile from ~/.vlp2ss.yaml (no credentials on the command line):
+   python screensteps_uploader.py \\
+       --content output/HOL-2601-03-VCF-L \\
+       --profile prod
+
+8. Store the API token in the OS keychain once, then omit --token:
+   python screensteps_uploader.py --auth-login --account myaccount --user admin
+   python screensteps_uploader.py \\
+       --content output/HOL-2601-03-VCF-L \\
+       --account myaccount \\
+       --user admin \\
+       --site 12345
+
+9. Check the planned structure and ordering before uploading:
+   python screensteps_uploader.py --content output/HOL-2601-03-VCF-L --print-structure
+
+10. Review what a re-migration would change in the live manual before updating it:
+   python screensteps_uploader.py \\
+       --content output/HOL-2601-03-VCF-L \\
+       --profile prod \\
+       --diff --manual-id 67890
+
+11. Back up a manual (structure, content blocks and images) before re-migrating over it:
+   python screensteps_uploader.py --profile prod --download --manual-id 67890 -o backup/
+
+12. Look up the IDs to pass as --site, --manual-id and --chapter-id:
+   python screensteps_uploader.py --profile prod --list sites
+   python screensteps_uploader.py --profile prod --list manuals --site 12345
+   python screensteps_uploader.py --profile prod --list chapters --manual-id 67890 --json
+
+13. Check whether a topic was migrated already before uploading another lab:
+   python screensteps_uploader.py --profile prod --site 12345 --search "vMotion"
+
+14. Upload as drafts, review in ScreenSteps, then publish:
+   python screensteps_uploader.py --content output/HOL-2601-03-VCF-L --profile prod --draft
+   python screensteps_uploader.py --content output/HOL-2601-03-VCF-L --profile prod --publish-manual 67890
+
+15. Clean up after a test migration:
+   python screensteps_uploader.py --profile prod --delete-article 4521,4522
+   python screensteps_uploader.py --profile prod --unpublish-manual 67890
+
+16. Retry the articles an upload failed or skipped images in, once the cause is fixed:
+   python screensteps_uploader.py --profile prod --retry-failed output/HOL-2601-03-VCF-L/summary.json
+
 ╔══════════════════════════════════════════════════════════════════════════╗
 ║                    GENERATING API TOKEN                                  ║
 ╚══════════════════════════════════════════════════════════════════════════╝
@@ -985,6 +2703,435 @@ def print_usage_examples():
 """
     print(examples)
 
+def build_options(args) -> Dict:
+    """Build the uploader options from parsed command-line arguments"""
+    return {
+        'update': args.update or args.sync or bool(args.only_article or args.start_from),
+        'sync': args.sync,
+        'atomic': args.atomic,
+        'upload_concurrency': max(1, args.upload_concurrency),
+        'article_concurrency': max(1, args.article_concurrency),
+        'publish_strategy': args.publish_strategy,
+        'base_url': args.base_url,
+        'cassette': args.replay_cassette or args.record_cassette,
+        'cassette_mode': 'replay' if args.replay_cassette else 'record',
+        'proxy': args.proxy,
+        'ca_bundle': args.ca_bundle,
+        'insecure_skip_verify': args.insecure_skip_verify,
+        'compress_requests': args.compress_requests,
+        'image_ignore': args.image_ignore,
+        'image_formats': args.image_formats,
+        'image_placeholder': args.image_placeholder,
+        'article_snippets': {} if args.no_article_snippets else args.article_snippets,
+        'metadata_tags': args.metadata_tags,
+        # A profile may give --tag as one string or a list
+        'tags': [tag.strip() for value in ([args.tag] if isinstance(args.tag, str) else args.tag or [])
+                 for tag in value.split(',') if tag.strip()],
+        'tag_rules': args.tag_rules,
+        'image_optimization': {key: value for key, value in (('max_width', args.max_image_width),
+                                                             ('png_compress', args.png_compress),
+                                                             ('jpeg_quality', args.jpeg_quality)) if value},
+        'qa_report': not args.no_qa_report,
+        'readback_cache': not args.no_readback_cache,
+        'secret_scan': not args.no_secret_scan,
+        'secret_patterns': args.secret_patterns,
+        'operation_log': None if args.no_operation_log else args.operation_log,
+        'record_operations': not args.no_operation_log,
+        'overrides': args.override,
+        'only_articles': args.only_article,
+        'manual_id': args.manual_id,
+        'chapter_id': args.chapter_id,
+        'start_from': args.start_from,
+        'content_filter': filter_from_args(args),
+        'mask_secrets': args.mask_secrets,
+        'fail_on_secrets': args.fail_on_secrets,
+        'quiet': args.quiet,
+        'log_dir': args.log_dir,
+        'log_keep': args.log_keep,
+        'retry_policy': RetryPolicy(
+            max_retries=max(0, args.max_retries),
+            backoff=args.retry_backoff,
+            timeout=args.request_timeout,
+            deadline=args.request_deadline or None
+        ),
+    }
+
+def report_error(result: Dict, message: str, exit_code: int = EXIT_ERROR) -> int:
+    """Print an error, record it for the --quiet result line, and return the exit code"""
+    result['error'] = message
+    print(f"{Colors.FAIL}Error: {message}{Colors.ENDC}")
+    return exit_code
+
+def is_auth_error(error: Exception) -> bool:
+    """Whether ScreenSteps rejected the credentials (HTTP 401/403)"""
+    response = getattr(error, 'response', None)
+    return isinstance(error, requests.exceptions.HTTPError) and response is not None \
+        and response.status_code in (401, 403)
+
+def language_content_dirs(manual_dir: Path, languages: List[str]) -> List[Path]:
+    """Content directories of the listed languages (or 'all') of a multi-language conversion"""
+    with open(manual_dir / LANGUAGES_FILE, 'r', encoding='utf-8') as f:
+        available = {entry['code']: manual_dir / entry['directory'] for entry in json.load(f).get('languages', [])}
+    if 'all' in languages:
+        return list(available.values())
+    unknown = [code for code in languages if code not in available]
+    if unknown:
+        raise ValueError(f"Languages not converted in {manual_dir}: {', '.join(unknown)} "
+                         f"(available: {', '.join(available)})")
+    return [available[code] for code in languages]
+
+def run_batch_upload(args, result: Dict, content_dirs: Optional[List[Path]] = None) -> int:
+    """Upload every converted manual found under --batch (or the given content directories) concurrently"""
+    batch_dir = Path(args.batch or args.content)
+    if content_dirs is None:
+        if not batch_dir.is_dir():
+            return report_error(result, f"Batch directory does not exist: {batch_dir}")
+        content_dirs = ManualUploadOrchestrator.find_content_dirs(batch_dir)
+    if not content_dirs:
+        return report_error(result, f"No converted manuals found in {batch_dir}")
+    
+    # Manuals merged into one manual or chapter go one after the other, so their positions stay in order
+    parallel = 1 if args.manual_id or args.chapter_id else args.parallel_manuals
+    print(f"{Colors.OKBLUE}Uploading {len(content_dirs)} manuals "
+          f"({parallel} at a time) from {batch_dir}{Colors.ENDC}")
+    
+    orchestrator = ManualUploadOrchestrator(
+        args.account, args.user, args.token,
+        verbose=args.verbose,
+        suffix=args.suffix,
+        options=build_options(args),
+        max_parallel=parallel
+    )
+    results = orchestrator.upload_all(content_dirs, args.site, create_new=not args.no_create)
+    
+    print(f"\n{Colors.HEADER}{Colors.BOLD}{'Batch Upload Summary'.center(70)}{Colors.ENDC}")
+    for result in results:
+        name = Path(result['content_dir']).name
+        if result['status'] == 'success':
+            print(f"{Colors.OKGREEN}✓ {name}: manual {result['manual_id']}, "
+                  f"{result['articles']} articles ({result['duration_seconds']}s){Colors.ENDC}")
+        else:
+            print(f"{Colors.FAIL}✗ {name}: {result['error']}{Colors.ENDC}")
+    
+    failed = sum(1 for r in results if r['status'] != 'success')
+    result.update({
+        'manuals': len(results),
+        'failed_manuals': failed,
+        'articles': sum(r.get('articles', 0) for r in results),
+        'images_uploaded': sum(r.get('images_uploaded', 0) for r in results),
+        'images_skipped': sum(r.get('images_skipped', 0) for r in results),
+        'images_deduplicated': sum(r.get('images_deduplicated', 0) for r in results),
+        'images_optimized': sum(r.get('images_optimized', 0) for r in results),
+        'failed_articles': sum(r.get('failed_articles', 0) for r in results),
+        'verification_problems': sum(r.get('verification_problems', 0) for r in results),
+        'api_retries': sum(r.get('api_retries', 0) for r in results),
+    })
+    return EXIT_ERROR if failed == len(results) else result_exit_code(result)
+
+def run_auth(args, result: Dict) -> int:
+    """Store (--auth-login) or remove (--auth-logout) an API token in the OS keychain"""
+    if not (args.account and args.user):
+        return report_error(result, "--account and --user are required to identify the stored token")
+    
+    try:
+        if args.auth_logout:
+            if delete_keychain_token(args.account, args.user):
+                print(f"{Colors.OKGREEN}✓ Removed stored token for {args.user} on {args.account}{Colors.ENDC}")
+            else:
+                print(f"{Colors.WARNING}⚠ No stored token for {args.user} on {args.account}{Colors.ENDC}")
+            return 0
+        
+        token = getpass.getpass(f"ScreenSteps API token for {args.user} on {args.account}: ").strip()
+        if not token:
+            return report_error(result, "No token entered")
+        
+        # Verify the token before storing it
+        uploader = ScreenStepsUploader(args.account, args.user, token, verbose=args.verbose,
+                                       options=build_options(args))
+        try:
+            uploader.api.get_sites()
+        except requests.exceptions.RequestException as e:
+            return report_error(result, f"Token verification failed: {e}",
+                                EXIT_AUTH if is_auth_error(e) else EXIT_ERROR)
+        
+        store_keychain_token(args.account, args.user, token)
+        print(f"{Colors.OKGREEN}✓ Token stored in the OS keychain for {args.user} on {args.account}{Colors.ENDC}")
+        print(f"{Colors.OKCYAN}ℹ --token and SS_TOKEN can now be omitted{Colors.ENDC}")
+        return 0
+    except ConfigError as e:
+        return report_error(result, str(e))
+
+def run_rollback(args, result: Dict) -> int:
+    """Roll back a previous upload from its journal (or delete a manual by ID)"""
+    if args.journal:
+        journal_path = Path(args.journal)
+    elif args.content:
+        journal_path = Path(args.content) / JOURNAL_FILE
+    else:
+        journal_path = None
+    
+    try:
+        if journal_path and journal_path.exists():
+            journal = UploadJournal.load(journal_path)
+            if args.manual_id and journal.manual_id and journal.manual_id != str(args.manual_id):
+                return report_error(result, f"Journal {journal_path} belongs to manual {journal.manual_id}, not {args.manual_id}")
+            if journal.data.get('site_id') and journal.data['site_id'] != str(args.site):
+                return report_error(result, f"Journal {journal_path} belongs to site {journal.data['site_id']}, not {args.site}")
+            entries = journal.entries
+        elif args.manual_id:
+            # Without a journal only the manual itself is known; uploaded images stay behind
+            journal = None
+            entries = [{'type': 'manual', 'id': str(args.manual_id), 'title': ''}]
+            print(f"{Colors.WARNING}⚠ No upload journal found - deleting manual {args.manual_id} only; uploaded image assets will remain{Colors.ENDC}")
+        else:
+            return report_error(result, f"--rollback requires --manual-id, --journal, or --content with an {JOURNAL_FILE}")
+        
+        uploader = ScreenStepsUploader(args.account, args.user, args.token, verbose=args.verbose,
+                                       options=build_options(args))
+        result['deleted'] = uploader.rollback(args.site, entries)
+        if journal:
+            journal.set_status('rolled_back')
+        return 0
+    
+    except Exception as e:
+        logging.exception("Rollback failed")
+        return report_error(result, str(e))
+
+def run_download(args, result: Dict) -> int:
+    """Save a ScreenSteps manual in the converter's output layout (--download)"""
+    if not args.manual_id:
+        return report_error(result, "--download requires --manual-id")
+    try:
+        uploader = ScreenStepsUploader(args.account, args.user, args.token, verbose=args.verbose,
+                                       options=build_options(args))
+        result.update(uploader.download_manual(args.site, args.manual_id, Path(args.output)))
+    except OSError as e:
+        return report_error(result, str(e))
+    except requests.exceptions.RequestException as e:
+        return report_error(result, f"Could not download the manual: {e}", EXIT_AUTH if is_auth_error(e) else EXIT_ERROR)
+    return result_exit_code(result)
+
+# Columns (header, field) printed by --list
+LIST_COLUMNS = {
+    'sites': [('ID', 'id'), ('Title', 'title')],
+    'manuals': [('ID', 'id'), ('Title', 'title')],
+    'chapters': [('ID', 'id'), ('Pos', 'position'), ('Articles', 'articles'), ('Title', 'title')],
+    'articles': [('ID', 'id'), ('Pos', 'position'), ('Published', 'published'), ('Title', 'title')],
+    'search': [('ID', 'id'), ('Manual', 'manual_id'), ('Chapter', 'chapter_id'), ('URL', 'url'), ('Title', 'title')],
+}
+
+def format_listing(kind: str, items: List[Dict]) -> List[str]:
+    """Table of the sites, manuals, chapters or articles printed by --list (or the --search matches)"""
+    columns = LIST_COLUMNS[kind]
+    rows = []
+    for item in items:
+        cells = []
+        for _, field in columns:
+            value = item.get(field)
+            if isinstance(value, bool):
+                value = 'yes' if value else 'no'
+            cells.append('-' if value is None else str(value))
+        rows.append(cells)
+    # The last column (the title) is not padded
+    widths = [max([len(header)] + [len(row[i]) for row in rows]) for i, (header, _) in enumerate(columns[:-1])]
+    lines = []
+    for cells in [[header for header, _ in columns]] + rows:
+        lines.append('  '.join([cell.ljust(width) for cell, width in zip(cells, widths)] + [cells[-1]]))
+    return lines
+
+def run_list(args, result: Dict) -> int:
+    """Print the sites, manuals of --site, chapters of --manual-id or articles of --chapter-id (--list)"""
+    if args.list == 'chapters' and not args.manual_id:
+        return report_error(result, "--list chapters requires --manual-id")
+    if args.list == 'articles' and not args.chapter_id:
+        return report_error(result, "--list articles requires --chapter-id")
+    try:
+        api = ScreenStepsUploader(args.account, args.user, args.token, verbose=args.verbose,
+                                  options=build_options(args)).api
+        if args.list == 'sites':
+            items = api.list_sites()
+        elif args.list == 'manuals':
+            items = api.list_manuals(args.site)
+        elif args.list == 'chapters':
+            items = api.list_chapters(args.site, args.manual_id)
+        else:
+            items = api.list_articles(args.site, args.chapter_id)
+    except requests.exceptions.RequestException as e:
+        return report_error(result, f"Could not list {args.list}: {e}", EXIT_AUTH if is_auth_error(e) else EXIT_ERROR)
+    
+    fields = [field for _, field in LIST_COLUMNS[args.list]]
+    items = [{field: item.get(field) for field in fields} for item in items]
+    for item in items:
+        if isinstance(item.get('articles'), list):
+            item['articles'] = len(item['articles'])
+    if args.json:
+        print(json.dumps(items, indent=2))
+    elif items:
+        for line in format_listing(args.list, items):
+            print(line)
+    else:
+        print(f"{Colors.WARNING}⚠ No {args.list} found{Colors.ENDC}")
+    result[args.list] = items
+    return EXIT_OK
+
+def run_search(args, result: Dict) -> int:
+    """Print the articles of --site matching the --search text, to spot content migrated before"""
+    try:
+        api = ScreenStepsUploader(args.account, args.user, args.token, verbose=args.verbose,
+                                  options=build_options(args)).api
+        matches = api.search_articles(args.site, args.search)
+    except requests.exceptions.RequestException as e:
+        return report_error(result, f"Search failed: {e}", EXIT_AUTH if is_auth_error(e) else EXIT_ERROR)
+    
+    fields = [field for _, field in LIST_COLUMNS['search']]
+    items = [{field: match.get(field) for field in fields} for match in matches]
+    for item in items:
+        item['url'] = api.article_url(item['id'])
+    if args.json:
+        print(json.dumps(items, indent=2))
+    elif items:
+        for line in format_listing('search', items):
+            print(line)
+        print(f"\n{Colors.OKCYAN}ℹ {len(items)} article(s) match '{args.search}'{Colors.ENDC}")
+    else:
+        print(f"{Colors.OKGREEN}✓ No articles match '{args.search}'{Colors.ENDC}")
+    result['search'] = items
+    return EXIT_OK
+
+def run_manage(args, result: Dict) -> int:
+    """Delete or unpublish articles, or unpublish a manual, by ID (cleanup of test migrations)"""
+    if args.delete_article:
+        action, kind, ids = 'delete', 'article', args.delete_article
+    elif args.unpublish_article:
+        action, kind, ids = 'unpublish', 'article', args.unpublish_article
+    else:
+        action, kind, ids = 'unpublish', 'manual', args.unpublish_manual
+    ids = [object_id.strip() for object_id in ids.split(',') if object_id.strip()]
+    if not ids:
+        return report_error(result, f"No {kind} IDs given")
+    
+    try:
+        uploader = ScreenStepsUploader(args.account, args.user, args.token, verbose=args.verbose,
+                                       options=build_options(args))
+        outcome = uploader.manage(args.site, action, kind, ids)
+    except requests.exceptions.RequestException as e:
+        return report_error(result, f"Could not {action} {kind}s: {e}", EXIT_AUTH if is_auth_error(e) else EXIT_ERROR)
+    result[f"{kind}s_{'deleted' if action == 'delete' else 'unpublished'}"] = outcome['changed']
+    if outcome['failed']:
+        result['failed_ids'] = outcome['failed']
+        return EXIT_ERROR
+    return EXIT_OK
+
+def run_publish_manual(args, result: Dict) -> int:
+    """Publish a manual uploaded as a draft, with its chapters and articles (--publish-manual)"""
+    try:
+        uploader = ScreenStepsUploader(args.account, args.user, args.token, verbose=args.verbose,
+                                       options=build_options(args))
+        published = uploader.publish_manual(args.site, args.publish_manual,
+                                            Path(args.content) if args.content else None)
+    except (OSError, ValueError, KeyError) as e:
+        return report_error(result, str(e))
+    except requests.exceptions.RequestException as e:
+        return report_error(result, f"Could not publish the manual: {e}", EXIT_AUTH if is_auth_error(e) else EXIT_ERROR)
+    result.update({'manual_id': args.publish_manual, 'chapters_published': published['chapters'],
+                   'articles_published': published['articles']})
+    return EXIT_OK
+
+def run_retry_failed(args, result: Dict) -> int:
+    """Re-upload the failed articles and skipped images listed in an upload's summary.json (--retry-failed)"""
+    report_path = Path(args.retry_failed) if args.retry_failed else None
+    if args.content:
+        content_dir = Path(args.content)
+    elif report_path:
+        content_dir = report_path.parent  # The report sits in the content directory it was written for
+        args.content = str(content_dir)  # Email report and metrics describe the retried content
+    else:
+        return report_error(result, "--retry-failed requires --content or the path of a summary.json")
+    try:
+        uploader = ScreenStepsUploader(args.account, args.user, args.token, verbose=args.verbose,
+                                       suffix=args.suffix, options=build_options(args))
+        retried = uploader.retry_failed(content_dir, args.site, report_path)
+    except (OSError, ValueError, KeyError) as e:
+        return report_error(result, str(e))
+    except requests.exceptions.RequestException as e:
+        return report_error(result, f"Could not retry the upload: {e}", EXIT_AUTH if is_auth_error(e) else EXIT_ERROR)
+    if retried is None:
+        result['articles'] = 0
+        return EXIT_OK
+    result.update(retried)
+    return result_exit_code(result)
+
+def run_replay(args, result: Dict) -> int:
+    """Send the operations of an operation log again, e.g. after an outage (--replay)"""
+    log_path = Path(args.replay)
+    try:
+        logged = load_operations(log_path)
+        operations = select_replay(logged, args.replay_from)
+    except OperationLogError as e:
+        return report_error(result, str(e))
+    result['replayed'] = 0
+    if not operations:
+        print(f"{Colors.OKGREEN}✓ No failed operations to replay in {log_path}{Colors.ENDC}")
+        return EXIT_OK
+    
+    uploader = ScreenStepsUploader(args.account, args.user, args.token, verbose=args.verbose,
+                                   options=build_options(args))
+    if uploader.api.operation_log is None and not args.no_operation_log:
+        # The replayed calls are appended to the log they came from, marked with the op they replay
+        uploader.api.operation_log = open_operation_log(log_path, args.account)
+    print(f"{Colors.OKCYAN}ℹ Replaying {len(operations)} operations from op {operations[0]['op']} "
+          f"(run {operations[0].get('run')}){Colors.ENDC}")
+    
+    def show(operation: Dict, error: Optional[str]):
+        line = f"#{operation['op']} {operation['method']} {operation['endpoint']}"
+        if error:
+            print(f"{Colors.FAIL}✗ {line}: {error}{Colors.ENDC}")
+        else:
+            print(f"{Colors.OKGREEN}✓ {line}{Colors.ENDC}")
+    
+    try:
+        outcome = replay_operations(uploader.api, operations, show, history=logged)
+    except OperationLogError as e:
+        return report_error(result, str(e))
+    result['replayed'] = outcome['replayed']
+    if outcome['failed_op'] is not None:
+        result['failed_op'] = outcome['failed_op']
+        return report_error(result, f"Operation {outcome['failed_op']} failed again ({outcome['error']}); "
+                                    f"resume with --replay {log_path}")
+    return EXIT_OK
+
+def run_diff(args, result: Dict) -> int:
+    """Report how --content differs from a live ScreenSteps manual (--diff); exits 2 when it differs"""
+    if not args.content:
+        return report_error(result, "--diff requires --content")
+    content_dir = Path(args.content)
+    if (content_dir / LANGUAGES_FILE).is_file():
+        if not args.lang or len(args.lang) != 1 or 'all' in args.lang:
+            return report_error(result, f"{content_dir} holds several languages; select one with --lang CODE")
+        try:
+            content_dir = language_content_dirs(content_dir, args.lang)[0]
+        except ValueError as e:
+            return report_error(result, str(e))
+    
+    try:
+        uploader = ScreenStepsUploader(args.account, args.user, args.token, verbose=args.verbose,
+                                       suffix=args.suffix, options=build_options(args))
+        diff = uploader.diff_manual(content_dir, args.site, args.manual_id)
+    except (OSError, ValueError, KeyError, OverridesError) as e:
+        return report_error(result, str(e))
+    except requests.exceptions.RequestException as e:
+        return report_error(result, f"Could not download the manual: {e}", EXIT_AUTH if is_auth_error(e) else EXIT_ERROR)
+    
+    symbols = {'+': Colors.OKGREEN, '-': Colors.FAIL, '~': Colors.WARNING}
+    for line in format_diff(diff):
+        color = symbols.get(line.strip()[:1])
+        print(f"{color}{line}{Colors.ENDC}" if color else line)
+    result['diff'] = diff
+    if not has_differences(diff):
+        print(f"{Colors.OKGREEN}✓ No differences{Colors.ENDC}")
+        return EXIT_OK
+    return EXIT_WARNINGS
+
 def main():
     """Main entry point"""
     parser = argparse.ArgumentParser(
@@ -995,29 +3142,219 @@ def main():
     
     parser.add_argument('--content', type=str,
                        help='Path to converted content directory')
+    parser.add_argument('--print-structure', action='store_true',
+                       help='Print the chapters/articles the upload would create with their VLP order values (-v adds steps) and exit')
+    parser.add_argument('--batch', type=str,
+                       help='Directory of converted manuals (e.g. batch conversion output) to upload together')
+    parser.add_argument('--lang', type=lambda value: [code.strip() for code in value.split(',') if code.strip()],
+                       metavar='CODE[,CODE...]',
+                       help='Languages to upload when --content is a multi-language conversion (converter --lang); '
+                            'each becomes a manual of its own')
+    parser.add_argument('--all-languages', action='store_true',
+                       help='Like --lang, for every language of a multi-language conversion')
+    parser.add_argument('--parallel-manuals', type=int, default=2, metavar='N',
+                       help='Manuals uploaded concurrently with --batch (default: 2)')
     parser.add_argument('--account', type=str, default=os.environ.get('SS_ACCOUNT'),
                        help='ScreenSteps account name (or SS_ACCOUNT env var)')
     parser.add_argument('--user', type=str, default=os.environ.get('SS_USER'),
                        help='ScreenSteps user ID (or SS_USER env var)')
     parser.add_argument('--token', type=str, default=os.environ.get('SS_TOKEN'),
-                       help='ScreenSteps API token (or SS_TOKEN env var)')
+                       help='ScreenSteps API token (or SS_TOKEN env var, or the OS keychain after --auth-login)')
     parser.add_argument('--site', type=str, default=os.environ.get('SS_SITE'),
                        help='ScreenSteps site ID (or SS_SITE env var)')
     parser.add_argument('--no-create', action='store_true',
                        help='Use existing manual (don\'t create new)')
+    parser.add_argument('--max-retries', type=int, default=5,
+                       help='Retries for rate-limited, 5xx, timed-out or reset requests (default: 5)')
+    parser.add_argument('--retry-backoff', type=float, default=2.0,
+                       help='Initial backoff in seconds, doubled per retry with jitter (default: 2.0)')
+    parser.add_argument('--request-timeout', type=float, default=60.0,
+                       help='Timeout in seconds for a single API call (default: 60)')
+    parser.add_argument('--request-deadline', type=float, default=600.0,
+                       help='Give up on a request after this many seconds including retries (default: 600, 0 = none)')
+    parser.add_argument('--base-url', type=str, default=os.environ.get('SS_BASE_URL'), metavar='URL',
+                       help='ScreenSteps site to call instead of https://<account>.screenstepslive.com, e.g. '
+                            'http://localhost:8765 for vlp2ss_mockserver.py (or SS_BASE_URL env var)')
+    parser.add_argument('--record-cassette', type=str, metavar='FILE',
+                       help='Record every API request and response to a cassette FILE for --replay-cassette')
+    parser.add_argument('--replay-cassette', type=str, metavar='FILE',
+                       help='Answer API calls from a recorded cassette FILE instead of ScreenSteps (no network '
+                            'or credentials needed; --site must match the recording)')
+    parser.add_argument('--proxy', type=str,
+                       help='HTTP/HTTPS/SOCKS proxy URL (default: HTTP_PROXY/HTTPS_PROXY environment variables)')
+    parser.add_argument('--ca-bundle', type=str, default=os.environ.get('SS_CA_BUNDLE'),
+                       help='Custom CA bundle for TLS verification (or SS_CA_BUNDLE env var)')
+    parser.add_argument('--insecure-skip-verify', action='store_true',
+                       help='Disable TLS certificate verification (not recommended)')
+    parser.add_argument('--compress-requests', action='store_true',
+                       help='gzip-compress large JSON request bodies (falls back automatically if the server refuses)')
+    parser.add_argument('--no-qa-report', action='store_true',
+                       help='Do not update qa_report.html in the content directory with the upload results')
+    parser.add_argument('--image-ignore', action='append', default=[], metavar='PATTERN',
+                       help='Ignore images matching PATTERN when indexing the content (e.g. "thumbnails/", "*_small.png"; repeatable)')
+    parser.add_argument('--image-placeholder', type=str, default=DEFAULT_IMAGE_PLACEHOLDER, metavar='TEXT',
+                       help='Text of the alert block left where an image could not be uploaded; '
+                            '{file} and {alt} are replaced with its file name and alt text '
+                            f'(default: "{DEFAULT_IMAGE_PLACEHOLDER}")')
+    parser.add_argument('--metadata-tags', action='store_true',
+                       help='Tag every article with its lab\'s VLP metadata (SKU, vlp-manual:ID, vlp-format:, vlp-export:DATE)')
+    parser.add_argument('--tag', action='append', metavar='TAG[,TAG...]',
+                       help='Tag every uploaded article with TAG (repeatable); adds to --metadata-tags and the '
+                            'profile\'s article_tags')
+    parser.add_argument('--no-article-snippets', action='store_true',
+                       help='Do not add the profile\'s article_snippets to the top and bottom of every article')
+    parser.add_argument('--gif-max-size', type=float, metavar='MB',
+                       help='Skip GIFs larger than MB megabytes (default: 10, or the profile image_formats; 0 = no limit)')
+    parser.add_argument('--rasterize-svg', type=int, metavar='WIDTH',
+                       help='Upload SVG images as PNGs WIDTH pixels wide (needs CairoSVG; 0 uploads SVGs as they are)')
+    parser.add_argument('--max-image-width', type=int, metavar='PIXELS',
+                       help='Scale PNG/JPEG screenshots wider than PIXELS down to that width before upload')
+    parser.add_argument('--png-compress', action='store_true',
+                       help='Recompress PNG screenshots with maximum lossless compression before upload')
+    parser.add_argument('--jpeg-quality', type=int, metavar='1-95',
+                       help='Re-encode JPEG images at this quality before upload')
+    parser.add_argument('--upload-concurrency', type=int, default=1, metavar='N',
+                       help='Number of parallel image uploads per article (default: 1)')
+    parser.add_argument('--article-concurrency', type=int, default=1, metavar='N',
+                       help='Number of articles created and filled in parallel (default: 1)')
+    parser.add_argument('--mask-secrets', action='store_true',
+                       help='Replace likely credentials (passwords, API/license keys) in article text with ******** before upload')
+    parser.add_argument('--fail-on-secrets', action='store_true',
+                       help='Abort before creating anything if likely credentials are found and not masked')
+    parser.add_argument('--override', type=str, metavar='FILE',
+                       help='YAML/JSON file renaming, dropping or repositioning chapters and articles at upload time')
+    parser.add_argument('--secret-patterns', type=str, metavar='FILE',
+                       help='YAML/JSON file with extra secret patterns and known-safe values (allow list)')
+    parser.add_argument('--no-secret-scan', action='store_true',
+                       help='Skip the scan for likely credentials before upload')
+    parser.add_argument('--no-readback-cache', action='store_true',
+                       help=f'Always download articles again when verifying (no {READBACK_CACHE_FILE})')
+    publish_group = parser.add_mutually_exclusive_group()
+    publish_group.add_argument('--publish-strategy', choices=['immediate', 'after-verify', 'never'],
+                               default='after-verify',
+                               help='When to publish: as created, after a verification pass (default), or never')
+    publish_group.add_argument('--publish', action='store_const', dest='publish_strategy', const='immediate',
+                               help='Publish the manual, chapters and articles as they are created '
+                                    '(same as --publish-strategy immediate)')
+    publish_group.add_argument('--draft', action='store_const', dest='publish_strategy', const='never',
+                               help='Leave everything unpublished for review (same as --publish-strategy never); '
+                                    'publish it later with --publish-manual')
+    parser.add_argument('--publish-manual', type=str, metavar='ID',
+                       help='Publish manual ID of --site with all its chapters and articles, then exit; with --content, '
+                            'chapters and articles marked restricted stay unpublished')
+    parser.add_argument('--atomic', action='store_true',
+                       help='Roll back everything created by the run if the upload fails')
+    parser.add_argument('--rollback', action='store_true',
+                       help='Delete the content created by a previous (failed) upload using its journal')
+    parser.add_argument('--retry-failed', nargs='?', const='', metavar='SUMMARY',
+                       help='Re-upload only the articles that failed or skipped images in the last upload of --content, '
+                            'as listed in its summary.json (or the given SUMMARY file), into the same manual')
+    parser.add_argument('--delete-article', type=str, metavar='ID[,ID...]',
+                       help='Delete the given articles of --site, then exit')
+    parser.add_argument('--unpublish-article', type=str, metavar='ID[,ID...]',
+                       help='Unpublish the given articles of --site (they stay as drafts), then exit')
+    parser.add_argument('--unpublish-manual', type=str, metavar='ID[,ID...]',
+                       help='Unpublish the given manuals of --site, then exit')
+    parser.add_argument('--diff', action='store_true',
+                       help='Compare --content with the live manual (--manual-id, or the one it was uploaded to) '
+                            'and report added/removed/changed articles and blocks without changing anything')
+    parser.add_argument('--download', action='store_true',
+                       help='Save the manual --manual-id (chapters, articles, content blocks and images) in the '
+                            'converter\'s output layout under --output, for backups and as a --diff/--sync baseline')
+    parser.add_argument('--list', choices=list(LIST_COLUMNS),
+                       help='Print the sites, the manuals of --site, the chapters of --manual-id or the articles of '
+                            '--chapter-id with their IDs, then exit')
+    parser.add_argument('--search', type=str, metavar='TEXT',
+                       help='Print the articles of --site matching TEXT (e.g. to find content migrated before), then exit')
+    parser.add_argument('--json', action='store_true',
+                       help='Print the --list or --search output as JSON instead of a table')
+    parser.add_argument('-o', '--output', type=str, default='backup', metavar='DIR',
+                       help='Directory --download writes the manual into (default: backup)')
+    parser.add_argument('--manual-id', type=str,
+                       help='Existing manual to merge the converted chapters into (after its own chapters), or the '
+                            'manual to roll back (with --rollback), compare with (with --diff) or download (with --download)')
+    parser.add_argument('--chapter-id', type=str,
+                       help='Existing chapter to merge all converted articles into (after its own articles)')
+    parser.add_argument('--journal', type=str,
+                       help=f'Upload journal to roll back (default: <content>/{JOURNAL_FILE})')
+    parser.add_argument('--operation-log', type=str, metavar='FILE',
+                       help=f'Append every API call that changes content (endpoint, payload, result) to FILE, '
+                            f'for auditing and --replay (default for uploads: <content>/{OPERATION_LOG_FILE})')
+    parser.add_argument('--no-operation-log', action='store_true',
+                       help='Do not log the API calls that change content')
+    parser.add_argument('--replay', type=str, metavar='FILE',
+                       help='Send the operations of an operation log again, e.g. after an outage: the ones of its last '
+                            'failed run that have not succeeded yet, or all from --replay-from, then exit')
+    parser.add_argument('--replay-from', type=int, metavar='OP',
+                       help='Operation number (op) to replay from, with the operations after it in the same run, '
+                            'including ones that succeeded')
+    parser.add_argument('--update', action='store_true',
+                       help='Update an existing manual (found via stored ID mapping or title) instead of creating a duplicate')
+    parser.add_argument('--sync', action='store_true',
+                       help='Like --update, but only re-upload articles whose content, images or upload options changed '
+                            'since the last upload (content hashes in the ID mapping)')
+    parser.add_argument('--only-article', action='append', default=[], metavar='ARTICLE',
+                       help='Re-upload only this article (title, converted, VLP or ScreenSteps ID) into the existing '
+                            'manual, e.g. one that failed or skipped images; repeatable, implies --update')
+    parser.add_argument('--start-from', type=str, metavar='ARTICLE',
+                       help='Re-upload this article and every article after it into the existing manual, e.g. to '
+                            'resume an interrupted upload; implies --update')
+    parser.add_argument('--auth-login', action='store_true',
+                       help='Prompt for the API token of --account/--user and store it in the OS keychain')
+    parser.add_argument('--auth-logout', action='store_true',
+                       help='Remove the stored API token of --account/--user from the OS keychain')
     parser.add_argument('-v', '--verbose', action='store_true',
                        help='Enable verbose logging')
-    parser.add_argument('--version', action='version',
-                       version='VLP2SS - The VLP to ScreenSteps Uploader\nVersion: 1.0.3\nAuthor: Burke Azbill\nLicense: MIT')
+    parser.add_argument('--notify-url', type=str, default=os.environ.get('VLP2SS_NOTIFY_URL'), metavar='URL',
+                       help='POST the run result (status, counts, manual URL) to this webhook when done (or VLP2SS_NOTIFY_URL env var)')
+    parser.add_argument('--email-report', type=str, default=os.environ.get('VLP2SS_EMAIL_REPORT'), metavar='ADDRESS',
+                       help='Email an HTML report of the run to these comma-separated addresses when done, using the '
+                            'profile smtp settings (or VLP2SS_EMAIL_REPORT env var)')
+    parser.add_argument('--metrics-file', type=str, default=str(DEFAULT_METRICS_FILE), metavar='FILE',
+                       help='History file that run metrics are appended to (see vlp2ss_metrics.py; or VLP2SS_METRICS_FILE env var)')
+    parser.add_argument('--no-metrics', action='store_true',
+                       help='Do not record this run in the metrics history')
+    parser.add_argument('--log-dir', type=str, default=DEFAULT_LOG_DIR, metavar='DIR',
+                       help='Directory for log files (default: logs, or VLP2SS_LOG_DIR env var)')
+    parser.add_argument('--log-keep', type=int, default=DEFAULT_LOG_KEEP, metavar='N',
+                       help=f'Previous uploader logs to keep in --log-dir; older ones are deleted (default: {DEFAULT_LOG_KEEP}, 0 keeps all)')
+    parser.add_argument('-q', '--quiet', action='store_true',
+                       help='Suppress headers and progress; print only a final JSON result line (errors go to stderr)')
+    parser.add_argument('--version', action=VersionAction,
+                       tool_name='VLP2SS - The VLP to ScreenSteps Uploader')
     parser.add_argument('--examples', action='store_true',
                        help='Show detailed usage examples')
+    add_filter_arguments(parser)
+    add_config_arguments(parser)
     parser.add_argument('--suffix', action='store_true',
                        help='Append -python suffix to manual titles')
     
+    try:
+        profile_name, profile = apply_profile(parser)
+    except ConfigError as e:
+        print(f"{Colors.FAIL}Error: {e}{Colors.ENDC}")
+        return 1
+    
     args = parser.parse_args()
+    try:
+        args.image_formats = load_format_policies(profile.get('image_formats'), args.gif_max_size, args.rasterize_svg)
+        args.article_snippets = load_article_snippets(profile.get('article_snippets'))
+        args.tag_rules = load_tag_rules(profile.get('article_tags'))
+    except (ImageFormatError, ConfigError) as e:
+        print(f"{Colors.FAIL}Error: {e}{Colors.ENDC}")
+        return 1
+    if args.jpeg_quality is not None and not 1 <= args.jpeg_quality <= 95:
+        print(f"{Colors.FAIL}Error: --jpeg-quality must be between 1 and 95{Colors.ENDC}")
+        return 1
+    if args.max_image_width is not None and args.max_image_width < 1:
+        print(f"{Colors.FAIL}Error: --max-image-width must be a positive number of pixels{Colors.ENDC}")
+        return 1
     
     # Show examples
-    if args.examples or not args.content:
+    if args.examples or not (args.content or args.rollback or args.batch or args.download or args.list or args.search or
+                             args.delete_article or args.unpublish_article or args.unpublish_manual or
+                             args.publish_manual or args.retry_failed is not None or args.replay or
+                             args.auth_login or args.auth_logout):
         if args.examples:
             print_usage_examples()
             return 0
@@ -1026,46 +3363,192 @@ def main():
         print_usage_examples()
         return 0
     
-    # Validate required arguments
-    if not all([args.account, args.user, args.token, args.site]):
-        print(f"{Colors.FAIL}Error: --account, --user, --token, and --site are required, or set SS_ACCOUNT, SS_USER, SS_TOKEN, and SS_SITE environment variables.{Colors.ENDC}")
-        return 1
+    result = {}
+    if args.quiet:
+        # --quiet: silence all decorative output and print one JSON result line
+        with open(os.devnull, 'w') as devnull, contextlib.redirect_stdout(devnull):
+            exit_code = run_command(args, profile_name, result)
+    else:
+        exit_code = run_command(args, profile_name, result)
+    
+    run_result = {'status': exit_status(exit_code), 'exit_code': exit_code, **result,
+                  'version': APP_VERSION, 'git_sha': build_info()['git_sha']}
+    if args.notify_url:
+        error = notify_webhook(args.notify_url, 'upload', run_result)
+        if error:
+            print(f"Warning: webhook notification failed: {error}", file=sys.stderr)
+    if args.email_report:
+        recipients = [address.strip() for address in args.email_report.split(',') if address.strip()]
+        error = email_report(recipients, 'upload', run_result, smtp_settings(profile),
+                             report_dir=Path(args.content) if args.content else None)
+        if error:
+            print(f"Warning: email report failed: {error}", file=sys.stderr)
+    if (args.content or args.batch) and not (args.no_metrics or args.print_structure or args.rollback or args.diff or
+                                             args.publish_manual):
+        error = record_run('upload', run_result, Path(args.metrics_file))
+        if error:
+            print(f"Warning: could not record run metrics: {error}", file=sys.stderr)
+    
+    if args.quiet:
+        if result.get('error'):
+            print(f"Error: {result['error']}", file=sys.stderr)
+        print(json.dumps(run_result))
+    return exit_code
+
+def run_print_structure(args, result: Dict) -> int:
+    """Print the structure the upload would create (--print-structure); needs no credentials"""
+    content_dir = Path(args.content)
+    toc_file = ScreenStepsUploader._find_toc_file(content_dir) if content_dir.is_dir() else None
+    if not toc_file:
+        return report_error(result, f"No TOC file found in content directory: {content_dir}")
+    with open(toc_file, 'r', encoding='utf-8') as f:
+        manual_data = json.load(f)
+    
+    if args.override:
+        try:
+            changes = apply_overrides(manual_data['manual'], load_overrides(Path(args.override)))
+        except OverridesError as e:
+            return report_error(result, str(e))
+        for change in changes:
+            print(f"{Colors.OKCYAN}ℹ Override: {change['type']} '{change['title'] or change['key']}': "
+                  f"{change['change']}{Colors.ENDC}")
+    
+    content_filter = filter_from_args(args)
+    if content_filter:
+        manual_data['manual']['chapters'], dropped = content_filter.apply(manual_data['manual']['chapters'])
+        for item in dropped:
+            print(f"{Colors.OKCYAN}ℹ Filter: left out {item['type']} '{item['title']}' ({item['reason']}){Colors.ENDC}")
+    
+    lines, flagged = format_structure(manual_data, include_steps=args.verbose)
+    for line in lines:
+        print(f"{Colors.WARNING}{line}{Colors.ENDC}" if '   ! ' in line else line)
+    if flagged:
+        print(f"\n{Colors.WARNING}⚠ {flagged} item(s) have a duplicated or out-of-sequence VLP order{Colors.ENDC}")
+    result['order_warnings'] = flagged
+    return 0
+
+def run_command(args, profile_name: Optional[str], result: Dict) -> int:
+    """Run the upload, batch upload, rollback or auth command selected on the command line
+    
+    Fills result with the fields of the --quiet result line and returns the
+    exit code.
+    """
+    if args.auth_login or args.auth_logout:
+        return run_auth(args, result)
+    
+    try:
+        filter_from_args(args)
+    except FilterError as e:
+        return report_error(result, str(e))
+    
+    if args.print_structure:
+        if not args.content:
+            return report_error(result, "--print-structure requires --content")
+        return run_print_structure(args, result)
+    
+    if args.record_cassette and args.replay_cassette:
+        return report_error(result, "--record-cassette and --replay-cassette cannot be combined")
+    if args.replay_cassette:
+        # Nothing reaches ScreenSteps, so any credentials do
+        args.account = args.account or 'cassette'
+        args.user = args.user or 'cassette'
+        args.token = args.token or 'cassette'
+    
+    # Fall back to a token stored with --auth-login
+    if not args.token and args.account and args.user:
+        args.token = get_keychain_token(args.account, args.user)
+    
+    # Validate required arguments (listing sites and replaying logged operations need no site)
+    if not all([args.account, args.user, args.token, args.site or args.list == 'sites' or args.replay]):
+        return report_error(result, "--account, --user, --token, and --site are required, or set SS_ACCOUNT, SS_USER, SS_TOKEN, and SS_SITE environment variables, or select a --profile from ~/.vlp2ss.yaml (tokens can be stored with --auth-login).")
+    
+    if profile_name:
+        print(f"{Colors.OKCYAN}ℹ Using profile: {profile_name}{Colors.ENDC}")
+    
+    if args.record_cassette or args.replay_cassette:
+        try:
+            open_cassette(Path(args.replay_cassette or args.record_cassette),
+                          'replay' if args.replay_cassette else 'record')
+        except (CassetteError, OSError) as e:
+            return report_error(result, str(e))
+    
+    if args.list:
+        return run_list(args, result)
+    if args.search:
+        return run_search(args, result)
+    if args.delete_article or args.unpublish_article or args.unpublish_manual:
+        return run_manage(args, result)
+    if args.publish_manual:
+        return run_publish_manual(args, result)
+    if args.rollback:
+        return run_rollback(args, result)
+    if args.diff:
+        return run_diff(args, result)
+    if args.retry_failed is not None:
+        return run_retry_failed(args, result)
+    if args.replay:
+        return run_replay(args, result)
+    if args.download:
+        return run_download(args, result)
+    if args.all_languages:
+        args.lang = ['all']
     
     try:
         start_time = time.time()
+        exit_code = EXIT_OK
         
-        content_dir = Path(args.content)
-        if not content_dir.exists():
-            print(f"{Colors.FAIL}Error: Content directory does not exist: {content_dir}{Colors.ENDC}")
-            return 1
-        
-        uploader = ScreenStepsUploader(
-            args.account,
-            args.user,
-            args.token,
-            verbose=args.verbose,
-            suffix=args.suffix
-        )
-        
-        uploader.upload(
-            content_dir,
-            args.site,
-            create_new=not args.no_create
-        )
+        if args.batch:
+            exit_code = run_batch_upload(args, result)
+        else:
+            content_dir = Path(args.content)
+            if not content_dir.exists():
+                return report_error(result, f"Content directory does not exist: {content_dir}")
+            
+            # A multi-language conversion: each selected language is uploaded as a manual of its own
+            if (content_dir / LANGUAGES_FILE).is_file():
+                if not args.lang:
+                    with open(content_dir / LANGUAGES_FILE, 'r', encoding='utf-8') as f:
+                        codes = [entry['code'] for entry in json.load(f).get('languages', [])]
+                    return report_error(result, f"{content_dir} holds several languages ({', '.join(codes)}); "
+                                                f"select them with --lang CODE[,CODE...] or --all-languages")
+                try:
+                    language_dirs = language_content_dirs(content_dir, args.lang)
+                except ValueError as e:
+                    return report_error(result, str(e))
+                if len(language_dirs) > 1:
+                    return run_batch_upload(args, result, content_dirs=language_dirs)
+                content_dir = language_dirs[0]
+                args.content = str(content_dir)  # Email report and metrics describe the uploaded language
+            
+            uploader = ScreenStepsUploader(
+                args.account,
+                args.user,
+                args.token,
+                verbose=args.verbose,
+                suffix=args.suffix,
+                options=build_options(args)
+            )
+            
+            result.update(uploader.upload(
+                content_dir,
+                args.site,
+                create_new=not args.no_create
+            ))
+            exit_code = result_exit_code(result)
         
         elapsed = time.time() - start_time
+        result['elapsed_seconds'] = round(elapsed, 1)
         minutes, seconds = divmod(int(elapsed), 60)
         if minutes > 0:
             print(f"{Colors.OKCYAN}ℹ Total execution time: {minutes}m {seconds}s{Colors.ENDC}")
         else:
             print(f"{Colors.OKCYAN}ℹ Total execution time: {seconds}s{Colors.ENDC}")
         
-        return 0
+        return exit_code
         
     except Exception as e:
-        print(f"{Colors.FAIL}Error: {e}{Colors.ENDC}")
         logging.exception("Upload failed")
-        return 1
+        return report_error(result, str(e), EXIT_AUTH if is_auth_error(e) else EXIT_ERROR)
 
 if __name__ == "__main__":
     sys.exit(main())
@@ -0,0 +1,250 @@
+#!/usr/bin/env python3
+"""
+VLP2SS Screenshot Annotations
+Finds the callouts (numbered markers, boxes, arrows, labels) VLP overlays on
+screenshots, as positioned sibling markup or annotation JSON, and burns them
+into a copy of the image so they survive migration
+
+Author: Burke Azbill
+Version: 1.0.3
+"""
+
+import re
+import json
+import math
+import hashlib
+from pathlib import Path
+from typing import Dict, List, Optional, Tuple
+from bs4 import BeautifulSoup, Tag
+
+# <img> attribute carrying an image's callouts from the parser to the writer (and kept with --annotations keep)
+ANNOTATION_ATTRIBUTE = 'data-annotations'
+# Attributes of VLP images holding callouts as JSON
+ANNOTATION_JSON_ATTRIBUTES = (ANNOTATION_ATTRIBUTE, 'data-callouts')
+# Classes of overlay elements placed over a screenshot, with the kind of callout they draw
+CALLOUT_CLASS_REGEX = re.compile(r'^(callout|annotation|hotspot|marker)(?:[-_](number|badge|step|box|rect|highlight|'
+                                 r'arrow|text|label))?$', re.IGNORECASE)
+CALLOUT_KINDS = {'number': 'number', 'badge': 'number', 'step': 'number', 'box': 'box', 'rect': 'box',
+                 'highlight': 'box', 'arrow': 'arrow', 'text': 'text', 'label': 'text'}
+SHAPE_TYPES = ('number', 'box', 'arrow', 'text')
+# Formats callouts are burned into (animated GIFs and SVGs keep them as data)
+BURNABLE_SUFFIXES = ('.png', '.jpg', '.jpeg', '.webp', '.bmp')
+# Callouts without a color of their own
+DEFAULT_COLOR = '#E4002B'
+
+class AnnotationError(Exception):
+    """Callouts that cannot be read or burned into their image"""
+
+def _number(value) -> Tuple[Optional[float], bool]:
+    """A coordinate (px or %) as (number, is_percent); (None, False) if missing or unreadable"""
+    match = re.match(r'\s*(-?\d+(?:\.\d+)?)\s*(%|px)?\s*$', str(value)) if value is not None else None
+    return (float(match.group(1)), match.group(2) == '%') if match else (None, False)
+
+def normalize_shape(raw: Dict) -> Optional[Dict]:
+    """A callout in the one shape format written to data-annotations, None if it has no position
+
+    Accepts type/kind/shape, x/left, y/top, width/w, height/h, x2/toX, y2/toY,
+    label/text and color; coordinates are pixels of the displayed image or
+    percentages ('40%') of the image.
+    """
+    if not isinstance(raw, dict):
+        return None
+    kind = str(raw.get('type') or raw.get('kind') or raw.get('shape') or '').lower()
+    kind = CALLOUT_KINDS.get(kind, kind)
+    fields = {'x': ('x', 'left'), 'y': ('y', 'top'), 'width': ('width', 'w'), 'height': ('height', 'h'),
+              'x2': ('x2', 'toX', 'endX'), 'y2': ('y2', 'toY', 'endY')}
+    shape, percent = {}, raw.get('unit') == '%'  # Already normalized shapes keep their unit
+    for name, keys in fields.items():
+        value, is_percent = _number(next((raw[key] for key in keys if raw.get(key) is not None), None))
+        if value is not None:
+            shape[name] = value
+            percent = percent or is_percent
+    if 'x' not in shape or 'y' not in shape:
+        return None
+    label = str(raw.get('label') or raw.get('text') or '').strip()
+    if kind not in SHAPE_TYPES:
+        kind = 'arrow' if 'x2' in shape else 'box' if 'width' in shape and 'height' in shape \
+            else 'number' if label.isdigit() or not label else 'text'
+    shape.update(type=kind, unit='%' if percent else 'px')
+    if label:
+        shape['label'] = label
+    if raw.get('color'):
+        shape['color'] = str(raw['color'])
+    return shape
+
+def _style(tag: Tag) -> Dict[str, str]:
+    return {name.strip().lower(): value.strip()
+            for name, _, value in (part.partition(':') for part in str(tag.get('style', '')).split(';')) if value}
+
+def _markup_shape(tag: Tag, kind: Optional[str]) -> Optional[Dict]:
+    """A callout from an overlay element: position and size from its inline style, label from its text"""
+    style = _style(tag)
+    raw = {'type': CALLOUT_KINDS.get((kind or '').lower()), 'left': style.get('left'), 'top': style.get('top'),
+           'width': style.get('width'), 'height': style.get('height'), 'label': tag.get_text(strip=True),
+           'color': style.get('border-color') or style.get('background-color') or style.get('color'),
+           'x2': tag.get('data-x2'), 'y2': tag.get('data-y2')}
+    rotate = re.search(r'rotate\(\s*(-?\d+(?:\.\d+)?)deg', style.get('transform', ''))
+    if raw['type'] == 'arrow' and raw['x2'] is None and rotate and raw['width']:
+        # An arrow drawn as a rotated bar: from its left/top point, width long
+        x, percent = _number(raw['left'])
+        y, _ = _number(raw['top'])
+        length, _ = _number(raw['width'])
+        if x is not None and y is not None and length is not None and not percent:
+            angle = math.radians(float(rotate.group(1)))
+            raw.update(x2=x + length * math.cos(angle), y2=y + length * math.sin(angle), width=None)
+    shape = normalize_shape(raw)
+    if shape and shape['type'] == 'number' and 'width' in shape and 'height' in shape:
+        # Markers are drawn centered on their position; the element's is its top-left corner
+        shape['x'] += shape.pop('width') / 2
+        shape['y'] += shape.pop('height') / 2
+    return shape
+
+def _json_shapes(value: str) -> List[Dict]:
+    try:
+        data = json.loads(value)
+    except ValueError as e:
+        raise AnnotationError(f"Callout JSON is not valid: {e}")
+    if isinstance(data, dict):
+        data = data.get('shapes') or data.get('annotations') or data.get('callouts') or []
+    if not isinstance(data, list):
+        raise AnnotationError("Callout JSON must be a list of shapes (or an object with 'shapes')")
+    return [shape for shape in map(normalize_shape, data) if shape]
+
+def _display_width(img: Tag) -> Optional[float]:
+    """Width the image is shown at in VLP, which callout pixel positions refer to"""
+    width, percent = _number(img.get('width') or _style(img).get('width'))
+    return width if width and not percent else None
+
+def collect_annotations(soup: BeautifulSoup) -> Tuple[int, List[str]]:
+    """Move the callouts of every screenshot into its data-annotations attribute
+
+    Callouts come from JSON in a data-annotations/data-callouts attribute, from
+    a <script type="application/json"> with an "annotation(s)" or "callouts"
+    class next to the image, or from overlay elements with callout classes
+    (callout-number, callout-box, callout-arrow, ...) positioned in the
+    image's container. The overlay markup and script are removed. Returns
+    the number of images annotated and the callout JSON that could not be read.
+    """
+    annotated, errors = 0, []
+    for img in soup.find_all('img'):
+        if img.find_parent('table'):
+            continue
+        shapes = []
+        for attribute in ANNOTATION_JSON_ATTRIBUTES:
+            if img.get(attribute):
+                try:
+                    shapes += _json_shapes(img[attribute])
+                except AnnotationError as e:
+                    errors.append(f"{img.get('src', '(no src)')}: {e}")
+                del img[attribute]
+        # The image's container: its parent, or the parent of a link/span around it
+        container = img.parent
+        if container is not None and container.name in ('a', 'span') and container.parent is not None:
+            container = container.parent
+        if container is not None and container.name not in ('[document]', 'body') \
+                and len(container.find_all('img')) == 1:
+            for script in container.find_all('script', type='application/json'):
+                if any(re.match(r'^(annotations?|callouts)$', c, re.IGNORECASE) for c in script.get('class', [])):
+                    try:
+                        shapes += _json_shapes(script.string or '')
+                    except AnnotationError as e:
+                        errors.append(f"{img.get('src', '(no src)')}: {e}")
+                    script.decompose()
+            for overlay in container.find_all(True):
+                if overlay is img or overlay.parent is None:
+                    continue
+                match = next(filter(None, (CALLOUT_CLASS_REGEX.match(c) for c in overlay.get('class', []))), None)
+                if match:
+                    shape = _markup_shape(overlay, match.group(2))
+                    if shape:
+                        shapes.append(shape)
+                    overlay.decompose()
+        if shapes:
+            img[ANNOTATION_ATTRIBUTE] = json.dumps({'display_width': _display_width(img), 'shapes': shapes},
+                                                   separators=(',', ':'))
+            annotated += 1
+    return annotated, errors
+
+def load_annotations(value: str) -> Dict:
+    """The callouts written by collect_annotations"""
+    try:
+        data = json.loads(value)
+    except ValueError as e:
+        raise AnnotationError(f"Invalid {ANNOTATION_ATTRIBUTE}: {e}")
+    if not isinstance(data, dict) or not isinstance(data.get('shapes'), list):
+        raise AnnotationError(f"Invalid {ANNOTATION_ATTRIBUTE}: no shapes")
+    return data
+
+def annotated_name(filename: str, annotations: Dict) -> str:
+    """File name of an annotated copy; the same screenshot with other callouts gets another name"""
+    digest = hashlib.sha256(json.dumps(annotations, sort_keys=True).encode('utf-8')).hexdigest()[:8]
+    path = Path(filename)
+    return f"{path.stem}.annotated-{digest}{path.suffix}"
+
+def burn_annotations(image_path: Path, annotations: Dict, output_path: Path) -> Path:
+    """Draw callouts onto a copy of a screenshot (needs Pillow); returns output_path
+
+    Pixel positions are scaled from the displayed width (the <img> width in
+    VLP) to the image's own width; percentages are of the image size.
+    """
+    if image_path.suffix.lower() not in BURNABLE_SUFFIXES:
+        raise AnnotationError(f"Callouts are not burned into {image_path.suffix} images ({image_path.name})")
+    try:
+        from PIL import Image, ImageDraw, ImageFont
+    except ImportError:
+        raise AnnotationError("Burning callouts into screenshots requires Pillow: pip3 install Pillow")
+    try:
+        with Image.open(image_path) as source:
+            image = source.convert('RGBA')
+    except OSError as e:
+        raise AnnotationError(f"Cannot read {image_path.name}: {e}")
+
+    scale = image.width / annotations['display_width'] if annotations.get('display_width') else 1.0
+    line = max(2, round(image.width / 400))
+    radius = max(10, round(12 * scale))
+    draw = ImageDraw.Draw(image)
+    try:
+        font = ImageFont.load_default(size=radius)
+    except TypeError:  # Pillow < 10.1: fixed-size bitmap font
+        font = ImageFont.load_default()
+
+    def point(shape: Dict, x: float, y: float) -> Tuple[float, float]:
+        if shape['unit'] == '%':
+            return x * image.width / 100, y * image.height / 100
+        return x * scale, y * scale
+
+    def label(text: str, center: Tuple[float, float], fill: str):
+        left, top, right, bottom = draw.textbbox((0, 0), text, font=font)
+        draw.text((center[0] - (right - left) / 2 - left, center[1] - (bottom - top) / 2 - top), text, font=font, fill=fill)
+
+    for shape in annotations['shapes']:
+        color = shape.get('color') or DEFAULT_COLOR
+        x, y = point(shape, shape['x'], shape['y'])
+        try:
+            if shape['type'] == 'box':
+                x2, y2 = point(shape, shape['x'] + shape.get('width', 0), shape['y'] + shape.get('height', 0))
+                draw.rectangle((x, y, x2, y2), outline=color, width=line)
+            elif shape['type'] == 'arrow' and 'x2' in shape:
+                x2, y2 = point(shape, shape['x2'], shape['y2'])
+                draw.line((x, y, x2, y2), fill=color, width=line)
+                angle = math.atan2(y2 - y, x2 - x)
+                head = line * 5
+                draw.polygon([(x2, y2),
+                              (x2 - head * math.cos(angle - 0.4), y2 - head * math.sin(angle - 0.4)),
+                              (x2 - head * math.cos(angle + 0.4), y2 - head * math.sin(angle + 0.4))], fill=color)
+            elif shape['type'] == 'text':
+                left, top, right, bottom = draw.textbbox((x, y), shape.get('label', ''), font=font)
+                draw.rectangle((left - line * 2, top - line * 2, right + line * 2, bottom + line * 2), fill=color)
+                draw.text((x, y), shape.get('label', ''), font=font, fill='white')
+            else:
+                # Numbered marker, centered on its position (also arrows without an end point)
+                draw.ellipse((x - radius, y - radius, x + radius, y + radius), fill=color, outline='white', width=line)
+                if shape.get('label'):
+                    label(shape['label'], (x, y), 'white')
+        except ValueError as e:  # Unknown color names
+            raise AnnotationError(f"Cannot draw {shape['type']} callout on {image_path.name}: {e}")
+
+    if output_path.suffix.lower() in ('.jpg', '.jpeg'):
+        image = image.convert('RGB')
+    image.save(output_path)
+    return output_path
@@ -0,0 +1,768 @@
+#!/usr/bin/env python3
+"""
+VLP2SS ScreenSteps API Client
+Reusable client for the ScreenSteps v2 API: reads, creates, updates and
+deletes sites, manuals, chapters, articles and file assets, with rate
+limiting, retries, request priorities and cancellation
+
+Author: Burke Azbill
+Version: 1.0.3
+"""
+
+import json
+import gzip
+import time
+import random
+import logging
+import mimetypes
+import threading
+import contextlib
+from collections import deque
+from pathlib import Path
+from urllib.parse import urlparse, parse_qs
+from typing import Dict, Iterator, List, Optional, Protocol, TypedDict
+import requests
+from requests.auth import HTTPBasicAuth
+from vlp2ss_oplog import MUTATING_METHODS
+
+# ScreenSteps rate limit for file uploads: 8 files per 10 seconds
+FILE_UPLOAD_RATE = (8, 10.0)
+# Overall API pacing shared by concurrently uploaded manuals (one call per 0.25s on average)
+API_REQUEST_RATE = (4, 1.0)
+
+# JSON bodies smaller than this are sent uncompressed with --compress-requests
+COMPRESS_MIN_BYTES = 1024
+# Response bytes logged per API call in verbose mode
+VERBOSE_BODY_LIMIT = 2000
+# Safety stop for paginated listings whose next page never runs out
+MAX_PAGES = 1000
+
+# Response objects (the fields VLP2SS uses; the API returns more)
+class ManualSummary(TypedDict, total=False):
+    id: int
+    title: str
+    chapters_count: int
+
+class Site(TypedDict, total=False):
+    id: int
+    title: str
+    url: str
+    manuals: List[ManualSummary]
+
+class ArticleSummary(TypedDict, total=False):
+    id: int
+    title: str
+    position: int
+    published: bool
+    updated_at: str
+    chapter_id: int
+    manual_id: int
+
+class Chapter(TypedDict, total=False):
+    id: int
+    title: str
+    description: str
+    position: int
+    published: bool
+    manual_id: int
+    articles: List[ArticleSummary]
+
+class Manual(TypedDict, total=False):
+    id: int
+    title: str
+    description: str
+    published: bool
+    chapters: List[Chapter]
+
+class Article(TypedDict, total=False):
+    id: int
+    title: str
+    position: int
+    published: bool
+    chapter_id: int
+    updated_at: str
+    tags: List[str]
+    content_blocks: List[Dict]
+
+class FileAsset(TypedDict, total=False):
+    id: int
+    url: str
+    name: str
+    type: str
+
+class ScreenStepsBackend(Protocol):
+    """The ScreenSteps operations the uploader needs, by site, manual, chapter, article and file ID
+
+    ScreenStepsClient implements them over HTTP. Anything else with these
+    methods (an alternative backend, an in-memory fake such as
+    vlp2ss_mockserver.MockBackend) can be passed to ScreenStepsUploader(backend=...).
+    Objects are plain dicts shaped like the API responses.
+    """
+
+    def list_sites(self) -> List[Site]: ...
+    def get_sites(self) -> List[Site]: ...
+    def get_site(self, site_id: str) -> Site: ...
+    def list_manuals(self, site_id: str) -> List[ManualSummary]: ...
+    def get_manual(self, site_id: str, manual_id: str) -> Manual: ...
+    def list_chapters(self, site_id: str, manual_id: str) -> List[Chapter]: ...
+    def get_chapter(self, site_id: str, chapter_id: str) -> Chapter: ...
+    def list_articles(self, site_id: str, chapter_id: str) -> List[ArticleSummary]: ...
+    def iter_articles(self, site_id: str, manual_id: str) -> Iterator[ArticleSummary]: ...
+    def search_articles(self, site_id: str, text: str) -> List[ArticleSummary]: ...
+    def find_manual_by_title(self, site_id: str, title: str) -> Optional[ManualSummary]: ...
+    def get_article(self, site_id: str, article_id: str) -> Article: ...
+    def get_file(self, site_id: str, file_id: str) -> FileAsset: ...
+    def create_manual(self, site_id: str, title: str, chapters: List[Dict] = None,
+                      published: bool = True, description: str = "") -> Manual: ...
+    def create_chapter(self, site_id: str, manual_id: str, title: str,
+                       position: int, description: str = "", published: bool = True) -> Chapter: ...
+    def create_article(self, site_id: str, chapter_id: str, title: str,
+                       position: int, published: bool = True) -> Article: ...
+    def upload_file(self, site_id: str, file_path: Path, asset_type: str = 'FileAsset') -> Dict: ...
+    def update_manual(self, site_id: str, manual_id: str, **fields) -> Manual: ...
+    def update_chapter(self, site_id: str, chapter_id: str, **fields) -> Chapter: ...
+    def update_article(self, site_id: str, article_id: str, **fields) -> Article: ...
+    def update_article_contents(self, site_id: str, article_id: str, title: str,
+                                content_blocks: List[Dict], publish: bool = True) -> Article: ...
+    def delete_manual(self, site_id: str, manual_id: str): ...
+    def delete_chapter(self, site_id: str, chapter_id: str): ...
+    def delete_article(self, site_id: str, article_id: str): ...
+    def delete_file(self, site_id: str, file_id: str): ...
+    def download_file(self, url: str, destination: Path) -> bool: ...
+    def article_url(self, article_id: str) -> str: ...
+    def manual_url(self, manual_id: str) -> str: ...
+
+# Method names of ScreenStepsBackend
+BACKEND_METHODS = tuple(name for name in vars(ScreenStepsBackend)
+                        if not name.startswith('_') and callable(vars(ScreenStepsBackend)[name]))
+
+def page_number(value) -> Optional[int]:
+    """A listing's next_page/total_pages value as a number: an int, a numeric string or a URL with ?page=N"""
+    if isinstance(value, int) and not isinstance(value, bool):
+        return value
+    text = str(value or '').strip()
+    if text.isdigit():
+        return int(text)
+    pages = parse_qs(urlparse(text).query).get('page', [])
+    return int(pages[0]) if pages and pages[0].isdigit() else None
+
+class RequestCancelled(Exception):
+    """Raised by API calls made or waiting after ScreenStepsClient.cancel()"""
+
+class RateLimiter:
+    """Thread-safe sliding-window rate limiter (at most max_calls per period seconds)"""
+
+    def __init__(self, max_calls: int, period: float):
+        self.max_calls = max_calls
+        self.period = period
+        self.calls = deque()
+        self.lock = threading.Lock()
+
+    def acquire(self):
+        """Block until another call is allowed, then reserve it"""
+        while True:
+            with self.lock:
+                now = time.monotonic()
+                while self.calls and now - self.calls[0] >= self.period:
+                    self.calls.popleft()
+                if len(self.calls) < self.max_calls:
+                    self.calls.append(now)
+                    return
+                wait = self.period - (now - self.calls[0])
+            time.sleep(wait)
+
+class RequestScheduler:
+    """Orders concurrent API calls by priority lane
+
+    At most max_in_flight calls run at once. When a slot frees up, waiting
+    structural calls (manual, chapter and article creation) go first, then
+    content updates and reads, then image uploads, so the manual's structure
+    appears in ScreenSteps while images are still uploading.
+    """
+
+    LANES = {'structure': 0, 'content': 1, 'images': 2}
+
+    def __init__(self, max_in_flight: int = 1):
+        self.max_in_flight = max(1, max_in_flight)
+        self.in_flight = 0
+        self.waiting = [0] * len(self.LANES)
+        self.condition = threading.Condition()
+
+    @contextlib.contextmanager
+    def slot(self, lane: str):
+        """Hold one of the in-flight slots; blocks while higher-priority calls are waiting"""
+        priority = self.LANES[lane]
+        with self.condition:
+            self.waiting[priority] += 1
+            self.condition.wait_for(lambda: self.in_flight < self.max_in_flight
+                                    and not any(self.waiting[:priority]))
+            self.waiting[priority] -= 1
+            self.in_flight += 1
+        try:
+            yield
+        finally:
+            with self.condition:
+                self.in_flight -= 1
+                self.condition.notify_all()
+
+def request_not_sent(error: Exception) -> bool:
+    """Whether a request error happened while connecting, before anything reached the server"""
+    if isinstance(error, requests.exceptions.ConnectTimeout):
+        return True
+    try:
+        from urllib3.exceptions import ConnectTimeoutError  # Base of NewConnectionError (refused, DNS)
+    except ImportError:
+        return False
+    reason = getattr(error.args[0], 'reason', None) if error.args else None
+    return isinstance(reason, ConnectTimeoutError)
+
+class RetryPolicy:
+    """Retry settings for API requests: exponential backoff with jitter
+
+    Server errors (5xx), timeouts and connection resets back off exponentially
+    from `backoff` seconds (capped at `max_backoff`, with jitter); 429 responses
+    wait for the server-provided retry_in. At most `max_retries` retries are
+    made, and never past `deadline` seconds after the first attempt.
+    
+    Only idempotent methods are retried after a 5xx, timeout or reset: the
+    server may already have carried out a POST (creating a manual, article or
+    file), so resending it would create a duplicate. A POST is retried only
+    when its connection failed before the request was sent, or after a 429.
+    """
+    
+    IDEMPOTENT_METHODS = ('GET', 'HEAD', 'PUT', 'DELETE')
+
+    def __init__(self, max_retries: int = 5, backoff: float = 2.0, max_backoff: float = 60.0,
+                 timeout: float = 60.0, deadline: Optional[float] = 600.0):
+        self.max_retries = max_retries
+        self.backoff = backoff
+        self.max_backoff = max_backoff
+        self.timeout = timeout
+        self.deadline = deadline
+
+    def backoff_delay(self, attempt: int) -> float:
+        """Delay before retrying after the given (1-based) failed attempt"""
+        delay = min(self.max_backoff, self.backoff * (2 ** (attempt - 1)))
+        # Equal jitter: keep half the delay, randomize the rest
+        return delay / 2 + random.uniform(0, delay / 2)
+
+    def retries_method(self, method: str, error: Optional[Exception] = None) -> bool:
+        """Whether a failed call (a 5xx response, or the given request error) may be resent"""
+        return method.upper() in self.IDEMPOTENT_METHODS or (error is not None and request_not_sent(error))
+
+    def can_retry(self, attempt: int, deadline: Optional[float], delay: float) -> bool:
+        """Whether another attempt is allowed after waiting `delay` seconds"""
+        if attempt > self.max_retries:
+            return False
+        if deadline is not None and time.monotonic() + delay > deadline:
+            return False
+        return True
+
+class ScreenStepsClient:
+    """ScreenSteps v2 API client
+
+    Usable on its own:
+
+        client = ScreenStepsClient('myaccount', 'admin', token)
+        for manual in client.list_manuals(site_id):
+            print(manual['id'], manual['title'])
+
+    Calls are paced by an optional shared RateLimiter, ordered by a
+    RequestScheduler and retried under a RetryPolicy. cancel() stops every
+    call in progress or waiting to retry with RequestCancelled (e.g. from a
+    signal handler or another thread). Objects created through the client
+    are passed to journal.record(kind, id, title) when a journal is set, and
+    every call that changes content to operation_log.record() when an
+    OperationLog (vlp2ss_oplog) is set.
+    """
+
+    def __init__(self, account: str, user: str, token: str, logger=None, base_url: Optional[str] = None):
+        self.account = account
+        self.user = user
+        self.token = token
+        self.logger = logger or logging.getLogger(__name__)
+        self.verbose = False  # Log every request and response
+        # The account's ScreenSteps site, unless base_url points elsewhere (e.g. vlp2ss_mockserver.py)
+        self.site_url = (base_url or f"https://{account}.screenstepslive.com").rstrip('/')
+        self.base_url = f"{self.site_url}/api/v2"
+        self.auth = HTTPBasicAuth(user, token)
+        self.session = requests.Session()
+        self.session.auth = self.auth
+        self.journal = None  # Records created objects (e.g. the uploader's UploadJournal), if any
+        self.operation_log = None  # Records every mutating call (vlp2ss_oplog.OperationLog), if any
+        self.retry_policy = RetryPolicy()
+        self.file_rate_limiter = RateLimiter(*FILE_UPLOAD_RATE)
+        self.request_rate_limiter = None  # Optional limiter shared with other API clients
+        self.scheduler = RequestScheduler()  # Priority lanes; replaced by a shared one for batches
+        self.compress_requests = False  # gzip JSON bodies; switched off if the server rejects them
+        self.paced = True  # Pause between calls and limit file uploads; off when replaying a cassette
+        self.retries = 0  # Requests retried after errors, timeouts or rate limiting (metrics)
+        self._cancelled = threading.Event()
+
+    def cancel(self):
+        """Stop all calls of this client: current and later ones raise RequestCancelled"""
+        self._cancelled.set()
+
+    def _wait(self, seconds: float):
+        """Sleep before a retry, ending early with RequestCancelled on cancel()"""
+        if self._cancelled.wait(seconds):
+            raise RequestCancelled("ScreenSteps API call cancelled")
+
+    def configure_transport(self, proxy: Optional[str] = None, ca_bundle: Optional[str] = None,
+                            insecure: bool = False):
+        """Configure proxy and TLS settings of the HTTP session
+
+        Without an explicit proxy, requests honors HTTP_PROXY/HTTPS_PROXY/NO_PROXY
+        from the environment. SOCKS proxies (socks5://, socks5h://) need the
+        PySocks package (pip install "requests[socks]").
+        """
+        if proxy:
+            if proxy.startswith('socks'):
+                try:
+                    import socks  # noqa: F401 - only checking availability
+                except ImportError:
+                    raise ValueError("SOCKS proxies require PySocks: pip3 install 'requests[socks]'")
+            self.session.proxies = {'http': proxy, 'https': proxy}
+            self.logger.info(f"Using proxy: {proxy}")
+
+        if insecure:
+            self.session.verify = False
+            import urllib3
+            urllib3.disable_warnings(urllib3.exceptions.InsecureRequestWarning)
+            self.logger.warning("TLS certificate verification is DISABLED (--insecure-skip-verify)")
+        elif ca_bundle:
+            if not Path(ca_bundle).exists():
+                raise FileNotFoundError(f"CA bundle not found: {ca_bundle}")
+            self.session.verify = ca_bundle
+            self.logger.info(f"Using CA bundle: {ca_bundle}")
+
+    def _record(self, kind: str, obj: Dict):
+        """Record a newly created object in the journal"""
+        if self.journal is not None and obj.get('id') is not None:
+            self.journal.record(kind, obj['id'], obj.get('title', ''))
+
+    def send(self, method: str, endpoint: str, replay_of: Optional[int] = None, **kwargs) -> requests.Response:
+        """Make a raw API call, e.g. an operation replayed from an operation log (replay_of is its op)"""
+        return self._request(method, endpoint, lane='structure', replay_of=replay_of, **kwargs)
+
+    def _request(self, method: str, endpoint: str, lane: str = 'content', expected_errors=(),
+                 replay_of: Optional[int] = None, **kwargs) -> requests.Response:
+        """Make an API request, recording it in the operation log if it changes content"""
+        if self.operation_log is None or method not in MUTATING_METHODS:
+            return self._send_request(method, endpoint, lane, expected_errors, **kwargs)
+        try:
+            response = self._send_request(method, endpoint, lane, expected_errors, **kwargs)
+        except Exception as e:
+            status = getattr(getattr(e, 'response', None), 'status_code', None)
+            self.operation_log.record(method, endpoint, kwargs, status, error=str(e) or type(e).__name__,
+                                      replay_of=replay_of)
+            raise
+        try:
+            result = response.json() if response.content else None
+        except ValueError:
+            result = None
+        self.operation_log.record(method, endpoint, kwargs, response.status_code, result=result, replay_of=replay_of)
+        return response
+
+    def _send_request(self, method: str, endpoint: str, lane: str = 'content', expected_errors=(),
+                      **kwargs) -> requests.Response:
+        """Make API request with rate limiting and retry logic
+        
+        lane is the RequestScheduler priority: 'structure', 'content' or 'images'.
+        Error statuses in expected_errors are raised without being logged as failures.
+        """
+        url = f"{self.base_url}/{endpoint}"
+        
+        # Log request details in verbose mode
+        if self.verbose:
+            self.logger.info("=" * 70)
+            self.logger.info("API REQUEST DETAILS:")
+            self.logger.info(f"  Endpoint: {method} {url}")
+            self.logger.info(f"  Username: {self.user}")
+            if 'json' in kwargs:
+                self.logger.info(f"  JSON Data: {json.dumps(kwargs['json'], indent=2)}")
+            if 'data' in kwargs:
+                self.logger.info(f"  Form Data: {kwargs['data']}")
+            if 'files' in kwargs:
+                self.logger.info(f"  Files: {list(kwargs['files'].keys())}")
+            self.logger.info("=" * 70)
+        
+        policy = self.retry_policy
+        kwargs.setdefault('timeout', policy.timeout)
+        deadline = time.monotonic() + policy.deadline if policy.deadline else None
+        attempt = 0
+        
+        while True:
+            if self._cancelled.is_set():
+                raise RequestCancelled(f"ScreenSteps API call cancelled: {method} {endpoint}")
+            attempt += 1
+            self._rewind_files(kwargs)
+            try:
+                with self.scheduler.slot(lane):
+                    if self.request_rate_limiter:
+                        self.request_rate_limiter.acquire()
+                    response = self.session.request(method, url, **self._encode_body(kwargs))
+            except (requests.exceptions.ConnectionError, requests.exceptions.Timeout) as e:
+                # Connection resets and timeouts are transient - back off and retry
+                delay = policy.backoff_delay(attempt)
+                if policy.retries_method(method, e) and policy.can_retry(attempt, deadline, delay):
+                    self.retries += 1
+                    self.logger.warning(f"{type(e).__name__} on {method} {endpoint}. "
+                                        f"Retrying in {delay:.1f} seconds (attempt {attempt}/{policy.max_retries})...")
+                    self._wait(delay)
+                    continue
+                self._log_request_exception(method, url, kwargs, e)
+                raise
+            except requests.exceptions.RequestException as e:
+                self._log_request_exception(method, url, kwargs, e)
+                raise
+            
+            # Log response details in verbose mode
+            if self.verbose:
+                self.logger.info("API RESPONSE:")
+                self.logger.info(f"  Status Code: {response.status_code}")
+                self.logger.info(f"  Headers: {dict(response.headers)}")
+                self._log_response_body(response)
+                self.logger.info("=" * 70)
+            
+            if response.status_code == 415 and self.compress_requests and 'json' in kwargs:
+                # Server does not accept compressed bodies - resend uncompressed from now on
+                self.logger.warning("Server rejected a gzip-compressed request body, disabling request compression")
+                self.compress_requests = False
+                continue
+            
+            if response.status_code in (200, 201, 204):
+                # Add delay between successful API calls to avoid rate limiting
+                if self.paced:
+                    time.sleep(0.25)
+                return response
+            elif response.status_code == 429:
+                # Rate limit exceeded - check for retry_in value
+                try:
+                    retry_in = float(response.json().get('retry_in', 60))
+                except (ValueError, TypeError, AttributeError):
+                    retry_in = 60.0
+                if policy.can_retry(attempt, deadline, retry_in):
+                    self.retries += 1
+                    self.logger.warning(f"Rate limit exceeded. Retrying in {retry_in:g} seconds...")
+                    self._wait(retry_in)
+                    continue
+            elif response.status_code >= 500:
+                delay = policy.backoff_delay(attempt)
+                if policy.retries_method(method) and policy.can_retry(attempt, deadline, delay):
+                    self.retries += 1
+                    self.logger.warning(f"Server error {response.status_code} on {method} {endpoint}. "
+                                        f"Retrying in {delay:.1f} seconds (attempt {attempt}/{policy.max_retries})...")
+                    self._wait(delay)
+                    continue
+            
+            if response.status_code in expected_errors:
+                response.raise_for_status()
+            self.logger.error("=" * 70)
+            self.logger.error("API REQUEST FAILED:")
+            self.logger.error(f"  Endpoint: {method} {url}")
+            self.logger.error(f"  Username: {self.user}")
+            self.logger.error(f"  Status Code: {response.status_code}")
+            if attempt > 1:
+                self.logger.error(f"  Attempts: {attempt}")
+            if 'json' in kwargs:
+                self.logger.error(f"  Request JSON: {json.dumps(kwargs['json'], indent=2)}")
+            self.logger.error(f"  Response: {response.text}")
+            self.logger.error("=" * 70)
+            response.raise_for_status()
+            return response
+    
+    def _log_request_exception(self, method: str, url: str, kwargs: Dict, error: Exception):
+        """Log a request that failed without a usable response"""
+        self.logger.error("=" * 70)
+        self.logger.error("REQUEST EXCEPTION:")
+        self.logger.error(f"  Endpoint: {method} {url}")
+        self.logger.error(f"  Username: {self.user}")
+        if 'json' in kwargs:
+            self.logger.error(f"  Request JSON: {json.dumps(kwargs['json'], indent=2)}")
+        self.logger.error(f"  Error: {error}")
+        self.logger.error("=" * 70)
+    
+    def _encode_body(self, kwargs: Dict) -> Dict:
+        """Return request arguments with a large JSON body gzip-compressed (--compress-requests)"""
+        if not self.compress_requests or 'json' not in kwargs:
+            return kwargs
+        body = json.dumps(kwargs['json']).encode('utf-8')
+        if len(body) < COMPRESS_MIN_BYTES:
+            return kwargs
+        
+        encoded = {k: v for k, v in kwargs.items() if k != 'json'}
+        encoded['data'] = gzip.compress(body)
+        encoded['headers'] = {**(kwargs.get('headers') or {}),
+                              'Content-Type': 'application/json',
+                              'Content-Encoding': 'gzip'}
+        return encoded
+    
+    def _log_response_body(self, response: requests.Response):
+        """Log a bounded preview of a (gzip/deflate-decoded) response body instead of the whole payload"""
+        body = response.content
+        text = body[:VERBOSE_BODY_LIMIT].decode(response.encoding or 'utf-8', errors='replace')
+        suffix = f" ... ({len(body)} bytes total)" if len(body) > VERBOSE_BODY_LIMIT else ''
+        self.logger.info(f"  Body: {text}{suffix}")
+    
+    @staticmethod
+    def _rewind_files(kwargs: Dict):
+        """Rewind multipart file handles so a retried upload resends the whole file"""
+        for value in (kwargs.get('files') or {}).values():
+            if isinstance(value, tuple) and len(value) > 1 and hasattr(value[1], 'seek'):
+                value[1].seek(0)
+    
+    def _paginate(self, endpoint: str, key: str, params: Optional[Dict] = None,
+                  expected_errors=()) -> Iterator[Dict]:
+        """Yield the objects listed under key on every page of a GET endpoint
+        
+        Listings are single pages today; a response carrying next_page (top
+        level or in meta, a number or a URL with ?page=N) or total_pages is
+        followed page by page. A next_page that names no page ends the listing.
+        """
+        params = dict(params or {})
+        page = int(params.get('page', 1))
+        for _ in range(MAX_PAGES):
+            data = self._request('GET', endpoint, expected_errors=expected_errors, params=params).json()
+            yield from data.get(key, [])
+            meta = data.get('meta') or {}
+            raw_next = data.get('next_page') or meta.get('next_page')
+            next_page = page_number(raw_next)
+            total_pages = page_number(data.get('total_pages') or meta.get('total_pages'))
+            if not raw_next and total_pages and page < total_pages:
+                next_page = page + 1
+            if next_page is None or next_page <= page or not data.get(key):
+                return
+            page = next_page
+            params['page'] = page
+    
+    def list_sites(self) -> List[Site]:
+        """All sites the user can access"""
+        return list(self._paginate('sites', 'sites'))
+    
+    def get_sites(self) -> List[Site]:
+        """Get all sites"""
+        return self.list_sites()
+    
+    def get_site(self, site_id: str) -> Site:
+        """Get site details"""
+        response = self._request('GET', f'sites/{site_id}')
+        return response.json().get('site', {})
+    
+    def list_manuals(self, site_id: str) -> List[ManualSummary]:
+        """Manuals of a site"""
+        return self.get_site(site_id).get('manuals', [])
+
+    def get_manual(self, site_id: str, manual_id: str) -> Manual:
+        """Get manual details, including its chapters"""
+        response = self._request('GET', f'sites/{site_id}/manuals/{manual_id}')
+        return response.json().get('manual', {})
+    
+    def list_chapters(self, site_id: str, manual_id: str) -> List[Chapter]:
+        """Chapters of a manual, in order"""
+        return sorted(self.get_manual(site_id, manual_id).get('chapters', []),
+                      key=lambda chapter: chapter.get('position', 0))
+
+    def get_chapter(self, site_id: str, chapter_id: str) -> Chapter:
+        """Get chapter details, including its articles"""
+        response = self._request('GET', f'sites/{site_id}/chapters/{chapter_id}')
+        return response.json().get('chapter', {})
+    
+    def list_articles(self, site_id: str, chapter_id: str) -> List[ArticleSummary]:
+        """Articles of a chapter, in order"""
+        return sorted(self.get_chapter(site_id, chapter_id).get('articles', []),
+                      key=lambda article: article.get('position', 0))
+    
+    def iter_articles(self, site_id: str, manual_id: str) -> Iterator[ArticleSummary]:
+        """Every article of a manual, chapter by chapter"""
+        for chapter in self.list_chapters(site_id, manual_id):
+            yield from self.list_articles(site_id, chapter['id'])
+
+    def search_articles(self, site_id: str, text: str) -> List[ArticleSummary]:
+        """Articles of a site matching a search text
+        
+        Uses the site search endpoint. Where it is not available (404), every
+        manual is walked instead and articles whose title contains all words
+        of the text are returned, with their manual_id set.
+        """
+        try:
+            return list(self._paginate(f'sites/{site_id}/searches', 'articles', {'text': text},
+                                       expected_errors=(404,)))
+        except requests.exceptions.HTTPError as e:
+            if e.response is None or e.response.status_code != 404:
+                raise
+        self.logger.warning("Search endpoint not available, matching article titles instead")
+        words = text.lower().split()
+        matches = []
+        for manual in self.list_manuals(site_id):
+            for article in self.iter_articles(site_id, manual['id']):
+                if all(word in (article.get('title') or '').lower() for word in words):
+                    matches.append({**article, 'manual_id': manual['id']})
+        return matches
+
+    def find_manual_by_title(self, site_id: str, title: str) -> Optional[ManualSummary]:
+        """Find an existing manual in a site by its exact title"""
+        for manual in self.get_site(site_id).get('manuals', []):
+            if manual.get('title') == title:
+                return manual
+        return None
+
+    def create_manual(self, site_id: str, title: str, chapters: List[Dict] = None, 
+                     published: bool = True, description: str = "") -> Manual:
+        """Create a new manual with chapters"""
+        data = {
+            'manual': {
+                'title': title,
+                'published': published
+            }
+        }
+        if description:
+            data['manual']['description'] = description
+        
+        # Add chapters array if provided
+        if chapters:
+            data['manual']['chapters'] = chapters
+        
+        response = self._request('POST', f'sites/{site_id}/manuals', lane='structure', json=data)
+        manual = response.json().get('manual', {})
+        self._record('manual', manual)
+        for chapter in manual.get('chapters', []):
+            self._record('chapter', chapter)
+        return manual
+    
+    def create_chapter(self, site_id: str, manual_id: str, title: str, 
+                      position: int, description: str = "", published: bool = True) -> Chapter:
+        """Create a new chapter"""
+        data = {
+            'chapter': {
+                'position': position,
+                'title': title,
+                'published': published,
+                'manual_id': int(manual_id)
+            }
+        }
+        if description:
+            data['chapter']['description'] = description
+        response = self._request('POST', f'sites/{site_id}/chapters', lane='structure',
+                                json=data)
+        chapter = response.json().get('chapter', {})
+        self._record('chapter', chapter)
+        return chapter
+    
+    def create_article(self, site_id: str, chapter_id: str, title: str, 
+                      position: int, published: bool = True) -> Article:
+        """Create a new article (placeholder - content added separately)"""
+        data = {
+            'article': {
+                'position': position,
+                'title': title,
+                'published': published,
+                'chapter_id': int(chapter_id)
+            }
+        }
+        response = self._request('POST', f'sites/{site_id}/articles', lane='structure',
+                                json=data)
+        article = response.json().get('article', {})
+        self._record('article', article)
+        return article
+    
+    def upload_file(self, site_id: str, file_path: Path, asset_type: str = 'FileAsset') -> Dict:
+        """Upload a file through the ScreenSteps Files API; returns the response with its 'file'
+        
+        asset_type is 'ImageAsset' for images, 'FileAsset' for other files (e.g. videos).
+        Reference: https://help.screensteps.com/a/1540764-creating-images-or-file-attachments-via-the-public-api
+        """
+        # ScreenSteps rate limit: 8 files per 10 seconds, shared by all upload workers
+        if self.paced:
+            self.file_rate_limiter.acquire()
+        content_type = mimetypes.guess_type(file_path.name)[0] or 'application/octet-stream'
+        with open(file_path, 'rb') as f:
+            files = {
+                'type': (None, asset_type),
+                'file': (file_path.name, f, content_type)
+            }
+            response = self._request('POST', f'sites/{site_id}/files', lane='images', files=files)
+            result = response.json()
+            self._record('file', result.get('file', {}))
+            return result
+    
+    def get_file(self, site_id: str, file_id: str) -> FileAsset:
+        """Get an uploaded file/image asset"""
+        response = self._request('GET', f'sites/{site_id}/files/{file_id}')
+        return response.json().get('file', {})
+    
+    def get_article(self, site_id: str, article_id: str) -> Article:
+        """Get article details, including its content blocks"""
+        response = self._request('GET', f'sites/{site_id}/articles/{article_id}')
+        return response.json().get('article', {})
+    
+    def update_manual(self, site_id: str, manual_id: str, **fields) -> Manual:
+        """Update manual attributes (e.g. published=True)"""
+        response = self._request('PUT', f'sites/{site_id}/manuals/{manual_id}',
+                                 json={'manual': fields})
+        return response.json().get('manual', {})
+    
+    def update_chapter(self, site_id: str, chapter_id: str, **fields) -> Chapter:
+        """Update chapter attributes (e.g. published=True)"""
+        response = self._request('PUT', f'sites/{site_id}/chapters/{chapter_id}',
+                                 json={'chapter': fields})
+        return response.json().get('chapter', {})
+    
+    def update_article(self, site_id: str, article_id: str, **fields) -> Article:
+        """Update article attributes (e.g. published=True)"""
+        response = self._request('PUT', f'sites/{site_id}/articles/{article_id}',
+                                 json={'article': fields})
+        return response.json().get('article', {})
+    
+    def delete_manual(self, site_id: str, manual_id: str):
+        """Delete a manual (including its chapters and articles)"""
+        self._request('DELETE', f'sites/{site_id}/manuals/{manual_id}')
+    
+    def delete_chapter(self, site_id: str, chapter_id: str):
+        """Delete a chapter"""
+        self._request('DELETE', f'sites/{site_id}/chapters/{chapter_id}')
+    
+    def delete_article(self, site_id: str, article_id: str):
+        """Delete an article"""
+        self._request('DELETE', f'sites/{site_id}/articles/{article_id}')
+    
+    def delete_file(self, site_id: str, file_id: str):
+        """Delete an uploaded file/image asset"""
+        self._request('DELETE', f'sites/{site_id}/files/{file_id}')
+    
+    def download_file(self, url: str, destination: Path) -> bool:
+        """Download an image asset to destination; False unless the response is an image
+        
+        Credentials are only sent to the account's own host, never to a CDN.
+        """
+        own_host = urlparse(url).hostname == urlparse(self.base_url).hostname
+        # auth=() overrides the session credentials for other hosts
+        response = self.session.get(url, auth=self.auth if own_host else (), timeout=self.retry_policy.timeout)
+        if response.status_code != 200 or not response.headers.get('Content-Type', '').startswith('image/'):
+            return False
+        destination.write_bytes(response.content)
+        return True
+    
+    def article_url(self, article_id: str) -> str:
+        """Public URL of an article"""
+        return f"{self.site_url}/a/{article_id}"
+    
+    def manual_url(self, manual_id: str) -> str:
+        """Public URL of a manual"""
+        return f"{self.site_url}/m/{manual_id}"
+    
+    def update_article_contents(self, site_id: str, article_id: str, 
+                               title: str, content_blocks: List[Dict], 
+                               publish: bool = True) -> Article:
+        """Update article contents with content blocks"""
+        data = {
+            'article': {
+                'title': title,
+                'content_blocks': content_blocks,
+                'publish': publish
+            }
+        }
+        response = self._request('POST', f'sites/{site_id}/articles/{article_id}/contents', 
+                                json=data)
+        return response.json().get('article', {})
+    
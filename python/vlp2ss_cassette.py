@@ -0,0 +1,190 @@
+#!/usr/bin/env python3
+"""
+VLP2SS API Cassettes
+Record ScreenSteps API request/response pairs to a cassette file and play
+them back without a server, for deterministic, credential-free test runs and demos
+
+Author: Burke Azbill
+Version: 1.0.3
+"""
+
+import json
+import gzip
+import base64
+import hashlib
+import threading
+from pathlib import Path
+from typing import Callable, Dict, List
+import requests
+from requests.structures import CaseInsensitiveDict
+
+CASSETTE_VERSION = 1
+# Recorded URLs start with this instead of the client's site URL, so a cassette
+# recorded against one account (or the mock server) plays back for any other
+SITE_PLACEHOLDER = '{site}'
+# Response headers kept in a cassette; the rest vary between runs
+RECORDED_HEADERS = ('Content-Type', 'Retry-After')
+
+class CassetteError(requests.exceptions.RequestException):
+    """A cassette that cannot be read, or a request it holds no response for"""
+
+class Cassette:
+    """Request/response pairs of one run, one JSON line each after a header line
+
+    In 'record' mode, wrap() returns a transport that sends requests on and
+    appends each exchange to the file as it completes. In 'replay' mode, the
+    transport answers from the file instead: a request gets the first unused
+    response recorded for the same method, URL and query, so repeated calls
+    (e.g. retries after 429) play back in order. Request bodies are recorded
+    for reading but not matched, since they carry generated UUIDs. Credentials
+    are never recorded; uploaded files are recorded by name, size and SHA-256.
+    """
+
+    def __init__(self, path: Path, mode: str):
+        if mode not in ('record', 'replay'):
+            raise ValueError(f"Cassette mode must be 'record' or 'replay', not {mode!r}")
+        self.path = Path(path)
+        self.mode = mode
+        self.interactions: List[Dict] = []
+        self._used = set()
+        self._lock = threading.Lock()
+        if mode == 'replay':
+            self.interactions = load_interactions(self.path)
+        else:
+            self.path.parent.mkdir(parents=True, exist_ok=True)
+            with open(self.path, 'w', encoding='utf-8') as f:
+                f.write(json.dumps({'cassette': CASSETTE_VERSION}) + '\n')
+
+    @property
+    def unplayed(self) -> int:
+        """Recorded exchanges not played back (yet)"""
+        return len(self.interactions) - len(self._used)
+
+    def wrap(self, send: Callable, site_url: str) -> Callable:
+        """A drop-in for Session.request: records around send, or replays without calling it"""
+        def transport(method, url, **kwargs):
+            request = recorded_request(method, url, kwargs, site_url)
+            if self.mode == 'replay':
+                return self._play(request)
+            response = send(method, url, **kwargs)
+            self._append({'request': request, 'response': recorded_response(response)})
+            return response
+        return transport
+
+    def _append(self, interaction: Dict):
+        with self._lock:
+            self.interactions.append(interaction)
+            with open(self.path, 'a', encoding='utf-8') as f:
+                f.write(json.dumps(interaction, ensure_ascii=False) + '\n')
+
+    def _play(self, request: Dict) -> requests.Response:
+        with self._lock:
+            for index, interaction in enumerate(self.interactions):
+                recorded = interaction['request']
+                if index not in self._used and recorded['method'] == request['method'] \
+                        and recorded['url'] == request['url'] and recorded.get('params') == request.get('params'):
+                    self._used.add(index)
+                    return build_response(interaction['response'], request['url'])
+        raise CassetteError(f"No recorded response for {request['method']} {request['url']} in {self.path}")
+
+def load_interactions(path: Path) -> List[Dict]:
+    """The exchanges of a cassette file, in recorded order"""
+    try:
+        with open(path, 'r', encoding='utf-8') as f:
+            lines = [line for line in f if line.strip()]
+    except OSError as e:
+        raise CassetteError(f"Cannot read cassette {path}: {e}")
+    try:
+        header = json.loads(lines[0]) if lines else {}
+        interactions = [json.loads(line) for line in lines[1:]]
+    except ValueError as e:
+        raise CassetteError(f"Cassette {path} is not valid JSON lines: {e}")
+    if header.get('cassette') != CASSETTE_VERSION:
+        raise CassetteError(f"{path} is not a version {CASSETTE_VERSION} cassette")
+    for interaction in interactions:
+        if not isinstance(interaction.get('request'), dict) or not isinstance(interaction.get('response'), dict):
+            raise CassetteError(f"Cassette {path} holds an entry without request and response")
+    return interactions
+
+def recorded_request(method: str, url: str, kwargs: Dict, site_url: str) -> Dict:
+    """How a request appears in a cassette (and is matched on replay)"""
+    if url.startswith(site_url):
+        url = SITE_PLACEHOLDER + url[len(site_url):]
+    request = {'method': method.upper(), 'url': url}
+    if kwargs.get('params'):
+        request['params'] = {str(key): str(value) for key, value in sorted(kwargs['params'].items())}
+    if kwargs.get('json') is not None:
+        request['json'] = kwargs['json']
+    elif isinstance(kwargs.get('data'), bytes):
+        # JSON bodies gzip-compressed by --compress-requests
+        data = kwargs['data']
+        if (kwargs.get('headers') or {}).get('Content-Encoding') == 'gzip':
+            data = gzip.decompress(data)
+        try:
+            request['json'] = json.loads(data)
+        except ValueError:
+            request['data'] = data.decode('utf-8', errors='replace')
+    elif kwargs.get('data') is not None:
+        request['data'] = kwargs['data']
+    files = {}
+    for field, value in (kwargs.get('files') or {}).items():
+        if isinstance(value, tuple) and len(value) > 1 and hasattr(value[1], 'read'):
+            handle = value[1]
+            position = handle.tell()
+            content = handle.read()
+            handle.seek(position)
+            files[field] = {'filename': value[0], 'size': len(content),
+                            'sha256': hashlib.sha256(content).hexdigest()}
+        else:
+            files[field] = value[1] if isinstance(value, tuple) and len(value) > 1 else value
+    if files:
+        request['files'] = files
+    return request
+
+def recorded_response(response: requests.Response) -> Dict:
+    """A response as stored in a cassette: status, selected headers and decoded body (text or base64)"""
+    recorded = {'status': response.status_code,
+                'headers': {name: response.headers[name] for name in RECORDED_HEADERS if name in response.headers}}
+    content = response.content or b''
+    try:
+        recorded['body'] = content.decode('utf-8')
+    except UnicodeDecodeError:
+        recorded['body_base64'] = base64.b64encode(content).decode('ascii')
+    return recorded
+
+def build_response(recorded: Dict, url: str) -> requests.Response:
+    """A requests.Response carrying a recorded status, headers and body"""
+    response = requests.Response()
+    response.status_code = recorded['status']
+    response.headers = CaseInsensitiveDict(recorded.get('headers') or {})
+    if 'body_base64' in recorded:
+        response._content = base64.b64decode(recorded['body_base64'])
+    else:
+        response._content = (recorded.get('body') or '').encode('utf-8')
+    response._content_consumed = True
+    response.encoding = 'utf-8'
+    response.url = url
+    response.reason = 'Recorded'
+    return response
+
+_open_cassettes = {}
+_open_cassettes_lock = threading.Lock()
+
+def open_cassette(path: Path, mode: str) -> Cassette:
+    """The Cassette of a file, shared by every client of this process that uses it"""
+    key = str(Path(path).resolve())
+    with _open_cassettes_lock:
+        if key not in _open_cassettes or _open_cassettes[key].mode != mode:
+            _open_cassettes[key] = Cassette(path, mode)
+        return _open_cassettes[key]
+
+def use_cassette(client, path: Path, mode: str) -> Cassette:
+    """Route a ScreenStepsClient's HTTP traffic through a cassette; returns the cassette
+
+    Replay skips the client's pacing, since nothing reaches ScreenSteps.
+    """
+    cassette = open_cassette(path, mode)
+    client.session.request = cassette.wrap(client.session.request, client.site_url)
+    if mode == 'replay':
+        client.paced = False
+    return cassette
@@ -0,0 +1,259 @@
+#!/usr/bin/env python3
+"""
+VLP2SS Run Artifacts
+Tracks the temp extraction and output directories of conversion runs in a
+state file and removes the ones interrupted runs left behind
+(python3 vlp2ss_clean.py), and guards the -o directory the converters clean
+
+Author: Burke Azbill
+Version: 1.0.3
+"""
+
+import os
+import sys
+import json
+import time
+import shutil
+import socket
+import argparse
+from pathlib import Path
+from contextlib import contextmanager
+from datetime import datetime
+from typing import Dict, List, Optional
+
+try:
+    import fcntl
+except ImportError:  # Windows
+    fcntl = None
+    import msvcrt
+
+# Artifacts of every run (override with --state-file or VLP2SS_STATE_FILE)
+DEFAULT_STATE_FILE = Path(os.environ.get('VLP2SS_STATE_FILE') or
+                          Path(os.environ.get('VLP2SS_CACHE_DIR') or Path.home() / ".cache" / "vlp2ss") / "artifacts.json")
+
+# Parent of the per-run extraction directories
+DEFAULT_TEMP_ROOT = Path("temp")
+
+# Orphans younger than this are left alone (a run may still be using them)
+DEFAULT_RETENTION_HOURS = 24
+
+# Marks an output directory the converters created, so a later run may clean it
+OUTPUT_MARKER = '.vlp2ss-output'
+
+# Files a converter writes at the top of a manual's output directory
+OUTPUT_INDEX_FILES = ('summary.json', 'languages.json')
+
+class OutputDirError(Exception):
+    """Raised instead of cleaning an output directory the converters did not create"""
+
+def _load(state_file: Path) -> List[Dict]:
+    try:
+        with open(state_file, 'r', encoding='utf-8') as f:
+            entries = json.load(f)
+    except (OSError, ValueError):
+        return []
+    return entries if isinstance(entries, list) else []
+
+def _save(state_file: Path, entries: List[Dict]):
+    state_file.parent.mkdir(parents=True, exist_ok=True)
+    partial = state_file.with_name(f"{state_file.name}.{os.getpid()}.tmp")
+    with open(partial, 'w', encoding='utf-8') as f:
+        json.dump(entries, f, indent=2)
+    os.replace(partial, state_file)
+
+@contextmanager
+def _locked(state_file: Path):
+    """Hold an exclusive lock on the state file's sidecar .lock file, so concurrent runs keep each other's entries"""
+    state_file.parent.mkdir(parents=True, exist_ok=True)
+    with open(state_file.with_name(f"{state_file.name}.lock"), 'a+') as lock:
+        if fcntl:
+            fcntl.flock(lock, fcntl.LOCK_EX)
+        else:
+            lock.seek(0)
+            msvcrt.locking(lock.fileno(), msvcrt.LK_LOCK, 1)
+        try:
+            yield
+        finally:
+            if fcntl:
+                fcntl.flock(lock, fcntl.LOCK_UN)
+            else:
+                lock.seek(0)
+                msvcrt.locking(lock.fileno(), msvcrt.LK_UNLCK, 1)
+
+def _update(state_file: Path, change) -> Optional[str]:
+    """Apply change(entries) to the state file under its lock; returns an error message, or None"""
+    try:
+        state_file = Path(state_file)
+        with _locked(state_file):
+            entries = _load(state_file)
+            change(entries)
+            _save(state_file, entries)
+    except OSError as e:
+        return str(e)
+    return None
+
+def register_artifact(kind: str, path: Path, source: str = '',
+                      state_file: Path = DEFAULT_STATE_FILE) -> Optional[str]:
+    """Record a temp ('temp') or output ('output') directory the current run is creating"""
+    entry = {
+        'kind': kind,
+        'path': str(Path(path).resolve()),
+        'source': str(source),
+        'pid': os.getpid(),
+        'host': socket.gethostname(),
+        'created_at': datetime.now().isoformat(timespec='seconds'),
+        'status': 'running',
+    }
+    return _update(state_file, lambda entries: entries.append(entry))
+
+def finish_artifact(path: Path, removed: bool = False, state_file: Path = DEFAULT_STATE_FILE) -> Optional[str]:
+    """Mark a directory as finished by its run: dropped from the state if removed, else 'complete'"""
+    path = str(Path(path).resolve())
+
+    def change(entries: List[Dict]):
+        for entry in [e for e in entries if e.get('path') == path]:
+            if removed:
+                entries.remove(entry)
+            else:
+                entry['status'] = 'complete'
+    return _update(state_file, change)
+
+def _run_alive(entry: Dict) -> bool:
+    """Whether the process that created an entry may still be running"""
+    if entry.get('host') != socket.gethostname() or os.name == 'nt':
+        return False  # Unknown; the retention period protects running conversions
+    try:
+        os.kill(int(entry.get('pid', 0)), 0)
+    except (OSError, ValueError):
+        return False
+    return True
+
+def _age_hours(path: Path) -> float:
+    return (time.time() - path.stat().st_mtime) / 3600
+
+def find_orphans(retention_hours: float = DEFAULT_RETENTION_HOURS, state_file: Path = DEFAULT_STATE_FILE,
+                 temp_root: Optional[Path] = DEFAULT_TEMP_ROOT, include_kept: bool = False) -> List[Dict]:
+    """Artifacts of interrupted runs older than the retention period
+
+    Tracked temp and output directories whose run did not finish and is no
+    longer running qualify; temp directories kept on purpose (--no-cleanup)
+    only with include_kept. Untracked directories under temp_root (left by
+    runs before tracking existed) qualify by age alone. Finished output
+    directories are never orphans.
+    """
+    orphans = []
+    entries = _load(Path(state_file))
+    tracked = {entry.get('path') for entry in entries}
+    for entry in entries:
+        path = Path(entry.get('path', ''))
+        if not path.exists() or _run_alive(entry) and entry.get('status') == 'running':
+            continue
+        if entry.get('status') == 'complete' and not (include_kept and entry.get('kind') == 'temp'):
+            continue
+        age = _age_hours(path)
+        if age >= retention_hours:
+            orphans.append({**entry, 'age_hours': round(age, 1), 'tracked': True})
+    if temp_root and Path(temp_root).is_dir():
+        for path in Path(temp_root).iterdir():
+            if path.is_dir() and str(path.resolve()) not in tracked:
+                age = _age_hours(path)
+                if age >= retention_hours:
+                    orphans.append({'kind': 'temp', 'path': str(path.resolve()), 'source': '',
+                                    'age_hours': round(age, 1), 'tracked': False})
+    return orphans
+
+def _size(path: Path) -> int:
+    return sum(f.stat().st_size for f in path.rglob('*') if f.is_file())
+
+def remove_orphans(orphans: List[Dict], state_file: Path = DEFAULT_STATE_FILE) -> List[str]:
+    """Delete orphaned directories and drop them from the state; returns error messages"""
+    errors = []
+    for orphan in orphans:
+        try:
+            shutil.rmtree(orphan['path'])
+        except OSError as e:
+            errors.append(f"{orphan['path']}: {e}")
+            continue
+        if orphan.get('tracked'):
+            error = finish_artifact(Path(orphan['path']), removed=True, state_file=state_file)
+            if error:
+                errors.append(error)
+    # Forget entries whose directory is gone for another reason
+    _update(Path(state_file), lambda entries: entries.__setitem__(
+        slice(None), [e for e in entries if Path(e.get('path', '')).exists()]))
+    return errors
+
+def is_tool_output(output_dir: Path) -> bool:
+    """Whether a directory was created by a converter: it has the marker, or (from
+    runs before the marker existed) only holds manual directories with a summary"""
+    if (output_dir / OUTPUT_MARKER).exists():
+        return True
+    children = list(output_dir.iterdir())
+    return bool(children) and all(child.is_dir() and any((child / name).exists() for name in OUTPUT_INDEX_FILES)
+                                  for child in children)
+
+def prepare_output_dir(output_dir: Path, clean: bool = True, force: bool = False,
+                       protected: Optional[List[Path]] = None):
+    """Create the -o directory, or empty it for a fresh run when clean
+
+    A non-empty directory is only emptied when a converter created it (see
+    is_tool_output) or with force; otherwise OutputDirError is raised. A
+    directory holding one of the protected paths (e.g. the input) or the
+    working directory is never emptied. Without clean nothing is removed
+    and the new output is written next to what is there.
+    """
+    output_dir = Path(output_dir)
+    if output_dir.is_dir() and any(output_dir.iterdir()):
+        if not clean:
+            return
+        resolved = output_dir.resolve()
+        for path in [Path.cwd(), Path.home()] + list(protected or []):
+            path = Path(path).resolve()
+            if path == resolved or resolved in path.parents:
+                raise OutputDirError(f"Refusing to empty {output_dir}: it contains {path}")
+        if not (force or is_tool_output(output_dir)):
+            raise OutputDirError(f"Refusing to empty {output_dir}: it is not empty and was not created by "
+                                 f"vlp2ss (use --force to empty it, or --no-clean-output to write next to its files)")
+        shutil.rmtree(output_dir)
+    elif output_dir.exists() and not output_dir.is_dir():
+        raise OutputDirError(f"Output path {output_dir} is a file")
+    output_dir.mkdir(parents=True, exist_ok=True)
+    (output_dir / OUTPUT_MARKER).touch()
+
+def main() -> int:
+    parser = argparse.ArgumentParser(
+        description='Remove temp extraction and output directories left behind by interrupted conversion runs',
+        epilog='Finished output directories are never removed')
+    parser.add_argument('--older-than', type=float, default=DEFAULT_RETENTION_HOURS, metavar='HOURS',
+                        help=f'Only remove artifacts older than HOURS (default: {DEFAULT_RETENTION_HOURS})')
+    parser.add_argument('--include-kept', action='store_true',
+                        help='Also remove temp directories kept with --no-cleanup')
+    parser.add_argument('--temp-dir', type=Path, default=DEFAULT_TEMP_ROOT, metavar='DIR',
+                        help=f'Extraction directory whose untracked subdirectories also count (default: {DEFAULT_TEMP_ROOT})')
+    parser.add_argument('--state-file', type=Path, default=DEFAULT_STATE_FILE, metavar='FILE',
+                        help=f'Artifact state file (default: {DEFAULT_STATE_FILE}, or VLP2SS_STATE_FILE env var)')
+    parser.add_argument('-n', '--dry-run', action='store_true',
+                        help='List what would be removed without deleting anything')
+    args = parser.parse_args()
+
+    orphans = find_orphans(args.older_than, args.state_file, args.temp_dir, args.include_kept)
+    if not orphans:
+        print(f"No orphaned artifacts older than {args.older_than:g} hours")
+        return 0
+    total = 0
+    for orphan in orphans:
+        size = _size(Path(orphan['path']))
+        total += size
+        source = f" from {orphan['source']}" if orphan.get('source') else ''
+        print(f"{orphan['kind']:6}  {size / (1024 * 1024):9.1f} MB  {orphan['age_hours']:7.1f} h  {orphan['path']}{source}")
+    verb = "Would remove" if args.dry_run else "Removing"
+    print(f"{verb} {len(orphans)} directories ({total / (1024 * 1024):.1f} MB)")
+    if args.dry_run:
+        return 0
+    errors = remove_orphans(orphans, args.state_file)
+    for error in errors:
+        print(f"Error: {error}", file=sys.stderr)
+    return 1 if errors else 0
+
+if __name__ == "__main__":
+    sys.exit(main())
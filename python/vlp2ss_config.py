@@ -0,0 +1,157 @@
+#!/usr/bin/env python3
+"""
+VLP2SS Configuration
+Loads the VLP2SS config file (~/.vlp2ss.yaml) and applies named profiles to the
+command-line parsers of the converter and uploader, and keeps API tokens in
+the OS keychain
+
+Author: Burke Azbill
+Version: 1.0.3
+"""
+
+import os
+import json
+import argparse
+from pathlib import Path
+from typing import Dict, List, Optional, Tuple
+
+# Default config file location (override with --config or VLP2SS_CONFIG)
+DEFAULT_CONFIG_PATH = Path.home() / ".vlp2ss.yaml"
+
+# Profile keys that are not command-line defaults
+PROFILE_SECTIONS = {'defaults', 'class_map', 'image_formats', 'smtp', 'article_snippets', 'article_tags'}
+
+class ConfigError(Exception):
+    """Raised for unreadable config files or unknown profiles"""
+
+def load_config(path: Path) -> Dict:
+    """Load a YAML (or JSON) config file"""
+    try:
+        with open(path, 'r', encoding='utf-8') as f:
+            text = f.read()
+    except OSError as e:
+        raise ConfigError(f"Cannot read config file {path}: {e}")
+
+    if path.suffix.lower() == '.json':
+        try:
+            config = json.loads(text)
+        except ValueError as e:
+            raise ConfigError(f"Invalid JSON in {path}: {e}")
+    else:
+        try:
+            import yaml
+        except ImportError:
+            raise ConfigError("YAML config files require PyYAML: pip3 install pyyaml")
+        try:
+            config = yaml.safe_load(text)
+        except yaml.YAMLError as e:
+            raise ConfigError(f"Invalid YAML in {path}: {e}")
+
+    if config is None:
+        return {}
+    if not isinstance(config, dict):
+        raise ConfigError(f"Config file {path} must contain a mapping at the top level")
+    return config
+
+def get_profile(config: Dict, name: Optional[str]) -> Tuple[Optional[str], Dict]:
+    """Select a named profile (or the config's default_profile)"""
+    profiles = config.get('profiles') or {}
+    name = name or config.get('default_profile')
+    if not name:
+        return None, {}
+    if name not in profiles:
+        available = ', '.join(sorted(profiles)) or 'none'
+        raise ConfigError(f"Profile '{name}' not found in config (available: {available})")
+    return name, profiles[name] or {}
+
+def add_config_arguments(parser: argparse.ArgumentParser):
+    """Add the --config and --profile options to a parser"""
+    parser.add_argument('--config', type=str, default=os.environ.get('VLP2SS_CONFIG'),
+                        help=f'Config file with named profiles (default: {DEFAULT_CONFIG_PATH}, or VLP2SS_CONFIG env var)')
+    parser.add_argument('--profile', type=str, default=os.environ.get('VLP2SS_PROFILE'),
+                        help='Named profile from the config file (or VLP2SS_PROFILE env var)')
+
+def apply_profile(parser: argparse.ArgumentParser, argv: Optional[List[str]] = None) -> Tuple[Optional[str], Dict]:
+    """Load the selected profile and install its values as parser defaults
+
+    Precedence is: command-line flags > environment variables > profile >
+    built-in defaults. Top-level profile keys (account, user, token, site, ...)
+    and the keys of its 'defaults' section name command-line options by their
+    long name ('upload-concurrency' or 'upload_concurrency'). A profile is
+    shared by the converter and the uploader, so keys a tool does not know are
+    ignored. Returns the profile name and the full profile so callers can read
+    the other sections.
+    """
+    pre_parser = argparse.ArgumentParser(add_help=False)
+    add_config_arguments(pre_parser)
+    pre_args, _ = pre_parser.parse_known_args(argv)
+
+    config_path = Path(pre_args.config).expanduser() if pre_args.config else DEFAULT_CONFIG_PATH
+    if not config_path.exists():
+        if pre_args.config or pre_args.profile:
+            raise ConfigError(f"Config file not found: {config_path}")
+        return None, {}
+
+    name, profile = get_profile(load_config(config_path), pre_args.profile)
+    if not profile:
+        return name, profile
+
+    values = {k: v for k, v in profile.items() if k not in PROFILE_SECTIONS}
+    values.update(profile.get('defaults') or {})
+
+    known = {action.dest: action for action in parser._actions}
+    defaults = {}
+    for key, value in values.items():
+        dest = str(key).lstrip('-').replace('-', '_')
+        action = known.get(dest)
+        if action is None:
+            continue
+        # Environment variables (already the parser default) win over the profile
+        if action.default is not None and action.default == os.environ.get(f"SS_{dest.upper()}"):
+            continue
+        defaults[dest] = str(value) if action.type is str and value is not None else value
+
+    parser.set_defaults(**defaults)
+    return name, profile
+
+# OS credential store (Keychain, Windows Credential Manager, Secret Service)
+KEYRING_SERVICE = "vlp2ss"
+
+def _keyring():
+    """Import the optional keyring package"""
+    try:
+        import keyring
+    except ImportError:
+        raise ConfigError("OS keychain support requires keyring: pip3 install keyring")
+    return keyring
+
+def keychain_username(account: str, user: str) -> str:
+    """Keychain entry name for a ScreenSteps account/user pair"""
+    return f"{account}:{user}"
+
+def get_keychain_token(account: str, user: str) -> Optional[str]:
+    """Read an API token from the OS credential store (None if absent or unavailable)"""
+    try:
+        keyring = _keyring()
+        return keyring.get_password(KEYRING_SERVICE, keychain_username(account, user))
+    except Exception:
+        return None
+
+def store_keychain_token(account: str, user: str, token: str):
+    """Store an API token in the OS credential store"""
+    keyring = _keyring()
+    try:
+        keyring.set_password(KEYRING_SERVICE, keychain_username(account, user), token)
+    except Exception as e:
+        raise ConfigError(f"Could not store token in the OS keychain: {e}")
+
+def delete_keychain_token(account: str, user: str) -> bool:
+    """Remove an API token from the OS credential store; False if none was stored"""
+    keyring = _keyring()
+    try:
+        keyring.delete_password(KEYRING_SERVICE, keychain_username(account, user))
+        return True
+    except keyring.errors.PasswordDeleteError:
+        return False
+    except Exception as e:
+        raise ConfigError(f"Could not remove token from the OS keychain: {e}")
@@ -0,0 +1,209 @@
+#!/usr/bin/env python3
+"""
+VLP2SS Manual Diff
+Compares a converted manual with a live ScreenSteps manual: added, removed
+and changed articles and their changed content blocks, to review a
+re-migration before it overwrites anything
+
+Author: Burke Azbill
+Version: 1.0.3
+"""
+
+import re
+import difflib
+from html import unescape
+from typing import Dict, List, Optional
+
+TAG_REGEX = re.compile(r'<[^>]+>')
+WHITESPACE_REGEX = re.compile(r'\s+')
+# Characters of block text shown in the report
+PREVIEW_LENGTH = 60
+
+def block_text(html: str) -> str:
+    """Visible text of a block's HTML, whitespace-normalized (markup and asset URLs are not compared)"""
+    return WHITESPACE_REGEX.sub(' ', unescape(TAG_REGEX.sub(' ', html or ''))).strip()
+
+def outline_block(kind: str, text: str, style: Optional[str] = None) -> Dict:
+    """One comparable content block: 'text' (with its style), 'image' (file name) or 'fold' (title)"""
+    return {'kind': kind, 'style': style or None, 'text': text}
+
+def remote_steps(article: Dict) -> List[Dict]:
+    """Steps (title and comparable blocks) of a ScreenSteps article, from its content_blocks"""
+    steps = []
+    for content_block in sorted(article.get('content_blocks', []), key=lambda b: b.get('sort_order') or 0):
+        block_type = content_block.get('type')
+        if block_type == 'StepContent' and not content_block.get('depth'):
+            steps.append({'title': content_block.get('title') or '', 'blocks': []})
+            continue
+        if not steps:
+            steps.append({'title': '', 'blocks': []})
+        if block_type == 'StepContent':
+            block = outline_block('fold', content_block.get('title') or '')
+        elif block_type == 'ImageContentBlock':
+            name = content_block.get('asset_file_name') or (content_block.get('url') or '').split('/')[-1].split('?')[0]
+            block = outline_block('image', name)
+        else:
+            block = outline_block('text', block_text(content_block.get('body', '')), content_block.get('style'))
+        steps[-1]['blocks'].append(block)
+    return steps
+
+def describe_block(block: Dict) -> str:
+    text = block['text'] if len(block['text']) <= PREVIEW_LENGTH else block['text'][:PREVIEW_LENGTH - 3] + '...'
+    kind = f"{block['style']} block" if block['kind'] == 'text' and block['style'] else f"{block['kind']} block"
+    return f"{kind} '{text}'"
+
+def _block_key(block: Dict):
+    return (block['kind'], block['style'], block['text'])
+
+def _diff_blocks(step_title: str, local: List[Dict], remote: List[Dict]) -> List[Dict]:
+    changes = []
+    matcher = difflib.SequenceMatcher(a=[_block_key(b) for b in remote], b=[_block_key(b) for b in local],
+                                      autojunk=False)
+    for tag, r1, r2, l1, l2 in matcher.get_opcodes():
+        if tag == 'equal':
+            continue
+        paired = min(r2 - r1, l2 - l1) if tag == 'replace' else 0
+        for offset in range(paired):
+            changes.append({'change': 'changed', 'what': 'block', 'step': step_title,
+                            'remote': describe_block(remote[r1 + offset]), 'local': describe_block(local[l1 + offset])})
+        for block in remote[r1 + paired:r2]:
+            changes.append({'change': 'removed', 'what': 'block', 'step': step_title, 'remote': describe_block(block)})
+        for block in local[l1 + paired:l2]:
+            changes.append({'change': 'added', 'what': 'block', 'step': step_title, 'local': describe_block(block)})
+    return changes
+
+def diff_steps(local: List[Dict], remote: List[Dict]) -> List[Dict]:
+    """Step and block changes that uploading the local steps would make to the remote ones"""
+    changes = []
+    matcher = difflib.SequenceMatcher(a=[s['title'] for s in remote], b=[s['title'] for s in local], autojunk=False)
+    for tag, r1, r2, l1, l2 in matcher.get_opcodes():
+        # Steps replaced one for one are retitled steps; their blocks are still compared
+        paired = r2 - r1 if tag == 'equal' or tag == 'replace' and r2 - r1 == l2 - l1 else 0
+        for offset in range(paired):
+            remote_step, local_step = remote[r1 + offset], local[l1 + offset]
+            if remote_step['title'] != local_step['title']:
+                changes.append({'change': 'changed', 'what': 'step', 'step': local_step['title'],
+                                'remote': remote_step['title'], 'local': local_step['title']})
+            changes.extend(_diff_blocks(local_step['title'], local_step['blocks'], remote_step['blocks']))
+        for step in remote[r1 + paired:r2]:
+            changes.append({'change': 'removed', 'what': 'step', 'step': step['title'],
+                            'remote': f"{len(step['blocks'])} blocks"})
+        for step in local[l1 + paired:l2]:
+            changes.append({'change': 'added', 'what': 'step', 'step': step['title'],
+                            'local': f"{len(step['blocks'])} blocks"})
+    return changes
+
+def _match(local_items: List[Dict], remote_items: List[Dict], id_map: Dict) -> Dict[int, int]:
+    """local index -> remote index, by the stored ID mapping first, then by title"""
+    remote_index = {str(item['id']): index for index, item in enumerate(remote_items)}
+    matches = {}
+    for index, item in enumerate(local_items):
+        mapped = remote_index.get(str(id_map.get(item['id'])))
+        if mapped is not None and mapped not in matches.values():
+            matches[index] = mapped
+    for index, item in enumerate(local_items):
+        if index in matches:
+            continue
+        mapped = next((r for r, remote in enumerate(remote_items)
+                       if remote['title'] == item['title'] and r not in matches.values()), None)
+        if mapped is not None:
+            matches[index] = mapped
+    return matches
+
+def diff_manuals(local: Dict, remote: Dict, chapter_map: Optional[Dict] = None,
+                 article_map: Optional[Dict] = None) -> Dict:
+    """Differences between a converted manual and a ScreenSteps manual
+
+    Both manuals are outlines: {'title', 'chapters': [{'id', 'title',
+    'articles': [{'id', 'title', 'steps': [{'title', 'blocks'}]}]}]}. Chapters
+    and articles are matched through the ID mapping of a previous upload
+    (converted ID -> ScreenSteps ID), then by title; an article matched in
+    another chapter gets a 'chapter' change.
+    """
+    matched_chapters = _match(local['chapters'], remote['chapters'], chapter_map or {})
+    chapters = [{'change': 'added', 'title': chapter['title']}
+                for index, chapter in enumerate(local['chapters']) if index not in matched_chapters]
+    chapters += [{'change': 'removed', 'title': chapter['title'], 'remote_id': str(chapter['id'])}
+                 for index, chapter in enumerate(remote['chapters']) if index not in matched_chapters.values()]
+    chapters += [{'change': 'renamed', 'title': local['chapters'][l]['title'], 'remote_title': remote['chapters'][r]['title'],
+                  'remote_id': str(remote['chapters'][r]['id'])}
+                 for l, r in matched_chapters.items() if local['chapters'][l]['title'] != remote['chapters'][r]['title']]
+
+    local_articles = [dict(article, chapter=chapter['title']) for chapter in local['chapters']
+                      for article in chapter['articles']]
+    remote_articles = [dict(article, chapter=chapter['title']) for chapter in remote['chapters']
+                       for article in chapter['articles']]
+    matched_articles = _match(local_articles, remote_articles, article_map or {})
+    # The local chapter each remote chapter corresponds to, to tell moved articles from renamed chapters
+    chapter_titles = {remote['chapters'][r]['title']: local['chapters'][l]['title'] for l, r in matched_chapters.items()}
+
+    articles = []
+    unchanged = 0
+    for index, article in enumerate(local_articles):
+        if index not in matched_articles:
+            articles.append({'change': 'added', 'title': article['title'], 'chapter': article['chapter'],
+                             'steps': len(article['steps'])})
+            continue
+        remote_article = remote_articles[matched_articles[index]]
+        changes = []
+        if remote_article['title'] != article['title']:
+            changes.append({'change': 'changed', 'what': 'title', 'remote': remote_article['title'],
+                            'local': article['title']})
+        if chapter_titles.get(remote_article['chapter'], remote_article['chapter']) != article['chapter']:
+            changes.append({'change': 'changed', 'what': 'chapter', 'remote': remote_article['chapter'],
+                            'local': article['chapter']})
+        changes += diff_steps(article['steps'], remote_article['steps'])
+        if changes:
+            articles.append({'change': 'changed', 'title': article['title'], 'chapter': article['chapter'],
+                             'remote_id': str(remote_article['id']), 'changes': changes})
+        else:
+            unchanged += 1
+    for index, article in enumerate(remote_articles):
+        if index not in matched_articles.values():
+            articles.append({'change': 'removed', 'title': article['title'], 'chapter': article['chapter'],
+                             'remote_id': str(article['id'])})
+
+    counts = {change: sum(1 for a in articles if a['change'] == change) for change in ('added', 'removed', 'changed')}
+    counts['unchanged'] = unchanged
+    return {'local_title': local['title'], 'remote_title': remote['title'], 'remote_id': str(remote.get('id', '')),
+            'chapters': chapters, 'articles': articles, 'counts': counts}
+
+def has_differences(diff: Dict) -> bool:
+    return bool(diff['chapters'] or diff['articles'] or diff['local_title'] != diff['remote_title'])
+
+def _format_change(change: Dict) -> str:
+    symbol = {'added': '+', 'removed': '-', 'changed': '~'}[change['change']]
+    detail = change.get('local') or change.get('remote')
+    if change['what'] == 'block':
+        if change['change'] == 'changed':
+            detail = f"{change['remote']} -> {change['local']}"
+        return f"{symbol} step '{change['step']}': {detail}"
+    if change['change'] != 'changed':
+        return f"{symbol} step '{change['step']}' ({detail})"
+    return f"~ {change['what']}: '{change['remote']}' -> '{change['local']}'"
+
+def format_diff(diff: Dict) -> List[str]:
+    """Render a manual diff as text lines ('+' added, '-' removed, '~' changed, relative to ScreenSteps)"""
+    lines = [f"Converted:   {diff['local_title']}", f"ScreenSteps: {diff['remote_title']} (ID: {diff['remote_id']})"]
+    if diff['local_title'] != diff['remote_title']:
+        lines.append(f"~ Manual title: '{diff['remote_title']}' -> '{diff['local_title']}'")
+    for chapter in diff['chapters']:
+        if chapter['change'] == 'added':
+            lines.append(f"+ Chapter '{chapter['title']}'")
+        elif chapter['change'] == 'removed':
+            lines.append(f"- Chapter '{chapter['title']}' (ID: {chapter['remote_id']})")
+        else:
+            lines.append(f"~ Chapter '{chapter['remote_title']}' -> '{chapter['title']}' (ID: {chapter['remote_id']})")
+    for article in diff['articles']:
+        if article['change'] == 'added':
+            lines.append(f"+ Article '{article['title']}' in '{article['chapter']}' ({article['steps']} steps)")
+        elif article['change'] == 'removed':
+            lines.append(f"- Article '{article['title']}' in '{article['chapter']}' (ID: {article['remote_id']})")
+            continue
+        else:
+            lines.append(f"~ Article '{article['title']}' in '{article['chapter']}' (ID: {article['remote_id']})")
+        lines += [f"    {_format_change(change)}" for change in article.get('changes', [])]
+    counts = diff['counts']
+    lines.append(f"Articles: {counts['added']} added, {counts['removed']} removed, "
+                 f"{counts['changed']} changed, {counts['unchanged']} unchanged")
+    return lines
@@ -0,0 +1,42 @@
+#!/usr/bin/env python3
+"""
+VLP2SS Exit Codes
+Process exit codes shared by the converter and uploader, so CI pipelines can
+tell a clean run from one that finished with warnings or partial failures
+
+Author: Burke Azbill
+Version: 1.0.3
+"""
+
+from typing import Dict
+
+EXIT_OK = 0                # Everything converted/uploaded
+EXIT_ERROR = 1             # Hard error, nothing usable produced
+EXIT_WARNINGS = 2          # Converted, but the conversion logged warnings
+EXIT_IMAGES_SKIPPED = 3    # Finished, but images were missing from the export or skipped on upload
+EXIT_PARTIAL = 4           # Upload finished, but articles or manuals failed or did not verify
+EXIT_AUTH = 5              # ScreenSteps rejected the credentials (401/403)
+
+# 'status' of the --quiet result line and webhook payload for each exit code
+EXIT_STATUS = {
+    EXIT_OK: 'ok',
+    EXIT_ERROR: 'error',
+    EXIT_WARNINGS: 'warnings',
+    EXIT_IMAGES_SKIPPED: 'images_skipped',
+    EXIT_PARTIAL: 'partial',
+    EXIT_AUTH: 'auth_failed',
+}
+
+def exit_status(exit_code: int) -> str:
+    """Result status for an exit code"""
+    return EXIT_STATUS.get(exit_code, 'error')
+
+def result_exit_code(result: Dict) -> int:
+    """Exit code of a run that finished, from its result counts (most severe first)"""
+    if result.get('failed_manuals') or result.get('failed_articles') or result.get('verification_problems'):
+        return EXIT_PARTIAL
+    if result.get('images_skipped') or result.get('missing_images'):
+        return EXIT_IMAGES_SKIPPED
+    if result.get('warnings'):
+        return EXIT_WARNINGS
+    return EXIT_OK
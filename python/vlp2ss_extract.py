@@ -0,0 +1,199 @@
+#!/usr/bin/env python3
+"""
+VLP2SS Safe Extraction
+Extracts VLP export ZIPs, or reads them in place (--in-memory), without
+trusting their member names: entries that escape the target directory,
+symlinks and oversized archives are refused
+
+Author: Burke Azbill
+Version: 1.0.3
+"""
+
+import os
+import stat
+import time
+import zipfile
+import posixpath
+from pathlib import Path
+from typing import Dict, IO, List, Optional, Tuple
+
+# Limits for one archive; a zip bomb fails on these before filling the disk
+DEFAULT_MAX_TOTAL_SIZE = 4 * 1024 ** 3  # Uncompressed bytes of all members
+DEFAULT_MAX_FILES = 50000
+DEFAULT_MAX_RATIO = 200  # Uncompressed / compressed size of one member (checked above 1 MB)
+RATIO_CHECK_MIN_SIZE = 1024 ** 2
+
+COPY_CHUNK_SIZE = 1024 ** 2
+
+class ExtractionError(Exception):
+    """Raised for an archive that is unsafe or too large to extract"""
+
+def normalize_member(name: str) -> Optional[str]:
+    """Relative POSIX path of a ZIP member, or None for an entry that would leave the target
+
+    Backslashes (written by some Windows tools) count as separators, so
+    'export\\content.xml' is found in its 'export' directory. Absolute paths,
+    drive letters and '..' components are refused rather than stripped.
+    """
+    path = name.replace('\\', '/')
+    if path.startswith('/') or (len(path) > 1 and path[1] == ':'):
+        return None
+    parts = [part for part in path.split('/') if part not in ('', '.')]
+    if not parts or '..' in parts:
+        return None
+    return '/'.join(parts)
+
+def is_symlink(member: zipfile.ZipInfo) -> bool:
+    """Whether a member was archived as a symlink (Unix mode in the external attributes)"""
+    return stat.S_ISLNK(member.external_attr >> 16)
+
+def content_root(names: List[str]) -> str:
+    """Directory of the shallowest content.xml among normalized member names ('' for the top level)"""
+    candidates = sorted((name for name in names if posixpath.basename(name) == 'content.xml'),
+                        key=lambda name: name.count('/'))
+    return posixpath.dirname(candidates[0]) if candidates else ''
+
+def _safe_members(archive: zipfile.ZipFile, skipped: List[Dict]) -> List[Tuple[zipfile.ZipInfo, str]]:
+    """Files of an archive with their normalized names; unsafe entries go to skipped"""
+    members = []
+    for member in archive.infolist():
+        name = normalize_member(member.filename)
+        if name is None:
+            skipped.append({'name': member.filename, 'reason': 'outside target directory'})
+        elif is_symlink(member):
+            skipped.append({'name': member.filename, 'reason': 'symlink'})
+        elif not member.is_dir() and not member.filename.endswith('\\'):
+            members.append((member, name))
+    return members
+
+def _check_limits(archive_name: str, members: List[Tuple[zipfile.ZipInfo, str]], max_total_size: int,
+                  max_files: int, max_ratio: int):
+    """Raise ExtractionError when the declared sizes of the members exceed a limit"""
+    if len(members) > max_files:
+        raise ExtractionError(f"{archive_name} has {len(members)} files (limit {max_files})")
+    declared = sum(member.file_size for member, _ in members)
+    if declared > max_total_size:
+        raise ExtractionError(f"{archive_name} expands to {declared} bytes (limit {max_total_size})")
+    for member, _ in members:
+        if member.file_size > RATIO_CHECK_MIN_SIZE and member.file_size > max_ratio * max(member.compress_size, 1):
+            raise ExtractionError(f"{member.filename} in {archive_name} is compressed more than "
+                                  f"{max_ratio}:1 (possible zip bomb)")
+
+def _member_timestamp(member: zipfile.ZipInfo) -> float:
+    return time.mktime(member.date_time + (0, 0, -1))
+
+class ExtractResult:
+    """What safe_extract wrote and refused"""
+
+    def __init__(self):
+        self.files = 0
+        self.bytes = 0
+        self.root = ''  # Member directory moved to the top (the one holding content.xml)
+        self.skipped: List[Dict] = []  # {'name', 'reason'} of members not extracted
+
+def safe_extract(zip_path: Path, target: Path, max_total_size: int = DEFAULT_MAX_TOTAL_SIZE,
+                 max_files: int = DEFAULT_MAX_FILES, max_ratio: int = DEFAULT_MAX_RATIO) -> ExtractResult:
+    """Extract a VLP export ZIP into target, with content.xml's directory as the top level
+
+    Members outside the export's root keep their own relative paths. Unsafe
+    names and symlinks are skipped and listed in the result; exceeding a
+    size limit raises ExtractionError. Sizes are counted while copying, so
+    a header that understates them does not help. File dates are kept
+    (screenshot freshness checks use them).
+    """
+    result = ExtractResult()
+    target = Path(target).resolve()
+    with zipfile.ZipFile(zip_path, 'r') as archive:
+        members = _safe_members(archive, result.skipped)
+        _check_limits(zip_path.name, members, max_total_size, max_files, max_ratio)
+
+        result.root = content_root([name for _, name in members])
+        prefix = f"{result.root}/" if result.root else ''
+        for member, name in members:
+            relative = name[len(prefix):] if prefix and name.startswith(prefix) else name
+            destination = target.joinpath(*relative.split('/'))
+            # Also guards against a directory replaced by a symlink while extracting
+            if target not in destination.resolve().parents:
+                result.skipped.append({'name': member.filename, 'reason': 'outside target directory'})
+                continue
+            destination.parent.mkdir(parents=True, exist_ok=True)
+            with archive.open(member) as source, open(destination, 'wb') as output:
+                while True:
+                    chunk = source.read(COPY_CHUNK_SIZE)
+                    if not chunk:
+                        break
+                    result.bytes += len(chunk)
+                    if result.bytes > max_total_size:
+                        raise ExtractionError(f"{zip_path.name} expands past {max_total_size} bytes")
+                    output.write(chunk)
+            timestamp = _member_timestamp(member)
+            try:
+                os.utime(destination, (timestamp, timestamp))
+            except OSError:
+                pass
+            result.files += 1
+    return result
+
+class ExportArchive:
+    """A VLP export ZIP read in place, without extracting it (--in-memory)
+
+    Paths are relative to the directory holding content.xml, with the same
+    safety rules and limits as safe_extract: unsafe entries are left out and
+    listed in skipped, and an archive over a limit raises ExtractionError on
+    opening. Use as a context manager, or call close().
+    """
+
+    def __init__(self, zip_path: Path, max_total_size: int = DEFAULT_MAX_TOTAL_SIZE,
+                 max_files: int = DEFAULT_MAX_FILES, max_ratio: int = DEFAULT_MAX_RATIO):
+        self.path = Path(zip_path)
+        self.skipped: List[Dict] = []
+        self.zip = zipfile.ZipFile(self.path, 'r')
+        try:
+            members = _safe_members(self.zip, self.skipped)
+            _check_limits(self.path.name, members, max_total_size, max_files, max_ratio)
+        except ExtractionError:
+            self.zip.close()
+            raise
+        self.root = content_root([name for _, name in members])
+        prefix = f"{self.root}/" if self.root else ''
+        self.members = {name[len(prefix):]: member for member, name in members if name.startswith(prefix)}
+
+    def __enter__(self):
+        return self
+
+    def __exit__(self, *exc):
+        self.close()
+
+    def close(self):
+        self.zip.close()
+
+    def exists(self, relative: str) -> bool:
+        return relative in self.members
+
+    def open(self, relative: str) -> IO[bytes]:
+        """Stream of a member; its date_time is the file date kept in the ZIP"""
+        if relative not in self.members:
+            raise FileNotFoundError(f"{relative} not found in {self.path}")
+        stream = self.zip.open(self.members[relative])
+        stream.date_time = self.members[relative].date_time
+        return stream
+
+    def members_below(self, directory: str = '') -> List[Tuple[str, zipfile.ZipInfo]]:
+        """(path relative to directory, member) of every file below a directory ('' for all)"""
+        prefix = f"{directory.strip('/')}/" if directory.strip('/') else ''
+        return [(name[len(prefix):], member) for name, member in self.members.items() if name.startswith(prefix)]
+
+    def copy(self, member: zipfile.ZipInfo, destination: Path) -> Path:
+        """Write one member to destination, keeping its file date"""
+        with self.zip.open(member) as source, open(destination, 'wb') as output:
+            while True:
+                chunk = source.read(COPY_CHUNK_SIZE)
+                if not chunk:
+                    break
+                output.write(chunk)
+        timestamp = _member_timestamp(member)
+        try:
+            os.utime(destination, (timestamp, timestamp))
+        except OSError:
+            pass
+        return destination
@@ -0,0 +1,143 @@
+#!/usr/bin/env python3
+"""
+VLP2SS Content Filters
+Selects the chapters and articles of a manual by title or ID patterns, to
+convert or upload a single module of a large manual without editing the export
+
+Author: Burke Azbill
+Version: 1.0.3
+"""
+
+import re
+import fnmatch
+from typing import Callable, Dict, List, Optional, Tuple
+
+# Prefix of a pattern that is a regular expression instead of a glob
+REGEX_PREFIX = 're:'
+
+class FilterError(Exception):
+    """Raised for invalid filter patterns"""
+
+def compile_pattern(pattern: str) -> Callable[[str], bool]:
+    """Matcher for one pattern: a case-insensitive glob, or a regex (searched) after 're:'"""
+    if pattern.startswith(REGEX_PREFIX):
+        try:
+            regex = re.compile(pattern[len(REGEX_PREFIX):])
+        except re.error as e:
+            raise FilterError(f"Invalid filter regex {pattern!r}: {e}")
+        return lambda value: bool(regex.search(value))
+    glob = pattern.lower()
+    return lambda value: fnmatch.fnmatchcase(value.lower(), glob)
+
+class ContentFilter:
+    """Include/exclude patterns for chapters and articles
+
+    An item matches a pattern when its title, converted ID or VLP ID does.
+    With include patterns only matching items are kept; exclude patterns
+    drop matching items and win over includes. Articles are only kept in
+    kept chapters.
+    """
+
+    def __init__(self, include_chapters: Optional[List[str]] = None, exclude_chapters: Optional[List[str]] = None,
+                 include_articles: Optional[List[str]] = None, exclude_articles: Optional[List[str]] = None):
+        self.include_chapters = [compile_pattern(p) for p in include_chapters or []]
+        self.exclude_chapters = [compile_pattern(p) for p in exclude_chapters or []]
+        self.include_articles = [compile_pattern(p) for p in include_articles or []]
+        self.exclude_articles = [compile_pattern(p) for p in exclude_articles or []]
+
+    def __bool__(self):
+        return bool(self.include_chapters or self.exclude_chapters or self.include_articles or self.exclude_articles)
+
+    @staticmethod
+    def _matches(patterns: List[Callable[[str], bool]], item: Dict) -> bool:
+        values = [str(item[key]) for key in ('title', 'id', 'vlp_id') if item.get(key)]
+        return any(match(value) for match in patterns for value in values)
+
+    def _reason(self, includes, excludes, item: Dict) -> Optional[str]:
+        """Why an item is dropped, or None to keep it"""
+        if self._matches(excludes, item):
+            return 'excluded'
+        if includes and not self._matches(includes, item):
+            return 'not included'
+        return None
+
+    def apply(self, chapters: List[Dict], position_key: str = 'position') -> Tuple[List[Dict], List[Dict]]:
+        """Kept chapters (copies holding only their kept articles) and one record per dropped item
+
+        Kept articles are renumbered under position_key so positions stay
+        sequential. A chapter whose articles were all dropped is dropped too.
+        The input is not modified.
+        """
+        kept_chapters = []
+        dropped = []
+        for chapter in chapters:
+            reason = self._reason(self.include_chapters, self.exclude_chapters, chapter)
+            if reason:
+                dropped.append({'type': 'chapter', 'id': chapter['id'], 'title': chapter['title'], 'reason': reason})
+                continue
+            articles = []
+            for article in chapter.get('articles', []):
+                reason = self._reason(self.include_articles, self.exclude_articles, article)
+                if reason:
+                    dropped.append({'type': 'article', 'id': article['id'], 'title': article['title'],
+                                    'chapter': chapter['title'], 'reason': reason})
+                    continue
+                articles.append(dict(article, **{position_key: len(articles) + 1}))
+            if chapter.get('articles') and not articles:
+                dropped.append({'type': 'chapter', 'id': chapter['id'], 'title': chapter['title'],
+                                'reason': 'no articles left'})
+                continue
+            kept_chapters.append(dict(chapter, articles=articles))
+        return kept_chapters, dropped
+
+def select_articles(chapters: List[Dict], only: Optional[List[str]] = None, start_from: Optional[str] = None,
+                    screensteps_ids: Optional[Dict[str, str]] = None) -> Tuple[List[Dict], List[Dict]]:
+    """Kept chapters holding only the articles named by only, and/or those from start_from on
+
+    Articles are named exactly by converted ID, VLP ID, title or, through
+    screensteps_ids (converted ID -> ScreenSteps ID), ScreenSteps ID. Kept
+    articles keep their positions, as they go back into an existing manual.
+    Raises FilterError for a name that matches no article.
+    """
+    screensteps_ids = screensteps_ids or {}
+
+    def names(article: Dict) -> set:
+        return {str(value) for value in (article['id'], article.get('vlp_id'), article['title'],
+                                         screensteps_ids.get(article['id'])) if value}
+
+    articles = [article for chapter in chapters for article in chapter.get('articles', [])]
+    for name in (only or []) + ([start_from] if start_from else []):
+        if not any(name in names(article) for article in articles):
+            raise FilterError(f"No article matches '{name}' (use its title, converted, VLP or ScreenSteps ID)")
+
+    kept_chapters = []
+    dropped = []
+    started = not start_from
+    for chapter in chapters:
+        kept = []
+        for article in chapter.get('articles', []):
+            started = started or start_from in names(article)
+            reason = 'before start' if not started else 'not selected' if only and not names(article) & set(only) else None
+            if reason:
+                dropped.append({'type': 'article', 'id': article['id'], 'title': article['title'],
+                                'chapter': chapter['title'], 'reason': reason})
+            else:
+                kept.append(article)
+        if kept:
+            kept_chapters.append(dict(chapter, articles=kept))
+    return kept_chapters, dropped
+
+def add_filter_arguments(parser):
+    """--include-chapter/--exclude-chapter/--include-article/--exclude-article, shared by both tools"""
+    pattern_help = 'glob on the title or ID, or re:REGEX; repeatable'
+    parser.add_argument('--include-chapter', action='append', default=[], metavar='PATTERN',
+                        help=f'Only keep chapters matching PATTERN ({pattern_help})')
+    parser.add_argument('--exclude-chapter', action='append', default=[], metavar='PATTERN',
+                        help=f'Leave out chapters matching PATTERN ({pattern_help})')
+    parser.add_argument('--include-article', action='append', default=[], metavar='PATTERN',
+                        help=f'Only keep articles matching PATTERN ({pattern_help})')
+    parser.add_argument('--exclude-article', action='append', default=[], metavar='PATTERN',
+                        help=f'Leave out articles matching PATTERN ({pattern_help})')
+
+def filter_from_args(args) -> ContentFilter:
+    return ContentFilter(args.include_chapter, args.exclude_chapter, args.include_article, args.exclude_article)
@@ -0,0 +1,164 @@
+#!/usr/bin/env python3
+"""
+VLP2SS Export Schema Versions
+Detects the schema version of a VLP content.xml and renames the elements of
+newer schemas to the schema 1 names the converter and validator read
+
+Author: Burke Azbill
+Version: 1.0.3
+"""
+
+import xml.etree.ElementTree as ET
+from typing import Optional
+
+# Root attributes that declare the schema version outright
+SCHEMA_VERSION_ATTRIBUTES = ('schemaVersion', 'exportVersion')
+
+# Known export schemas. A version is detected from a declared schemaVersion,
+# else the manual's <dataFormat>, else the element holding the content nodes;
+# 'elements' renames the schema's elements to their schema 1 names.
+VLP_SCHEMAS = {
+    '1': {
+        'description': 'Manual > contentNodes > ContentNode > localizations > LocaleContent',
+        'data_formats': ('default', 'html'),
+        'containers': ('contentNodes',),
+        'elements': {},
+    },
+    '2': {
+        'description': 'Lab > sections > Section > translations > Translation',
+        'data_formats': ('v2', 'structured-html'),
+        'containers': ('sections',),
+        'elements': {
+            'Lab': 'Manual',
+            'defaultLocale': 'defaultLanguageCode',
+            'sections': 'contentNodes',
+            'Section': 'ContentNode',
+            'subsections': 'children',
+            'position': 'orderIndex',
+            'translations': 'localizations',
+            'Translation': 'LocaleContent',
+            'locale': 'languageCode',
+            'body': 'content',
+            'media': 'images',
+            'image': 'img',
+        },
+    },
+}
+# Attributes renamed per (schema 1) element, for schemas that name them differently
+VLP_SCHEMA_ATTRIBUTES = {
+    '2': {'img': {'file': 'filename'}},
+}
+
+class SchemaVersionError(ValueError):
+    """content.xml declares or uses an export schema the converter does not know"""
+
+def supported_versions() -> str:
+    return ', '.join(VLP_SCHEMAS)
+
+def declared_version(root: ET.Element) -> Optional[str]:
+    """Schema version from the root's schemaVersion attribute, None if it declares none"""
+    for name in SCHEMA_VERSION_ATTRIBUTES:
+        value = (root.get(name) or '').strip()
+        if value:
+            # '2.0' and '2.1' are minor revisions of schema 2
+            version = value.split('.')[0]
+            if version not in VLP_SCHEMAS:
+                raise SchemaVersionError(f"Unsupported VLP export schema version {value} "
+                                         f"(supported: {supported_versions()})")
+            return version
+    return None
+
+def version_for_format(data_format: Optional[str]) -> Optional[str]:
+    """Schema version of a <dataFormat> value, None without one"""
+    value = (data_format or '').strip()
+    if not value:
+        return None
+    for version, schema in VLP_SCHEMAS.items():
+        if value.lower() in schema['data_formats']:
+            return version
+    formats = ', '.join(f for schema in VLP_SCHEMAS.values() for f in schema['data_formats'])
+    raise SchemaVersionError(f"Unsupported VLP export dataFormat '{value}' (supported: {formats})")
+
+def version_for_module_root(tag: str) -> Optional[str]:
+    """Schema version whose content node element is named tag (a module-level export's root)"""
+    return next((version for version, schema in VLP_SCHEMAS.items()
+                 if schema['elements'].get(tag, tag) == 'ContentNode'), None)
+
+def version_for_container(tag: str) -> Optional[str]:
+    """Schema version whose content nodes live in a root child named tag"""
+    return next((version for version, schema in VLP_SCHEMAS.items() if tag in schema['containers']), None)
+
+def rename_element(element: ET.Element, version: str):
+    """Give an element (not its children) its schema 1 tag and attribute names"""
+    element.tag = VLP_SCHEMAS[version]['elements'].get(element.tag, element.tag)
+    for old, new in VLP_SCHEMA_ATTRIBUTES.get(version, {}).get(element.tag, {}).items():
+        if old in element.attrib and new not in element.attrib:
+            element.set(new, element.attrib.pop(old))
+
+class SchemaStream:
+    """Schema detection and renaming for content.xml read with ET.iterparse
+
+    Call start() for every start event and end() for every end event, with
+    the element's depth (0 for the root). Elements are renamed from the
+    moment the version is known, which is at the root for a declared version
+    or a module-level export, and no later than the element holding the
+    content nodes otherwise; the root and the manual fields read before that
+    are renamed then. module tells whether the root is a single content node
+    (a module exported on its own) rather than a manual.
+    """
+
+    def __init__(self):
+        self.version: Optional[str] = None
+        self.root: Optional[ET.Element] = None
+        self.module = False
+
+    def start(self, element: ET.Element, depth: int):
+        if depth == 0:
+            self.root = element
+            version = declared_version(element) or version_for_module_root(element.tag)
+            if version:
+                self._detected(version)
+            self.module = element.tag == 'ContentNode'
+            return
+        if self.version is None and depth == 1:
+            version = version_for_container(element.tag)
+            if version:
+                self._detected(version)
+        if self.version:
+            rename_element(element, self.version)
+
+    def end(self, element: ET.Element, depth: int):
+        if self.version is None and depth == 1 and element.tag == 'dataFormat':
+            version = version_for_format(element.text)
+            if version:
+                self._detected(version)
+
+    def finish(self) -> str:
+        """The detected version; raises SchemaVersionError if the export matched none"""
+        if self.version is None:
+            raise unrecognized_export(self.root)
+        return self.version
+
+    def _detected(self, version: str):
+        self.version = version
+        rename_element(self.root, version)
+        for child in self.root:
+            rename_element(child, version)
+
+def unrecognized_export(root: Optional[ET.Element]) -> SchemaVersionError:
+    containers = ' or '.join(f"<{c}>" for schema in VLP_SCHEMAS.values() for c in schema['containers'])
+    return SchemaVersionError(f"Unrecognized VLP export: <{root.tag if root is not None else '?'}> has no {containers} "
+                              f"and declares no schemaVersion (supported versions: {supported_versions()})")
+
+def normalize_tree(root: ET.Element) -> str:
+    """Detect the schema of a parsed content.xml and rename it to schema 1 in place; returns the version
+
+    The root of a module-level export is a ContentNode afterwards.
+    """
+    version = declared_version(root) or version_for_module_root(root.tag) \
+        or version_for_format(root.findtext('dataFormat')) or next(filter(None, (version_for_container(child.tag) for child in root)), None)
+    if version is None:
+        raise unrecognized_export(root)
+    for element in root.iter():
+        rename_element(element, version)
+    return version
@@ -0,0 +1,197 @@
+#!/usr/bin/env python3
+"""
+VLP2SS Golden-File Regression Harness
+Converts the checked-in sample exports under tests/samples and compares the
+output (JSON, HTML, file hashes) with the golden files under tests/golden
+
+Author: Burke Azbill
+Version: 1.0.3
+"""
+
+import os
+import re
+import sys
+import json
+import shlex
+import shutil
+import difflib
+import hashlib
+import argparse
+import platform
+import tempfile
+import subprocess
+from pathlib import Path
+from typing import Dict, List, Optional
+from vlp2ss_version import APP_VERSION
+
+TESTS_DIR = Path(__file__).resolve().parent.parent / 'tests'
+SAMPLES_DIR = TESTS_DIR / 'samples'
+GOLDEN_DIR = TESTS_DIR / 'golden'
+CONVERTER = Path(__file__).resolve().parent / 'vlp_converter.py'
+
+# Optional file in a sample directory: extra converter arguments, shell-quoted,
+# with paths relative to the sample (e.g. --class-map class-map.yaml)
+ARGS_FILE = 'convert.args'
+# Golden file listing the SHA-256 of every output that is not compared as text
+BINARY_MANIFEST = 'binary-files.json'
+# Output compared line by line; anything else (images, attachments) by hash
+TEXT_SUFFIXES = ('.json', '.html', '.htm', '.txt', '.csv', '.md', '.yaml', '.yml', '.xml')
+# Output that is not part of the conversion result
+IGNORED_FILES = ('.vlp2ss-output',)
+
+# JSON values that change on every run (text output has its timestamps replaced instead)
+VOLATILE_KEYS = ('generator', 'started_at', 'finished_at', 'created_at', 'updated_at',
+                 'duration_seconds', 'elapsed_seconds', 'timings')
+TIMESTAMP_REGEX = re.compile(r'\d{4}-\d{2}-\d{2}[T ]\d{2}:\d{2}(:\d{2}(\.\d+)?)?(Z|[+-]\d{2}:?\d{2})?')
+# Lines of a difference shown per file unless verbose
+DIFF_LINES = 40
+
+def list_samples(names: Optional[List[str]] = None) -> List[Path]:
+    """Sample export directories, all or the named ones"""
+    samples = sorted(path for path in SAMPLES_DIR.iterdir() if (path / 'content.xml').is_file()) \
+        if SAMPLES_DIR.is_dir() else []
+    if names:
+        unknown = set(names) - {sample.name for sample in samples}
+        if unknown:
+            raise ValueError(f"Unknown sample(s): {', '.join(sorted(unknown))} (in {SAMPLES_DIR})")
+        samples = [sample for sample in samples if sample.name in names]
+    return samples
+
+def convert_sample(sample: Path, output_dir: Path, work_dir: Path):
+    """Run the converter on a sample, isolated from the user's logs, metrics and state"""
+    args = shlex.split((sample / ARGS_FILE).read_text(encoding='utf-8'), comments=True) \
+        if (sample / ARGS_FILE).is_file() else []
+    env = dict(os.environ, VLP2SS_CACHE_DIR=str(work_dir / 'cache'), VLP2SS_STATE_FILE=str(work_dir / 'state.json'),
+               VLP2SS_GIT_SHA='golden', VLP2SS_BUILD_DATE='golden', PYTHONDONTWRITEBYTECODE='1')
+    command = [sys.executable, str(CONVERTER), '-i', str(sample), '-o', str(output_dir),
+               '--log-dir', str(work_dir / 'logs'), '--temp-dir', str(work_dir / 'temp'), '--no-metrics', '-q'] + args
+    completed = subprocess.run(command, cwd=sample, env=env, capture_output=True, text=True)
+    # Exit codes 2 and 3 (warnings, missing images) still produce output worth comparing
+    if completed.returncode not in (0, 2, 3):
+        raise RuntimeError(f"Converting {sample.name} failed (exit code {completed.returncode}):\n"
+                           f"{completed.stderr.strip() or completed.stdout.strip()}")
+
+def normalize_json(value, replacements: Dict[str, str]):
+    """JSON output with run-specific values replaced by placeholders"""
+    if isinstance(value, dict):
+        return {key: '<volatile>' if key in VOLATILE_KEYS else normalize_json(item, replacements)
+                for key, item in value.items()}
+    if isinstance(value, list):
+        return [normalize_json(item, replacements) for item in value]
+    if isinstance(value, str):
+        return replace_all(value, replacements)
+    return value
+
+def replace_all(text: str, replacements: Dict[str, str]) -> str:
+    """Text with paths and build details replaced by placeholders"""
+    for old, new in replacements.items():
+        text = text.replace(old, new)
+    return text
+
+def normalize_text(text: str, replacements: Dict[str, str]) -> str:
+    """Text output (the QA report) with paths, build details and timestamps replaced by placeholders"""
+    return TIMESTAMP_REGEX.sub('<time>', replace_all(text, replacements))
+
+def snapshot(output_dir: Path, sample: Path) -> Dict[str, str]:
+    """Normalized output of a conversion: relative path -> text, plus the binary manifest"""
+    replacements = {str(output_dir): '<output>', str(sample): '<sample>',
+                    platform.python_version(): '<python>', APP_VERSION: '<version>'}
+    files, binaries = {}, {}
+    for path in sorted(p for p in output_dir.rglob('*') if p.is_file() and p.name not in IGNORED_FILES):
+        relative = path.relative_to(output_dir).as_posix()
+        if path.suffix.lower() not in TEXT_SUFFIXES:
+            binaries[relative] = hashlib.sha256(path.read_bytes()).hexdigest()
+        elif path.suffix.lower() == '.json':
+            data = normalize_json(json.loads(path.read_text(encoding='utf-8')), replacements)
+            files[relative] = json.dumps(data, indent=2, ensure_ascii=False, sort_keys=True) + '\n'
+        else:
+            files[relative] = normalize_text(path.read_text(encoding='utf-8'), replacements)
+    files[BINARY_MANIFEST] = json.dumps(binaries, indent=2, sort_keys=True) + '\n'
+    return files
+
+def read_golden(golden_dir: Path) -> Dict[str, str]:
+    """Golden files of a sample: relative path -> text"""
+    return {path.relative_to(golden_dir).as_posix(): path.read_text(encoding='utf-8')
+            for path in sorted(golden_dir.rglob('*')) if path.is_file()}
+
+def compare(golden: Dict[str, str], actual: Dict[str, str], verbose: bool = False) -> List[str]:
+    """Differences between golden and actual output, one message (with a diff) per file"""
+    differences = []
+    for name in sorted(set(golden) | set(actual)):
+        if name not in actual:
+            differences.append(f"{name}: missing from the output")
+        elif name not in golden:
+            differences.append(f"{name}: not in the golden files")
+        elif golden[name] != actual[name]:
+            diff = list(difflib.unified_diff(golden[name].splitlines(), actual[name].splitlines(),
+                                             f'golden/{name}', f'output/{name}', lineterm=''))
+            if not verbose and len(diff) > DIFF_LINES:
+                diff = diff[:DIFF_LINES] + [f"... {len(diff) - DIFF_LINES} more lines (-v shows all)"]
+            differences.append(f"{name}: differs\n" + '\n'.join(diff))
+    return differences
+
+def write_golden(golden_dir: Path, files: Dict[str, str]):
+    """Replace a sample's golden files"""
+    if golden_dir.exists():
+        shutil.rmtree(golden_dir)
+    for name, text in files.items():
+        path = golden_dir / name
+        path.parent.mkdir(parents=True, exist_ok=True)
+        path.write_text(text, encoding='utf-8')
+
+def check_sample(sample: Path, update: bool = False, verbose: bool = False) -> List[str]:
+    """Convert a sample and compare it with (or, with update, save it as) its golden files"""
+    golden_dir = GOLDEN_DIR / sample.name
+    with tempfile.TemporaryDirectory(prefix='vlp2ss-golden-') as work:
+        work_dir = Path(work)
+        output_dir = work_dir / 'output'
+        convert_sample(sample, output_dir, work_dir)
+        actual = snapshot(output_dir, sample)
+    if update:
+        write_golden(golden_dir, actual)
+        return []
+    if not golden_dir.is_dir():
+        return [f"No golden files in {golden_dir} (create them with --golden-update)"]
+    return compare(read_golden(golden_dir), actual, verbose)
+
+def main() -> int:
+    parser = argparse.ArgumentParser(
+        description='Convert the sample exports in tests/samples and compare the output with tests/golden',
+        epilog='After an intended output change, review the diff and run again with --golden-update')
+    parser.add_argument('samples', nargs='*', metavar='SAMPLE',
+                        help='Sample directory names to check (default: all)')
+    parser.add_argument('--golden-update', action='store_true',
+                        help='Write the current output as the new golden files instead of comparing')
+    parser.add_argument('-v', '--verbose', action='store_true', help='Show complete diffs')
+    args = parser.parse_args()
+
+    try:
+        samples = list_samples(args.samples)
+    except ValueError as e:
+        print(f"Error: {e}", file=sys.stderr)
+        return 1
+    if not samples:
+        print(f"Error: no sample exports in {SAMPLES_DIR}", file=sys.stderr)
+        return 1
+
+    failed = 0
+    for sample in samples:
+        try:
+            differences = check_sample(sample, args.golden_update, args.verbose)
+        except (RuntimeError, OSError, ValueError) as e:
+            differences = [str(e)]
+        if args.golden_update and not differences:
+            print(f"UPDATED  {sample.name} -> {GOLDEN_DIR / sample.name}")
+        elif differences:
+            failed += 1
+            print(f"FAIL     {sample.name}")
+            for difference in differences:
+                print('  ' + difference.replace('\n', '\n  '))
+        else:
+            print(f"OK       {sample.name}")
+    if failed:
+        print(f"\n{failed} of {len(samples)} sample(s) differ from their golden files")
+    return 1 if failed else 0
+
+if __name__ == "__main__":
+    sys.exit(main())
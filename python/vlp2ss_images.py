@@ -0,0 +1,301 @@
+#!/usr/bin/env python3
+"""
+VLP2SS Image Index
+One-time index of an images tree (or of a ZIP export read in place) used by the
+converter and uploader to resolve image references, with ignore patterns and
+fallback matching, and the
+per-format policies applied to GIF and SVG images, and the optional resize
+and recompression of screenshots before upload
+
+Author: Burke Azbill
+Version: 1.0.3
+"""
+
+import os
+import shutil
+import fnmatch
+import threading
+from pathlib import Path
+from concurrent.futures import ThreadPoolExecutor
+from typing import Dict, Iterable, List, Optional, Tuple
+
+# File extensions treated as images
+IMAGE_EXTENSIONS = {'.png', '.jpg', '.jpeg', '.gif', '.svg', '.webp', '.bmp'}
+
+# Per-format image handling (override in a profile's image_formats section).
+# GIFs pass through (animation intact) unless larger than max_size_mb (0 = no
+# limit); SVGs can be rasterized to PNG at a fixed width, since ScreenSteps
+# rejects some SVG uploads.
+DEFAULT_FORMAT_POLICIES = {
+    'gif': {'max_size_mb': 10},
+    'svg': {'rasterize': False, 'width': 1200},
+}
+
+class ImageFormatError(Exception):
+    """Raised for invalid format policies, or for an image its policy rejects"""
+
+def load_format_policies(section: Optional[Dict] = None, gif_max_size: Optional[float] = None,
+                         rasterize_svg: Optional[int] = None) -> Dict[str, Dict]:
+    """Merge a profile's image_formats section and command-line overrides into the defaults
+
+    rasterize_svg is a width in pixels (0 turns rasterization off).
+    """
+    policies = {fmt: dict(policy) for fmt, policy in DEFAULT_FORMAT_POLICIES.items()}
+    if section:
+        if not isinstance(section, dict):
+            raise ImageFormatError("image_formats must map a format (gif, svg) to its policy")
+        for fmt, policy in section.items():
+            fmt = str(fmt).lower().lstrip('.')
+            if fmt not in policies:
+                raise ImageFormatError(f"Unknown image format '{fmt}' in image_formats "
+                                       f"(supported: {', '.join(policies)})")
+            if not isinstance(policy, dict):
+                raise ImageFormatError(f"image_formats.{fmt} must be a mapping")
+            unknown = set(policy) - set(policies[fmt])
+            if unknown:
+                raise ImageFormatError(f"Unknown image_formats.{fmt} keys: {', '.join(sorted(unknown))} "
+                                       f"(supported: {', '.join(policies[fmt])})")
+            policies[fmt].update(policy)
+    if gif_max_size is not None:
+        policies['gif']['max_size_mb'] = gif_max_size
+    if rasterize_svg is not None:
+        policies['svg'].update(rasterize=rasterize_svg > 0, width=rasterize_svg or policies['svg']['width'])
+
+    try:
+        policies['gif']['max_size_mb'] = float(policies['gif']['max_size_mb'] or 0)
+        policies['svg']['width'] = int(policies['svg']['width'])
+    except (TypeError, ValueError):
+        raise ImageFormatError("image_formats.gif.max_size_mb and image_formats.svg.width must be numbers")
+    if policies['gif']['max_size_mb'] < 0 or policies['svg']['width'] <= 0:
+        raise ImageFormatError("image_formats.gif.max_size_mb must be 0 or more and image_formats.svg.width positive")
+    policies['svg']['rasterize'] = bool(policies['svg']['rasterize'])
+    return policies
+
+def apply_format_policy(image_path: Path, policies: Optional[Dict], output_dir: Path) -> Path:
+    """Return the file to use for an image under its format's policy
+
+    GIFs over the size limit raise ImageFormatError; SVGs are rasterized to
+    <stem>.png in output_dir when enabled. Other images are used unchanged.
+    """
+    policies = policies or DEFAULT_FORMAT_POLICIES
+    suffix = image_path.suffix.lower()
+    if suffix == '.gif':
+        limit = policies['gif'].get('max_size_mb') or 0
+        size_mb = image_path.stat().st_size / (1024 * 1024)
+        if limit and size_mb > limit:
+            raise ImageFormatError(f"{image_path.name} is {size_mb:.1f} MB, over the {limit:g} MB GIF limit")
+    elif suffix == '.svg' and policies['svg'].get('rasterize'):
+        return rasterize_svg(image_path, Path(output_dir) / f"{image_path.stem}.png", policies['svg']['width'])
+    return image_path
+
+def optimize_image(image_path: Path, output_dir: Path, max_width: Optional[int] = None,
+                   png_compress: bool = False, jpeg_quality: Optional[int] = None) -> Path:
+    """Resize a PNG/JPEG screenshot to max_width and recompress it into output_dir
+
+    Returns the optimized copy, or image_path itself when nothing applies or
+    the result would not be smaller (other formats, e.g. animated GIFs, are
+    never touched). Aspect ratio is kept; images are never enlarged.
+    """
+    suffix = image_path.suffix.lower()
+    is_png, is_jpeg = suffix == '.png', suffix in ('.jpg', '.jpeg')
+    if not (is_png or is_jpeg):
+        return image_path
+    from PIL import Image
+    try:
+        with Image.open(image_path) as image:
+            resize = bool(max_width) and image.width > max_width
+            if not (resize or (is_png and png_compress) or (is_jpeg and jpeg_quality)):
+                return image_path
+            if resize:
+                height = max(1, round(image.height * max_width / image.width))
+                image = image.resize((max_width, height), Image.LANCZOS)
+            output = Path(output_dir) / image_path.name
+            if is_png:
+                image.save(output, 'PNG', optimize=png_compress, compress_level=9 if png_compress else 6)
+            else:
+                image.convert('RGB').save(output, 'JPEG', quality=jpeg_quality or 90, optimize=True)
+    except OSError:
+        return image_path  # Unreadable by Pillow: upload the original
+    if not resize and output.stat().st_size >= image_path.stat().st_size:
+        return image_path
+    return output
+
+def rasterize_svg(svg_path: Path, png_path: Path, width: int) -> Path:
+    """Render an SVG to a PNG of the given width (needs CairoSVG)"""
+    try:
+        import cairosvg
+    except ImportError:
+        raise ImageFormatError("SVG rasterization requires CairoSVG: pip3 install cairosvg")
+    try:
+        cairosvg.svg2png(url=str(svg_path), write_to=str(png_path), output_width=width)
+    except Exception as e:
+        raise ImageFormatError(f"Cannot rasterize {svg_path.name}: {e}")
+    return png_path
+
+class ImageIndex:
+    """Index of every image below a root directory
+
+    The tree is scanned once, one worker per top-level directory, instead of
+    checking the filesystem for every reference. Ignore patterns ending in '/'
+    exclude directories by name (e.g. 'thumbnails/'); other patterns are
+    matched against file names and relative paths (e.g. '*_small.png').
+
+    Lookups try the exact relative path first, then fall back to a unique file
+    name match anywhere in the tree, a case-insensitive name match, and finally
+    the same name with a different extension. Fallback matches are recorded in
+    fuzzy_matches so callers can report them. Other file types (e.g. videos)
+    can be indexed by passing their extensions.
+    """
+
+    def __init__(self, root: Path, ignore_patterns: Iterable[str] = (), workers: int = 8,
+                 extensions: Iterable[str] = IMAGE_EXTENSIONS):
+        self.root = Path(root)
+        self.extensions = {e.lower() for e in extensions}
+        self.ignore_dirs = [p.rstrip('/') for p in ignore_patterns if p.endswith('/')]
+        self.ignore_files = [p for p in ignore_patterns if not p.endswith('/')]
+        self.workers = max(1, workers)
+        self.paths: Dict[str, Path] = {}           # relative posix path -> path
+        self.by_name: Dict[str, List[str]] = {}    # lower-case file name -> relative paths
+        self.by_stem: Dict[str, List[str]] = {}    # lower-case stem -> relative paths
+        self.ignored = 0
+        self.fuzzy_matches: List[Dict[str, str]] = []
+        self._lock = threading.Lock()
+        self.build()
+
+    def _is_ignored_dir(self, name: str) -> bool:
+        return any(fnmatch.fnmatch(name, pattern) for pattern in self.ignore_dirs)
+
+    def _is_ignored_file(self, relative: str) -> bool:
+        name = relative.rsplit('/', 1)[-1]
+        return any(fnmatch.fnmatch(name, p) or fnmatch.fnmatch(relative, p) for p in self.ignore_files)
+
+    def _scan(self, directory: Path) -> Tuple[List[Tuple[str, Path]], int]:
+        """Recursively list the images below one directory"""
+        found = []
+        ignored = 0
+        stack = [directory]
+        while stack:
+            current = stack.pop()
+            try:
+                entries = list(os.scandir(current))
+            except OSError:
+                continue
+            for entry in entries:
+                if entry.is_dir(follow_symlinks=False):
+                    if self._is_ignored_dir(entry.name):
+                        ignored += 1
+                    else:
+                        stack.append(Path(entry.path))
+                elif Path(entry.name).suffix.lower() in self.extensions:
+                    relative = Path(entry.path).relative_to(self.root).as_posix()
+                    if self._is_ignored_file(relative):
+                        ignored += 1
+                    else:
+                        found.append((relative, Path(entry.path)))
+        return found, ignored
+
+    def build(self):
+        """Scan the images tree"""
+        if not self.root.is_dir():
+            return
+        top_dirs = []
+        files = []
+        for entry in os.scandir(self.root):
+            if entry.is_dir(follow_symlinks=False):
+                if self._is_ignored_dir(entry.name):
+                    self.ignored += 1
+                else:
+                    top_dirs.append(Path(entry.path))
+            elif Path(entry.name).suffix.lower() in self.extensions:
+                if self._is_ignored_file(entry.name):
+                    self.ignored += 1
+                else:
+                    files.append((entry.name, Path(entry.path)))
+
+        with ThreadPoolExecutor(max_workers=self.workers) as pool:
+            for found, ignored in pool.map(self._scan, top_dirs):
+                files.extend(found)
+                self.ignored += ignored
+        self._add(files)
+
+    def _add(self, files: List[Tuple[str, object]]):
+        """Index (relative path, file) pairs"""
+        for relative, path in sorted(files, key=lambda item: item[0]):
+            self.paths[relative] = path
+            name = relative.rsplit('/', 1)[-1].lower()
+            self.by_name.setdefault(name, []).append(relative)
+            self.by_stem.setdefault(Path(name).stem, []).append(relative)
+
+    def __len__(self):
+        return len(self.paths)
+
+    def copy_to(self, source: Path, destination: Path) -> Path:
+        """Copy a looked-up file to destination, keeping its file date"""
+        shutil.copy2(source, destination)
+        return destination
+
+    def lookup(self, reference: str, within: str = '') -> Optional[Path]:
+        """Resolve an image reference (relative path or file name)
+
+        within names a subdirectory (e.g. an article's image directory) whose
+        files are preferred when falling back to name matches.
+        """
+        reference = reference.replace('\\', '/')
+        if reference.startswith('./'):
+            reference = reference[2:]
+        relative = f"{within.strip('/')}/{reference}" if within else reference
+        if relative in self.paths:
+            return self.paths[relative]
+        if within and reference in self.paths:
+            return self.paths[reference]
+
+        name = reference.rsplit('/', 1)[-1]
+        for rule, candidates in (
+            ('file name', [c for c in self.by_name.get(name.lower(), []) if c.rsplit('/', 1)[-1] == name]),
+            ('case-insensitive name', self.by_name.get(name.lower(), [])),
+            ('different extension', self.by_stem.get(Path(name).stem.lower(), [])),
+        ):
+            match = self._pick(candidates, within)
+            if match:
+                with self._lock:
+                    self.fuzzy_matches.append({'reference': relative, 'matched': match, 'rule': rule})
+                return self.paths[match]
+        return None
+
+    @staticmethod
+    def _pick(candidates: List[str], within: str) -> Optional[str]:
+        """Pick the candidate inside 'within', or the only candidate overall"""
+        if within:
+            local = [c for c in candidates if c.startswith(within.strip('/') + '/')]
+            if len(local) == 1:
+                return local[0]
+        return candidates[0] if len(candidates) == 1 else None
+
+class ArchiveIndex(ImageIndex):
+    """ImageIndex of the files below a directory of an ExportArchive (--in-memory)
+
+    Lookups return the ZIP members instead of paths; copy_to writes one out.
+    Ignored directories count once per file below them.
+    """
+
+    def __init__(self, archive, directory: str = '', ignore_patterns: Iterable[str] = (),
+                 extensions: Iterable[str] = IMAGE_EXTENSIONS):
+        self.archive = archive
+        self.directory = directory
+        super().__init__(Path(archive.path.name) / directory, ignore_patterns, workers=1, extensions=extensions)
+
+    def build(self):
+        """List the archive's members below the directory"""
+        files = []
+        for relative, member in self.archive.members_below(self.directory):
+            if Path(relative).suffix.lower() not in self.extensions:
+                continue
+            if any(self._is_ignored_dir(part) for part in relative.split('/')[:-1]) or \
+                    self._is_ignored_file(relative):
+                self.ignored += 1
+            else:
+                files.append((relative, member))
+        self._add(files)
+
+    def copy_to(self, source, destination: Path) -> Path:
+        return self.archive.copy(source, destination)
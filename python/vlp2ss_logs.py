@@ -0,0 +1,44 @@
+#!/usr/bin/env python3
+"""
+VLP2SS Log Files
+Log directory selection and rotation shared by the converter and uploader;
+each tool keeps its own N most recent logs and never touches other files
+
+Author: Burke Azbill
+Version: 1.0.3
+"""
+
+import os
+from pathlib import Path
+from datetime import datetime
+
+# Log directory (override with --log-dir or VLP2SS_LOG_DIR)
+DEFAULT_LOG_DIR = os.environ.get('VLP2SS_LOG_DIR', 'logs')
+
+# Previous logs kept per tool (override with --log-keep; 0 keeps all)
+DEFAULT_LOG_KEEP = 20
+
+def new_log_file(log_dir: Path, prefix: str, keep: int = DEFAULT_LOG_KEEP) -> Path:
+    """Create the log directory and return the path of a new timestamped log
+
+    Only logs of the same tool (prefix_*.log) are rotated: the oldest are
+    deleted so that at most 'keep' previous logs remain next to the new one.
+    """
+    log_dir = Path(log_dir)
+    log_dir.mkdir(parents=True, exist_ok=True)
+
+    previous = sorted(log_dir.glob(f"{prefix}_*.log"))
+    if keep > 0:
+        for old_log in previous[:max(0, len(previous) - keep)]:
+            try:
+                old_log.unlink()
+            except OSError:
+                pass
+
+    timestamp = datetime.now().strftime("%Y%m%d_%H%M%S")
+    log_file = log_dir / f"{prefix}_{timestamp}.log"
+    counter = 1
+    while log_file.exists():  # Several runs within the same second
+        log_file = log_dir / f"{prefix}_{timestamp}_{counter}.log"
+        counter += 1
+    return log_file
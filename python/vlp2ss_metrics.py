@@ -0,0 +1,129 @@
+#!/usr/bin/env python3
+"""
+VLP2SS Metrics History
+Appends the metrics of every conversion and upload run to a local history
+file and prints trends across runs (python3 vlp2ss_metrics.py)
+
+Author: Burke Azbill
+Version: 1.0.3
+"""
+
+import os
+import sys
+import json
+import argparse
+from pathlib import Path
+from datetime import datetime
+from typing import Dict, List, Optional
+
+# One JSON line per run (override with --metrics-file or VLP2SS_METRICS_FILE)
+DEFAULT_METRICS_FILE = Path(os.environ.get('VLP2SS_METRICS_FILE') or
+                            Path(os.environ.get('VLP2SS_CACHE_DIR') or Path.home() / ".cache" / "vlp2ss") / "metrics.jsonl")
+
+# Run result fields kept in the history, in display order
+METRIC_FIELDS = ('elapsed_seconds', 'chapters', 'articles', 'images', 'missing_images', 'warnings',
+                 'images_uploaded', 'images_skipped', 'images_deduplicated', 'images_optimized', 'failed_articles', 'api_retries')
+
+# Tool names used in the history and by --tool
+TOOLS = ('conversion', 'upload')
+
+def record_run(tool: str, run_result: Dict, metrics_file: Path = DEFAULT_METRICS_FILE) -> Optional[str]:
+    """Append one run's metrics to the history; returns an error message, or None on success"""
+    entry = {
+        'recorded_at': datetime.now().isoformat(timespec='seconds'),
+        'tool': tool,
+        'status': run_result.get('status'),
+        'exit_code': run_result.get('exit_code'),
+        'version': run_result.get('version'),
+        'git_sha': run_result.get('git_sha'),
+        'output': run_result.get('output') or run_result.get('manual_url'),
+    }
+    entry.update({field: run_result[field] for field in METRIC_FIELDS if run_result.get(field) is not None})
+    try:
+        metrics_file = Path(metrics_file)
+        metrics_file.parent.mkdir(parents=True, exist_ok=True)
+        with open(metrics_file, 'a', encoding='utf-8') as f:
+            f.write(json.dumps(entry) + '\n')
+    except OSError as e:
+        return str(e)
+    return None
+
+def load_history(metrics_file: Path = DEFAULT_METRICS_FILE, tool: Optional[str] = None) -> List[Dict]:
+    """Runs recorded in the history file, oldest first (unreadable lines are skipped)"""
+    runs = []
+    try:
+        with open(metrics_file, 'r', encoding='utf-8') as f:
+            for line in f:
+                try:
+                    run = json.loads(line)
+                except ValueError:
+                    continue
+                if isinstance(run, dict) and (tool is None or run.get('tool') == tool):
+                    runs.append(run)
+    except OSError:
+        pass
+    return runs
+
+def _average(runs: List[Dict], field: str) -> Optional[float]:
+    values = [run[field] for run in runs if isinstance(run.get(field), (int, float))]
+    return sum(values) / len(values) if values else None
+
+def format_trends(runs: List[Dict], last: int = 10) -> List[str]:
+    """Table of the last runs of one tool, then each metric's average against the runs before them"""
+    recent, earlier = runs[-last:], runs[-2 * last:-last]
+    fields = [field for field in METRIC_FIELDS if any(field in run for run in recent)]
+    lines = ['  '.join(['Date'.ljust(19), 'Status'.ljust(14), 'Version'.ljust(10)] +
+                       [field.replace('_', ' ') for field in fields])]
+    for run in recent:
+        cells = [str(run.get('recorded_at', '')).ljust(19), str(run.get('status', '')).ljust(14),
+                 str(run.get('version') or '').ljust(10)]
+        cells += [str(run.get(field, '-')).rjust(len(field)) for field in fields]
+        lines.append('  '.join(cells))
+
+    lines.append('')
+    if not earlier:
+        lines.append(f"Trend: needs more than {last} runs to compare against earlier runs")
+        return lines
+    lines.append(f"Trend (average of the last {len(recent)} runs vs the {len(earlier)} before):")
+    for field in fields:
+        before, now = _average(earlier, field), _average(recent, field)
+        if before is None or now is None:
+            continue
+        change = f" ({(now - before) / before:+.0%})" if before else ""
+        lines.append(f"  {field.replace('_', ' ')}: {before:.1f} -> {now:.1f}{change}")
+    failed_before = sum(1 for run in earlier if run.get('exit_code') not in (0, 2))
+    failed_now = sum(1 for run in recent if run.get('exit_code') not in (0, 2))
+    lines.append(f"  runs with errors or skipped content: {failed_before} -> {failed_now}")
+    return lines
+
+def main() -> int:
+    parser = argparse.ArgumentParser(
+        description='Print conversion and upload metrics across recorded runs',
+        epilog='Runs are recorded by vlp_converter.py and screensteps_uploader.py unless --no-metrics is given')
+    parser.add_argument('--tool', choices=TOOLS,
+                        help='Only show conversion or upload runs (default: both)')
+    parser.add_argument('--last', type=int, default=10, metavar='N',
+                        help='Runs to list per tool and to compare against the N before them (default: 10)')
+    parser.add_argument('--metrics-file', type=Path, default=DEFAULT_METRICS_FILE, metavar='FILE',
+                        help=f'History file (default: {DEFAULT_METRICS_FILE}, or VLP2SS_METRICS_FILE env var)')
+    parser.add_argument('--json', action='store_true',
+                        help='Print the selected runs as JSON instead of the trend report')
+    args = parser.parse_args()
+
+    tools = [args.tool] if args.tool else list(TOOLS)
+    runs = {tool: load_history(args.metrics_file, tool) for tool in tools}
+    if args.json:
+        print(json.dumps({tool: tool_runs[-args.last:] for tool, tool_runs in runs.items()}, indent=2))
+        return 0
+    if not any(runs.values()):
+        print(f"No runs recorded in {args.metrics_file}")
+        return 0
+    for tool in tools:
+        if not runs[tool]:
+            continue
+        print(f"\n{tool.capitalize()} runs ({len(runs[tool])} recorded)")
+        print('\n'.join(format_trends(runs[tool], max(1, args.last))))
+    return 0
+
+if __name__ == "__main__":
+    sys.exit(main())
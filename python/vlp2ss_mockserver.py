@@ -0,0 +1,661 @@
+#!/usr/bin/env python3
+"""
+VLP2SS Mock ScreenSteps Server
+In-memory stand-in for the ScreenSteps v2 API (sites, manuals, chapters,
+articles and files, with 429 rate limiting) for offline upload testing,
+served over HTTP or plugged into the uploader as a backend
+
+Author: Burke Azbill
+Version: 1.0.3
+"""
+
+import re
+import sys
+import json
+import gzip
+import base64
+import argparse
+import itertools
+import mimetypes
+import threading
+import time
+from io import BytesIO
+from collections import deque
+from datetime import datetime, timezone
+from email import message_from_bytes
+from email.policy import HTTP
+from http.server import BaseHTTPRequestHandler, ThreadingHTTPServer
+from pathlib import Path
+from typing import Dict, Iterator, List, Optional, Tuple
+from urllib.parse import urlparse, parse_qs
+import requests
+from PIL import Image
+from vlp2ss_api import FILE_UPLOAD_RATE
+
+DEFAULT_PORT = 8765
+# The one site of the mock account
+MOCK_SITE_ID = 1
+
+# Routes: (method, pattern) -> MockScreenSteps handler name
+ROUTES = [
+    ('GET', r'/api/v2/sites', 'list_sites'),
+    ('GET', r'/api/v2/sites/(\d+)', 'get_site'),
+    ('POST', r'/api/v2/sites/(\d+)/manuals', 'create_manual'),
+    ('GET', r'/api/v2/sites/(\d+)/manuals/(\d+)', 'get_manual'),
+    ('PUT', r'/api/v2/sites/(\d+)/manuals/(\d+)', 'update_manual'),
+    ('DELETE', r'/api/v2/sites/(\d+)/manuals/(\d+)', 'delete_manual'),
+    ('POST', r'/api/v2/sites/(\d+)/chapters', 'create_chapter'),
+    ('GET', r'/api/v2/sites/(\d+)/chapters/(\d+)', 'get_chapter'),
+    ('PUT', r'/api/v2/sites/(\d+)/chapters/(\d+)', 'update_chapter'),
+    ('DELETE', r'/api/v2/sites/(\d+)/chapters/(\d+)', 'delete_chapter'),
+    ('POST', r'/api/v2/sites/(\d+)/articles', 'create_article'),
+    ('GET', r'/api/v2/sites/(\d+)/articles/(\d+)', 'get_article'),
+    ('PUT', r'/api/v2/sites/(\d+)/articles/(\d+)', 'update_article'),
+    ('DELETE', r'/api/v2/sites/(\d+)/articles/(\d+)', 'delete_article'),
+    ('POST', r'/api/v2/sites/(\d+)/articles/(\d+)/contents', 'update_contents'),
+    ('GET', r'/api/v2/sites/(\d+)/searches', 'search'),
+    ('POST', r'/api/v2/sites/(\d+)/files', 'upload_file'),
+    ('GET', r'/api/v2/sites/(\d+)/files/(\d+)', 'get_file'),
+    ('DELETE', r'/api/v2/sites/(\d+)/files/(\d+)', 'delete_file'),
+]
+
+class MockError(Exception):
+    """An API error response: HTTP status, message and extra response fields"""
+
+    def __init__(self, status: int, message: str, **fields):
+        super().__init__(message)
+        self.status = status
+        self.fields = fields
+
+class MockScreenSteps:
+    """State and API behaviour of the mock: one site holding manuals, chapters, articles and files
+
+    Chapters and articles are kept in position order; creating or moving one
+    to a position shifts the ones after it, as ScreenSteps does. File uploads
+    are limited like ScreenSteps (8 per 10 seconds) and answered with 429 and
+    retry_in beyond that; rate_limit_every additionally answers every Nth
+    API call with 429. With user and token set, other credentials get 401.
+    """
+
+    def __init__(self, site_title: str = 'Mock Site', user: Optional[str] = None, token: Optional[str] = None,
+                 rate_limit_every: int = 0, retry_in: float = 1.0, file_rate: Tuple[int, float] = FILE_UPLOAD_RATE):
+        self.user = user
+        self.token = token
+        self.rate_limit_every = rate_limit_every
+        self.retry_in = retry_in
+        self.file_rate = file_rate
+        self.site = {'id': MOCK_SITE_ID, 'title': site_title}
+        self.manuals: Dict[int, Dict] = {}
+        self.chapters: Dict[int, Dict] = {}
+        self.articles: Dict[int, Dict] = {}
+        self.files: Dict[int, Dict] = {}
+        self.file_data: Dict[int, bytes] = {}
+        self.calls: List[Tuple[str, str, int]] = []  # (method, path, status) of every API call
+        self.lock = threading.RLock()
+        self._ids = itertools.count(1001)
+        self._requests = 0
+        self._file_times = deque()
+
+    # Request handling
+
+    def handle(self, method: str, path: str, query: Dict[str, List[str]], headers, body: bytes,
+               base_url: str) -> Tuple[int, object]:
+        """Answer one API call; returns the status and the JSON body"""
+        with self.lock:
+            status, payload = self._dispatch(method, path, query, headers, body, base_url)
+            self.calls.append((method, path, status))
+            return status, payload
+
+    def _dispatch(self, method, path, query, headers, body, base_url) -> Tuple[int, object]:
+        if not self._authorized(headers.get('Authorization')):
+            return 401, {'error': 'Invalid credentials'}
+        self._requests += 1
+        if self.rate_limit_every and self._requests % self.rate_limit_every == 0:
+            return 429, {'error': 'Rate limit exceeded', 'retry_in': self.retry_in}
+        for route_method, pattern, handler in ROUTES:
+            match = re.fullmatch(pattern, path)
+            if route_method == method and match:
+                break
+        else:
+            return 404, {'error': f'No route for {method} {path}'}
+        ids = [int(value) for value in match.groups()]
+        if ids and ids[0] != MOCK_SITE_ID:
+            return 404, {'error': f'Site {ids[0]} not found'}
+        try:
+            if handler == 'upload_file':
+                return 201, self.upload_file(headers.get('Content-Type', ''), body, base_url)
+            data = self._json_body(headers, body) if method in ('POST', 'PUT') else {}
+            if handler == 'search':
+                return 200, self.search(' '.join(query.get('text', [])))
+            result = getattr(self, handler)(*ids[1:], data) if method in ('POST', 'PUT') else \
+                getattr(self, handler)(*ids[1:])
+            return (201 if method == 'POST' and handler.startswith('create') else 200), result
+        except MockError as e:
+            return e.status, dict({'error': str(e)}, **e.fields)
+
+    def _authorized(self, header: Optional[str]) -> bool:
+        if not header or not header.startswith('Basic '):
+            return False
+        if self.user is None and self.token is None:
+            return True
+        try:
+            user, _, token = base64.b64decode(header[6:]).decode('utf-8').partition(':')
+        except ValueError:
+            return False
+        return (self.user is None or user == self.user) and (self.token is None or token == self.token)
+
+    @staticmethod
+    def _json_body(headers, body: bytes) -> Dict:
+        if headers.get('Content-Encoding') == 'gzip':
+            body = gzip.decompress(body)
+        try:
+            data = json.loads(body or b'{}')
+        except ValueError:
+            raise MockError(400, 'Request body is not JSON')
+        if not isinstance(data, dict):
+            raise MockError(400, 'Request body is not a JSON object')
+        return data
+
+    def _next_id(self) -> int:
+        return next(self._ids)
+
+    @staticmethod
+    def _now() -> str:
+        return datetime.now(timezone.utc).isoformat(timespec='seconds')
+
+    @staticmethod
+    def _insert(order: List[int], object_id: int, position: Optional[int]):
+        """Put an ID at a 1-based position of an ordered list (the end if unset or past it)"""
+        if object_id in order:
+            order.remove(object_id)
+        index = len(order) if not position else max(0, min(int(position) - 1, len(order)))
+        order.insert(index, object_id)
+
+    def _get(self, table: Dict[int, Dict], kind: str, object_id: int) -> Dict:
+        if object_id not in table:
+            raise MockError(404, f'{kind.capitalize()} {object_id} not found')
+        return table[object_id]
+
+    # Views
+
+    def _chapter_view(self, chapter: Dict, articles: bool = True) -> Dict:
+        view = {key: value for key, value in chapter.items() if key != 'article_ids'}
+        view['position'] = self.manuals[chapter['manual_id']]['chapter_ids'].index(chapter['id']) + 1
+        if articles:
+            view['articles'] = [self._article_view(self.articles[article_id], blocks=False)
+                                for article_id in chapter['article_ids']]
+        return view
+
+    def _article_view(self, article: Dict, blocks: bool = True) -> Dict:
+        view = dict(article) if blocks else {key: value for key, value in article.items() if key != 'content_blocks'}
+        chapter = self.chapters[article['chapter_id']]
+        view['position'] = chapter['article_ids'].index(article['id']) + 1
+        view['manual_id'] = chapter['manual_id']
+        return view
+
+    def _manual_view(self, manual: Dict) -> Dict:
+        view = {key: value for key, value in manual.items() if key != 'chapter_ids'}
+        view['chapters'] = [self._chapter_view(self.chapters[chapter_id], articles=False)
+                            for chapter_id in manual['chapter_ids']]
+        return view
+
+    # Sites
+
+    def list_sites(self) -> Dict:
+        return {'sites': [dict(self.site)]}
+
+    def get_site(self) -> Dict:
+        manuals = [{'id': manual['id'], 'title': manual['title'], 'chapters_count': len(manual['chapter_ids'])}
+                   for manual in self.manuals.values()]
+        return {'site': dict(self.site, manuals=manuals)}
+
+    # Manuals
+
+    def create_manual(self, data: Dict) -> Dict:
+        fields = data.get('manual') or {}
+        if not fields.get('title'):
+            raise MockError(422, 'Manual title is required')
+        manual = {'id': self._next_id(), 'title': fields['title'], 'description': fields.get('description', ''),
+                  'published': bool(fields.get('published', True)), 'site_id': MOCK_SITE_ID, 'chapter_ids': []}
+        self.manuals[manual['id']] = manual
+        for chapter in sorted(fields.get('chapters') or [], key=lambda c: c.get('position') or 0):
+            self.create_chapter({'chapter': dict(chapter, manual_id=manual['id'])})
+        return {'manual': self._manual_view(manual)}
+
+    def get_manual(self, manual_id: int) -> Dict:
+        return {'manual': self._manual_view(self._get(self.manuals, 'manual', manual_id))}
+
+    def update_manual(self, manual_id: int, data: Dict) -> Dict:
+        manual = self._get(self.manuals, 'manual', manual_id)
+        manual.update({key: value for key, value in (data.get('manual') or {}).items()
+                       if key in ('title', 'description', 'published')})
+        return {'manual': self._manual_view(manual)}
+
+    def delete_manual(self, manual_id: int) -> Dict:
+        manual = self._get(self.manuals, 'manual', manual_id)
+        for chapter_id in list(manual['chapter_ids']):
+            self.delete_chapter(chapter_id)
+        del self.manuals[manual_id]
+        return {}
+
+    # Chapters
+
+    def create_chapter(self, data: Dict) -> Dict:
+        fields = data.get('chapter') or {}
+        manual = self._get(self.manuals, 'manual', int(fields.get('manual_id') or 0))
+        chapter = {'id': self._next_id(), 'title': fields.get('title', ''), 'description': fields.get('description', ''),
+                   'published': bool(fields.get('published', True)), 'manual_id': manual['id'], 'article_ids': []}
+        self.chapters[chapter['id']] = chapter
+        self._insert(manual['chapter_ids'], chapter['id'], fields.get('position'))
+        return {'chapter': self._chapter_view(chapter)}
+
+    def get_chapter(self, chapter_id: int) -> Dict:
+        return {'chapter': self._chapter_view(self._get(self.chapters, 'chapter', chapter_id))}
+
+    def update_chapter(self, chapter_id: int, data: Dict) -> Dict:
+        chapter = self._get(self.chapters, 'chapter', chapter_id)
+        fields = data.get('chapter') or {}
+        chapter.update({key: value for key, value in fields.items() if key in ('title', 'description', 'published')})
+        if fields.get('position'):
+            self._insert(self.manuals[chapter['manual_id']]['chapter_ids'], chapter_id, fields['position'])
+        return {'chapter': self._chapter_view(chapter)}
+
+    def delete_chapter(self, chapter_id: int) -> Dict:
+        chapter = self._get(self.chapters, 'chapter', chapter_id)
+        for article_id in list(chapter['article_ids']):
+            del self.articles[article_id]
+        self.manuals[chapter['manual_id']]['chapter_ids'].remove(chapter_id)
+        del self.chapters[chapter_id]
+        return {}
+
+    # Articles
+
+    def create_article(self, data: Dict) -> Dict:
+        fields = data.get('article') or {}
+        chapter = self._get(self.chapters, 'chapter', int(fields.get('chapter_id') or 0))
+        article = {'id': self._next_id(), 'title': fields.get('title', ''), 'published': bool(fields.get('published', True)),
+                   'chapter_id': chapter['id'], 'tags': [], 'updated_at': self._now(), 'content_blocks': []}
+        self.articles[article['id']] = article
+        self._insert(chapter['article_ids'], article['id'], fields.get('position'))
+        return {'article': self._article_view(article)}
+
+    def get_article(self, article_id: int) -> Dict:
+        return {'article': self._article_view(self._get(self.articles, 'article', article_id))}
+
+    def update_article(self, article_id: int, data: Dict) -> Dict:
+        article = self._get(self.articles, 'article', article_id)
+        fields = data.get('article') or {}
+        article.update({key: value for key, value in fields.items() if key in ('title', 'published', 'tags')})
+        if fields.get('chapter_id') and int(fields['chapter_id']) != article['chapter_id']:
+            target = self._get(self.chapters, 'chapter', int(fields['chapter_id']))
+            self.chapters[article['chapter_id']]['article_ids'].remove(article_id)
+            article['chapter_id'] = target['id']
+            self._insert(target['article_ids'], article_id, fields.get('position'))
+        elif fields.get('position'):
+            self._insert(self.chapters[article['chapter_id']]['article_ids'], article_id, fields['position'])
+        article['updated_at'] = self._now()
+        return {'article': self._article_view(article)}
+
+    def update_contents(self, article_id: int, data: Dict) -> Dict:
+        article = self._get(self.articles, 'article', article_id)
+        fields = data.get('article') or {}
+        if not isinstance(fields.get('content_blocks'), list):
+            raise MockError(422, 'content_blocks must be a list')
+        article['content_blocks'] = fields['content_blocks']
+        article['title'] = fields.get('title') or article['title']
+        article['published'] = article['published'] or bool(fields.get('publish'))
+        article['updated_at'] = self._now()
+        return {'article': self._article_view(article)}
+
+    def delete_article(self, article_id: int) -> Dict:
+        article = self._get(self.articles, 'article', article_id)
+        self.chapters[article['chapter_id']]['article_ids'].remove(article_id)
+        del self.articles[article_id]
+        return {}
+
+    def search(self, text: str) -> Dict:
+        """Articles whose title or text blocks contain every word of text"""
+        words = text.lower().split()
+        matches = []
+        for article in self.articles.values():
+            haystack = ' '.join([article['title']] + [block.get('body') or block.get('title') or ''
+                                                      for block in article['content_blocks']]).lower()
+            if words and all(word in haystack for word in words):
+                matches.append(self._article_view(article, blocks=False))
+        return {'articles': matches}
+
+    # Files
+
+    def upload_file(self, content_type: str, body: bytes, base_url: str) -> Dict:
+        max_files, period = self.file_rate
+        now = time.monotonic()
+        while self._file_times and now - self._file_times[0] >= period:
+            self._file_times.popleft()
+        if len(self._file_times) >= max_files:
+            raise MockError(429, f'File upload limit of {max_files} per {period:g} seconds exceeded',
+                            retry_in=round(period - (now - self._file_times[0]), 2))
+        self._file_times.append(now)
+
+        message = message_from_bytes(f'Content-Type: {content_type}\r\n\r\n'.encode('latin-1') + body, policy=HTTP)
+        if not message.is_multipart():
+            raise MockError(422, 'File uploads must be multipart/form-data')
+        parts = {part.get_param('name', header='content-disposition'): part for part in message.iter_parts()}
+        if 'file' not in parts or not parts['file'].get_filename():
+            raise MockError(422, 'A file part is required')
+        return self.store_file(parts['file'].get_filename(), parts['file'].get_content_type(),
+                               parts['file'].get_payload(decode=True) or b'',
+                               parts['type'].get_content().strip() if 'type' in parts else 'FileAsset', base_url)
+
+    def store_file(self, name: str, content_type: str, data: bytes, asset_type: str, base_url: str) -> Dict:
+        """Keep an uploaded file, served from base_url/files/<id>/<name>"""
+        file_id = self._next_id()
+        asset = {'id': file_id, 'file_name': name, 'content_type': content_type, 'size': len(data),
+                 'type': asset_type, 'url': f'{base_url}/files/{file_id}/{name}'}
+        try:
+            with Image.open(BytesIO(data)) as image:
+                asset['width'], asset['height'] = image.size
+        except Exception:
+            pass  # Not an image
+        self.files[file_id] = asset
+        self.file_data[file_id] = data
+        return {'file': dict(asset)}
+
+    def get_file(self, file_id: int) -> Dict:
+        return {'file': dict(self._get(self.files, 'file', file_id))}
+
+    def delete_file(self, file_id: int) -> Dict:
+        self._get(self.files, 'file', file_id)
+        del self.files[file_id]
+        del self.file_data[file_id]
+        return {}
+
+    def state(self) -> Dict:
+        """Everything the mock holds, as JSON-ready data (also served at /_mock/state)"""
+        with self.lock:
+            return {
+                'site': dict(self.site),
+                'manuals': [self._manual_view(manual) for manual in self.manuals.values()],
+                'chapters': [self._chapter_view(chapter) for chapter in self.chapters.values()],
+                'articles': [self._article_view(article) for article in self.articles.values()],
+                'files': list(self.files.values()),
+                'calls': len(self.calls),
+            }
+
+class MockBackend:
+    """A MockScreenSteps used in-process as the uploader's ScreenStepsBackend, without HTTP
+
+        backend = MockBackend()
+        uploader = ScreenStepsUploader('mock', 'user', 'token', backend=backend)
+        uploader.upload(content_dir, '1')
+        print(backend.mock.state()['articles'])
+
+    Errors are raised as requests HTTPErrors with the status the API would
+    return. File uploads are not rate limited.
+    """
+
+    def __init__(self, mock: Optional[MockScreenSteps] = None, site_url: str = 'http://mock.invalid'):
+        self.mock = mock or MockScreenSteps()
+        self.site_url = site_url
+
+    @staticmethod
+    def _http_error(error: MockError) -> requests.exceptions.HTTPError:
+        response = requests.Response()
+        response.status_code = error.status
+        response._content = json.dumps(dict({'error': str(error)}, **error.fields)).encode('utf-8')
+        return requests.exceptions.HTTPError(f"{error.status} Error: {error}", response=response)
+
+    def _call(self, handler, *args):
+        with self.mock.lock:
+            try:
+                return handler(*args)
+            except MockError as e:
+                raise self._http_error(e)
+
+    def _site(self, site_id: str):
+        if int(site_id) != MOCK_SITE_ID:
+            raise self._http_error(MockError(404, f'Site {site_id} not found'))
+
+    def list_sites(self) -> List[Dict]:
+        return self._call(self.mock.list_sites)['sites']
+
+    def get_sites(self) -> List[Dict]:
+        return self.list_sites()
+
+    def get_site(self, site_id: str) -> Dict:
+        self._site(site_id)
+        return self._call(self.mock.get_site)['site']
+
+    def list_manuals(self, site_id: str) -> List[Dict]:
+        return self.get_site(site_id)['manuals']
+
+    def get_manual(self, site_id: str, manual_id: str) -> Dict:
+        self._site(site_id)
+        return self._call(self.mock.get_manual, int(manual_id))['manual']
+
+    def list_chapters(self, site_id: str, manual_id: str) -> List[Dict]:
+        return self.get_manual(site_id, manual_id)['chapters']
+
+    def get_chapter(self, site_id: str, chapter_id: str) -> Dict:
+        self._site(site_id)
+        return self._call(self.mock.get_chapter, int(chapter_id))['chapter']
+
+    def list_articles(self, site_id: str, chapter_id: str) -> List[Dict]:
+        return self.get_chapter(site_id, chapter_id)['articles']
+
+    def iter_articles(self, site_id: str, manual_id: str) -> Iterator[Dict]:
+        for chapter in self.list_chapters(site_id, manual_id):
+            yield from self.list_articles(site_id, chapter['id'])
+
+    def search_articles(self, site_id: str, text: str) -> List[Dict]:
+        self._site(site_id)
+        return self._call(self.mock.search, text)['articles']
+
+    def find_manual_by_title(self, site_id: str, title: str) -> Optional[Dict]:
+        return next((manual for manual in self.list_manuals(site_id) if manual['title'] == title), None)
+
+    def get_article(self, site_id: str, article_id: str) -> Dict:
+        self._site(site_id)
+        return self._call(self.mock.get_article, int(article_id))['article']
+
+    def get_file(self, site_id: str, file_id: str) -> Dict:
+        self._site(site_id)
+        return self._call(self.mock.get_file, int(file_id))['file']
+
+    def create_manual(self, site_id: str, title: str, chapters: List[Dict] = None,
+                      published: bool = True, description: str = "") -> Dict:
+        self._site(site_id)
+        fields = {'title': title, 'published': published, 'description': description, 'chapters': chapters or []}
+        return self._call(self.mock.create_manual, {'manual': fields})['manual']
+
+    def create_chapter(self, site_id: str, manual_id: str, title: str,
+                       position: int, description: str = "", published: bool = True) -> Dict:
+        self._site(site_id)
+        fields = {'manual_id': int(manual_id), 'title': title, 'position': position,
+                  'description': description, 'published': published}
+        return self._call(self.mock.create_chapter, {'chapter': fields})['chapter']
+
+    def create_article(self, site_id: str, chapter_id: str, title: str,
+                       position: int, published: bool = True) -> Dict:
+        self._site(site_id)
+        fields = {'chapter_id': int(chapter_id), 'title': title, 'position': position, 'published': published}
+        return self._call(self.mock.create_article, {'article': fields})['article']
+
+    def upload_file(self, site_id: str, file_path: Path, asset_type: str = 'FileAsset') -> Dict:
+        self._site(site_id)
+        file_path = Path(file_path)
+        content_type = mimetypes.guess_type(file_path.name)[0] or 'application/octet-stream'
+        return self._call(self.mock.store_file, file_path.name, content_type, file_path.read_bytes(),
+                          asset_type, self.site_url)
+
+    def update_manual(self, site_id: str, manual_id: str, **fields) -> Dict:
+        self._site(site_id)
+        return self._call(self.mock.update_manual, int(manual_id), {'manual': fields})['manual']
+
+    def update_chapter(self, site_id: str, chapter_id: str, **fields) -> Dict:
+        self._site(site_id)
+        return self._call(self.mock.update_chapter, int(chapter_id), {'chapter': fields})['chapter']
+
+    def update_article(self, site_id: str, article_id: str, **fields) -> Dict:
+        self._site(site_id)
+        return self._call(self.mock.update_article, int(article_id), {'article': fields})['article']
+
+    def update_article_contents(self, site_id: str, article_id: str, title: str,
+                                content_blocks: List[Dict], publish: bool = True) -> Dict:
+        self._site(site_id)
+        fields = {'title': title, 'content_blocks': content_blocks, 'publish': publish}
+        return self._call(self.mock.update_contents, int(article_id), {'article': fields})['article']
+
+    def delete_manual(self, site_id: str, manual_id: str):
+        self._site(site_id)
+        self._call(self.mock.delete_manual, int(manual_id))
+
+    def delete_chapter(self, site_id: str, chapter_id: str):
+        self._site(site_id)
+        self._call(self.mock.delete_chapter, int(chapter_id))
+
+    def delete_article(self, site_id: str, article_id: str):
+        self._site(site_id)
+        self._call(self.mock.delete_article, int(article_id))
+
+    def delete_file(self, site_id: str, file_id: str):
+        self._site(site_id)
+        self._call(self.mock.delete_file, int(file_id))
+
+    def download_file(self, url: str, destination: Path) -> bool:
+        match = re.search(r'/files/(\d+)/', url)
+        with self.mock.lock:
+            asset = self.mock.files.get(int(match.group(1))) if match else None
+            if asset is None or not asset['content_type'].startswith('image/'):
+                return False
+            Path(destination).write_bytes(self.mock.file_data[asset['id']])
+        return True
+
+    def article_url(self, article_id: str) -> str:
+        return f"{self.site_url}/a/{article_id}"
+
+    def manual_url(self, manual_id: str) -> str:
+        return f"{self.site_url}/m/{manual_id}"
+
+class MockRequestHandler(BaseHTTPRequestHandler):
+    """HTTP front end of a MockScreenSteps (set as the server's mock attribute)"""
+
+    protocol_version = 'HTTP/1.1'
+
+    def _respond(self, status: int, body: bytes, content_type: str = 'application/json'):
+        self.send_response(status)
+        self.send_header('Content-Type', content_type)
+        self.send_header('Content-Length', str(len(body)))
+        self.end_headers()
+        if self.command != 'HEAD':
+            self.wfile.write(body)
+
+    def _handle(self):
+        mock: MockScreenSteps = self.server.mock
+        url = urlparse(self.path)
+        body = self.rfile.read(int(self.headers.get('Content-Length') or 0))
+        base_url = f"http://{self.headers.get('Host') or '%s:%s' % self.server.server_address[:2]}"
+        if self.command == 'GET' and url.path == '/_mock/state':
+            return self._respond(200, json.dumps(mock.state(), indent=2).encode('utf-8'))
+        file_match = re.fullmatch(r'/files/(\d+)/[^/]+', url.path)
+        if self.command == 'GET' and file_match:
+            # Public asset URL, like the ScreenSteps CDN: no credentials needed
+            file_id = int(file_match.group(1))
+            with mock.lock:
+                asset, data = mock.files.get(file_id), mock.file_data.get(file_id)
+            if asset is None:
+                return self._respond(404, b'Not found', 'text/plain')
+            return self._respond(200, data, asset['content_type'])
+        page_match = re.fullmatch(r'/([am])/(\d+)', url.path)
+        if self.command == 'GET' and page_match:
+            # Public article/manual pages, so migrated links have something to open
+            table = mock.articles if page_match.group(1) == 'a' else mock.manuals
+            item = table.get(int(page_match.group(2)))
+            if item is None:
+                return self._respond(404, b'Not found', 'text/plain')
+            return self._respond(200, f"<html><body><h1>{item['title']}</h1></body></html>".encode('utf-8'),
+                                 'text/html; charset=utf-8')
+        status, payload = mock.handle(self.command, url.path, parse_qs(url.query), self.headers, body, base_url)
+        self._respond(status, json.dumps(payload).encode('utf-8'))
+
+    do_GET = do_POST = do_PUT = do_DELETE = _handle
+
+    def log_message(self, format, *args):
+        if getattr(self.server, 'verbose', False):
+            super().log_message(format, *args)
+
+class MockServer:
+    """A MockScreenSteps served over HTTP in a background thread
+
+        with MockServer() as server:
+            uploader = ScreenStepsUploader('mock', 'user', 'token', options={'base_url': server.url})
+
+    port 0 picks a free port.
+    """
+
+    def __init__(self, mock: Optional[MockScreenSteps] = None, host: str = '127.0.0.1', port: int = 0,
+                 verbose: bool = False):
+        self.mock = mock or MockScreenSteps()
+        self.httpd = ThreadingHTTPServer((host, port), MockRequestHandler)
+        self.httpd.mock = self.mock
+        self.httpd.verbose = verbose
+        self.httpd.daemon_threads = True
+        self._thread = None
+
+    @property
+    def url(self) -> str:
+        host, port = self.httpd.server_address[:2]
+        return f"http://{host}:{port}"
+
+    def start(self) -> 'MockServer':
+        self._thread = threading.Thread(target=self.httpd.serve_forever, name='vlp2ss-mock-server', daemon=True)
+        self._thread.start()
+        return self
+
+    def stop(self):
+        self.httpd.shutdown()
+        self.httpd.server_close()
+        if self._thread:
+            self._thread.join()
+
+    def __enter__(self) -> 'MockServer':
+        return self.start()
+
+    def __exit__(self, *exc_info):
+        self.stop()
+
+def main() -> int:
+    parser = argparse.ArgumentParser(
+        description='Serve an in-memory mock of the ScreenSteps API for offline upload testing',
+        epilog='Point the uploader at it with --base-url; any account, user and token work unless --user/--token are set')
+    parser.add_argument('--host', default='127.0.0.1', help='Address to listen on (default: 127.0.0.1)')
+    parser.add_argument('--port', type=int, default=DEFAULT_PORT, help=f'Port to listen on (default: {DEFAULT_PORT})')
+    parser.add_argument('--site-title', default='Mock Site', help='Title of the mock site (ID 1)')
+    parser.add_argument('--user', help='Only accept this user ID')
+    parser.add_argument('--token', help='Only accept this API token')
+    parser.add_argument('--rate-limit-every', type=int, default=0, metavar='N',
+                        help='Answer every Nth API call with 429 Too Many Requests, to exercise retries (default: off)')
+    parser.add_argument('--retry-in', type=float, default=1.0, metavar='SECONDS',
+                        help='retry_in seconds sent with 429 responses (default: 1)')
+    parser.add_argument('-v', '--verbose', action='store_true', help='Log every request')
+    args = parser.parse_args()
+
+    mock = MockScreenSteps(args.site_title, args.user, args.token, max(0, args.rate_limit_every), args.retry_in)
+    try:
+        server = MockServer(mock, args.host, args.port, args.verbose)
+    except OSError as e:
+        print(f"Error: cannot listen on {args.host}:{args.port}: {e}", file=sys.stderr)
+        return 1
+    print(f"Mock ScreenSteps server on {server.url} (site ID {MOCK_SITE_ID}); state at {server.url}/_mock/state")
+    print(f"  python3 screensteps_uploader.py --base-url {server.url} --account mock --user test --token test "
+          f"--site {MOCK_SITE_ID} --content <dir>")
+    print("Press Ctrl+C to stop")
+    try:
+        server.httpd.serve_forever()
+    except KeyboardInterrupt:
+        pass
+    finally:
+        server.httpd.server_close()
+    return 0
+
+if __name__ == "__main__":
+    sys.exit(main())
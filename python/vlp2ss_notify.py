@@ -0,0 +1,165 @@
+#!/usr/bin/env python3
+"""
+VLP2SS Notifications
+Posts the result of a conversion or upload run to an incoming webhook
+(Slack, Microsoft Teams, or any endpoint accepting JSON), or emails an HTML
+report of it through the SMTP server configured in the profile
+
+Author: Burke Azbill
+Version: 1.0.3
+"""
+
+import os
+import json
+import smtplib
+import urllib.request
+import urllib.error
+from html import escape
+from pathlib import Path
+from email.message import EmailMessage
+from typing import Dict, List, Optional
+
+# Result fields listed in the notification text, in order
+SUMMARY_COUNT_FIELDS = ('chapters', 'articles', 'images', 'missing_images', 'warnings', 'images_uploaded',
+                        'images_skipped', 'failed_articles', 'manuals', 'failed_manuals')
+
+# Statuses of runs that finished but need attention (see vlp2ss_exitcodes)
+PARTIAL_STATUS_TEXT = {'warnings': 'warnings', 'images_skipped': 'skipped images', 'partial': 'partial failures'}
+
+# Reports larger than this are referenced by path instead of attached (mail servers reject large messages)
+EMAIL_ATTACHMENT_LIMIT = 5 * 1024 * 1024
+
+# Skipped images listed in the email body; the full list is in summary.json
+EMAIL_IMAGE_LIST_LIMIT = 50
+
+# SMTP settings of a profile's smtp section, with the environment variables that override them
+SMTP_ENV = {'host': 'VLP2SS_SMTP_HOST', 'port': 'VLP2SS_SMTP_PORT', 'user': 'VLP2SS_SMTP_USER',
+            'password': 'VLP2SS_SMTP_PASSWORD', 'from': 'VLP2SS_SMTP_FROM'}
+
+def summary_text(tool: str, result: Dict) -> str:
+    """One-message summary of a run result (the webhook's 'text' field)"""
+    status = result.get('status')
+    if status == 'ok':
+        headline = f"✅ VLP2SS {tool} succeeded"
+    elif status in PARTIAL_STATUS_TEXT:
+        headline = f"⚠️ VLP2SS {tool} finished with {PARTIAL_STATUS_TEXT[status]}"
+    else:
+        headline = f"❌ VLP2SS {tool} failed"
+    lines = [f"{headline} (exit code {result.get('exit_code')})"]
+    counts = [f"{field.replace('_', ' ')}: {result[field]}" for field in SUMMARY_COUNT_FIELDS
+              if result.get(field) is not None]
+    if counts:
+        lines.append(', '.join(counts))
+    if result.get('manual_url'):
+        lines.append(f"Manual: {result['manual_url']}")
+    elif result.get('output'):
+        lines.append(f"Output: {result['output']}")
+    if result.get('error'):
+        lines.append(f"Error: {result['error']}")
+    return '\n'.join(lines)
+
+def notify_webhook(url: str, tool: str, result: Dict, timeout: float = 10.0) -> Optional[str]:
+    """POST the run result to a webhook; returns an error message, or None on success
+
+    The body carries a 'text' field (rendered by Slack and Teams incoming
+    webhooks) followed by the full result so other receivers can parse it.
+    """
+    body = json.dumps({'text': summary_text(tool, result), 'tool': tool, **result}).encode('utf-8')
+    request = urllib.request.Request(url, data=body, method='POST',
+                                     headers={'Content-Type': 'application/json'})
+    try:
+        with urllib.request.urlopen(request, timeout=timeout) as response:
+            response.read()
+    except urllib.error.HTTPError as e:
+        return f"HTTP {e.code} {e.reason}"
+    except (urllib.error.URLError, OSError, ValueError) as e:
+        return str(getattr(e, 'reason', e))
+    return None
+
+def smtp_settings(profile: Dict) -> Dict:
+    """SMTP settings from the profile's smtp section, overridden by VLP2SS_SMTP_* env vars"""
+    settings = {'port': 587, 'security': 'starttls', **(profile.get('smtp') or {})}
+    settings.update({key: os.environ[var] for key, var in SMTP_ENV.items() if os.environ.get(var)})
+    return settings
+
+def _skipped_images(summary: Dict) -> List[str]:
+    """Images left out by the conversion or skipped by the upload, as readable lines"""
+    lines = [f"{image.get('article')} / {image.get('step')}: {image.get('file')} ({image.get('reason')})"
+             for image in summary.get('conversion', {}).get('rejected_images', [])]
+    lines += [f"{image.get('article_title')} / {image.get('step_title')}: {Path(image.get('image_path', '')).name}"
+              for image in summary.get('upload', {}).get('skipped_images', [])]
+    return lines
+
+def report_html(tool: str, result: Dict, summary: Dict, report_note: str = '') -> str:
+    """HTML body of the email report: summary, counts, warnings and skipped images"""
+    headline, *details = summary_text(tool, result).split('\n')
+    counts = dict(summary.get('conversion', {}).get('counts', {}))
+    counts.update({f"upload {k}": v for k, v in summary.get('upload', {}).get('counts', {}).items()})
+    counts = counts or {field: result[field] for field in SUMMARY_COUNT_FIELDS if result.get(field) is not None}
+    rows = ''.join(f"<tr><td>{escape(str(k).replace('_', ' '))}</td><td>{escape(str(v))}</td></tr>"
+                   for k, v in counts.items())
+    warnings = summary.get('conversion', {}).get('warnings', [])
+    skipped = _skipped_images(summary)
+    parts = [f"<h2>{escape(headline)}</h2>", ''.join(f"<p>{escape(line)}</p>" for line in details)]
+    if rows:
+        parts.append(f"<h3>Counts</h3><table border=\"1\" cellpadding=\"4\" cellspacing=\"0\">{rows}</table>")
+    if warnings:
+        parts.append(f"<h3>Warnings ({len(warnings)})</h3><ul>"
+                     + ''.join(f"<li>{escape(str(w))}</li>" for w in warnings[:EMAIL_IMAGE_LIST_LIMIT]) + "</ul>")
+    if skipped:
+        more = len(skipped) - EMAIL_IMAGE_LIST_LIMIT
+        parts.append(f"<h3>Skipped images ({len(skipped)})</h3><ul>"
+                     + ''.join(f"<li>{escape(line)}</li>" for line in skipped[:EMAIL_IMAGE_LIST_LIMIT])
+                     + (f"<li>... and {more} more (see summary.json)</li>" if more > 0 else '') + "</ul>")
+    if report_note:
+        parts.append(f"<p>{report_note}</p>")
+    return f"<html><body>{''.join(parts)}</body></html>"
+
+def email_report(recipients: List[str], tool: str, result: Dict, smtp: Dict,
+                 report_dir: Optional[Path] = None, timeout: float = 30.0) -> Optional[str]:
+    """Email an HTML report of the run; returns an error message, or None on success
+
+    With a report_dir, its summary.json fills in warnings and skipped images
+    and its qa_report.html is attached (or referenced by path when too large).
+    smtp holds host, port, user, password, from and security (starttls, ssl
+    or none), see smtp_settings().
+    """
+    if not smtp.get('host'):
+        return "no SMTP host configured (profile smtp.host or VLP2SS_SMTP_HOST)"
+    summary, report = {}, None
+    if report_dir:
+        try:
+            with open(Path(report_dir) / 'summary.json', 'r', encoding='utf-8') as f:
+                summary = json.load(f)
+        except (OSError, ValueError):
+            pass
+        report = Path(report_dir) / 'qa_report.html'
+        report = report if report.is_file() else None
+
+    note = ''
+    attach = report is not None and report.stat().st_size <= EMAIL_ATTACHMENT_LIMIT
+    if report is not None:
+        note = (f"The full QA report is attached ({escape(report.name)})." if attach else
+                f"The full QA report is too large to attach: <code>{escape(str(report.resolve()))}</code>")
+
+    message = EmailMessage()
+    message['Subject'] = summary_text(tool, result).split('\n')[0]
+    message['From'] = smtp.get('from') or smtp.get('user') or 'vlp2ss@localhost'
+    message['To'] = ', '.join(recipients)
+    message.set_content(summary_text(tool, result))
+    message.add_alternative(report_html(tool, result, summary, note), subtype='html')
+    if attach:
+        message.add_attachment(report.read_bytes(), maintype='text', subtype='html', filename=report.name)
+
+    security = str(smtp.get('security', 'starttls')).lower()
+    try:
+        smtp_class = smtplib.SMTP_SSL if security == 'ssl' else smtplib.SMTP
+        with smtp_class(smtp['host'], int(smtp.get('port') or 0), timeout=timeout) as server:
+            if security == 'starttls':
+                server.starttls()
+            if smtp.get('user') and smtp.get('password'):
+                server.login(smtp['user'], smtp['password'])
+            server.send_message(message)
+    except (smtplib.SMTPException, OSError, ValueError) as e:
+        return str(e)
+    return None
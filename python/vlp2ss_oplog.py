@@ -0,0 +1,252 @@
+#!/usr/bin/env python3
+"""
+VLP2SS API Operation Log
+Append-only record of every ScreenSteps API call that changes content (endpoint,
+payload, result), kept as an audit trail and replayable after an outage
+
+Author: Burke Azbill
+Version: 1.0.3
+"""
+
+import json
+import threading
+import contextlib
+from pathlib import Path
+from datetime import datetime
+from typing import Callable, Dict, List, Optional
+import requests
+
+# Written next to the uploaded content unless --operation-log names another file
+OPERATION_LOG_FILE = 'api_operations.ndjson'
+
+# Calls that change content; reads are not logged
+MUTATING_METHODS = ('POST', 'PUT', 'PATCH', 'DELETE')
+
+# Response fields kept in the log: enough to audit what was created and to map IDs on replay
+RESULT_FIELDS = ('id', 'title', 'url')
+
+class OperationLogError(Exception):
+    """An operation log that cannot be read or replayed"""
+
+class OperationLog:
+    """One JSON line per mutating API call, appended and flushed as it completes
+
+    Lines carry a sequence number (op) that keeps counting across runs, the
+    run they belong to, the call and its payload (files by path), and the
+    status with the IDs, titles and URLs of the response. Authentication is
+    never logged. Safe to share between threads; use open_operation_log() so
+    uploads in one process writing the same file share one instance.
+    """
+
+    def __init__(self, path: Path, account: str):
+        self.path = Path(path)
+        self.account = account
+        self.run_id = datetime.now().strftime('%Y%m%dT%H%M%S%f')
+        self._lock = threading.Lock()
+        operations = load_operations(self.path) if self.path.exists() else []
+        self._next_op = max((operation['op'] for operation in operations), default=0) + 1
+
+    def record(self, method: str, endpoint: str, kwargs: Dict, status: Optional[int],
+               result=None, error: Optional[str] = None, replay_of: Optional[int] = None) -> int:
+        """Append one operation; returns its sequence number"""
+        entry = {
+            'op': None,
+            'run': self.run_id,
+            'time': datetime.now().isoformat(timespec='seconds'),
+            'account': self.account,
+            'method': method,
+            'endpoint': endpoint,
+            'payload': request_payload(kwargs),
+            'status': status,
+            'ok': error is None,
+        }
+        if result is not None:
+            entry['result'] = summarize_result(result)
+        if error is not None:
+            entry['error'] = error
+        if replay_of is not None:
+            entry['replay_of'] = replay_of
+        with self._lock:
+            entry['op'] = self._next_op
+            self._next_op += 1
+            self.path.parent.mkdir(parents=True, exist_ok=True)
+            with open(self.path, 'a', encoding='utf-8') as f:
+                f.write(json.dumps(entry, ensure_ascii=False, default=str) + '\n')
+        return entry['op']
+
+_open_logs = {}
+_open_logs_lock = threading.Lock()
+
+def open_operation_log(path: Path, account: str) -> OperationLog:
+    """The OperationLog of a file, shared by every client of this process that writes it"""
+    key = (str(Path(path).resolve()), account)
+    with _open_logs_lock:
+        if key not in _open_logs:
+            _open_logs[key] = OperationLog(path, account)
+        return _open_logs[key]
+
+def request_payload(kwargs: Dict) -> Dict:
+    """The replayable parts of request arguments: JSON body, form data, query and files by path"""
+    payload = {key: kwargs[key] for key in ('json', 'data', 'params') if kwargs.get(key) is not None}
+    files = {}
+    for field, value in (kwargs.get('files') or {}).items():
+        # Multipart parts are (filename, file or value, content type); files are logged by path
+        if isinstance(value, tuple) and len(value) > 1 and hasattr(value[1], 'read'):
+            files[field] = {'path': str(getattr(value[1], 'name', '')), 'filename': value[0],
+                            'content_type': value[2] if len(value) > 2 else None}
+        else:
+            files[field] = value[1] if isinstance(value, tuple) and len(value) > 1 else value
+    if files:
+        payload['files'] = files
+    if isinstance(payload.get('data'), bytes):
+        payload['data'] = payload['data'].decode('utf-8', errors='replace')
+    return payload
+
+def summarize_result(value):
+    """A response reduced to the IDs, titles and URLs of the objects it holds"""
+    if isinstance(value, dict):
+        kept = {key: summarize_result(item) for key, item in value.items()
+                if key in RESULT_FIELDS or isinstance(item, (dict, list))}
+        return {key: item for key, item in kept.items() if item not in ({}, [])}
+    if isinstance(value, list):
+        return [item for item in (summarize_result(item) for item in value) if item not in ({}, [])]
+    return value
+
+def load_operations(path: Path) -> List[Dict]:
+    """Every operation in a log, in order; raises OperationLogError for lines that are not operations"""
+    operations = []
+    try:
+        with open(path, 'r', encoding='utf-8') as f:
+            for number, line in enumerate(f, 1):
+                if not line.strip():
+                    continue
+                try:
+                    operation = json.loads(line)
+                except ValueError as e:
+                    raise OperationLogError(f"{path}:{number}: not a JSON line: {e}")
+                if not isinstance(operation, dict) or not isinstance(operation.get('op'), int) \
+                        or operation.get('method') not in MUTATING_METHODS or not operation.get('endpoint'):
+                    raise OperationLogError(f"{path}:{number}: not an API operation")
+                operations.append(operation)
+    except OSError as e:
+        raise OperationLogError(f"Cannot read operation log {path}: {e}")
+    return operations
+
+def original_operation(operation: Dict, by_op: Dict[int, Dict]) -> Dict:
+    """The logged operation a replayed one repeats, following replays of replays"""
+    seen = set()
+    while operation.get('replay_of') in by_op and operation['op'] not in seen:
+        seen.add(operation['op'])
+        operation = by_op[operation['replay_of']]
+    return operation
+
+def completed_operations(operations: List[Dict]) -> set:
+    """The ops that succeeded, themselves or through a later replay"""
+    by_op = {operation['op']: operation for operation in operations}
+    return {original_operation(operation, by_op)['op'] for operation in operations if operation.get('ok')}
+
+def select_replay(operations: List[Dict], from_op: Optional[int] = None) -> List[Dict]:
+    """The operations to replay: from_op and the ones after it in the same run
+
+    Without from_op, replay starts at the first failed operation of the last
+    run that still has one, and leaves out operations that succeeded, the
+    first time or in a later replay, so replaying a log twice does not send
+    them again. An explicit from_op sends every operation from it on.
+    """
+    if from_op is None:
+        completed = completed_operations(operations)
+        pending = [operation for operation in operations
+                   if operation.get('replay_of') is None and operation['op'] not in completed]
+        if not pending:
+            return []
+        run = pending[-1].get('run')
+        return [operation for operation in pending if operation.get('run') == run]
+    start = next((operation for operation in operations if operation['op'] == from_op), None)
+    if start is None:
+        raise OperationLogError(f"No operation {from_op} in the log")
+    return [operation for operation in operations
+            if operation.get('run') == start.get('run') and operation['op'] >= from_op]
+
+def collect_changes(old, new, ids: Dict[str, object], urls: Dict[str, str]):
+    """Record the IDs and URLs that changed between a logged result and the replayed one"""
+    if isinstance(old, dict) and isinstance(new, dict):
+        for key, value in old.items():
+            if key not in new:
+                continue
+            if key == 'id' and value != new[key]:
+                ids[str(value)] = new[key]
+            elif key == 'url' and isinstance(value, str) and value != new[key]:
+                urls[value] = new[key]
+            else:
+                collect_changes(value, new[key], ids, urls)
+    elif isinstance(old, list) and isinstance(new, list):
+        for old_item, new_item in zip(old, new):
+            collect_changes(old_item, new_item, ids, urls)
+
+def remap(value, ids: Dict[str, object], urls: Dict[str, str], key: str = ''):
+    """A payload with IDs (in *id fields) and URLs changed by earlier replayed operations replaced"""
+    if isinstance(value, dict):
+        return {k: remap(item, ids, urls, k) for k, item in value.items()}
+    if isinstance(value, list):
+        return [remap(item, ids, urls, key) for item in value]
+    if key.endswith('id') and not isinstance(value, bool) and str(value) in ids:
+        return ids[str(value)]
+    if isinstance(value, str):
+        for old, new in urls.items():
+            value = value.replace(old, str(new))
+    return value
+
+def remap_endpoint(endpoint: str, ids: Dict[str, object]) -> str:
+    """An endpoint with the IDs of re-created objects replaced"""
+    return '/'.join(str(ids.get(part, part)) for part in endpoint.split('/'))
+
+def replay_operations(client, operations: List[Dict],
+                      on_result: Optional[Callable[[Dict, Optional[str]], None]] = None,
+                      history: Optional[List[Dict]] = None) -> Dict:
+    """Send logged operations again through client.send(), in order
+
+    IDs and URLs of objects the replay creates anew replace the logged ones in
+    later operations, including objects re-created by earlier successful
+    replays in history (the whole log). Stops at the first operation that
+    fails, since later ones usually depend on it. on_result(operation, error)
+    is called after each operation. Returns the number replayed and the
+    failed operation, if any.
+    """
+    ids, urls = {}, {}
+    by_op = {operation['op']: operation for operation in history or []}
+    for operation in history or []:
+        if operation.get('ok') and operation.get('replay_of') is not None:
+            collect_changes(original_operation(operation, by_op).get('result'), operation.get('result'), ids, urls)
+    replayed = 0
+    for operation in operations:
+        if operation.get('account') and operation['account'] != client.account:
+            raise OperationLogError(f"Operation {operation['op']} was sent to account {operation['account']}, "
+                                    f"not {client.account}")
+        payload = remap(operation.get('payload') or {}, ids, urls)
+        kwargs = {key: payload[key] for key in ('json', 'data', 'params') if key in payload}
+        try:
+            with contextlib.ExitStack() as stack:
+                if payload.get('files'):
+                    kwargs['files'] = {}
+                    for field, value in payload['files'].items():
+                        if isinstance(value, dict) and 'path' in value:
+                            handle = stack.enter_context(open(value['path'], 'rb'))
+                            kwargs['files'][field] = (value.get('filename') or Path(value['path']).name, handle,
+                                                      value.get('content_type') or 'application/octet-stream')
+                        else:
+                            kwargs['files'][field] = (None, value)
+                response = client.send(operation['method'], remap_endpoint(operation['endpoint'], ids),
+                                       replay_of=operation['op'], **kwargs)
+        except (OSError, requests.exceptions.RequestException) as e:
+            if on_result:
+                on_result(operation, str(e))
+            return {'replayed': replayed, 'failed_op': operation['op'], 'error': str(e)}
+        try:
+            result = response.json() if response.content else None
+        except ValueError:
+            result = None
+        collect_changes(operation.get('result'), summarize_result(result), ids, urls)
+        replayed += 1
+        if on_result:
+            on_result(operation, None)
+    return {'replayed': replayed, 'failed_op': None}
@@ -0,0 +1,110 @@
+#!/usr/bin/env python3
+"""
+VLP2SS Upload Overrides
+Renames, drops or repositions chapters and articles of a converted manual at
+upload time, for last-minute editorial changes without re-running conversion
+
+Author: Burke Azbill
+Version: 1.0.3
+"""
+
+from pathlib import Path
+from typing import Dict, List
+
+from vlp2ss_config import load_config, ConfigError
+
+# Keys an override entry may set
+OVERRIDE_KEYS = {'title', 'position', 'drop'}
+
+class OverridesError(Exception):
+    """Raised for unreadable or invalid override files"""
+
+def load_overrides(path: Path) -> Dict[str, Dict[str, Dict]]:
+    """Load a YAML/JSON overrides file
+
+    The file has 'chapters' and/or 'articles' sections mapping a converted ID,
+    VLP ID or exact title to the changes for that item:
+
+        chapters:
+          "Lab Overview": {title: "Introduction", position: 1}
+        articles:
+          "Appendix: Old Screenshots": {drop: true}
+          "3f9c2a...": {title: "Deploy the Cluster", position: 2}
+    """
+    try:
+        data = load_config(path)
+    except ConfigError as e:
+        raise OverridesError(str(e))
+
+    unknown = set(data) - {'chapters', 'articles'}
+    if unknown:
+        raise OverridesError(f"Overrides file {path} has unknown sections: {', '.join(sorted(unknown))} "
+                             f"(expected chapters, articles)")
+    overrides = {}
+    for section in ('chapters', 'articles'):
+        entries = data.get(section) or {}
+        if not isinstance(entries, dict):
+            raise OverridesError(f"Overrides section '{section}' in {path} must map IDs or titles to changes")
+        for key, changes in entries.items():
+            if not isinstance(changes, dict) or not changes or set(changes) - OVERRIDE_KEYS:
+                raise OverridesError(f"Override '{key}' in {path} must set one or more of: "
+                                     f"{', '.join(sorted(OVERRIDE_KEYS))}")
+            if 'position' in changes and not isinstance(changes['position'], int):
+                raise OverridesError(f"Override '{key}' in {path}: position must be a number")
+        overrides[section] = {str(k): v for k, v in entries.items()}
+    return overrides
+
+def _matches(key: str, item: Dict) -> bool:
+    return key in (item.get('id'), str(item.get('vlp_id') or ''), item.get('title'))
+
+def apply_overrides(manual_info: Dict, overrides: Dict[str, Dict[str, Dict]]) -> List[Dict]:
+    """Apply overrides to a manual's TOC in place
+
+    Returns one record ({type, key, title, change}) per change, plus an
+    'unmatched' record for every key that matched nothing. When a position
+    is overridden or an item dropped, the chapters (or the articles of that
+    chapter) are re-sorted and renumbered; otherwise their order is left alone.
+    """
+    applied = []
+    used = set()  # (kind, key) of the overrides that matched
+
+    def apply(kind: str, items: List[Dict], position_key: str) -> List[Dict]:
+        kept = []
+        renumber = False  # Positions changed or items dropped
+        for index, item in enumerate(items, 1):
+            changes = {}
+            for key, entry in overrides.get(f"{kind}s", {}).items():
+                if _matches(key, item):
+                    changes.update(entry)
+                    used.add((kind, key))
+            if changes.get('drop'):
+                applied.append({'type': kind, 'key': item['id'], 'title': item['title'], 'change': 'dropped'})
+                renumber = True
+                continue
+            if changes.get('title') and changes['title'] != item['title']:
+                applied.append({'type': kind, 'key': item['id'], 'title': item['title'],
+                                'change': f"renamed to '{changes['title']}'"})
+                item['title'] = str(changes['title'])
+            if 'position' in changes:
+                applied.append({'type': kind, 'key': item['id'], 'title': item['title'],
+                                'change': f"moved to position {changes['position']}"})
+                renumber = True
+            # Overridden position first, else the original place; an override wins ties
+            kept.append((changes.get('position', index), 0 if 'position' in changes else 1, index, item))
+        if not renumber:
+            return [entry[3] for entry in kept]
+        kept.sort(key=lambda entry: entry[:3])
+        result = [entry[3] for entry in kept]
+        for position, item in enumerate(result, 1):
+            item[position_key] = position
+        return result
+
+    manual_info['chapters'] = apply('chapter', manual_info.get('chapters', []), 'order')
+    for chapter in manual_info['chapters']:
+        chapter['articles'] = apply('article', chapter.get('articles', []), 'position')
+
+    for section, kind in (('chapters', 'chapter'), ('articles', 'article')):
+        for key in overrides.get(section, {}):
+            if (kind, key) not in used:
+                applied.append({'type': kind, 'key': key, 'title': None, 'change': 'unmatched'})
+    return applied
@@ -0,0 +1,200 @@
+#!/usr/bin/env python3
+"""
+VLP2SS Preview Server
+Serves a converted manual directory as a rendered approximation of the
+ScreenSteps article layout, to review a migration before uploading it
+(python3 vlp2ss_preview.py output/<manual> --port 8080)
+
+Author: Burke Azbill
+Version: 1.0.3
+"""
+
+import re
+import sys
+import json
+import argparse
+import mimetypes
+from pathlib import Path
+from html import escape
+from typing import Dict, List, Optional, Tuple
+from urllib.parse import unquote, urlparse
+from http.server import BaseHTTPRequestHandler, ThreadingHTTPServer
+
+# JSON files in a content directory that are not the table of contents
+STATE_FILES = ('screensteps_ids.json', 'upload_journal.json', 'summary.json', 'readback_cache.json', 'languages.json')
+
+# Local image references in step content (rewritten to the article's images/<article id>/ directory)
+LOCAL_SRC_REGEX = re.compile(r'(<(?:img|video|source)\b[^>]*?\bsrc=")(?!https?:|data:|//)([^"]+)(")', re.IGNORECASE)
+# Links to other converted articles (data-ss-article, set by the converter)
+ARTICLE_LINK_REGEX = re.compile(r'href="([^"]*?)(#[^"]*)?"(\s+data-ss-article="([^"]+)")')
+# Foldable Lab Credentials blocks (--credentials-block foldable) and quiz answers
+FOLDABLE_REGEX = re.compile(r'<div class="screensteps-styled-block" data-style="([^"]*)" '
+                            r'data-(?:credentials="foldable"|foldable="true")><p><strong>(.*?)</strong></p>(.*?)</div>', re.DOTALL)
+
+# Approximation of the ScreenSteps reader styles
+PAGE_STYLE = """
+body { font-family: -apple-system, "Segoe UI", Helvetica, Arial, sans-serif; margin: 0; color: #2d3a45; }
+nav { position: fixed; top: 0; bottom: 0; width: 300px; overflow-y: auto; background: #f4f6f8; padding: 16px; box-sizing: border-box; font-size: 14px; }
+nav h1 { font-size: 16px; } nav h2 { font-size: 13px; text-transform: uppercase; color: #6b7a88; margin: 18px 0 6px; }
+nav a { display: block; padding: 3px 0; color: #2d3a45; text-decoration: none; } nav a.current { font-weight: bold; color: #1a73c8; }
+main { margin-left: 300px; padding: 24px 48px; max-width: 880px; }
+main img { max-width: 100%; border: 1px solid #dde3e8; }
+.step { margin: 32px 0; } .step > h2 { border-bottom: 1px solid #dde3e8; padding-bottom: 6px; font-size: 20px; }
+.screensteps-styled-block { border-left: 4px solid #9aa7b2; background: #f4f6f8; padding: 8px 14px; margin: 12px 0; }
+.screensteps-styled-block[data-style="info"] { border-color: #1a73c8; background: #e8f1fb; }
+.screensteps-styled-block[data-style="tip"] { border-color: #2e9e5b; background: #e9f6ee; }
+.screensteps-styled-block[data-style="warning"] { border-color: #e0a100; background: #fdf6e3; }
+.screensteps-styled-block[data-style="alert"] { border-color: #d64541; background: #fbeaea; }
+.screensteps-styled-block[data-style="introduction"] { border-color: #7b5ea7; background: #f2eef8; }
+.screensteps-styled-block[data-style="code"] { border-color: #2d3a45; background: #23292f; color: #e8eaec; }
+.screensteps-styled-block[data-style="code"] pre { margin: 0; white-space: pre-wrap; }
+.badge { font-size: 12px; background: #d64541; color: #fff; border-radius: 3px; padding: 1px 6px; margin-left: 6px; }
+.pager { display: flex; justify-content: space-between; margin: 40px 0; }
+"""
+
+class ManualSite:
+    """A converted manual loaded from its table of contents, rendered as HTML pages"""
+
+    def __init__(self, content_dir: Path):
+        self.content_dir = content_dir
+        self.toc_file = self.find_toc_file(content_dir)
+        if not self.toc_file:
+            if (content_dir / 'languages.json').is_file():
+                raise ValueError(f"{content_dir} holds several languages; preview one of its language directories")
+            raise ValueError(f"No converted manual (table of contents) found in {content_dir}")
+        self.reload()
+
+    @staticmethod
+    def find_toc_file(content_dir: Path) -> Optional[Path]:
+        for file in sorted(content_dir.glob('*.json')):
+            if file.name not in STATE_FILES:
+                return file
+        return None
+
+    def reload(self):
+        """Read the table of contents again (the converter may have rewritten it)"""
+        with open(self.toc_file, 'r', encoding='utf-8') as f:
+            self.manual = json.load(f)['manual']
+        self.articles: List[Tuple[Dict, Dict]] = [(chapter, article) for chapter in self.manual['chapters']
+                                                  for article in chapter['articles']]
+
+    def page(self, title: str, body: str, current: Optional[str] = None) -> str:
+        nav = [f'<h1><a href="/">{escape(self.manual["title"])}</a></h1>']
+        for chapter in self.manual['chapters']:
+            nav.append(f'<h2>{escape(chapter["title"])}</h2>')
+            for article in chapter['articles']:
+                css = ' class="current"' if article['id'] == current else ''
+                nav.append(f'<a{css} href="/a/{article["id"]}">{escape(article["title"])}</a>')
+        return (f'<!DOCTYPE html><html><head><meta charset="utf-8"><title>{escape(title)}</title>'
+                f'<style>{PAGE_STYLE}</style></head><body><nav>{"".join(nav)}</nav><main>{body}</main></body></html>')
+
+    def render_index(self) -> str:
+        parts = [f'<h1>{escape(self.manual["title"])}</h1>',
+                 f'<p>Preview of {escape(str(self.content_dir))}. Layout is an approximation of ScreenSteps.</p>']
+        for chapter in self.manual['chapters']:
+            badge = '<span class="badge">restricted</span>' if chapter.get('restricted') else ''
+            parts.append(f'<h2>{escape(chapter["title"])}{badge}</h2>')
+            if chapter.get('description'):
+                parts.append(f'<p>{escape(chapter["description"])}</p>')
+            parts.append('<ul>' + ''.join(f'<li><a href="/a/{a["id"]}">{escape(a["title"])}</a></li>'
+                                          for a in chapter['articles']) + '</ul>')
+        return self.page(self.manual['title'], ''.join(parts))
+
+    def render_step_content(self, html: str, article: Dict) -> str:
+        """Point local images at the article's image directory and article links at their preview pages"""
+        html = LOCAL_SRC_REGEX.sub(
+            lambda m: f'{m.group(1)}/images/{article["id"]}/{unquote(m.group(2)).split("/")[-1].split("?")[0]}{m.group(3)}',
+            html)
+        html = ARTICLE_LINK_REGEX.sub(lambda m: f'href="/a/{m.group(4)}{m.group(2) or ""}"{m.group(3)}', html)
+        return FOLDABLE_REGEX.sub(lambda m: f'<details class="screensteps-styled-block" data-style="{m.group(1)}">'
+                                            f'<summary><strong>{m.group(2)}</strong></summary>{m.group(3)}</details>',
+                                  html)
+
+    def render_article(self, article_id: str) -> Optional[str]:
+        index = next((i for i, (_, a) in enumerate(self.articles) if a['id'] == article_id), None)
+        if index is None:
+            return None
+        chapter, article = self.articles[index]
+        badge = '<span class="badge">restricted</span>' if article.get('restricted') else ''
+        parts = [f'<p>{escape(chapter["title"])}</p><h1>{escape(article["title"])}{badge}</h1>']
+        for step in article['steps']:
+            # Same anchor as the uploader's step_anchor(), so converted links land on the step
+            anchor = escape(step.get('anchor') or re.sub(r'[-\s]+', '-', re.sub(r'[^\w\s-]', '', step['title'].lower())).strip('-'))
+            parts.append(f'<section class="step" id="{anchor}"><h2>{escape(step["title"])}</h2>'
+                         f'{self.render_step_content(step.get("content", ""), article)}</section>')
+        previous = self.articles[index - 1][1] if index > 0 else None
+        following = self.articles[index + 1][1] if index + 1 < len(self.articles) else None
+        parts.append('<div class="pager">'
+                     + (f'<a href="/a/{previous["id"]}">&larr; {escape(previous["title"])}</a>' if previous else '<span></span>')
+                     + (f'<a href="/a/{following["id"]}">{escape(following["title"])} &rarr;</a>' if following else '')
+                     + '</div>')
+        return self.page(article['title'], ''.join(parts), current=article_id)
+
+    def image_file(self, path: str) -> Optional[Path]:
+        """File under the manual's images directory for /images/<article id>/<name>, if it exists"""
+        images_dir = (self.content_dir / 'images').resolve()
+        file = (images_dir / unquote(path)).resolve()
+        if images_dir not in file.parents or not file.is_file():
+            return None
+        return file
+
+def make_handler(site: ManualSite):
+    class PreviewHandler(BaseHTTPRequestHandler):
+        def do_GET(self):
+            path = urlparse(self.path).path
+            if path == '/':
+                site.reload()
+                return self.send(200, site.render_index())
+            if path.startswith('/a/'):
+                site.reload()
+                html = site.render_article(path[len('/a/'):])
+                return self.send(200, html) if html else self.send(404, site.page('Not found', '<h1>Article not found</h1>'))
+            if path.startswith('/images/'):
+                file = site.image_file(path[len('/images/'):])
+                if file:
+                    return self.send(200, file.read_bytes(), mimetypes.guess_type(file.name)[0] or 'application/octet-stream')
+            self.send(404, site.page('Not found', '<h1>Not found</h1>'))
+
+        def send(self, status: int, body, content_type: str = 'text/html; charset=utf-8'):
+            data = body.encode('utf-8') if isinstance(body, str) else body
+            self.send_response(status)
+            self.send_header('Content-Type', content_type)
+            self.send_header('Content-Length', str(len(data)))
+            self.send_header('Cache-Control', 'no-store')
+            self.end_headers()
+            self.wfile.write(data)
+
+        def log_message(self, format, *args):
+            pass  # Keep the console for the startup message
+    return PreviewHandler
+
+def main() -> int:
+    parser = argparse.ArgumentParser(
+        description='Serve a converted manual as an approximation of the ScreenSteps article layout')
+    parser.add_argument('content', type=Path, help='Converted manual directory (output/<manual>)')
+    parser.add_argument('--port', type=int, default=8080, help='Port to listen on (default: 8080)')
+    parser.add_argument('--host', type=str, default='127.0.0.1',
+                        help='Address to listen on (default: 127.0.0.1; 0.0.0.0 shares the preview on the network)')
+    args = parser.parse_args()
+
+    try:
+        site = ManualSite(args.content)
+    except (ValueError, OSError, KeyError) as e:
+        print(f"Error: {e}", file=sys.stderr)
+        return 1
+    try:
+        server = ThreadingHTTPServer((args.host, args.port), make_handler(site))
+    except OSError as e:
+        print(f"Error: cannot listen on {args.host}:{args.port}: {e}", file=sys.stderr)
+        return 1
+    print(f"Previewing {site.manual['title']} at http://{args.host}:{args.port}/ (Ctrl+C to stop)")
+    try:
+        server.serve_forever()
+    except KeyboardInterrupt:
+        pass
+    finally:
+        server.server_close()
+    return 0
+
+if __name__ == "__main__":
+    sys.exit(main())
@@ -0,0 +1,107 @@
+#!/usr/bin/env python3
+"""
+VLP2SS Progress Tracking
+Thread-safe progress counters shared by the converter and uploader loggers;
+percentages and the ETA are derived from one consistent snapshot
+
+Author: Burke Azbill
+Version: 1.0.3
+"""
+
+import time
+import threading
+from typing import Dict, Tuple
+
+# Levels with a total and a current position (shown as percentages)
+PROGRESS_LEVELS = ('manual', 'chapter', 'article')
+
+# Work items counted as finished (drive the ETA)
+PROGRESS_ITEMS = ('articles', 'images')
+
+# Rough cost of one work item: ~10-15 seconds per article + ~2 seconds per image
+SECONDS_PER_ITEM = {'articles': 12.5, 'images': 2.0}
+
+class ProgressTracker:
+    """Totals, positions and finished-item counters guarded by one lock
+
+    Counters only change through set_totals(), enter() and advance(), so
+    concurrent chapter or article workers never lose an update, and the
+    derived values are always computed from a single snapshot.
+    """
+
+    def __init__(self):
+        self._lock = threading.Lock()
+        self.start_time = time.time()
+        self._totals = {'manual': 0, 'chapter': 0, 'article': 0, 'images': 0}
+        self._current = {level: 0 for level in PROGRESS_LEVELS}
+        self._processed = {item: 0 for item in PROGRESS_ITEMS}
+
+    def set_totals(self, manuals: int = 1, chapters: int = 0, articles: int = 0, images: int = 0):
+        """Set total counts for progress tracking"""
+        with self._lock:
+            self._totals.update(manual=manuals, chapter=chapters, article=articles, images=images)
+
+    def enter(self, level: str, position: int = None) -> int:
+        """Move to a manual, chapter or article and return the new position
+
+        Without a position the level advances by one (the next article);
+        with one it only moves forward, so a worker finishing an earlier
+        chapter late never makes progress run backwards.
+        """
+        with self._lock:
+            current = self._current[level]
+            self._current[level] = current + 1 if position is None else max(current, position)
+            return self._current[level]
+
+    def advance(self, item: str, count: int = 1) -> int:
+        """Count finished articles or images and return the new total"""
+        with self._lock:
+            self._processed[item] += count
+            return self._processed[item]
+
+    def snapshot(self) -> Dict:
+        """Copy of every counter, taken under the lock"""
+        with self._lock:
+            return {
+                'totals': dict(self._totals),
+                'current': dict(self._current),
+                'processed': dict(self._processed),
+                'elapsed_seconds': round(time.time() - self.start_time, 1),
+            }
+
+    def processed(self, item: str) -> int:
+        """Finished articles or images so far"""
+        with self._lock:
+            return self._processed[item]
+
+    def percentages(self, snapshot: Dict = None) -> Dict[str, float]:
+        """Completion of each level in percent (0 while its total is unknown)"""
+        snapshot = snapshot or self.snapshot()
+        totals, current = snapshot['totals'], snapshot['current']
+        return {level: min(100.0, current[level] / totals[level] * 100) if totals[level] > 0 else 0.0
+                for level in PROGRESS_LEVELS}
+
+    def progress_string(self, snapshot: Dict = None) -> str:
+        """Generate progress percentage string"""
+        pct = self.percentages(snapshot)
+        return f"[ Manual: {pct['manual']:.0f}%, Chapter: {pct['chapter']:.0f}%, Article: {pct['article']:.0f}% ]"
+
+    def estimate_time_remaining(self, snapshot: Dict = None) -> str:
+        """Estimate remaining time from the articles and images not yet processed"""
+        snapshot = snapshot or self.snapshot()
+        totals, processed = snapshot['totals'], snapshot['processed']
+        if processed['articles'] == 0:
+            return "Calculating..."
+        remaining = {'articles': totals['article'] - processed['articles'],
+                     'images': totals['images'] - processed['images']}
+        estimated_remaining = sum(max(0, remaining[item]) * SECONDS_PER_ITEM[item] for item in PROGRESS_ITEMS)
+
+        minutes, seconds = divmod(int(estimated_remaining), 60)
+        if minutes > 0:
+            return f"~{minutes}m {seconds}s"
+        return f"~{seconds}s"
+
+    def status(self) -> Tuple[str, str]:
+        """Progress string and ETA from the same snapshot, for one progress line"""
+        snapshot = self.snapshot()
+        return self.progress_string(snapshot), self.estimate_time_remaining(snapshot)
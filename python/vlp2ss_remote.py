@@ -0,0 +1,105 @@
+#!/usr/bin/env python3
+"""
+VLP2SS Remote Files
+Fetches centrally maintained mapping and rules files over HTTPS, caching them
+locally with their ETag and optionally pinning their SHA-256 checksum
+
+Author: Burke Azbill
+Version: 1.0.3
+"""
+
+import os
+import json
+import hashlib
+import urllib.request
+import urllib.error
+from pathlib import Path
+from urllib.parse import urlparse, urldefrag
+from typing import Callable, Dict, Optional, Tuple
+
+# Downloaded files and their ETags (override with VLP2SS_CACHE_DIR)
+DEFAULT_CACHE_DIR = Path(os.environ.get('VLP2SS_CACHE_DIR') or Path.home() / ".cache" / "vlp2ss") / "remote"
+
+class RemoteFileError(Exception):
+    """Raised when a remote file cannot be fetched or fails its checksum"""
+
+def is_remote(location: str) -> bool:
+    """Whether a file option names a URL rather than a local path"""
+    return urlparse(str(location)).scheme in ('http', 'https')
+
+def _split_pin(location: str) -> Tuple[str, Optional[str]]:
+    """Split 'https://host/file.yaml#sha256=<hex>' into the URL and the pinned checksum"""
+    url, fragment = urldefrag(location)
+    if not fragment:
+        return url, None
+    key, _, value = fragment.partition('=')
+    if key.lower() != 'sha256' or len(value) != 64:
+        raise RemoteFileError(f"Unsupported pin '#{fragment}' in {location} (expected #sha256=<64 hex digits>)")
+    return url, value.lower()
+
+def _load_meta(meta_file: Path) -> Dict:
+    try:
+        with open(meta_file, 'r', encoding='utf-8') as f:
+            return json.load(f)
+    except (OSError, ValueError):
+        return {}
+
+def fetch_file(location: str, cache_dir: Path = DEFAULT_CACHE_DIR, timeout: float = 30.0,
+               warn: Optional[Callable[[str], None]] = None) -> Path:
+    """Return a local path for a file option that may be an HTTPS URL
+
+    Local paths are returned unchanged. URLs are downloaded into the cache
+    and revalidated with If-None-Match on later runs, so an unchanged file is
+    not transferred again. When the server cannot be reached, the cached copy
+    is used with a warning. The cached copy is hashed on every use and
+    downloaded again if it no longer matches. A '#sha256=<hex>' suffix pins
+    the expected checksum; a mismatch is an error, even for the cached copy.
+    """
+    if not is_remote(location):
+        return Path(location).expanduser()
+
+    url, pinned = _split_pin(location)
+    if urlparse(url).scheme != 'https':
+        raise RemoteFileError(f"Remote files must use HTTPS: {url}")
+
+    # Keep the file suffix so YAML/JSON detection still works on the cached copy
+    key = hashlib.sha256(url.encode('utf-8')).hexdigest()[:16]
+    cache_dir.mkdir(parents=True, exist_ok=True)
+    cached = cache_dir / f"{key}{Path(urlparse(url).path).suffix}"
+    meta_file = cache_dir / f"{key}.meta.json"
+    meta = _load_meta(meta_file) if cached.exists() else {}
+    if meta and hashlib.sha256(cached.read_bytes()).hexdigest() != meta.get('sha256'):
+        meta = {}  # The cached copy was corrupted or modified: download it again
+    etag = meta.get('etag')
+    if pinned and meta.get('sha256') == pinned:
+        return cached  # A pinned file cannot change, no need to revalidate
+
+    request = urllib.request.Request(url)
+    if meta.get('etag'):
+        request.add_header('If-None-Match', meta['etag'])
+    try:
+        with urllib.request.urlopen(request, timeout=timeout) as response:
+            data = response.read()
+            etag = response.headers.get('ETag')
+    except urllib.error.HTTPError as e:
+        if e.code != 304:
+            raise RemoteFileError(f"Cannot fetch {url}: HTTP {e.code} {e.reason}")
+        data = None  # 304 Not Modified
+    except (urllib.error.URLError, OSError) as e:
+        if not meta:
+            raise RemoteFileError(f"Cannot fetch {url}: {getattr(e, 'reason', e)}")
+        data = None
+        if warn:
+            warn(f"Cannot reach {url} ({getattr(e, 'reason', e)}), using cached copy")
+
+    if data is None:
+        data = cached.read_bytes()
+    digest = hashlib.sha256(data).hexdigest()
+    if pinned and digest != pinned:
+        raise RemoteFileError(f"Checksum mismatch for {url}: expected sha256 {pinned}, got {digest}")
+
+    if digest != meta.get('sha256') or etag != meta.get('etag'):
+        cached.write_bytes(data)
+        with open(meta_file, 'w', encoding='utf-8') as f:
+            json.dump({'url': url, 'etag': etag, 'sha256': digest}, f, indent=2)
+    return cached
@@ -0,0 +1,278 @@
+#!/usr/bin/env python3
+"""
+VLP2SS QA Report
+Self-contained HTML report of a converted manual listing every chapter,
+article and step with its warnings and image thumbnails, for reviewers, and
+the plain-text structure tree printed by --print-structure
+
+Author: Burke Azbill
+Version: 1.0.3
+"""
+
+import re
+import json
+from html import escape, unescape
+from pathlib import Path
+from datetime import datetime
+from typing import Dict, List, Optional, Tuple
+
+# Written next to summary.json; regenerated by the uploader with upload results
+QA_REPORT_FILE = 'qa_report.html'
+
+# Generated VLP/Google Docs classes (c2, c44, ...) left in converted HTML
+UNCONVERTED_CLASS_REGEX = re.compile(r'^c\d+$')
+CLASS_ATTR_REGEX = re.compile(r'class="([^"]*)"')
+IMG_SRC_REGEX = re.compile(r'<img[^>]+src="([^"]+)"')
+TAG_REGEX = re.compile(r'<[^>]+>')
+
+REPORT_CSS = """
+body { font-family: -apple-system, "Segoe UI", Helvetica, Arial, sans-serif; margin: 2em; color: #222; }
+h1 { margin-bottom: 0.2em; }
+.meta { color: #666; margin-bottom: 1.5em; }
+table.counts td { padding: 2px 12px 2px 0; }
+details { margin: 0.4em 0; }
+details.chapter > summary { font-size: 1.15em; font-weight: bold; cursor: pointer; }
+details.article { margin-left: 1.5em; border-left: 3px solid #ddd; padding-left: 0.8em; }
+details.article > summary { cursor: pointer; }
+.step { margin: 0.6em 0 0.6em 1.5em; }
+.issues { color: #b00020; margin: 0.2em 0; padding-left: 1.2em; }
+.badge { display: inline-block; font-size: 0.8em; padding: 0 6px; border-radius: 8px; margin-left: 6px; }
+.badge.warn { background: #fde2e1; color: #b00020; }
+.badge.ok { background: #e3f4e1; color: #1e6b1a; }
+.badge.info { background: #e8eef9; color: #2a4d8f; }
+.thumbs img { max-width: 160px; max-height: 120px; margin: 4px 6px 4px 0; border: 1px solid #ccc; }
+.ids { color: #999; font-size: 0.8em; }
+label.filter { display: block; margin: 1em 0; }
+body.issues-only .clean { display: none; }
+"""
+
+REPORT_SCRIPT = """
+document.getElementById('issues-only').addEventListener('change', function (e) {
+  document.body.classList.toggle('issues-only', e.target.checked);
+});
+"""
+
+def step_images(content: str) -> List[str]:
+    """File names of the local images referenced by step HTML, in order"""
+    names = []
+    for src in IMG_SRC_REGEX.findall(content):
+        if src.startswith(('http://', 'https://', 'data:')):
+            continue
+        name = unescape(src).split('/')[-1].split('?')[0]
+        if name not in names:
+            names.append(name)
+    return names
+
+def step_issues(step: Dict, article_images_dir: Path) -> List[str]:
+    """Warnings for one converted step"""
+    issues = []
+    content = step.get('content') or ''
+    if not TAG_REGEX.sub('', unescape(content)).strip() and '<img' not in content:
+        issues.append("Empty step")
+
+    for name in step_images(content):
+        if not (article_images_dir / name).exists():
+            issues.append(f"Missing image: {name}")
+
+    leftover = sorted({cls for attr in CLASS_ATTR_REGEX.findall(content)
+                       for cls in attr.split() if UNCONVERTED_CLASS_REGEX.match(cls)})
+    if leftover:
+        issues.append(f"Unconverted VLP classes: {', '.join(leftover)}")
+    return issues
+
+def _load_json(path: Path) -> Dict:
+    try:
+        with open(path, 'r', encoding='utf-8') as f:
+            return json.load(f)
+    except (OSError, ValueError):
+        return {}
+
+def _badge(text: str, kind: str) -> str:
+    return f'<span class="badge {kind}">{escape(text)}</span>'
+
+def write_qa_report(content_dir: Path, manual_data: Optional[Dict] = None) -> Optional[Path]:
+    """Write qa_report.html into a converted manual's directory
+
+    Uses the manual TOC (loaded via summary.json when not given) and the
+    conversion/upload sections of summary.json. Returns the report path, or
+    None when there is no manual to report on.
+    """
+    summary = _load_json(content_dir / 'summary.json')
+    conversion = summary.get('conversion', {})
+    upload = summary.get('upload', {})
+    if manual_data is None and conversion.get('manual_id'):
+        manual_data = _load_json(content_dir / f"{conversion['manual_id']}.json")
+    if not manual_data or 'manual' not in manual_data:
+        return None
+    manual = manual_data['manual']
+
+    # Screenshots older than --stale-before, per (article ID, file name)
+    stale = {(image['article_id'], image['file']): image['modified'] for image in conversion.get('stale_images', [])}
+
+    # Links that matched no lesson or step, and images without alt text, per (article title, step title)
+    unresolved = {}
+    for link in conversion.get('unresolved_links', []):
+        unresolved.setdefault((link['article'], link['step']), []).append(link['href'])
+    missing_alt = {}
+    for image in conversion.get('missing_alt_text', []):
+        missing_alt.setdefault((image['article'], image['step']), []).append(image['file'])
+    # Images left out by their format policy report the reason instead of "Missing image"
+    rejected = {(image['article'], image['step'], f"Missing image: {Path(image['file']).name}"): f"Image left out: {image['reason']}"
+                for image in conversion.get('rejected_images', [])}
+    
+    # Upload results per converted article ID, and images the uploader skipped
+    uploaded = {a['id']: a for a in upload.get('articles', [])}
+    skipped = {}
+    for image in upload.get('skipped_images', []):
+        skipped.setdefault((image.get('article_title'), image.get('step_title')), []).append(
+            Path(image.get('image_path', '')).name)
+
+    total_issues = 0
+    chapter_html = []
+    for chapter in manual.get('chapters', []):
+        article_html = []
+        chapter_issues = 0
+        for article in chapter.get('articles', []):
+            images_dir = content_dir / 'images' / article['id']
+            step_html = []
+            article_issues = 0
+            for step in article.get('steps', []):
+                issues = [rejected.get((article['title'], step.get('title'), issue), issue)
+                          for issue in step_issues(step, images_dir)]
+                issues += [f"Image skipped during upload: {name}"
+                           for name in skipped.get((article['title'], step.get('title')), [])]
+                issues += [f"Stale screenshot: {name} (dated {stale[(article['id'], name)]})"
+                           for name in step_images(step.get('content') or '') if (article['id'], name) in stale]
+                issues += [f"Unresolved link: {href}" for href in unresolved.get((article['title'], step.get('title')), [])]
+                issues += [f"Missing alt text: {name}" for name in missing_alt.get((article['title'], step.get('title')), [])]
+                article_issues += len(issues)
+                thumbs = ''
+                for name in step_images(step.get('content') or ''):
+                    if (images_dir / name).exists():
+                        rel = escape(f"images/{article['id']}/{name}")
+                        thumbs += f'<a href="{rel}"><img loading="lazy" src="{rel}" alt="{escape(name)}"></a>'
+                step_html.append(
+                    f'<div class="step {"" if issues else "clean"}">'
+                    f'<div>{escape(step.get("title") or "(untitled step)")}'
+                    f'{_badge(f"{len(issues)} warning(s)", "warn") if issues else ""}</div>'
+                    + (f'<ul class="issues">{"".join(f"<li>{escape(i)}</li>" for i in issues)}</ul>' if issues else '')
+                    + (f'<div class="thumbs">{thumbs}</div>' if thumbs else '')
+                    + '</div>')
+            if not article.get('steps'):
+                article_issues += 1
+                step_html.append('<ul class="issues"><li>Article has no steps</li></ul>')
+
+            badges = _badge(f"{article_issues} warning(s)", 'warn') if article_issues else _badge('OK', 'ok')
+            result = uploaded.get(article['id'])
+            if result:
+                badges += _badge(result['status'], 'warn' if result['status'] == 'failed' else 'info')
+                if result['status'] == 'failed':
+                    article_issues += 1
+            chapter_issues += article_issues
+            article_html.append(
+                f'<details class="article {"" if article_issues else "clean"}"{" open" if article_issues else ""}>'
+                f'<summary>{escape(article["title"])}{badges}'
+                f' <span class="ids">{escape(article["id"])}'
+                f'{" / VLP " + escape(str(article["vlp_id"])) if article.get("vlp_id") else ""}'
+                f'{" / ScreenSteps " + escape(str(result["screensteps_id"])) if result and result.get("screensteps_id") else ""}'
+                f'</span></summary>{"".join(step_html)}</details>')
+        total_issues += chapter_issues
+        badge = _badge(f"{chapter_issues} warning(s)", 'warn') if chapter_issues else _badge('OK', 'ok')
+        chapter_html.append(
+            f'<details class="chapter {"" if chapter_issues else "clean"}"{" open" if chapter_issues else ""}>'
+            f'<summary>{escape(chapter["title"])}{badge}</summary>{"".join(article_html)}</details>')
+
+    counts = dict(conversion.get('counts', {}))
+    counts['QA warnings'] = total_issues
+    if upload:
+        counts['upload status'] = upload.get('status')
+        counts.update({f"upload {k}": v for k, v in upload.get('counts', {}).items()})
+    generator = summary.get('generator', {})
+    run_warnings = conversion.get('warnings', []) + [
+        f"Image {m['reference']} matched {m['matched']} by {m['rule']}"
+        for m in conversion.get('fuzzy_image_matches', []) + upload.get('fuzzy_image_matches', [])] + [
+        f"{f['message']} at {f['node_path']}" + (f" ({f['occurrences']} times)" if f.get('occurrences', 1) > 1 else '')
+        for f in conversion.get('findings', [])]
+
+    html = f"""<!DOCTYPE html>
+<html lang="{escape(manual.get('language') or 'en')}">
+<head>
+<meta charset="utf-8">
+<title>QA Report - {escape(manual['title'])}</title>
+<style>{REPORT_CSS}</style>
+</head>
+<body>
+<h1>{escape(manual['title'])}</h1>
+<div class="meta">QA report generated {datetime.now().strftime('%Y-%m-%d %H:%M')}
+ by VLP2SS {escape(str(generator.get('version', '')))} ({escape(str(generator.get('git_sha', 'unknown')))})
+ &middot; manual {escape(manual['id'])}</div>
+<table class="counts">{''.join(f'<tr><td>{escape(str(k))}</td><td>{escape(str(v))}</td></tr>' for k, v in counts.items())}</table>
+{f'<h2>Run warnings</h2><ul class="issues">{"".join(f"<li>{escape(w)}</li>" for w in run_warnings)}</ul>' if run_warnings else ''}
+<h2>Content</h2>
+<label class="filter"><input type="checkbox" id="issues-only"> Show only items with warnings</label>
+{''.join(chapter_html)}
+<script>{REPORT_SCRIPT}</script>
+</body>
+</html>
+"""
+    report_file = content_dir / QA_REPORT_FILE
+    with open(report_file, 'w', encoding='utf-8') as f:
+        f.write(html)
+    return report_file
+
+def _order_flags(orders: List[Optional[int]]) -> List[str]:
+    """Flag VLP order values that are duplicated or lower than a previous sibling's"""
+    flags = []
+    seen = set()
+    highest = None
+    for order in orders:
+        flag = ''
+        if order is not None:
+            if order in seen:
+                flag = 'duplicate VLP order'
+            elif highest is not None and order < highest:
+                flag = 'VLP order out of sequence'
+            seen.add(order)
+            highest = order if highest is None else max(highest, order)
+        flags.append(flag)
+    return flags
+
+def format_structure(manual_data: Dict, include_steps: bool = False) -> Tuple[List[str], int]:
+    """Render the planned ScreenSteps structure as an indented text tree
+
+    Each line shows the ScreenSteps position next to the source VLP
+    OrderIndex ('-' for generated description/introduction items). Items whose
+    VLP order is duplicated or out of sequence among their siblings are marked
+    with '!'. Returns the lines and the number of flagged items.
+    """
+    manual = manual_data['manual']
+    rows = []  # (position, vlp order, depth, title, flag)
+    chapters = manual.get('chapters', [])
+    chapter_flags = _order_flags([c.get('order') for c in chapters])
+    for chapter_pos, (chapter, chapter_flag) in enumerate(zip(chapters, chapter_flags), 1):
+        rows.append((str(chapter_pos), chapter.get('order'), 0, chapter['title'], chapter_flag))
+        articles = chapter.get('articles', [])
+        # Description articles reuse the chapter's VLP node and have no order of their own
+        article_orders = [None if a.get('vlp_id') and a.get('vlp_id') == chapter.get('vlp_id') else a.get('vlp_order')
+                          for a in articles]
+        for article, order, flag in zip(articles, article_orders, _order_flags(article_orders)):
+            position = f"{chapter_pos}.{article.get('position')}"
+            rows.append((position, order, 1, article['title'], flag))
+            if not include_steps:
+                continue
+            steps = article.get('steps', [])
+            step_orders = [st.get('order') if st.get('vlp_id') else None for st in steps]
+            for step_pos, (step, step_order, step_flag) in enumerate(zip(steps, step_orders, _order_flags(step_orders)), 1):
+                rows.append((f"{position}.{step_pos}", step_order, 2, step.get('title') or '(untitled step)', step_flag))
+
+    pos_width = max([len('POS')] + [len(r[0]) for r in rows])
+    lines = [f"{manual['title']}", f"{'POS'.ljust(pos_width)}  {'VLP':>5}  STRUCTURE"]
+    flagged = 0
+    for position, order, depth, title, flag in rows:
+        vlp = '-' if order is None else str(order)
+        line = f"{position.ljust(pos_width)}  {vlp:>5}  {'    ' * depth}{title}"
+        if flag:
+            line += f"   ! {flag}"
+            flagged += 1
+        lines.append(line)
+    return lines, flagged
@@ -0,0 +1,167 @@
+#!/usr/bin/env python3
+"""
+VLP2SS Replacement Rules
+Ordered regex find/replace rules applied to converted HTML, titles and image
+references, loaded from a YAML or JSON rules file
+
+Author: Burke Azbill
+Version: 1.0.3
+"""
+
+import re
+import json
+from pathlib import Path
+from typing import Dict, List, Sequence, Tuple
+
+# What a rule may apply to: article/step HTML, chapter/article/step titles, image references
+SCOPES = ('body', 'title', 'image_src')
+# Image references in HTML (scope image_src)
+IMG_SRC_REGEX = re.compile(r'(<img\b[^>]*?\bsrc\s*=\s*)(["\'])(.*?)\2', re.IGNORECASE | re.DOTALL)
+
+class RulesError(Exception):
+    """Raised for unreadable rules files or invalid rules"""
+
+class ReplacementRule:
+    """A single find/replace rule"""
+
+    FLAGS = {
+        'ignore_case': re.IGNORECASE,
+        'multiline': re.MULTILINE,
+        'dotall': re.DOTALL,
+    }
+
+    def __init__(self, name: str, find: str, replace: str = '', literal: bool = False, flags: int = 0,
+                 scopes: Sequence[str] = ('body',)):
+        self.name = name
+        self.replace = replace
+        self.scopes = tuple(scopes)
+        try:
+            self.pattern = re.compile(re.escape(find) if literal else find, flags)
+        except re.error as e:
+            raise RulesError(f"Rule '{name}': invalid regex {find!r}: {e}")
+        # Literal rules must not interpret backslashes in the replacement
+        self.replacement = (lambda m: replace) if literal else replace
+
+    @classmethod
+    def from_dict(cls, data: Dict, index: int) -> 'ReplacementRule':
+        """Build a rule from one entry of a rules file"""
+        if not isinstance(data, dict) or 'find' not in data:
+            raise RulesError(f"Rule #{index} must be a mapping with at least a 'find' key")
+        name = str(data.get('name') or f"rule-{index}")
+        flags = 0
+        for key, flag in cls.FLAGS.items():
+            if data.get(key):
+                flags |= flag
+        scopes = data.get('scope', 'body')
+        scopes = [scopes] if isinstance(scopes, str) else scopes
+        if not isinstance(scopes, list) or not scopes:
+            raise RulesError(f"Rule '{name}': scope must be one of {', '.join(SCOPES)} or a list of them")
+        for scope in scopes:
+            if scope not in SCOPES:
+                raise RulesError(f"Rule '{name}': unknown scope {scope!r} (use {', '.join(SCOPES)})")
+        return cls(
+            name=name,
+            find=str(data['find']),
+            replace=str(data.get('replace', '')),
+            literal=bool(data.get('literal', False)),
+            flags=flags,
+            scopes=scopes
+        )
+
+    def apply(self, text: str) -> Tuple[str, int]:
+        """Apply the rule, returning the new text and the number of replacements"""
+        return self.pattern.subn(self.replacement, text)
+
+    def apply_to_image_srcs(self, html: str) -> Tuple[str, int]:
+        """Apply the rule to the src of every <img> in the HTML only"""
+        total = 0
+
+        def rewrite(match):
+            nonlocal total
+            src, count = self.apply(match.group(3))
+            total += count
+            return f"{match.group(1)}{match.group(2)}{src}{match.group(2)}"
+        return IMG_SRC_REGEX.sub(rewrite, html), total
+
+class RuleSet:
+    """Ordered list of replacement rules; each rule sees the output of the previous one"""
+
+    def __init__(self, rules: List[ReplacementRule]):
+        self.rules = rules
+
+    def __bool__(self):
+        return bool(self.rules)
+
+    @staticmethod
+    def _count(counts: Dict[Tuple[str, str], int], rule: ReplacementRule, scope: str, count: int):
+        if count:
+            counts[(rule.name, scope)] = counts.get((rule.name, scope), 0) + count
+
+    def apply(self, text: str) -> Tuple[str, Dict[Tuple[str, str], int]]:
+        """Apply the body and image_src rules in order to article/step HTML
+
+        Returns the new HTML and the match counts per (rule name, scope). A
+        body rule sees the whole HTML, image sources included.
+        """
+        counts = {}
+        for rule in self.rules:
+            if 'body' in rule.scopes:
+                text, count = rule.apply(text)
+                self._count(counts, rule, 'body', count)
+            elif 'image_src' in rule.scopes:
+                text, count = rule.apply_to_image_srcs(text)
+                self._count(counts, rule, 'image_src', count)
+        return text, counts
+
+    def apply_title(self, title: str) -> Tuple[str, Dict[Tuple[str, str], int]]:
+        """Apply the title rules in order to a manual, chapter, article or step title"""
+        return self._apply_scope(title, 'title')
+
+    def apply_image_src(self, reference: str) -> Tuple[str, Dict[Tuple[str, str], int]]:
+        """Apply the image_src rules in order to a bare image reference (the export's image list)"""
+        return self._apply_scope(reference, 'image_src')
+
+    def _apply_scope(self, text: str, scope: str) -> Tuple[str, Dict[Tuple[str, str], int]]:
+        counts = {}
+        for rule in self.rules:
+            if scope in rule.scopes:
+                text, count = rule.apply(text)
+                self._count(counts, rule, scope, count)
+        return text, counts
+
+def load_rules(path: Path) -> RuleSet:
+    """Load a rules file
+
+    The file holds a list of rules, either at the top level or under a 'rules'
+    key. Each rule has 'find' (a regex, or plain text with 'literal: true'),
+    'replace' (may use \\1 / \\g<name> group references), an optional 'name',
+    the optional flags 'ignore_case', 'multiline' and 'dotall', and an
+    optional 'scope': body (default), title, image_src, or a list of them.
+    """
+    try:
+        with open(path, 'r', encoding='utf-8') as f:
+            text = f.read()
+    except OSError as e:
+        raise RulesError(f"Cannot read rules file {path}: {e}")
+
+    if path.suffix.lower() == '.json':
+        try:
+            data = json.loads(text)
+        except ValueError as e:
+            raise RulesError(f"Invalid JSON in {path}: {e}")
+    else:
+        try:
+            import yaml
+        except ImportError:
+            raise RulesError("YAML rules files require PyYAML: pip3 install pyyaml")
+        try:
+            data = yaml.safe_load(text)
+        except yaml.YAMLError as e:
+            raise RulesError(f"Invalid YAML in {path}: {e}")
+
+    if isinstance(data, dict):
+        data = data.get('rules')
+    if not isinstance(data, list):
+        raise RulesError(f"Rules file {path} must contain a list of rules")
+
+    return RuleSet([ReplacementRule.from_dict(entry, i) for i, entry in enumerate(data, 1)])
@@ -0,0 +1,224 @@
+#!/usr/bin/env python3
+"""
+VLP2SS Sample Export Generator
+Writes a synthetic VLP export that exercises every construct the converter
+supports, so the full pipeline can be tried without a real lab export
+
+Author: Burke Azbill
+Version: 1.0.3
+"""
+
+import sys
+import zlib
+import struct
+import zipfile
+import argparse
+from pathlib import Path
+from typing import Dict, List, Optional, Tuple
+import xml.etree.ElementTree as ET
+
+SAMPLE_MANUAL_ID = 'SAMPLE-0001'
+SAMPLE_MANUAL_NAME = 'VLP2SS-Sample-Lab'
+# Fixed export date, so converting the sample never depends on the date of content.xml
+SAMPLE_EXPORT_DATE = '2026-01-15T09:00:00Z'
+
+# Languages of the sample's localized content; the first is the export's default
+SAMPLE_LANGUAGES = ('en', 'es')
+
+# Class map matching the sample's custom paragraph classes (pass with --class-map)
+SAMPLE_CLASS_MAP = """\
+# Class map for the VLP2SS sample export
+span:
+  c6: code
+  c2: strong
+paragraph:
+  note-box: info
+  warning-box: warning
+"""
+
+# Referenced by the content but deliberately left out of images/
+MISSING_IMAGE = 'missing-screenshot.png'
+
+def _png(width: int, height: int, rgb: Tuple[int, int, int]) -> bytes:
+    """Minimal solid-color PNG (no imaging library needed)"""
+    def chunk(kind: bytes, data: bytes) -> bytes:
+        return struct.pack('>I', len(data)) + kind + data + struct.pack('>I', zlib.crc32(kind + data) & 0xffffffff)
+    row = b'\x00' + bytes(rgb) * width
+    return (b'\x89PNG\r\n\x1a\n' + chunk(b'IHDR', struct.pack('>IIBBBBB', width, height, 8, 2, 0, 0, 0))
+            + chunk(b'IDAT', zlib.compress(row * height)) + chunk(b'IEND', b''))
+
+def _node(node_id: str, title: str, order: int, content: Dict[str, str], images: List[str] = (),
+          duration: Optional[str] = None, children: List[ET.Element] = ()) -> ET.Element:
+    """A ContentNode with one LocaleContent per language (content keyed by language code)"""
+    node = ET.Element('ContentNode', {'id': node_id})
+    ET.SubElement(node, 'title').text = title
+    ET.SubElement(node, 'orderIndex').text = str(order)
+    if duration:
+        ET.SubElement(node, 'estimatedDuration').text = duration
+    localizations = ET.SubElement(node, 'localizations')
+    for language in SAMPLE_LANGUAGES:
+        locale = ET.SubElement(localizations, 'LocaleContent')
+        ET.SubElement(locale, 'languageCode').text = language
+        ET.SubElement(locale, 'title').text = title if language == SAMPLE_LANGUAGES[0] else f"[{language}] {title}"
+        ET.SubElement(locale, 'content').text = content.get(language, content[SAMPLE_LANGUAGES[0]])
+        if images:
+            images_element = ET.SubElement(locale, 'images')
+            for name in images:
+                ET.SubElement(images_element, 'img', {'src': f"./images/{name}", 'filename': name,
+                                                      'width': '640', 'height': '400'})
+    if children:
+        children_element = ET.SubElement(node, 'children')
+        children_element.extend(children)
+    return node
+
+def _sample_nodes() -> List[ET.Element]:
+    """The sample's chapters, one construct (or a few related ones) per step"""
+    steps_formatting = [
+        _node('step-styled', 'Styled Blocks', 1, {
+            'en': '<p class="note-box">Note: this paragraph uses a class mapped to an info block.</p>'
+                  '<p class="warning-box">Warning: consecutive paragraphs of a class form one block.</p>'
+                  '<p class="warning-box">This is the second line of the warning.</p>'
+                  '<p>Inline <span class="c6">code</span>, <span class="c2">bold</span> text and a '
+                  '<span class="icon-tip"></span> font icon.</p>',
+            'es': '<p class="note-box">Nota: este párrafo usa una clase asignada a un bloque de información.</p>'
+        }),
+        _node('step-lists', 'Nested Lists', 2, {
+            'en': '<ol class="lst-kix_sample-0 start"><li>First item</li><li>Second item</li></ol>'
+                  '<ol class="lst-kix_sample-1 start"><li>Nested item a</li><li>Nested item b</li></ol>'
+                  '<ol class="lst-kix_sample-0"><li>Third item with a screenshot '
+                  '<img src="./images/list-shot.png" title="Screenshot inside a list"></li></ol>'
+                  '<ul><li>Bullet</li><li>Another bullet</li></ul>'
+        }, images=['list-shot.png']),
+        _node('step-code', 'Code and Commands', 3, {
+            'en': '<p>Run the following commands:</p>'
+                  '<p class="command">esxcli network ip interface list</p>'
+                  '<p class="command">esxcli system version get</p>'
+                  '<pre>kubectl get nodes\nkubectl get pods -A</pre>'
+                  '<p>Paste into the console:</p><div class="copy-text"><span>df -h /var/log</span>'
+                  '<button class="copy-button">Copy</button></div>'
+                  '<p>Press <span class="key">Ctrl</span>+<span class="key">C</span> to stop a command, then '
+                  '<span class="key-enter"></span>.</p>'
+        }),
+        _node('step-tables', 'Tables and Layout', 4, {
+            'en': '<table><thead><tr><th>Host</th><th>IP address</th></tr></thead>'
+                  '<tbody><tr><td>esx-01a</td><td>10.0.0.11</td></tr><tr><td>esx-02a</td><td>10.0.0.12</td></tr></tbody></table>'
+                  '<div class="row"><div class="col-md-6"><p>Left column</p></div>'
+                  '<div class="col-md-6"><p>Right column</p></div></div>'
+                  '<p style="float: right">A floated paragraph</p>'
+                  '<table><tr><th>VM</th><th>Username</th><th>Password</th></tr>'
+                  '<tr><td>vcsa-01a</td><td>administrator@vsphere.local</td><td>VMware1!</td></tr></table>'
+        }),
+        _node('step-widgets', 'Knowledge Check', 5, {
+            'en': '<div class="knowledge-check"><h4>Check your understanding</h4><div class="question">'
+                  '<p class="prompt">Which product manages ESXi hosts?</p>'
+                  '<label><input type="radio" name="kc1" value="a">PowerCLI</label>'
+                  '<label><input type="radio" name="kc1" value="b" data-correct="true">vCenter Server</label>'
+                  '<div class="feedback">vCenter Server manages the hosts of a data center.</div></div></div>'
+                  '<ul><li><input type="checkbox"> Power on the VM</li><li><input type="checkbox"> Log in</li></ul>'
+        }),
+    ]
+    steps_media = [
+        _node('step-images', 'Screenshots', 1, {
+            'en': '<p>A screenshot with alt text:</p><p><img src="./images/login.png" alt="Login page"></p>'
+                  '<figure><img src="./images/dashboard.png"><figcaption>The dashboard after login</figcaption></figure>'
+                  '<p>A linked screenshot:</p><p><a href="https://example.com/full-size.png">'
+                  '<img src="./images/thumbnail.png" alt="Thumbnail"></a></p>'
+                  f'<p>This image is missing from the export:</p><p><img src="./images/{MISSING_IMAGE}"></p>'
+        }, images=['login.png', 'dashboard.png', 'thumbnail.png', MISSING_IMAGE, 'orphan.png']),
+        _node('step-video', 'Videos and Embeds', 2, {
+            'en': '<div class="mediatag-thumb youtube-thumb" data-media-id="dQw4w9WgXcQ" '
+                  'data-thumb-url="https://img.youtube.com/vi/dQw4w9WgXcQ/0.jpg"></div>'
+                  '<div class="mediatag-thumb vimeo-thumb" data-media-id="76979871"></div>'
+                  '<video controls><source src="https://example.com/videos/demo.mp4" type="video/mp4"></video>'
+                  '<iframe src="https://player.vimeo.com/video/76979871" width="640" height="360"></iframe>'
+                  '<iframe src="https://tools.example.com/calculator" width="400" height="300"></iframe>'
+        }),
+        _node('step-links', 'Links', 3, {
+            'en': '<p>See <a href="step-styled">the styled blocks step</a>, '
+                  '<a href="article-formatting#step-code">the code step</a>, '
+                  '<a href="no-such-node">a link to a missing lesson</a> and '
+                  '<a href="https://docs.vmware.com/">external documentation</a>.</p>'
+                  '<p>Download the <a href="./files/setup.ps1">setup script</a> before you start.</p>'
+        }),
+    ]
+    deep_steps = [
+        _node('step-deep', 'Deeply Nested Step', 1, {'en': '<p>Level 3 content.</p>'}, children=[
+            _node('step-deeper', 'Level 4 Node', 1, {'en': '<p>Level 4 content below a step.</p>'}, children=[
+                _node('step-deepest', 'Level 5 Node', 1, {'en': '<p>Level 5 content.</p>'}),
+            ]),
+        ]),
+    ]
+    return [
+        _node('chapter-intro', 'Introduction', 1, {
+            'en': '<p>This sample lab exercises every construct supported by VLP2SS. '
+                  'It is generated by vlp2ss_sample.py and contains no real lab content.</p>'
+        }, duration='PT45M', children=[
+            _node('article-formatting', 'Formatting', 1, {'en': '<p>Text formatting, lists, code and tables.</p>'},
+                  duration='20 minutes', children=steps_formatting),
+            _node('article-media', 'Media', 2, {'en': ''}, duration='00:15', children=steps_media),
+        ]),
+        _node('chapter-advanced', 'Advanced', 2, {'en': ''}, children=[
+            _node('article-deep', 'Deep Nesting', 1, {'en': ''}, children=deep_steps),
+        ]),
+        _node('chapter-single', 'Single Article Chapter', 3, {'en': ''}, children=[
+            _node('article-single', 'The Only Article', 1, {'en': '<p>A chapter with exactly one article.</p>'}),
+        ]),
+        _node('chapter-copyright', '', 4, {'en': '<p>Copyright © 2026 Example, Inc. All rights reserved.</p>'}),
+    ]
+
+def generate_sample(output_dir: Path, make_zip: bool = False) -> Path:
+    """Write the sample export (content.xml, images/, files/, class-map.yaml) into output_dir
+
+    With make_zip the export is also packed into <output_dir>/<name>.zip,
+    laid out like a VLP download. Returns the export directory (or the ZIP).
+    """
+    output_dir = Path(output_dir)
+    images_dir = output_dir / 'images'
+    images_dir.mkdir(parents=True, exist_ok=True)
+
+    root = ET.Element('Manual', {'id': SAMPLE_MANUAL_ID})
+    ET.SubElement(root, 'name').text = SAMPLE_MANUAL_NAME
+    ET.SubElement(root, 'defaultLanguageCode').text = SAMPLE_LANGUAGES[0]
+    ET.SubElement(root, 'dataFormat').text = 'default'
+    ET.SubElement(root, 'exportDate').text = SAMPLE_EXPORT_DATE
+    ET.SubElement(root, 'contentNodes').extend(_sample_nodes())
+    tree = ET.ElementTree(root)
+    if hasattr(ET, 'indent'):  # Python 3.9+
+        ET.indent(tree)
+    tree.write(output_dir / 'content.xml', encoding='utf-8', xml_declaration=True)
+
+    colors = {'list-shot.png': (66, 133, 244), 'login.png': (52, 168, 83), 'dashboard.png': (251, 188, 5),
+              'thumbnail.png': (234, 67, 53), 'orphan.png': (128, 128, 128)}
+    for name, rgb in colors.items():
+        (images_dir / name).write_bytes(_png(64, 40, rgb))
+    (output_dir / 'class-map.yaml').write_text(SAMPLE_CLASS_MAP, encoding='utf-8')
+    files_dir = output_dir / 'files'
+    files_dir.mkdir(exist_ok=True)
+    (files_dir / 'setup.ps1').write_text('Write-Host "VLP2SS sample attachment"\n', encoding='utf-8')
+
+    if not make_zip:
+        return output_dir
+    zip_path = output_dir / f"{SAMPLE_MANUAL_NAME}_{SAMPLE_LANGUAGES[0]}.zip"
+    with zipfile.ZipFile(zip_path, 'w', zipfile.ZIP_DEFLATED) as archive:
+        for path in sorted([output_dir / 'content.xml'] + list(images_dir.iterdir()) + list(files_dir.iterdir())):
+            archive.write(path, f"{SAMPLE_MANUAL_NAME}/{path.relative_to(output_dir).as_posix()}")
+    return zip_path
+
+def main() -> int:
+    parser = argparse.ArgumentParser(
+        description='Generate a synthetic VLP export that exercises every supported construct',
+        epilog='Convert it with: python3 vlp_converter.py -i sample/ -o output/ --class-map sample/class-map.yaml')
+    parser.add_argument('-o', '--output', type=Path, default=Path('sample'), metavar='DIR',
+                        help='Directory to write the export into (default: sample)')
+    parser.add_argument('--zip', action='store_true',
+                        help='Also pack the export into a ZIP like a VLP download')
+    args = parser.parse_args()
+
+    result = generate_sample(args.output, make_zip=args.zip)
+    print(f"Sample VLP export written to: {result}")
+    print(f"Convert it with: python3 vlp_converter.py -i {result} -o output/ "
+          f"--class-map {args.output / 'class-map.yaml'}")
+    return 0
+
+if __name__ == "__main__":
+    sys.exit(main())
@@ -0,0 +1,212 @@
+#!/usr/bin/env python3
+"""
+VLP2SS Output Schema
+JSON Schemas of the converter's output directory (manual table of contents,
+article files) and of the content blocks the uploader sends to ScreenSteps
+
+Author: Burke Azbill
+Version: 1.0.3
+"""
+
+import sys
+import json
+import argparse
+from pathlib import Path
+from typing import Dict
+
+from vlp2ss_version import APP_VERSION
+
+# Version of the output contract; bumped whenever a field is renamed, removed or changes meaning
+SCHEMA_VERSION = '1.0'
+
+SCHEMA_BASE_URI = 'https://github.com/burkeazbill-bc/VLP2SS/schema'
+
+_NULLABLE_STRING = {'type': ['string', 'null']}
+_NULLABLE_MINUTES = {'type': ['integer', 'null'], 'minimum': 0,
+                     'description': 'Estimated duration in minutes (from the VLP estimatedDuration)'}
+
+# Shared definitions; every schema carries the ones it references
+DEFINITIONS = {
+    'generator': {
+        'type': 'object',
+        'description': 'Build that produced the file (see --version -v)',
+        'properties': {
+            'version': {'type': 'string'},
+            'git_sha': {'type': 'string'},
+            'build_date': {'type': 'string'},
+            'python_version': {'type': 'string'},
+        },
+        'required': ['version', 'git_sha'],
+    },
+    'image': {
+        'type': 'object',
+        'description': 'Image listed for a step in the VLP XML; copied to images/<article id>/<filename>',
+        'properties': {
+            'src': {'type': 'string'},
+            'filename': {'type': 'string'},
+            'width': {'type': 'string'},
+            'height': {'type': 'string'},
+        },
+        'required': ['filename'],
+    },
+    'step': {
+        'type': 'object',
+        'description': 'One ScreenSteps step (a VLP level 3 node, or the own content of a chapter/article)',
+        'properties': {
+            'id': {'type': 'string', 'description': 'Stable ID derived from the VLP node ID'},
+            'vlp_id': _NULLABLE_STRING,
+            'title': {'type': 'string'},
+            'order': {'type': 'integer', 'description': 'VLP order; -1 for an article\'s own content, 0 for a chapter description'},
+            'anchor': {'type': 'string', 'description': 'Step anchor (--stable-anchors or moved credentials); '
+                                                        'else the slugified title is used'},
+            'content': {'type': 'string', 'description': 'Converted step HTML (see docs/FORMATTING.md)'},
+            'images': {'type': 'array', 'items': {'$ref': '#/definitions/image'}},
+        },
+        'required': ['id', 'title', 'order', 'content', 'images'],
+    },
+    'article': {
+        'type': 'object',
+        'description': 'One ScreenSteps article, also written to articles/<id>.json',
+        'properties': {
+            'id': {'type': 'string'},
+            'vlp_id': _NULLABLE_STRING,
+            'title': {'type': 'string'},
+            'description': {'type': 'string', 'description': 'Plain-text summary of the first paragraph'},
+            'position': {'type': 'integer', 'minimum': 1, 'description': 'Sequential position within the chapter'},
+            'vlp_order': {'type': ['integer', 'null']},
+            'duration_minutes': _NULLABLE_MINUTES,
+            'restricted': {'type': 'boolean', 'description': 'Never published by the uploader'},
+            'steps': {'type': 'array', 'items': {'$ref': '#/definitions/step'}},
+        },
+        'required': ['id', 'title', 'position', 'steps'],
+    },
+    'chapter': {
+        'type': 'object',
+        'properties': {
+            'id': {'type': 'string'},
+            'vlp_id': _NULLABLE_STRING,
+            'title': {'type': 'string'},
+            'order': {'type': 'integer'},
+            'description': {'type': 'string'},
+            'duration_minutes': _NULLABLE_MINUTES,
+            'restricted': {'type': 'boolean', 'description': 'Never published by the uploader'},
+            'articles': {'type': 'array', 'items': {'$ref': '#/definitions/article'}},
+        },
+        'required': ['id', 'title', 'order', 'articles'],
+    },
+    'content_block': {
+        'type': 'object',
+        'description': 'ScreenSteps content block as sent by the uploader (POST .../articles/<id>/contents)',
+        'properties': {
+            'uuid': {'type': 'string'},
+            'type': {'enum': ['StepContent', 'TextContent', 'ImageContentBlock']},
+            'depth': {'type': 'integer', 'minimum': 0, 'description': '0 for steps, 1 for their blocks, 2 inside folded sub-steps'},
+            'sort_order': {'type': 'integer', 'minimum': 1},
+            'title': {'type': 'string', 'description': 'StepContent'},
+            'content_block_ids': {'type': 'array', 'items': {'type': 'string'}, 'description': 'StepContent: child block UUIDs'},
+            'anchor_name': {'type': 'string', 'description': 'StepContent'},
+            'auto_numbered': {'type': 'boolean'},
+            'foldable': {'type': 'boolean'},
+            'body': {'type': 'string', 'description': 'TextContent HTML'},
+            'style': {'type': ['string', 'null'], 'description': 'TextContent style: info, warning, alert, tip, code, '
+                                                                  'introduction, html-embed, ... or null for plain text'},
+            'show_copy_clipboard': {'type': 'boolean'},
+            'image_asset_id': {'type': ['string', 'integer'], 'description': 'ImageContentBlock: uploaded asset'},
+            'asset_file_name': {'type': 'string'},
+            'width': {'type': 'integer'},
+            'height': {'type': 'integer'},
+            'alt_tag': {'type': 'string'},
+            'url': {'type': 'string', 'description': 'ImageContentBlock: link target when clicked'},
+        },
+        'required': ['uuid', 'type', 'sort_order'],
+    },
+}
+
+# Root of each schema: the definition it describes and the definitions it references
+SCHEMA_ROOTS = {
+    'manual': ('Manual table of contents (<manual id>.json in the output directory)',
+               {'type': 'object',
+                'properties': {
+                    'generator': {'$ref': '#/definitions/generator'},
+                    'source': {
+                        'type': 'object',
+                        'description': 'ScreenSteps manual a backup was downloaded from (uploader --download)',
+                        'properties': {
+                            'account': {'type': 'string'},
+                            'site_id': {'type': 'string'},
+                            'manual_id': {'type': 'string'},
+                            'downloaded_at': {'type': 'string', 'format': 'date-time'},
+                        },
+                    },
+                    'manual': {
+                        'type': 'object',
+                        'properties': {
+                            'id': {'type': 'string'},
+                            'vlp_id': _NULLABLE_STRING,
+                            'title': {'type': 'string'},
+                            'description': {'type': 'string', 'description': 'Plain-text summary from the VLP manual '
+                                                                             'description (uploaded with the manual)'},
+                            'language': {'type': 'string'},
+                            'metadata': {
+                                'type': 'object',
+                                'description': 'VLP lab metadata (uploader --metadata-tags and article_snippets fields)',
+                                'properties': {
+                                    'sku': _NULLABLE_STRING,
+                                    'data_format': _NULLABLE_STRING,
+                                    'export_date': _NULLABLE_STRING,
+                                },
+                            },
+                            'created_at': {'type': 'string', 'format': 'date-time'},
+                            'updated_at': {'type': 'string', 'format': 'date-time'},
+                            'chapters': {'type': 'array', 'items': {'$ref': '#/definitions/chapter'}},
+                        },
+                        'required': ['id', 'title', 'chapters'],
+                    },
+                },
+                'required': ['manual']},
+               ('generator', 'chapter', 'article', 'step', 'image')),
+    'article': ('Article file (articles/<article id>.json in the output directory)',
+                {'$ref': '#/definitions/article'},
+                ('article', 'step', 'image')),
+    'content-blocks': ('Content blocks of one ScreenSteps article, as sent by the uploader',
+                       {'type': 'array', 'items': {'$ref': '#/definitions/content_block'}},
+                       ('content_block',)),
+}
+
+def build_schema(name: str) -> Dict:
+    """The JSON Schema (draft-07) of one output format: manual, article or content-blocks"""
+    title, root, references = SCHEMA_ROOTS[name]
+    return {
+        '$schema': 'http://json-schema.org/draft-07/schema#',
+        '$id': f"{SCHEMA_BASE_URI}/{SCHEMA_VERSION}/{name}.schema.json",
+        'title': title,
+        'x-schema-version': SCHEMA_VERSION,
+        'x-generator-version': APP_VERSION,
+        **root,
+        'definitions': {ref: DEFINITIONS[ref] for ref in references},
+    }
+
+def main() -> int:
+    parser = argparse.ArgumentParser(
+        description='Print the JSON Schemas of the converter output and the uploaded content blocks',
+        epilog=f'Schema version {SCHEMA_VERSION}; see docs/usage-python.md for the versioning rules')
+    parser.add_argument('schema', nargs='?', choices=list(SCHEMA_ROOTS) + ['all'], default='all',
+                        help='Schema to print (default: all, as one JSON object keyed by name)')
+    parser.add_argument('-o', '--output', type=Path, metavar='DIR',
+                        help='Write <name>.schema.json files into DIR instead of printing')
+    args = parser.parse_args()
+
+    names = list(SCHEMA_ROOTS) if args.schema == 'all' else [args.schema]
+    if args.output:
+        args.output.mkdir(parents=True, exist_ok=True)
+        for name in names:
+            path = args.output / f"{name}.schema.json"
+            path.write_text(json.dumps(build_schema(name), indent=2) + '\n', encoding='utf-8')
+            print(f"Wrote {path}")
+        return 0
+    schemas = {name: build_schema(name) for name in names}
+    print(json.dumps(schemas[names[0]] if len(names) == 1 else schemas, indent=2))
+    return 0
+
+if __name__ == "__main__":
+    sys.exit(main())
@@ -0,0 +1,143 @@
+#!/usr/bin/env python3
+"""
+VLP2SS Secrets Scanner
+Finds likely credentials (lab passwords, API keys, license keys) in article
+HTML before it is uploaded, and optionally masks them
+
+Author: Burke Azbill
+Version: 1.0.3
+"""
+
+import re
+import json
+from pathlib import Path
+from typing import Dict, Iterable, List, Optional, Tuple
+
+# Text that replaces a masked secret
+DEFAULT_MASK = '********'
+
+# Tags that may sit between a label and its value ("Password: <strong>VMware1!</strong>")
+_TAGS = r'(?:\s*<[^>]+>)*\s*'
+
+# Built-in patterns. 'group' names the regex group holding the secret itself
+# (0 = the whole match); only that part is reported and masked.
+DEFAULT_SECRET_PATTERNS = [
+    {'name': 'password', 'group': 1, 'ignore_case': True,
+     'find': r'\b(?:password|passwd|pwd|passphrase)\b' + _TAGS + r'(?:is|:|=)' + _TAGS + r'([^\s<>"]{4,})'},
+    {'name': 'api-key', 'group': 1, 'ignore_case': True,
+     'find': r'\b(?:api[_ -]?key|api[_ -]?token|secret[_ -]?key|access[_ -]?token|client[_ -]?secret)\b'
+             + _TAGS + r'(?:is|:|=)' + _TAGS + r'([A-Za-z0-9_\-]{16,})'},
+    {'name': 'aws-access-key', 'find': r'\b(?:AKIA|ASIA)[0-9A-Z]{16}\b'},
+    {'name': 'github-token', 'find': r'\bgh[pousr]_[A-Za-z0-9]{36,}\b'},
+    {'name': 'license-key', 'find': r'\b[A-Z0-9]{5}(?:-[A-Z0-9]{5}){4}\b'},
+    {'name': 'private-key', 'find': r'-----BEGIN [A-Z ]*PRIVATE KEY-----'},
+]
+
+class SecretsError(Exception):
+    """Raised for unreadable pattern files or invalid patterns"""
+
+class SecretPattern:
+    """A named regex whose match (or one group of it) is a likely secret"""
+
+    def __init__(self, name: str, find: str, group: int = 0, ignore_case: bool = False):
+        self.name = name
+        self.group = group
+        try:
+            self.regex = re.compile(find, re.IGNORECASE if ignore_case else 0)
+        except re.error as e:
+            raise SecretsError(f"Secret pattern '{name}': invalid regex {find!r}: {e}")
+        if group > self.regex.groups:
+            raise SecretsError(f"Secret pattern '{name}' has no group {group}")
+
+    @classmethod
+    def from_dict(cls, data: Dict, index: int) -> 'SecretPattern':
+        """Build a pattern from one entry of a patterns file"""
+        if not isinstance(data, dict) or 'find' not in data:
+            raise SecretsError(f"Secret pattern #{index} must be a mapping with at least a 'find' key")
+        return cls(
+            name=str(data.get('name') or f"pattern-{index}"),
+            find=str(data['find']),
+            group=int(data.get('group', 0)),
+            ignore_case=bool(data.get('ignore_case', False))
+        )
+
+class SecretsScanner:
+    """Scans HTML for secrets; values listed in 'allow' are never reported"""
+
+    def __init__(self, patterns: List[SecretPattern], allow: Iterable[str] = (), mask: str = DEFAULT_MASK):
+        self.patterns = patterns
+        self.allow = set(allow)
+        self.mask = mask
+
+    def _matches(self, html: str) -> List[Tuple[int, int, str]]:
+        """(start, end, pattern name) of every reportable secret, without overlaps"""
+        spans = []
+        for pattern in self.patterns:
+            for match in pattern.regex.finditer(html):
+                start, end = match.span(pattern.group)
+                if match.group(pattern.group) in self.allow:
+                    continue
+                if any(start < e and s < end for s, e, _ in spans):
+                    continue
+                spans.append((start, end, pattern.name))
+        return sorted(spans)
+
+    def scan(self, html: str) -> List[Dict[str, str]]:
+        """Findings in an HTML fragment; secrets are reported redacted"""
+        return [{'pattern': name, 'value': redact(html[start:end])}
+                for start, end, name in self._matches(html)]
+
+    def mask_html(self, html: str) -> Tuple[str, int]:
+        """Replace every finding with the mask text, returning the new HTML and the count"""
+        spans = self._matches(html)
+        for start, end, _ in reversed(spans):
+            html = html[:start] + self.mask + html[end:]
+        return html, len(spans)
+
+def redact(value: str) -> str:
+    """Hide a secret but its length and, for longer ones, its first characters ('V*******')
+
+    At most a sixth of the value (and never more than two characters) stays
+    visible, so short secrets are masked completely.
+    """
+    keep = min(2, len(value) // 6)
+    return value[:keep] + '*' * (len(value) - keep)
+
+def load_scanner(path: Optional[Path] = None, mask: str = DEFAULT_MASK) -> SecretsScanner:
+    """Build a scanner from the built-in patterns plus an optional patterns file
+
+    The file (YAML or JSON) holds 'patterns' - a list of mappings with 'find',
+    optional 'name', 'group' and 'ignore_case' - and 'allow', a list of values
+    that are known to be safe (e.g. a published demo password). Set
+    'replace_defaults: true' to use only the file's patterns.
+    """
+    data = {}
+    if path:
+        try:
+            with open(path, 'r', encoding='utf-8') as f:
+                text = f.read()
+        except OSError as e:
+            raise SecretsError(f"Cannot read secret patterns file {path}: {e}")
+        if path.suffix.lower() == '.json':
+            try:
+                data = json.loads(text)
+            except ValueError as e:
+                raise SecretsError(f"Invalid JSON in {path}: {e}")
+        else:
+            try:
+                import yaml
+            except ImportError:
+                raise SecretsError("YAML pattern files require PyYAML: pip3 install pyyaml")
+            try:
+                data = yaml.safe_load(text)
+            except yaml.YAMLError as e:
+                raise SecretsError(f"Invalid YAML in {path}: {e}")
+        if isinstance(data, list):
+            data = {'patterns': data}
+        if not isinstance(data, dict):
+            raise SecretsError(f"Secret patterns file {path} must contain a mapping or a list of patterns")
+
+    entries = [] if data.get('replace_defaults') else list(DEFAULT_SECRET_PATTERNS)
+    entries += data.get('patterns') or []
+    patterns = [SecretPattern.from_dict(entry, i) for i, entry in enumerate(entries, 1)]
+    return SecretsScanner(patterns, allow=[str(v) for v in data.get('allow') or []], mask=mask)
@@ -0,0 +1,112 @@
+#!/usr/bin/env python3
+"""
+VLP2SS Content Transforms
+User hooks that rewrite the converted HTML of every step before it is written
+(and later split into content blocks), e.g. to remove company boilerplate
+
+Author: Burke Azbill
+Version: 1.0.3
+"""
+
+import os
+import shlex
+import importlib
+import importlib.util
+import subprocess
+from pathlib import Path
+from typing import Callable, Dict, List
+
+# Prefix of a hook spec that runs a command instead of a Python function
+EXEC_PREFIX = 'exec:'
+# Function called when a Python hook spec names no function
+DEFAULT_FUNCTION = 'transform'
+# Seconds an exec hook may take for one step
+EXEC_TIMEOUT = 60
+
+class TransformError(Exception):
+    """Raised for hooks that cannot be loaded or fail on a step"""
+
+class Transform:
+    """One hook: takes the step HTML and its context, returns the new HTML"""
+
+    def __init__(self, name: str, function: Callable[[str, Dict], str]):
+        self.name = name
+        self.function = function
+
+    def apply(self, html: str, context: Dict) -> str:
+        try:
+            result = self.function(html, context)
+        except TransformError:
+            raise
+        except Exception as e:
+            raise TransformError(f"Transform {self.name} failed on '{context.get('step')}': {e}")
+        if not isinstance(result, str):
+            raise TransformError(f"Transform {self.name} returned {type(result).__name__} instead of HTML")
+        return result
+
+def _exec_hook(command: str) -> Callable[[str, Dict], str]:
+    """Hook running a command with the HTML on stdin and the context in VLP2SS_* variables"""
+    args = shlex.split(command, posix=os.name != 'nt')
+    if not args:
+        raise TransformError("exec: transform needs a command")
+
+    def run(html: str, context: Dict) -> str:
+        env = dict(os.environ, **{f"VLP2SS_{key.upper()}": str(value if value is not None else '')
+                                  for key, value in context.items()})
+        try:
+            completed = subprocess.run(args, input=html, capture_output=True, text=True, encoding='utf-8',
+                                       env=env, timeout=EXEC_TIMEOUT)
+        except (OSError, subprocess.TimeoutExpired) as e:
+            raise TransformError(f"Transform {command} could not run: {e}")
+        if completed.returncode != 0:
+            raise TransformError(f"Transform {command} exited with {completed.returncode} on "
+                                 f"'{context.get('step')}': {completed.stderr.strip()}")
+        return completed.stdout
+    return run
+
+def _python_hook(spec: str) -> Callable[[str, Dict], str]:
+    """Function named by 'path/to/file.py:function' or 'package.module:function'"""
+    module_name, separator, function_name = spec.rpartition(':')
+    if not separator or not function_name.isidentifier():
+        module_name, function_name = spec, ''  # No function, or the ':' of a Windows drive
+    function_name = function_name or DEFAULT_FUNCTION
+    try:
+        if module_name.endswith('.py'):
+            path = Path(module_name)
+            module_spec = importlib.util.spec_from_file_location(f"vlp2ss_transform_{path.stem}", path)
+            if module_spec is None or not path.is_file():
+                raise TransformError(f"Transform file not found: {path}")
+            module = importlib.util.module_from_spec(module_spec)
+            module_spec.loader.exec_module(module)
+        else:
+            module = importlib.import_module(module_name)
+    except TransformError:
+        raise
+    except Exception as e:
+        raise TransformError(f"Cannot load transform {spec}: {e}")
+    function = getattr(module, function_name, None)
+    if not callable(function):
+        raise TransformError(f"Transform {spec}: {module_name} has no function {function_name}()")
+    return function
+
+def load_transforms(specs: List[str]) -> List[Transform]:
+    """Load hooks in the given order
+
+    A spec is 'exec:COMMAND' (the step HTML on stdin, the new HTML on
+    stdout), or a Python function 'file.py:function' / 'module:function'
+    called as function(html, context). Without ':function', transform() is
+    called.
+    """
+    transforms = []
+    for spec in specs:
+        if spec.startswith(EXEC_PREFIX):
+            transforms.append(Transform(spec, _exec_hook(spec[len(EXEC_PREFIX):])))
+        else:
+            transforms.append(Transform(spec, _python_hook(spec)))
+    return transforms
+
+def apply_transforms(transforms: List[Transform], html: str, context: Dict) -> str:
+    """Run every hook in order; each sees the output of the previous one"""
+    for transform in transforms:
+        html = transform.apply(html, context)
+    return html
@@ -0,0 +1,216 @@
+#!/usr/bin/env python3
+"""
+VLP2SS Export Validator
+Checks a VLP export (ZIP or extracted directory) before conversion: content.xml
+structure, referenced images, empty localizations. Writes no output files
+
+Author: Burke Azbill
+Version: 1.0.3
+"""
+
+import re
+import sys
+import json
+import zipfile
+import argparse
+import posixpath
+from pathlib import Path
+from typing import Dict, List, Optional
+from urllib.parse import unquote
+import xml.etree.ElementTree as ET
+
+from vlp2ss_version import APP_VERSION
+from vlp2ss_exitcodes import EXIT_OK, EXIT_ERROR, EXIT_WARNINGS, EXIT_IMAGES_SKIPPED, exit_status
+from vlp2ss_extract import normalize_member
+from vlp2ss_formats import normalize_tree, SchemaVersionError
+
+# Image references in localized content (external and inline data images are not checked)
+CONTENT_IMG_REGEX = re.compile(r'<img\b[^>]*?\bsrc\s*=\s*["\']([^"\']+)["\']', re.IGNORECASE)
+
+# Severity of each finding code; errors make the export unconvertible
+FINDING_SEVERITY = {
+    'unreadable_archive': 'error',
+    'missing_content_xml': 'error',
+    'malformed_xml': 'error',
+    'unknown_schema': 'error',
+    'missing_manual_name': 'error',
+    'missing_content_nodes': 'error',
+    'missing_node_id': 'error',
+    'invalid_order_index': 'error',
+    'missing_image': 'warning',
+    'duplicate_node_id': 'warning',
+    'missing_title': 'warning',
+    'no_localization': 'warning',
+    'empty_localization': 'warning',
+    'empty_content': 'warning',
+    'missing_language': 'warning',
+}
+
+class ExportFiles:
+    """Files of a ZIP or extracted export, relative to the directory holding content.xml"""
+
+    def __init__(self, input_path: Path):
+        self.input_path = input_path
+        self.zip = zipfile.ZipFile(input_path) if input_path.is_file() else None
+        self.zip_names = {}  # Normalized name -> member name (may use '\\' separators)
+        if self.zip:
+            for name in self.zip.namelist():
+                normalized = normalize_member(name)
+                if normalized and not name.endswith(('/', '\\')):
+                    self.zip_names[normalized] = name
+            names = list(self.zip_names)
+        else:
+            names = [path.relative_to(input_path).as_posix() for path in input_path.rglob('*') if path.is_file()]
+        # content.xml may be nested one or more directories deep (as in VLP downloads)
+        candidates = sorted((name for name in names if posixpath.basename(name) == 'content.xml'),
+                            key=lambda name: name.count('/'))
+        self.content_xml = candidates[0] if candidates else None
+        base = posixpath.dirname(self.content_xml) if self.content_xml else ''
+        prefix = f"{base}/" if base else ''
+        self.files = {name[len(prefix):] for name in names if name.startswith(prefix)}
+        self.basenames = {posixpath.basename(name) for name in self.files}
+
+    def read_content_xml(self) -> bytes:
+        if self.zip:
+            return self.zip.read(self.zip_names[self.content_xml])
+        return (self.input_path / self.content_xml).read_bytes()
+
+    def has_image(self, reference: str) -> bool:
+        """Whether a referenced image is part of the export (by path, else by file name like the converter)"""
+        path = posixpath.normpath(unquote(reference.split('?')[0].split('#')[0]).lstrip('/'))
+        return path in self.files or posixpath.basename(path) in self.basenames
+
+def validate_export(input_path: Path) -> List[Dict]:
+    """Findings for a VLP export: dicts with severity, code, node (ID or None) and message"""
+    findings = []
+
+    def add(code: str, message: str, node: Optional[str] = None):
+        findings.append({'severity': FINDING_SEVERITY[code], 'code': code, 'node': node, 'message': message})
+
+    try:
+        export = ExportFiles(input_path)
+    except (zipfile.BadZipFile, OSError) as e:
+        add('unreadable_archive', f"Cannot read {input_path}: {e}")
+        return findings
+    if not export.content_xml:
+        add('missing_content_xml', f"No content.xml in {input_path}")
+        return findings
+    try:
+        root = ET.fromstring(export.read_content_xml())
+    except ET.ParseError as e:
+        add('malformed_xml', f"{export.content_xml} is not well-formed XML: {e}")
+        return findings
+    try:
+        normalize_tree(root)  # Newer export schemas are checked with their schema 1 names
+    except SchemaVersionError as e:
+        add('unknown_schema', str(e))
+        return findings
+
+    # A module-level export is one ContentNode, converted as the only chapter of a manual named after it
+    module = root.tag == 'ContentNode'
+    if not module and not (root.findtext('name') or '').strip():
+        add('missing_manual_name', "The manual has no <name> (it names the output directory and the manual)")
+    top_nodes = [root] if module else root.findall('contentNodes/ContentNode')
+    if not top_nodes:
+        add('missing_content_nodes', "content.xml has no <contentNodes> with ContentNode elements")
+        return findings
+
+    languages = {code.text.strip() for code in root.iter('languageCode') if code.text and code.text.strip()}
+    seen_ids = set()
+    missing_images = set()
+
+    def check_node(node: ET.Element, path: str):
+        node_id = node.get('id')
+        label = node_id or path
+        if not node_id:
+            add('missing_node_id', f"ContentNode at {path} has no id attribute", label)
+        elif node_id in seen_ids:
+            add('duplicate_node_id', f"ContentNode id {node_id} is used more than once", label)
+        seen_ids.add(node_id)
+        order = node.findtext('orderIndex')
+        if order is not None and not order.strip().lstrip('-').isdigit():
+            add('invalid_order_index', f"orderIndex '{order}' is not a number", label)
+
+        children = node.find('children')
+        has_children = children is not None and children.find('ContentNode') is not None
+        locales = node.findall('localizations/LocaleContent')
+        if not locales:
+            add('no_localization', "Node has no LocaleContent; it converts without text", label)
+        node_languages = set()
+        for locale in locales:
+            language = (locale.findtext('languageCode') or '').strip()
+            node_languages.add(language)
+            title = (locale.findtext('title') or node.findtext('title') or '').strip()
+            content = (locale.findtext('content') or '').strip()
+            where = f" ({language})" if language else ''
+            if not title and not content:
+                add('empty_localization', f"LocaleContent{where} has neither title nor content", label)
+                continue
+            if not title:
+                add('missing_title', f"LocaleContent{where} has no title", label)
+            if not content and not has_children:
+                add('empty_content', f"Leaf node{where} has no content; it becomes an empty step", label)
+            references = [img.get('src') or img.get('filename') or '' for img in locale.findall('images/img')]
+            references += CONTENT_IMG_REGEX.findall(content)
+            for reference in references:
+                if not reference or reference.startswith(('http://', 'https://', 'data:', '//')):
+                    continue
+                if not export.has_image(reference) and (label, reference) not in missing_images:
+                    missing_images.add((label, reference))
+                    add('missing_image', f"Image {reference}{where} is not in the export", label)
+        if len(languages) > 1 and locales and languages - node_languages:
+            add('missing_language', f"No localization for {', '.join(sorted(languages - node_languages))}; "
+                                    f"the default language is used", label)
+
+        if has_children:
+            for index, child in enumerate(children.findall('ContentNode'), 1):
+                check_node(child, f"{path}/{index}")
+
+    for index, node in enumerate(top_nodes, 1):
+        check_node(node, str(index))
+    return findings
+
+def findings_exit_code(findings: List[Dict]) -> int:
+    """Exit code for a findings list (most severe first), matching the converter's codes"""
+    codes = {finding['code'] for finding in findings}
+    if any(finding['severity'] == 'error' for finding in findings):
+        return EXIT_ERROR
+    if 'missing_image' in codes:
+        return EXIT_IMAGES_SKIPPED
+    if findings:
+        return EXIT_WARNINGS
+    return EXIT_OK
+
+def main() -> int:
+    parser = argparse.ArgumentParser(
+        description='Validate a VLP export (content.xml structure, referenced images, empty localizations) '
+                    'without converting it',
+        epilog='Exit codes: 0 valid, 1 errors, 2 warnings, 3 missing images')
+    parser.add_argument('-i', '--input', type=Path, required=True, metavar='PATH',
+                        help='VLP export ZIP or extracted directory')
+    parser.add_argument('--json', action='store_true',
+                        help='Print the findings as one JSON object instead of text')
+    args = parser.parse_args()
+
+    if not args.input.exists():
+        findings = [{'severity': 'error', 'code': 'unreadable_archive', 'node': None,
+                     'message': f"Input path does not exist: {args.input}"}]
+    else:
+        findings = validate_export(args.input)
+    exit_code = findings_exit_code(findings)
+
+    if args.json:
+        counts = {severity: sum(1 for f in findings if f['severity'] == severity) for severity in ('error', 'warning')}
+        print(json.dumps({'input': str(args.input), 'status': exit_status(exit_code), 'exit_code': exit_code,
+                          'version': APP_VERSION, 'counts': counts, 'findings': findings}, indent=2))
+        return exit_code
+
+    for finding in findings:
+        node = f" [{finding['node']}]" if finding['node'] else ''
+        print(f"{finding['severity'].upper():8} {finding['code']}{node}: {finding['message']}")
+    errors = sum(1 for f in findings if f['severity'] == 'error')
+    print(f"{args.input}: {errors} errors, {len(findings) - errors} warnings" if findings else f"{args.input}: valid")
+    return exit_code
+
+if __name__ == "__main__":
+    sys.exit(main())
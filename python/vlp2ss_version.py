@@ -0,0 +1,72 @@
+#!/usr/bin/env python3
+"""
+VLP2SS Version Information
+Version, git SHA and build date of the running tools, recorded in output and
+mapping files so a migration can be traced back to the build that produced it
+
+Author: Burke Azbill
+Version: 1.0.3
+"""
+
+import os
+import sys
+import argparse
+import platform
+import subprocess
+from pathlib import Path
+from typing import Dict
+
+APP_VERSION = "1.0.3"
+
+# Packaged builds set these (e.g. in a container image); source checkouts ask git
+GIT_SHA_ENV = "VLP2SS_GIT_SHA"
+BUILD_DATE_ENV = "VLP2SS_BUILD_DATE"
+
+_build_info = None
+
+def _git(*args: str) -> str:
+    """Run a git command in the source directory, returning '' on any failure"""
+    try:
+        output = subprocess.run(['git', '-C', str(Path(__file__).resolve().parent), *args],
+                                capture_output=True, text=True, timeout=5)
+        return output.stdout.strip() if output.returncode == 0 else ''
+    except (OSError, subprocess.SubprocessError):
+        return ''
+
+def build_info() -> Dict[str, str]:
+    """Version, git SHA, build date and Python version (computed once per process)"""
+    global _build_info
+    if _build_info is None:
+        git_sha = os.environ.get(GIT_SHA_ENV) or _git('rev-parse', '--short=12', 'HEAD')
+        if git_sha and not os.environ.get(GIT_SHA_ENV) and _git('status', '--porcelain', '--untracked-files=no'):
+            git_sha += '-dirty'
+        _build_info = {
+            'version': APP_VERSION,
+            'git_sha': git_sha or 'unknown',
+            'build_date': os.environ.get(BUILD_DATE_ENV) or _git('log', '-1', '--format=%cI') or 'unknown',
+            'python_version': platform.python_version(),
+        }
+    return _build_info
+
+def version_text(name: str, verbose: bool = False) -> str:
+    """Text printed by --version (with build details when verbose)"""
+    text = f"{name}\nVersion: {APP_VERSION}\nAuthor: Burke Azbill\nLicense: MIT"
+    if verbose:
+        info = build_info()
+        text += (f"\nGit SHA: {info['git_sha']}\nBuild date: {info['build_date']}"
+                 f"\nPython: {info['python_version']} ({platform.platform()})")
+    return text
+
+class VersionAction(argparse.Action):
+    """--version action that adds build details when combined with -v/--verbose"""
+
+    def __init__(self, option_strings, dest=argparse.SUPPRESS, default=argparse.SUPPRESS,
+                 help='Show version information (add -v for git SHA, build date and Python version)',
+                 tool_name: str = 'VLP2SS'):
+        super().__init__(option_strings=option_strings, dest=dest, default=default, nargs=0, help=help)
+        self.tool_name = tool_name
+
+    def __call__(self, parser, namespace, values, option_string=None):
+        verbose = any(arg in ('-v', '--verbose') for arg in sys.argv[1:])
+        print(version_text(self.tool_name, verbose))
+        parser.exit()
@@ -15,19 +15,198 @@ import shutil
 import argparse
 import logging
 import time
+import difflib
+import contextlib
+import tempfile
 from pathlib import Path
 from datetime import datetime
 import xml.etree.ElementTree as ET
 from typing import Dict, List, Optional, Tuple
 import re
-from html import unescape
+from html import unescape, escape
+from urllib.parse import urlparse, unquote
 import uuid
+import unicodedata
 from bs4 import BeautifulSoup
 from PIL import Image
 from bs4 import Tag # Added this import for Tag type hinting
+from vlp2ss_config import add_config_arguments, apply_profile, load_config, ConfigError
+from vlp2ss_rules import load_rules, RulesError
+from vlp2ss_transforms import load_transforms, apply_transforms, TransformError
+from vlp2ss_filters import add_filter_arguments, filter_from_args, FilterError
+from vlp2ss_extract import safe_extract, ExportArchive, ExtractionError, DEFAULT_MAX_TOTAL_SIZE
+from vlp2ss_version import APP_VERSION, VersionAction, build_info
+from vlp2ss_images import ImageIndex, ArchiveIndex, ImageFormatError, apply_format_policy, load_format_policies
+from vlp2ss_report import write_qa_report, format_structure
+from vlp2ss_notify import notify_webhook, email_report, smtp_settings
+from vlp2ss_metrics import DEFAULT_METRICS_FILE, record_run
+from vlp2ss_exitcodes import EXIT_ERROR, exit_status, result_exit_code
+from vlp2ss_logs import DEFAULT_LOG_DIR, DEFAULT_LOG_KEEP, new_log_file
+from vlp2ss_remote import fetch_file, RemoteFileError
+from vlp2ss_formats import SchemaStream, SchemaVersionError, VLP_SCHEMAS
+from vlp2ss_annotations import (ANNOTATION_ATTRIBUTE, AnnotationError, collect_annotations, load_annotations,
+                                annotated_name, burn_annotations)
+from vlp2ss_progress import ProgressTracker
+from vlp2ss_clean import (DEFAULT_TEMP_ROOT, register_artifact, finish_artifact, prepare_output_dir,
+                          OutputDirError)
 
 # --- Constants ---
-APP_VERSION = "1.0.3"
+
+# End-of-run report written into the manual output directory (the uploader adds an 'upload' section)
+SUMMARY_FILE = 'summary.json'
+
+# Index of the per-language content directories of a multi-language conversion (<output>/<manual>/languages.json)
+LANGUAGES_FILE = 'languages.json'
+
+# Namespace for IDs derived from VLP node IDs (see stable_id)
+VLP2SS_ID_NAMESPACE = uuid.UUID('5f0c2a8e-6d3b-4f1a-9c7e-2b8d4e6a1f30')
+
+# Text of the introduction block generated for nodes with an estimated duration
+DEFAULT_DURATION_TEMPLATE = 'Estimated time: {duration}'
+
+# Font-icon classes used in VLP content, mapped to a replacement that survives
+# migration. Values may be plain text/emoji, inline <svg> markup, or an image URL.
+DEFAULT_ICON_MAP = {
+    'icon-warning': '⚠️',
+    'icon-caution': '⚠️',
+    'icon-alert': '❗',
+    'icon-info': 'ℹ️',
+    'icon-note': '📝',
+    'icon-tip': '💡',
+    'icon-lightbulb': '💡',
+    'icon-check': '✅',
+    'icon-ok': '✅',
+    'icon-error': '⛔',
+    'icon-danger': '⛔',
+    'icon-question': '❓',
+    'icon-clock': '⏱️',
+    'icon-download': '⬇️',
+    'icon-link': '🔗',
+    'icon-arrow-right': '➡️',
+}
+
+# Domains whose iframes are kept as embeds; other iframes become links.
+# Add domains with --iframe-allow ('*' embeds every iframe).
+DEFAULT_IFRAME_ALLOWLIST = ('youtube.com', 'youtube-nocookie.com', 'player.vimeo.com', 'vmwaretv.vmware.com')
+
+# Embed URL template per VLP media tag provider (<div class="mediatag-thumb <provider>-thumb">).
+# Add or replace providers with --media-provider NAME=TEMPLATE.
+DEFAULT_MEDIA_PROVIDERS = {
+    'youtube': 'https://www.youtube.com/embed/{id}',
+    'vimeo': 'https://player.vimeo.com/video/{id}',
+    'vmware': 'https://vmwaretv.vmware.com/embed/secure/iframe/entryId/{id}',
+}
+MEDIA_PROVIDER_TITLES = {'youtube': 'YouTube', 'vimeo': 'Vimeo', 'vmware': 'VMware'}
+
+# Video files embedded as players and uploaded as file assets when part of the export
+VIDEO_EXTENSIONS = {'.mp4', '.m4v', '.webm', '.mov', '.ogv'}
+
+# Downloadable files; links to them in the export are uploaded as file assets
+ATTACHMENT_EXTENSIONS = {'.pdf', '.zip', '.gz', '.tgz', '.7z', '.txt', '.csv', '.json', '.yaml', '.yml', '.xml',
+                         '.md', '.log', '.cfg', '.conf', '.ini', '.ps1', '.psm1', '.sh', '.py', '.bat', '.cmd',
+                         '.doc', '.docx', '.xls', '.xlsx', '.ppt', '.pptx', '.ova', '.ovf', '.iso'}
+
+# Inline style properties that only make sense with the VLP page layout
+LAYOUT_STYLE_PROPERTIES = {'float', 'position', 'top', 'left', 'right', 'bottom', 'z-index',
+                           'column-count', 'columns', 'column-gap', 'column-width'}
+# display values that only lay out columns (display: none and the rest are kept, so hidden content stays hidden)
+LAYOUT_DISPLAY_VALUES = {'flex', 'inline-flex', 'grid', 'inline-grid'}
+# Class names used for column grids and floats by common editors/themes
+LAYOUT_COLUMN_CLASS_REGEX = re.compile(r'^(row|columns?|col(-[a-z]{2})?-\d+|(two|three|multi)-col(umn)?s?)$', re.IGNORECASE)
+LAYOUT_FLOAT_CLASS_REGEX = re.compile(r'^(float|pull|align)-?(left|right)$', re.IGNORECASE)
+
+# VLP span classes with a fixed meaning in the export theme, mapped to the inline
+# tag they become. Checked in order; profile and --class-map entries extend it.
+DEFAULT_SPAN_CLASS_MAP = {
+    'c6': 'code',
+    'c2': 'strong',
+    'c11': 'strong',
+}
+# VLP paragraph classes mapped to the ScreenSteps styled block they become. Empty by
+# default: generated classes such as c10/c44/c48 only carry indentation and alignment
+# in the default theme, so only themes with semantic paragraph classes should map them
+DEFAULT_PARAGRAPH_STYLE_MAP = {}
+# Class map value that removes a default mapping
+CLASS_MAP_NONE = 'none'
+
+# Google Docs list level classes (lst-kix_<list id>-<level>) and the numbering of nested ordered levels
+LIST_LEVEL_CLASS_REGEX = re.compile(r'^lst-kix_(.+)-(\d+)$')
+LIST_LEVEL_TYPES = ('1', 'a', 'i')
+
+# Paragraph classes VLP themes use for CLI commands; such paragraphs become code blocks
+DEFAULT_COMMAND_CLASSES = ('command', 'cli', 'code', 'console')
+
+# Chapter/article descriptions: maximum length, and the block boundaries that end a paragraph
+DESCRIPTION_MAX_LENGTH = 200
+DESCRIPTION_BLOCK_REGEX = re.compile(r'</(?:p|div|li|h[1-6]|pre|blockquote|td|th)\s*>|<br\s*/?>', re.IGNORECASE)
+
+# Lab credentials tables: header cells naming a user and a password column, and the block they become
+CREDENTIALS_USER_REGEX = re.compile(r'\b(user\s*(name|id)?|login|account)\b', re.IGNORECASE)
+CREDENTIALS_PASSWORD_REGEX = re.compile(r'\b(password|passwd|pwd)\b', re.IGNORECASE)
+CREDENTIALS_TITLE = 'Lab Credentials'
+OVERVIEW_TITLE = 'Overview'
+CREDENTIALS_BLOCK_REGEX = re.compile(r'<div class="screensteps-styled-block" data-style="warning" data-credentials="[^"]*">.*?</div>',
+                                     re.DOTALL)
+
+# Keyboard keys: key spans/images (a class or file name suffix names the key, e.g. key-enter, kbd_ctrl.png),
+# key combinations (Ctrl+Alt+Del) and how key names from classes and file names are written
+KEY_CLASS_REGEX = re.compile(r'^(?:key|kbd|keycap|keyboard-?key|shortcut-?key)(?:[-_]([a-z0-9]+))?$', re.IGNORECASE)
+KEY_COMBO_CLASS_REGEX = re.compile(r'^(?:key-?(?:combo|combination|s)|keys|hotkey|(?:keyboard-?)?shortcut)$', re.IGNORECASE)
+KEY_IMAGE_REGEX = re.compile(r'(?:^|[/_-])(?:key|kbd|keycap|keyboard)[-_]([a-z0-9]+)\.(?:png|gif|svg|jpe?g)$', re.IGNORECASE)
+KEY_NAMES = {'ctrl': 'Ctrl', 'control': 'Ctrl', 'alt': 'Alt', 'option': 'Option', 'shift': 'Shift', 'cmd': 'Cmd',
+             'command': 'Cmd', 'win': 'Win', 'windows': 'Win', 'super': 'Win', 'meta': 'Meta', 'fn': 'Fn',
+             'enter': 'Enter', 'return': 'Return', 'esc': 'Esc', 'escape': 'Esc', 'tab': 'Tab', 'space': 'Space',
+             'spacebar': 'Space', 'backspace': 'Backspace', 'del': 'Del', 'delete': 'Delete', 'ins': 'Insert',
+             'insert': 'Insert', 'home': 'Home', 'end': 'End', 'pgup': 'Page Up', 'pageup': 'Page Up',
+             'pgdn': 'Page Down', 'pagedown': 'Page Down', 'up': '↑', 'down': '↓', 'left': '←', 'right': '→',
+             'capslock': 'Caps Lock', 'prtsc': 'PrtSc', 'printscreen': 'Print Screen'}
+# Longest text of a key or key combination element; longer ones are left alone
+KEY_MAX_LENGTH = 30
+
+# VLP copy-text widgets (console paste boxes): classes, attributes holding the text to copy, and their buttons
+COPY_WIDGET_CLASS_REGEX = re.compile(r'^(copy-?(?:text|box|block|command)|copyable|send-?text|console-?(?:paste|text)|'
+                                     r'paste-?(?:text|box)|clipboard(?:-text)?)$', re.IGNORECASE)
+COPY_WIDGET_ATTRIBUTES = ('data-copy-text', 'data-clipboard-text', 'data-send-text', 'data-paste-text')
+COPY_BUTTON_CLASS_REGEX = re.compile(r'^((copy|send|paste)(-?(button|btn|icon|link))?|btn(-.*)?)$', re.IGNORECASE)
+
+# Knowledge-check and checklist widgets: container classes (or data-widget values) and the parts of a question
+QUIZ_CLASS_REGEX = re.compile(r'^(quiz|knowledge-?check|kc|self-?check|assessment)(?:[-_](question|block|widget|container))?$',
+                              re.IGNORECASE)
+QUIZ_QUESTION_CLASS_REGEX = re.compile(r'^(?:quiz-|kc-)?question$', re.IGNORECASE)
+QUIZ_PROMPT_CLASS_REGEX = re.compile(r'^(?:question-?text|prompt|stem|(?:quiz|kc)-(?:question-)?(?:text|prompt))$', re.IGNORECASE)
+QUIZ_OPTION_CLASS_REGEX = re.compile(r'^(?:(?:quiz|kc|answer)-)?(?:option|choice)$', re.IGNORECASE)
+QUIZ_CORRECT_CLASS_REGEX = re.compile(r'^(?:is-)?(?:correct|right)(?:-(?:answer|option|choice))?$', re.IGNORECASE)
+QUIZ_FEEDBACK_CLASS_REGEX = re.compile(r'^(?:feedback|explanation|rationale|solution|answer-?explanation)$', re.IGNORECASE)
+CHECKLIST_CLASS_REGEX = re.compile(r'^(check-?list|task-?list|todo(?:-?list)?)$', re.IGNORECASE)
+QUIZ_TITLE = 'Knowledge Check'
+QUIZ_ANSWER_TITLE = 'Answer'
+
+# Lab metadata: the SKU in the manual name when the export has no <sku>, and the export date elements
+LAB_SKU_REGEX = re.compile(r'\b[A-Z]{2,5}-\d{4}(?:-\d{2})?(?:-[A-Z0-9]+)*\b')
+EXPORT_DATE_FIELDS = ('exportDate', 'exportedAt', 'exportTime', 'lastModified')
+# Elements read for a module/lesson's estimated duration, on the ContentNode or its LocaleContent
+DURATION_FIELDS = ('estimatedDuration', 'duration', 'estimatedTime', 'timeEstimate')
+
+# content.xml elements the parser reads, per parent element. Anything else below
+# these parents is ignored by the conversion and reported as an unknown_element finding
+KNOWN_XML_ELEMENTS = {
+    'Manual': ('name', 'description', 'defaultLanguageCode', 'dataFormat', 'sku', 'contentNodes')
+              + EXPORT_DATE_FIELDS,
+    'contentNodes': ('ContentNode',),
+    'ContentNode': ('title', 'orderIndex', 'localizations', 'children') + DURATION_FIELDS,
+    'children': ('ContentNode',),
+    'localizations': ('LocaleContent',),
+    'LocaleContent': ('languageCode', 'title', 'content', 'images') + DURATION_FIELDS,
+    'images': ('img',),
+    # Text-only fields: elements inside them are lost (findtext)
+    **{field: () for field in ('name', 'description', 'defaultLanguageCode', 'dataFormat', 'sku', 'title',
+                               'orderIndex', 'languageCode', 'content', 'img') + EXPORT_DATE_FIELDS + DURATION_FIELDS},
+}
+# Span tags in node content, to find unterminated and unbalanced ones
+SPAN_TAG_REGEX = re.compile(r'<(/?)span\b[^<>]*?(/?)(>|(?=<)|$)', re.IGNORECASE)
+
+class StrictModeError(ValueError):
+    """An export issue that --strict turns into an error, with the node path where it was found"""
 
 # ANSI color codes for terminal output
 class Colors:
@@ -44,27 +223,19 @@ class Colors:
 class ProgressLogger:
     """Enhanced logging with progress indicators"""
     
-    def __init__(self, verbose: bool = False):
+    def __init__(self, verbose: bool = False, quiet: bool = False,
+                 log_dir: str = DEFAULT_LOG_DIR, log_keep: int = DEFAULT_LOG_KEEP):
         self.verbose = verbose
+        self.quiet = quiet  # --quiet: console shows errors only (stdout is silenced by main)
+        self.log_dir = Path(log_dir)
+        self.log_keep = log_keep
         self.setup_logging()
-        self.start_time = time.time()
-        self.total_manuals = 0
-        self.total_chapters = 0
-        self.total_articles = 0
-        self.total_images = 0
-        self.current_manual = 0
-        self.current_chapter = 0
-        self.current_article = 0
-        self.processed_articles = 0
-        self.processed_images = 0
+        self.tracker = ProgressTracker()  # Thread-safe progress counters
+        self.warnings = []  # Warning messages of this run (for the summary report)
     
     def setup_logging(self):
         """Configure logging with file and console handlers"""
-        log_dir = Path("logs")
-        log_dir.mkdir(exist_ok=True)
-        
-        timestamp = datetime.now().strftime("%Y%m%d_%H%M%S")
-        log_file = log_dir / f"vlp_converter_{timestamp}.log"
+        log_file = new_log_file(self.log_dir, "vlp_converter", self.log_keep)
         
         # File handler - detailed logs
         file_handler = logging.FileHandler(log_file)
@@ -77,7 +248,10 @@ class ProgressLogger:
         
         # Console handler - user-friendly output
         console_handler = logging.StreamHandler()
-        console_handler.setLevel(logging.INFO if not self.verbose else logging.DEBUG)
+        if self.quiet:
+            console_handler.setLevel(logging.ERROR)
+        else:
+            console_handler.setLevel(logging.INFO if not self.verbose else logging.DEBUG)
         console_formatter = logging.Formatter('%(message)s')
         console_handler.setFormatter(console_formatter)
         
@@ -111,6 +285,7 @@ class ProgressLogger:
         """Print a warning message"""
         print(f"{Colors.WARNING}⚠ {message}{Colors.ENDC}")
         logging.warning(message)
+        self.warnings.append(message)
     
     def error(self, message: str):
         """Print an error message"""
@@ -131,50 +306,19 @@ class ProgressLogger:
     
     def set_totals(self, manuals: int = 1, chapters: int = 0, articles: int = 0, images: int = 0):
         """Set total counts for progress tracking"""
-        self.total_manuals = manuals
-        self.total_chapters = chapters
-        self.total_articles = articles
-        self.total_images = images
+        self.tracker.set_totals(manuals=manuals, chapters=chapters, articles=articles, images=images)
     
     def get_progress_string(self) -> str:
         """Generate progress percentage string"""
-        manual_pct = (self.current_manual / self.total_manuals * 100) if self.total_manuals > 0 else 0
-        chapter_pct = (self.current_chapter / self.total_chapters * 100) if self.total_chapters > 0 else 0
-        article_pct = (self.current_article / self.total_articles * 100) if self.total_articles > 0 else 0
-        return f"[ Manual: {manual_pct:.0f}%, Chapter: {chapter_pct:.0f}%, Article: {article_pct:.0f}% ]"
+        return self.tracker.progress_string()
     
     def estimate_time_remaining(self) -> str:
         """Estimate remaining time based on progress"""
-        if self.processed_articles == 0:
-            return "Calculating..."
-        
-        elapsed = time.time() - self.start_time
-        
-        # Use weighted formula: articles are primary driver, images add time
-        # Based on user data: ~10-15 seconds per article + ~2 seconds per image
-        avg_time_per_article = 12.5  # seconds
-        avg_time_per_image = 2.0     # seconds
-        
-        # Calculate estimated total time
-        estimated_total = (self.total_articles * avg_time_per_article) + (self.total_images * avg_time_per_image)
-        
-        # Calculate progress-based estimate
-        articles_remaining = self.total_articles - self.processed_articles
-        images_remaining = self.total_images - self.processed_images
-        
-        estimated_remaining = (articles_remaining * avg_time_per_article) + (images_remaining * avg_time_per_image)
-        
-        # Format time
-        minutes, seconds = divmod(int(estimated_remaining), 60)
-        if minutes > 0:
-            return f"~{minutes}m {seconds}s"
-        else:
-            return f"~{seconds}s"
+        return self.tracker.estimate_time_remaining()
     
     def progress(self, message: str):
         """Print a progress message with percentages and time estimate"""
-        progress_str = self.get_progress_string()
-        time_est = self.estimate_time_remaining()
+        progress_str, time_est = self.tracker.status()
         print(f"{Colors.OKBLUE}{progress_str} {message} {Colors.OKCYAN}[ETA: {time_est}]{Colors.ENDC}")
         logging.info(f"{progress_str} {message} [ETA: {time_est}]")
 
@@ -183,6 +327,14 @@ def generate_uuid():
     """Generate a UUID v4 for content blocks"""
     return str(uuid.uuid4()).upper()
 
+def stable_id(*parts) -> str:
+    """Derive a stable, filesystem-safe ID (UUID v5) from VLP identifiers
+    
+    The same VLP manual/node IDs always produce the same ID, so output file
+    names, image directories and upload mapping records survive re-conversion.
+    """
+    return str(uuid.uuid5(VLP2SS_ID_NAMESPACE, '/'.join(str(p) for p in parts)))
+
 def slugify(text):
     """Convert text to URL-friendly slug"""
     text = text.lower()
@@ -190,6 +342,48 @@ def slugify(text):
     text = re.sub(r'[-\s]+', '-', text)
     return text.strip('-')
 
+def parse_duration_minutes(value) -> Optional[int]:
+    """Parse a VLP duration value into minutes
+    
+    Accepts plain minutes ("30"), clock format ("01:30" or "00:30:00"),
+    ISO 8601 durations ("PT1H30M") and text ("30 minutes", "1 hour").
+    """
+    if value is None:
+        return None
+    value = str(value).strip()
+    if not value:
+        return None
+    
+    if value.isdigit():
+        return int(value)
+    
+    clock = re.fullmatch(r'(\d+):(\d{2})(?::(\d{2}))?', value)
+    if clock:
+        if clock.group(3) is not None:
+            return int(clock.group(1)) * 60 + int(clock.group(2)) + round(int(clock.group(3)) / 60)
+        return int(clock.group(1)) * 60 + int(clock.group(2))
+    
+    iso = re.fullmatch(r'P(?:T)?(?:(\d+)H)?(?:(\d+)M)?(?:(\d+)S)?', value, re.IGNORECASE)
+    if iso and any(iso.groups()):
+        hours, minutes, seconds = (int(g or 0) for g in iso.groups())
+        return hours * 60 + minutes + round(seconds / 60)
+    
+    text = re.findall(r'(\d+)\s*(h|hr|hour|m|min|minute)', value, re.IGNORECASE)
+    if text:
+        return sum(int(n) * (60 if unit.lower().startswith('h') else 1) for n, unit in text)
+    
+    return None
+
+def format_duration(minutes: int) -> str:
+    """Format minutes as readable text, e.g. '1 hour 30 minutes'"""
+    hours, mins = divmod(minutes, 60)
+    parts = []
+    if hours:
+        parts.append(f"{hours} hour{'s' if hours != 1 else ''}")
+    if mins or not hours:
+        parts.append(f"{mins} minute{'s' if mins != 1 else ''}")
+    return ' '.join(parts)
+
 def extract_images_from_html(html_content):
     """Extract image references from HTML"""
     if not html_content:
@@ -260,39 +454,179 @@ def remove_style_divs(html_content):
 class VLPParser:
     """Parser for VLP XML content"""
     
-    def __init__(self, logger: ProgressLogger):
+    def __init__(self, logger: ProgressLogger, options: Optional[Dict] = None):
         self.logger = logger
         self.verbose = logger.verbose  # Enable verbose logging for debugging
+        self.options = options or {}
+        self._manual_key = ''
+        self._issued_ids = set()
+        self._language = None  # Language being converted (parse_xml), None for the first LocaleContent
+        self.default_language = 'en'
+        self.iframe_sources = []  # Every iframe encountered and what became of it (summary.json)
+        self.chapter_merges = []  # Single-article chapters folded or renamed (summary.json)
+        self.filtered = []  # Chapters and articles left out by --include-*/--exclude-* (summary.json)
+        self.code_blocks = 0  # <pre> sections and command paragraphs turned into code blocks
+        self.copy_widgets = 0  # VLP copy-text widgets turned into code blocks or inline code
+        self.keyboard_keys = 0  # Key spans and key images turned into <kbd> tags
+        self.unresolved_links = []  # Links that look like lesson references but match no node (summary.json)
+        self.missing_alt_text = []  # Images left without alt text (summary.json, --missing-alt)
+        self.image_links = 0  # Linked screenshots whose link was moved onto the image
+        self.media_embeds = []  # Every media tag/video encountered and what became of it (summary.json)
+        self.credentials_tables = 0  # Lab credentials tables turned into Lab Credentials blocks
+        self.widgets = []  # Knowledge-check and checklist widgets and what became of them (summary.json)
+        self.nested_steps = 0  # Nodes below the step level, kept as steps after their parent step
+        self.transformed_steps = 0  # Steps whose HTML a --transform hook changed
+        self.intro_node = None  # What became of a childless first top-level node (summary.json)
+        self.findings = []  # Export issues the conversion works around, with node paths (summary.json, --strict)
+        self.schema_version = None  # content.xml schema of the last parse (see vlp2ss_formats)
+        self.module_export = False  # Whether the last parse read a single module instead of a manual
+        self._finding_keys = {}
+
+    def _finding(self, code: str, node_path: str, message: str, key: Optional[Tuple] = None):
+        """Record an export issue, or raise StrictModeError for it with --strict
+
+        Findings with the same key (e.g. one unknown element under every node)
+        are recorded once, at their first node path, and counted.
+        """
+        if self.options.get('strict'):
+            raise StrictModeError(f"{message} at {node_path} (--strict)")
+        if key is not None and key in self._finding_keys:
+            self._finding_keys[key]['occurrences'] += 1
+            return
+        finding = {'code': code, 'node_path': node_path, 'message': message, 'occurrences': 1}
+        self.findings.append(finding)
+        if key is not None:
+            self._finding_keys[key] = finding
+        self.logger.info(f"Finding {code} at {node_path}: {message}")
+
+    def _make_id(self, kind: str, *parts) -> str:
+        """Stable ID for a chapter/article/step, namespaced by manual and kind
+        
+        VLP exports occasionally repeat node IDs (copied lessons); repeats get a
+        deterministic suffix based on traversal order so IDs never collide.
+        """
+        base = stable_id(self._manual_key, kind, *parts)
+        node_id = base
+        repeat = 1
+        while node_id in self._issued_ids:
+            repeat += 1
+            node_id = stable_id(self._manual_key, kind, *parts, repeat)
+        if node_id != base:
+            self.logger.warning(f"Duplicate VLP {kind} ID {'/'.join(map(str, parts))}, assigned {node_id}")
+        self._issued_ids.add(node_id)
+        return node_id
     
-    def parse_xml(self, xml_path: Path) -> Dict:
-        """Parse VLP content.xml file"""
-        self.logger.info(f"Parsing VLP XML: {xml_path}")
+    def parse_xml(self, xml_path: Path, language: Optional[str] = None) -> Dict:
+        """Parse VLP content.xml file
+        
+        Each node's text comes from its LocaleContent for language; nodes
+        without one fall back to the default language, then to their first
+        LocaleContent. Without a language the first LocaleContent is used.
+        
+        Elements the conversion ignores, missing localizations, unbalanced
+        spans and images without a filename are recorded in self.findings
+        with their node path (an XPath such as /Manual/contentNodes/
+        ContentNode[@id="m1"]/localizations/LocaleContent[2]), or raise
+        StrictModeError with --strict.
+        
+        The file is streamed: each ContentNode is parsed when its end tag is
+        read and then dropped from the tree, so memory stays bounded by the
+        largest node instead of the whole export. The manual's own fields
+        (name, defaultLanguageCode, ...) are kept. With a language, the
+        default language is looked up first (see find_default_language), so
+        the fallback works wherever defaultLanguageCode is in the file.
+        """
+        self.logger.info(f"Parsing VLP XML: {getattr(xml_path, 'name', xml_path)}"
+                         + (f" (language: {language})" if language else ""))
         
         try:
-            tree = ET.parse(xml_path)
-            root = tree.getroot()
+            self._language = language
+            self.default_language = 'en'
+            if language:
+                self.default_language = self.find_default_language(xml_path)
+                if hasattr(xml_path, 'seek'):
+                    xml_path.seek(0)  # A file object (content.xml read from the ZIP in place)
+            self.findings = []
+            self._finding_keys = {}
+            root = None
+            path = []  # Tags of the open elements, root first
+            steps = []  # Node path steps of the open elements
+            counts = [{}]  # Per open element: how many children of each tag it has so far
+            siblings = []  # Per open ContentNode: its parsed children, or None for a node outside the structure
+            chapters = []
+            schema = SchemaStream()
+            for event, element in ET.iterparse(xml_path, events=('start', 'end')):
+                if event == 'start':
+                    tag = element.tag  # As in the file, for node paths
+                    schema.start(element, len(path))
+                    if root is None:
+                        root = element
+                        if schema.module:
+                            siblings.append([])
+                    elif len(path) == 1 and element.tag == 'contentNodes':
+                        path[0] = root.tag  # Renamed with the schema's manual fields
+                        self.default_language = root.findtext('defaultLanguageCode') or 'en'
+                    elif element.tag == 'ContentNode':
+                        top_level = path[1:] == ['contentNodes']
+                        nested = path[-1] == 'children' and path[-2] == 'ContentNode' and siblings[-1] is not None
+                        siblings.append([] if top_level or nested else None)
+                    counts[-1][tag] = index = counts[-1].get(tag, 0) + 1
+                    counts.append({})
+                    steps.append(self._path_step(tag, element, index))
+                    if path and element.tag not in KNOWN_XML_ELEMENTS.get(path[-1], (element.tag,)):
+                        self._finding('unknown_element', '/' + '/'.join(steps),
+                                      f"<{element.tag}> in <{path[-1]}> is not converted", key=(path[-1], element.tag))
+                    path.append(element.tag)
+                    continue
+                node_path = '/' + '/'.join(steps)
+                path.pop()
+                steps.pop()
+                counts.pop()
+                schema.end(element, len(path))
+                if element.tag == 'ContentNode':
+                    children = siblings.pop()
+                    if children is not None:
+                        node_data = self._parse_content_node(element, node_path)
+                        node_data['children'] = children
+                        (siblings[-1] if siblings else chapters).append(node_data)
+                    if element is not root:
+                        element.clear()
+                elif len(path) == 1 and element.tag == 'defaultLanguageCode':
+                    self.default_language = element.text or 'en'
+            
+            self.schema_version = schema.finish()
+            if self.schema_version != '1':
+                self.logger.substep(f"Export schema {self.schema_version}: {VLP_SCHEMAS[self.schema_version]['description']}")
+            
+            self.module_export = schema.module
+            if schema.module:
+                self.logger.substep("Module-level export: converting the module as the only chapter of a manual")
             
             manual_data = {
                 'id': root.get('id'),
-                'name': root.findtext('name', ''),
-                'language': root.findtext('defaultLanguageCode', 'en'),
+                # A module export has no manual name; the manual is named after the module (in every language)
+                'name': self._apply_rules(root.findtext('title' if schema.module else 'name') or '', 'title')
+                        or (chapters[0]['title'] if schema.module else ''),
+                'language': language or (chapters[0].get('language') if schema.module else None)
+                            or root.findtext('defaultLanguageCode', 'en'),
                 'format': root.findtext('dataFormat', 'default'),
-                'chapters': []
+                'export_date': self._export_date(root, xml_path),
+                'description': self._extract_description(root.findtext('description') or ''),
+                'chapters': chapters
             }
-            
-            # Parse content nodes (chapters and articles)
-            content_nodes = root.find('contentNodes')
-            if content_nodes is not None:
-                for node in content_nodes.findall('ContentNode'):
-                    parsed_node = self._parse_content_node(node)
-                    if parsed_node:
-                        manual_data['chapters'].append(parsed_node)
+            sku_match = LAB_SKU_REGEX.search(manual_data['name'])
+            manual_data['sku'] = root.get('sku') or root.findtext('sku') or (sku_match.group(0) if sku_match else None)
             
             self.logger.success(f"Parsed manual: {manual_data['name']}")
             self.logger.substep(f"Found {len(manual_data['chapters'])} top-level sections")
+            if self.findings:
+                self.logger.substep(f"Recorded {len(self.findings)} export findings in {SUMMARY_FILE} "
+                                    f"(--strict makes them errors)")
             
             return manual_data
             
+        except (StrictModeError, SchemaVersionError):
+            raise
         except ET.ParseError as e:
             self.logger.error(f"XML parsing error: {e}")
             raise
@@ -300,94 +634,229 @@ class VLPParser:
             self.logger.error(f"Unexpected error parsing XML: {e}")
             raise
     
-    def _parse_content_node(self, node: ET.Element, level: int = 0) -> Optional[Dict]:
-        """Recursively parse content nodes (chapters/articles)"""
+    @staticmethod
+    def _path_step(tag: str, element: ET.Element, index: int) -> str:
+        """Node path step of an element (tag as in the file): ContentNodes by ID, repeated elements by position"""
+        if element.tag == 'ContentNode' and element.get('id'):
+            return f'{tag}[@id="{element.get("id")}"]'
+        if element.tag in ('ContentNode', 'LocaleContent', 'img') or index > 1:
+            return f"{tag}[{index}]"
+        return tag
+    
+    def _select_locale(self, localizations: ET.Element) -> Optional[ET.Element]:
+        """LocaleContent of the language being converted (see parse_xml)"""
+        locales = localizations.findall('LocaleContent')
+        if self._language:
+            by_code = {locale.findtext('languageCode', ''): locale for locale in locales}
+            for code in (self._language, self.default_language):
+                if code in by_code:
+                    return by_code[code]
+        return locales[0] if locales else None
+    
+    @staticmethod
+    def find_default_language(xml_path: Path) -> str:
+        """The export's defaultLanguageCode, read up to contentNodes (or past it, if it comes later)"""
+        depth = 0
+        schema = SchemaStream()
+        for event, element in ET.iterparse(xml_path, events=('start', 'end')):
+            if event == 'start':
+                schema.start(element, depth)
+                if depth == 1 and element.tag == 'contentNodes' and schema.root.findtext('defaultLanguageCode'):
+                    return schema.root.findtext('defaultLanguageCode')
+                depth += 1
+                continue
+            depth -= 1
+            schema.end(element, depth)
+            if element.tag == 'defaultLanguageCode' and depth == 1:
+                return element.text or 'en'
+            elif element.tag == 'ContentNode':
+                element.clear()  # Streamed like parse_xml
+        return 'en'
+    
+    @staticmethod
+    def list_languages(xml_path: Path) -> List[str]:
+        """Language codes of the export's LocaleContent elements, the default language first"""
+        default = 'en'
+        codes = []
+        depth = 0
+        schema = SchemaStream()
+        for event, element in ET.iterparse(xml_path, events=('start', 'end')):
+            if event == 'start':
+                schema.start(element, depth)
+                if depth == 1 and element.tag == 'contentNodes':
+                    default = schema.root.findtext('defaultLanguageCode') or default
+                depth += 1
+                continue
+            depth -= 1
+            schema.end(element, depth)
+            if element.tag == 'languageCode' and element.text and element.text.strip() not in codes:
+                codes.append(element.text.strip())
+            elif element.tag == 'defaultLanguageCode' and depth == 1:
+                default = element.text or 'en'
+            elif element.tag == 'ContentNode':
+                element.clear()  # Streamed like parse_xml
+        return [default] + [code for code in codes if code != default]
+    
+    def _export_date(self, root: ET.Element, xml_path: Path) -> Optional[str]:
+        """Export date from the manual element, else the date of content.xml (kept from the ZIP)"""
+        for name in EXPORT_DATE_FIELDS:
+            value = root.get(name) or root.findtext(name)
+            if value:
+                return value.strip()
+        if getattr(xml_path, 'date_time', None):  # Read from the ZIP in place (--in-memory)
+            return datetime(*xml_path.date_time).isoformat(timespec='seconds')
+        try:
+            return datetime.fromtimestamp(os.stat(xml_path).st_mtime).isoformat(timespec='seconds')
+        except (OSError, TypeError):
+            return None
+    
+    def _apply_rules(self, text: str, scope: str) -> str:
+        """Apply the --rules of a scope (title, image_src) to a title or image reference"""
+        rules = self.options.get('rules')
+        if not rules or not text:
+            return text
+        return (rules.apply_title(text) if scope == 'title' else rules.apply_image_src(text))[0]
+    
+    def _parse_content_node(self, node: ET.Element, node_path: str = '') -> Dict:
+        """Parse a content node (chapter/article) without its children, which parse_xml adds"""
         node_data = {
             'id': node.get('id'),
             'title': node.findtext('title', ''),
             'order': int(node.findtext('orderIndex', '0')),
             'content': '',
             'images': [],
-            'children': []
+            'children': [],
+            'duration': self._parse_node_duration(node)
         }
         
         # Parse localizations
         localizations = node.find('localizations')
-        if localizations is not None:
-            locale_content = localizations.find('LocaleContent')
-            if locale_content is not None:
-                node_data['title'] = locale_content.findtext('title', node_data['title'])
-                node_data['language'] = locale_content.findtext('languageCode', 'en')
-                node_data['content'] = locale_content.findtext('content', '')
-                
-                # Parse images
-                images = locale_content.find('images')
-                if images is not None:
-                    for img in images.findall('img'):
-                        node_data['images'].append({
-                            'src': img.get('src', ''),
-                            'filename': img.get('filename', ''),
-                            'width': img.get('width', ''),
-                            'height': img.get('height', '')
-                        })
-        
-        # Parse children recursively
-        children = node.find('children')
-        if children is not None:
-            for child_node in children.findall('ContentNode'):
-                child_data = self._parse_content_node(child_node, level + 1)
-                if child_data:
-                    node_data['children'].append(child_data)
+        locale_content = self._select_locale(localizations) if localizations is not None else None
+        if locale_content is None:
+            self._finding('no_localization', node_path, "Node has no LocaleContent; it converts without text")
+        else:
+            locales = localizations.findall('LocaleContent')
+            locale_path = f"{node_path}/localizations/LocaleContent[{locales.index(locale_content) + 1}]"
+            node_data['title'] = locale_content.findtext('title', node_data['title'])
+            node_data['language'] = locale_content.findtext('languageCode', 'en')
+            node_data['content'] = locale_content.findtext('content', '')
+            if self._language and node_data['language'] != self._language:
+                self._finding('missing_language', node_path,
+                              f"No {self._language} localization; converted from the {node_data['language']} one")
+            self._check_spans(node_data['content'], f"{locale_path}/content")
+
+            # Parse images
+            images = locale_content.find('images')
+            if images is not None:
+                for index, img in enumerate(images.findall('img'), 1):
+                    if not img.get('filename'):
+                        self._finding('image_without_filename', f"{locale_path}/images/img[{index}]",
+                                      f"Image {img.get('src') or '(no src)'} has no filename; it is not copied")
+                    node_data['images'].append({
+                        'src': self._apply_rules(img.get('src', ''), 'image_src'),
+                        'filename': self._apply_rules(img.get('filename', ''), 'image_src'),
+                        'width': img.get('width', ''),
+                        'height': img.get('height', '')
+                    })
+
+        node_data['title'] = self._apply_rules(node_data['title'], 'title')
         
         return node_data
     
+    def _check_spans(self, content: str, node_path: str):
+        """Record an unterminated <span tag, or span tags that do not balance
+        
+        The HTML parser closes such spans wherever the markup ends, so their
+        formatting can spread over (or drop from) the rest of the step.
+        """
+        opened = closed = 0
+        for match in SPAN_TAG_REGEX.finditer(content or ''):
+            if match.group(3) != '>':
+                self._finding('unparseable_span', node_path,
+                              f"Unterminated span tag {content[match.start():match.start() + 40]!r}")
+                return
+            if match.group(1):
+                closed += 1
+            elif not match.group(2):
+                opened += 1
+        if opened != closed:
+            self._finding('unparseable_span', node_path, f"{opened} <span> tags but {closed} </span> tags")
+    
+    def _parse_node_duration(self, node: ET.Element) -> Optional[int]:
+        """Read the estimated duration (in minutes) of a module/lesson node, if any"""
+        locale_content = node.find('localizations/LocaleContent')
+        for element in (node, locale_content):
+            if element is None:
+                continue
+            for name in DURATION_FIELDS:
+                raw = element.get(name) or element.findtext(name)
+                minutes = parse_duration_minutes(raw)
+                if minutes:
+                    return minutes
+                if raw:
+                    self.logger.warning(f"Unrecognized duration '{raw}' on node {node.get('id')}")
+        return None
+    
     def flatten_structure(self, manual_data: Dict) -> List[Dict]:
         """
         Flatten VLP hierarchical structure into ScreenSteps format:
         - Level 1 nodes become chapters
         - Level 2 nodes become articles  
         - Level 3 nodes become steps (content_blocks) within articles
+        - Deeper nodes become steps following their parent step
+        
+        --chapter-depth/--article-depth move the chapter and article levels
+        (see _map_hierarchy); steps are always the level below articles.
         """
+        chapter_nodes = self._map_hierarchy(manual_data['chapters'])
+        
         # First pass: count totals for progress tracking
-        total_chapters = len(manual_data['chapters'])
+        total_chapters = len(chapter_nodes)
         total_articles = 0
         total_images = 0
         
-        for chapter_node in manual_data['chapters']:
+        for chapter_node in chapter_nodes:
             if chapter_node.get('children'):
                 total_articles += len(chapter_node['children'])
                 for article_node in chapter_node['children']:
                     # Count images in article
                     total_images += len(article_node.get('images', []))
                     # Count images in steps
-                    if article_node.get('children'):
-                        for step_node in article_node['children']:
-                            total_images += len(step_node.get('images', []))
+                    for step_node in self._step_nodes(article_node.get('children', [])):
+                        total_images += len(step_node.get('images', []))
+        
+        self.nested_steps = 0  # Counted again while building the steps
         
         # Set totals for progress tracking
         self.logger.set_totals(manuals=1, chapters=total_chapters, articles=total_articles, images=total_images)
-        self.logger.current_manual = 1
+        self.logger.tracker.enter('manual', 1)
+        
+        self._manual_key = manual_data.get('id') or manual_data.get('name', '')
+        self._issued_ids = set()
         
         chapters = []
         
-        for chapter_idx, chapter_node in enumerate(manual_data['chapters'], 1):
-            self.logger.current_chapter = chapter_idx
+        for chapter_idx, chapter_node in enumerate(chapter_nodes, 1):
+            self.logger.tracker.enter('chapter', chapter_idx)
             
             chapter_title = chapter_node['title']
-            chapter_desc = self._clean_html(chapter_node['content'])
+            chapter_content = self._clean_html(chapter_node['content'])
 
             # Handle missing chapter titles (often Copyright page)
             if not chapter_title:
-                if "Copyright" in chapter_desc:
+                if "Copyright" in chapter_content:
                     chapter_title = "Copyright"
                 else:
                     chapter_title = "Unknown Title"
 
+            chapter_key = chapter_node['id'] or f"chapter-{chapter_idx}"
             chapter = {
-                'id': chapter_node['id'],
+                'id': self._make_id('chapter', chapter_key),
+                'vlp_id': chapter_node['id'],
                 'title': chapter_title,
                 'order': chapter_node['order'],
-                'description': chapter_desc,
+                'description': self._extract_description(chapter_content),
+                'duration_minutes': chapter_node.get('duration'),
                 'articles': []
             }
             
@@ -395,30 +864,32 @@ class VLPParser:
             current_position = 1
             
             # If chapter has description content, create it as the first article
-            if chapter['description']:
+            if chapter_content:
                 self.logger.info(f"Creating article from description for chapter: {chapter['title']}")
                 
                 # Create a step with the description content
                 step = {
-                    'id': generate_uuid(),
+                    'id': self._make_id('step', chapter_key, 'description'),
                     'title': chapter['title'],
                     'order': 0,
-                    'content': chapter['description'],
+                    'content': chapter_content,
                     'images': chapter_node.get('images', [])
                 }
                 
                 # Create the article
                 desc_article = {
-                    'id': generate_uuid(),
+                    'id': self._make_id('article', chapter_key, 'description'),
+                    'vlp_id': chapter_node['id'],
                     'title': chapter['title'],
+                    'description': chapter['description'],
                     'vlp_order': 0,  # Place at start
                     'position': current_position,
                     'steps': [step]
                 }
                 
                 chapter['articles'].append(desc_article)
-                self.logger.processed_articles += 1
-                self.logger.processed_images += len(chapter_node.get('images', []))
+                self.logger.tracker.advance('articles')
+                self.logger.tracker.advance('images', len(chapter_node.get('images', [])))
                 current_position += 1
             
             # Process level 2 children as articles
@@ -430,7 +901,7 @@ class VLPParser:
                     position = current_position
                     current_position += 1
                     
-                    self.logger.current_article += 1
+                    self.logger.tracker.enter('article')
                     
                     article_title = article_node['title']
                     
@@ -444,11 +915,14 @@ class VLPParser:
 
                     self.logger.progress(f"Processing article: {article_title}")
                     
+                    article_key = article_node['id'] or f"{chapter_key}-article-{position}"
                     article = {
-                        'id': article_node['id'],
+                        'id': self._make_id('article', article_key),
+                        'vlp_id': article_node['id'],
                         'title': article_title,
-                        'vlp_order': article_node['order'],  # Keep VLP order for reference
+                        'vlp_order': article_node.get('vlp_order', article_node['order']),  # Keep VLP order for reference
                         'position': position,  # Sequential position for ScreenSteps
+                        'duration_minutes': article_node.get('duration'),
                         'steps': []  # Store level 3 as steps
                     }
                     
@@ -458,22 +932,21 @@ class VLPParser:
                     if article_content:
                         # Create a step for the article's own content
                         intro_step = {
-                            'id': generate_uuid(),
+                            'id': self._make_id('step', article_key, 'intro'),
                             'title': article['title'],
                             'order': -1, # Ensure it comes first
                             'content': article_content,
                             'images': article_node.get('images', [])
                         }
                         article['steps'].append(intro_step)
-                        self.logger.processed_images += len(article_node.get('images', []))
+                        self.logger.tracker.advance('images', len(article_node.get('images', [])))
                     
-                    # Process level 3 children as steps
+                    # Process level 3 children (and anything nested below them) as steps
                     if article_node.get('children'):
-                        # Sort steps by VLP order
-                        sorted_steps = sorted(article_node['children'], key=lambda x: x['order'])
-                        for step_node in sorted_steps:
+                        for step_idx, step_node in enumerate(self._step_nodes(article_node['children']), 1):
                             step = {
-                                'id': step_node['id'],
+                                'id': self._make_id('step', step_node['id'] or f"{article_key}-step-{step_idx}"),
+                                'vlp_id': step_node['id'],
                                 'title': step_node['title'],
                                 'order': step_node['order'],
                                 'content': self._clean_html(step_node['content']),
@@ -481,17 +954,358 @@ class VLPParser:
                             }
                             article['steps'].append(step)
                             # Count processed images
-                            self.logger.processed_images += len(step_node.get('images', []))
+                            self.logger.tracker.advance('images', len(step_node.get('images', [])))
                     # Removed else block that only processed content if no children existed
                     # Content is now handled before children processing
                     
+                    # Describe the article by its own content, else by its first step
+                    for step in article['steps']:
+                        article['description'] = self._extract_description(step['content'])
+                        if article['description']:
+                            break
+                    
                     chapter['articles'].append(article)
-                    self.logger.processed_articles += 1
+                    self.logger.tracker.advance('articles')
             
             chapters.append(chapter)
         
+        if self.nested_steps:
+            self.logger.substep(f"Kept {self.nested_steps} nodes below the step level as steps")
+        
+        self.intro_node = None
+        if chapter_nodes and not chapter_nodes[0].get('children'):
+            chapters = self._place_intro_node(chapters)
+        
+        # Before the passes that link articles, so links into left-out content are reported
+        self.filtered = []
+        content_filter = self.options.get('content_filter')
+        if content_filter:
+            chapters, self.filtered = content_filter.apply(chapters)
+            self.logger.substep(f"Filters left out {sum(1 for f in self.filtered if f['type'] == 'chapter')} chapters "
+                                f"and {sum(1 for f in self.filtered if f['type'] == 'article')} articles")
+            if not chapters:
+                self.logger.warning("The --include/--exclude filters left no chapters to convert")
+        
+        if self.options.get('duration_template', DEFAULT_DURATION_TEMPLATE):
+            self._add_duration_blocks(chapters)
+        
+        if self.options.get('append_orphan_images'):
+            self._append_orphan_images(chapters)
+        
+        if self.options.get('stable_anchors'):
+            self._assign_stable_anchors(chapters)
+        
+        self._check_alt_text(chapters)
+        
+        self._convert_internal_links(chapters)
+        
+        if self.options.get('merge_singleton_chapters'):
+            chapters = self._merge_singleton_chapters(chapters)
+        
+        # After merging, so the single-article credentials chapter stays on its own
+        if self.options.get('credentials_block') == 'article':
+            self._externalize_credentials(chapters)
+        
+        # Last structural change, so it lists the chapters and articles as uploaded
+        if self.options.get('overview_article'):
+            self._add_overview_article(chapters)
+        
+        if self.options.get('transforms'):
+            self._apply_transforms(chapters, manual_data.get('name', ''))
+        
+        return chapters
+    
+    def _place_intro_node(self, chapters: List[Dict]) -> List[Dict]:
+        """Handle a first top-level node without children (a lab intro or copyright page) per --intro-node
+        
+        keep leaves it a chapter of its own (its content as the chapter's only
+        article), article moves that article to the top of the next chapter,
+        and skip leaves the node out. The choice is logged and recorded.
+        """
+        mode = self.options.get('intro_node', 'keep')
+        intro = chapters[0]
+        self.intro_node = {'title': intro['title'], 'vlp_id': intro['vlp_id'], 'action': mode}
+        reason = "first top-level node has no children"
+        if mode == 'article' and len(chapters) == 1:
+            self.intro_node['action'] = 'keep'
+            self.logger.warning(f"Kept intro node '{intro['title']}' as a chapter ({reason}): "
+                                f"there is no next chapter to move it into")
+            return chapters
+        if mode == 'keep':
+            self.logger.info(f"Kept intro node '{intro['title']}' as a chapter ({reason}; "
+                             f"--intro-node article or skip changes this)")
+            return chapters
+        if mode == 'skip':
+            self.logger.warning(f"Skipped intro node '{intro['title']}' ({reason}, --intro-node skip)")
+            return chapters[1:]
+        target = chapters[1]
+        target['articles'] = intro['articles'] + target['articles']
+        for position, article in enumerate(target['articles'], 1):
+            article['position'] = position
+        self.intro_node['into'] = target['title']
+        self.logger.info(f"Moved intro node '{intro['title']}' into chapter '{target['title']}' as its first article "
+                         f"({reason}, --intro-node article)")
+        return chapters[1:]
+    
+    def _map_hierarchy(self, nodes: List[Dict]) -> List[Dict]:
+        """Reshape the VLP tree so chapters, articles and steps are its first three levels
+        
+        Levels above --chapter-depth (and between it and --article-depth) are
+        dissolved: their children move up in order, and a dissolved node with
+        content of its own stays as a chapter (or article) holding just that
+        content. With the article depth equal to the chapter depth, every
+        chapter node also becomes the single article of its chapter. Moved
+        articles are renumbered in order; their VLP order is kept as vlp_order.
+        """
+        chapter_depth = self.options.get('chapter_depth', 1)
+        article_depth = self.options.get('article_depth') or chapter_depth + 1
+        if (chapter_depth, article_depth) == (1, 2):
+            return nodes
+        
+        def lift(level_nodes: List[Dict], levels: int) -> List[Dict]:
+            """Descendants levels below level_nodes, in order, plus the dissolved nodes with content"""
+            if levels == 0:
+                return level_nodes
+            lifted = []
+            for node in sorted(level_nodes, key=lambda x: x['order']):
+                if node['content'].strip():
+                    lifted.append({**node, 'children': []})
+                lifted.extend(lift(sorted(node['children'], key=lambda x: x['order']), levels - 1))
+            return lifted
+        
+        chapters = []
+        for chapter_node in lift(nodes, chapter_depth - 1):
+            if article_depth == chapter_depth:
+                articles = [chapter_node]
+                chapter_node = {**chapter_node, 'content': '', 'images': [], 'duration': None}
+            else:
+                articles = lift(sorted(chapter_node['children'], key=lambda x: x['order']),
+                                article_depth - chapter_depth - 1)
+            children = [{**article, 'order': position, 'vlp_order': article['order']}
+                        for position, article in enumerate(articles, 1)]
+            chapters.append({**chapter_node, 'children': children})
+        self.logger.substep(f"Mapped levels {chapter_depth}/{article_depth}/{article_depth + 1} to "
+                            f"chapters/articles/steps: {len(chapters)} chapters")
         return chapters
     
+    def _step_nodes(self, nodes: List[Dict]):
+        """Step nodes by VLP order, each followed by the nodes nested below it"""
+        for node in sorted(nodes, key=lambda x: x['order']):
+            yield node
+            if node.get('children'):
+                self.nested_steps += len(node['children'])
+                yield from self._step_nodes(node['children'])
+    
+    def _merge_singleton_chapters(self, chapters: List[Dict]) -> List[Dict]:
+        """Fold chapters holding exactly one article into a parent chapter
+        
+        With a 'singleton_parent' title the article moves into the chapter of
+        that title (created at the position of the first merged chapter if the
+        manual has none); without one the chapter is kept but renamed after its
+        article. Runs after link conversion, which refers to article IDs only.
+        """
+        parent_title = self.options.get('singleton_parent')
+        parent = None
+        if parent_title:
+            parent = next((c for c in chapters if c['title'].strip().lower() == parent_title.strip().lower()), None)
+        
+        merged = []
+        for chapter in chapters:
+            if chapter is parent or len(chapter['articles']) != 1:
+                merged.append(chapter)
+                continue
+            article = chapter['articles'][0]
+            if not parent_title:
+                if chapter['title'] != article['title']:
+                    self.chapter_merges.append({'chapter': chapter['title'], 'article': article['title'],
+                                                'action': 'renamed', 'into': article['title']})
+                    self.logger.info(f"Renamed single-article chapter '{chapter['title']}' to '{article['title']}'")
+                    chapter['title'] = article['title']
+                merged.append(chapter)
+                continue
+            if parent is None:
+                parent = {
+                    'id': self._make_id('chapter', 'merged', parent_title),
+                    'vlp_id': None,
+                    'title': parent_title,
+                    'order': chapter['order'],
+                    'description': '',
+                    'duration_minutes': None,
+                    'articles': []
+                }
+                merged.append(parent)
+            parent['articles'].append(article)
+            self.chapter_merges.append({'chapter': chapter['title'], 'article': article['title'],
+                                        'action': 'merged', 'into': parent_title})
+            self.logger.info(f"Merged single-article chapter '{chapter['title']}' into '{parent_title}'")
+        
+        if parent is not None:
+            for position, article in enumerate(parent['articles'], 1):
+                article['position'] = position
+        if self.chapter_merges:
+            self.logger.substep(f"Merged or renamed {len(self.chapter_merges)} single-article chapters")
+        return merged
+    
+    def _add_duration_blocks(self, chapters: List[Dict]) -> None:
+        """Prepend 'Estimated time' introduction blocks for timed chapters/articles
+        
+        An article's estimate opens its first step; a chapter's estimate opens
+        the first article of the chapter.
+        """
+        template = self.options.get('duration_template', DEFAULT_DURATION_TEMPLATE)
+        
+        def prepend(article: Dict, minutes: int, title: str):
+            if not article['steps']:
+                return
+            text = template.format(duration=format_duration(minutes), minutes=minutes, title=title)
+            block = (f'<div class="screensteps-styled-block" data-style="introduction">'
+                     f'<p>{escape(text)}</p></div>')
+            article['steps'][0]['content'] = block + article['steps'][0]['content']
+        
+        for chapter in chapters:
+            for article in chapter['articles']:
+                if article.get('duration_minutes'):
+                    prepend(article, article['duration_minutes'], article['title'])
+            if chapter.get('duration_minutes') and chapter['articles']:
+                prepend(chapter['articles'][0], chapter['duration_minutes'], chapter['title'])
+    
+    def _append_orphan_images(self, chapters: List[Dict]) -> None:
+        """Append XML-listed images that the step HTML never references
+        
+        The LocaleContent images list sometimes contains screenshots that are not
+        used in the content; they would be copied but never uploaded. Each orphan
+        is appended to the end of its step as an image with a generated caption.
+        """
+        caption_template = self.options.get('orphan_caption', 'Additional screenshot: {name}')
+        appended = 0
+        
+        for chapter in chapters:
+            for article in chapter['articles']:
+                for step in article['steps']:
+                    referenced = {img['filename'] for img in extract_images_from_html(step['content'])}
+                    for img_info in step.get('images', []):
+                        filename = img_info.get('filename', '')
+                        if not filename or filename in referenced:
+                            continue
+                        
+                        caption = caption_template.format(name=Path(filename).stem, filename=filename,
+                                                          step=step['title'], article=article['title'])
+                        step['content'] += (
+                            f'<p><em>{escape(caption)}</em></p>'
+                            f'<img src="images/{escape(filename)}" alt="{escape(caption)}">'
+                        )
+                        referenced.add(filename)
+                        appended += 1
+                        self.logger.substep(f"Appended orphan image {filename} to step: {step['title']}")
+        
+        if appended:
+            self.logger.info(f"Appended {appended} orphan images that were not referenced in content")
+    
+    def _check_alt_text(self, chapters: List[Dict]) -> None:
+        """Record images that still have no alt text after conversion
+        
+        They are listed in summary.json and the QA report; with --missing-alt
+        warn each one is also logged as a warning (fail additionally fails
+        the run once the output is written).
+        """
+        for chapter in chapters:
+            for article in chapter['articles']:
+                for step in article['steps']:
+                    for img_tag in re.findall(r'<img\b[^>]*>', step['content'] or ''):
+                        alt = re.search(r'\balt="([^"]*)"', img_tag)
+                        if 'inline-icon' in img_tag or (alt and unescape(alt.group(1)).strip()):
+                            continue
+                        src = re.search(r'\bsrc="([^"]*)"', img_tag)
+                        name = unescape(src.group(1)).split('/')[-1].split('?')[0] if src else ''
+                        self.missing_alt_text.append({'article': article['title'], 'step': step['title'], 'file': name})
+                        if self.options.get('missing_alt', 'ignore') != 'ignore':
+                            self.logger.warning(f"Image {name} in '{article['title']}' has no alt text")
+        if self.missing_alt_text:
+            self.logger.info(f"{len(self.missing_alt_text)} images have no alt text")
+    
+    def _assign_stable_anchors(self, chapters: List[Dict]) -> None:
+        """Use VLP node IDs instead of slugified titles as step anchors
+        
+        Sets each step's 'anchor' (used by the uploader as the StepContent
+        anchor_name) to its VLP node ID, or the article's node ID for the
+        step holding the article's own content, so deep links like
+        .../article#<nodeID> keep working after migration and survive
+        retitling. Steps without a node ID, or whose ID would repeat within
+        the article, keep the title slug.
+        """
+        for chapter in chapters:
+            for article in chapter['articles']:
+                used = set()
+                for step in article['steps']:
+                    node_id = step.get('vlp_id') or (article.get('vlp_id') if step.get('order') == -1 else None)
+                    anchor = re.sub(r'[^\w-]+', '-', str(node_id)).strip('-') if node_id else ''
+                    if not anchor or anchor in used:
+                        anchor = slugify(step['title'] or '')
+                    step['anchor'] = anchor
+                    used.add(anchor)
+    
+    def _convert_internal_links(self, chapters: List[Dict]) -> None:
+        """Mark links to other VLP nodes/steps for resolution at upload time
+        
+        VLP links to another lesson as nodeID, or to a step within it as
+        nodeID#stepID (optionally prefixed with # or ./). Such links are
+        rewritten to the step anchor ScreenSteps generates from the step title
+        plus a data-ss-article attribute naming the converted article; the
+        uploader replaces them with the real article URL once it exists.
+        Links that look like node references but match no node are left
+        alone and reported as unresolved.
+        """
+        # VLP node ID -> (converted article ID, step anchor or None)
+        targets = {}
+        for chapter in chapters:
+            for article in chapter['articles']:
+                if article.get('vlp_id'):
+                    targets.setdefault(article['vlp_id'], (article['id'], None))
+                for step in article['steps']:
+                    if step.get('vlp_id'):
+                        targets.setdefault(step['vlp_id'], (article['id'], step.get('anchor') or slugify(step['title'] or '')))
+            if chapter.get('vlp_id') and chapter['articles']:
+                targets.setdefault(chapter['vlp_id'], (chapter['articles'][0]['id'], None))
+        
+        def resolve(href: str) -> Optional[Tuple[str, Optional[str]]]:
+            if re.match(r'^[a-z][a-z0-9+.-]*:|^//', href, re.IGNORECASE):
+                return None  # External, mailto:, etc.
+            target = href[2:] if href.startswith('./') else href
+            parts = [p for p in target.split('#') if p]
+            if not parts or parts[0] not in targets:
+                # A bare word (no dots or slashes) outside the page is a reference to a missing node
+                if parts and not href.startswith('#') and re.fullmatch(r'[\w-]+', parts[0]):
+                    raise KeyError(parts[0])
+                return None
+            if len(parts) > 1 and parts[1] in targets:
+                return targets[parts[1]]
+            return targets[parts[0]]
+        
+        converted = 0
+        
+        def rewrite(match):
+            nonlocal converted
+            href = unescape(match.group(2))
+            try:
+                target = resolve(href)
+            except KeyError:
+                self.unresolved_links.append({'article': article['title'], 'step': step['title'], 'href': href})
+                self.logger.warning(f"Link '{href}' in '{article['title']}' matches no lesson or step")
+                return match.group(0)
+            if not target:
+                return match.group(0)
+            converted += 1
+            article_id, anchor = target
+            return f'{match.group(1)}#{anchor or ""}" data-ss-article="{article_id}"'
+        
+        for chapter in chapters:
+            for article in chapter['articles']:
+                for step in article['steps']:
+                    step['content'] = re.sub(r'(<a\b[^>]*?\bhref=")([^"]*)"', rewrite, step['content'])
+        
+        if converted:
+            self.logger.info(f"Converted {converted} links to other lessons/steps")
+    
     def _node_to_article(self, node: Dict, parent: Dict) -> Dict:
         """Convert a VLP node to a ScreenSteps article"""
         return {
@@ -519,6 +1333,11 @@ class VLPParser:
         # Fix image paths - remove ./ prefix
         html = re.sub(r'src=["\']\./', 'src="', html)
 
+        # User-defined find/replace rules (--rules)
+        rules = self.options.get('rules')
+        if rules:
+            html, _ = rules.apply(html)
+
         return html.strip()
     
     def _convert_vlp_formatting(self, html: str) -> str:
@@ -539,8 +1358,35 @@ class VLPParser:
             soup = BeautifulSoup(html, 'html.parser')
             # Use BeautifulSoup to parse and transform the HTML
             
-            # Convert YouTube embeds first (before other transformations)
-            self._convert_youtube_embeds(soup)
+            # Convert media tags and videos first (before other transformations)
+            self._convert_media_embeds(soup)
+            
+            # Keep allow-listed iframes as embeds, turn the rest into links
+            self._convert_iframes(soup)
+            
+            # Apply the ScreenSteps link conventions to external links
+            self._apply_link_policy(soup)
+            
+            # Replace font-icon spans before empty spans get stripped
+            self._convert_font_icons(soup)
+            
+            # Key spans and images become <kbd>, also before their (often empty) spans get stripped
+            self._convert_keyboard_keys(soup)
+            
+            # Rebuild knowledge checks and checklists before their inputs and labels run together
+            if self.options.get('widgets', 'convert') != 'keep':
+                self._convert_widgets(soup)
+            
+            # Keep copy-text widgets copyable before their wrappers are unwrapped into plain paragraphs
+            self._convert_copy_widgets(soup)
+            
+            # Move screenshot callouts onto their image before the layout is flattened into text
+            for error in collect_annotations(soup)[1]:
+                self.logger.warning(f"Screenshot callouts left out: {error}")
+            
+            # Linearize columns, positioned boxes and floats into reading order
+            if self.options.get('flatten_layout', True):
+                self._flatten_layout(soup)
             
             # STEP 1: Pre-process - Identify link classes
             # These classes should NEVER be converted to bold
@@ -557,10 +1403,8 @@ class VLPParser:
                 link_count = len(link_classes)
                 self.logger.substep(f"Processing {total_spans} spans, found {link_count} link classes")
             
-            # Explicit code class mapping (relatively consistent)
-            code_class = 'c6'
-            # Explicit bold class mapping (for this specific manual and potentially others)
-            explicit_bold_classes = {'c2', 'c11'}
+            # Explicit class mapping (c6 = code, c2/c11 = bold for the default export theme)
+            span_class_map = self.options.get('span_class_map', DEFAULT_SPAN_CLASS_MAP)
             
             # STEP 2: Process spans with hybrid context + pattern detection
             for span in soup.find_all('span', class_=True):
@@ -593,13 +1437,9 @@ class VLPParser:
                 
                 # CONTEXT 2: Regular content - hybrid multi-class + content pattern
                 else:
-                    # Check for explicit code class
-                    if code_class in classes:
-                        replacement_tag = 'code'
-                    # Check for explicit bold classes (NEW FIX)
-                    elif any(cls in explicit_bold_classes for cls in classes):
-                        replacement_tag = 'strong'
-                    else:
+                    # Check for explicitly mapped classes (code, bold, ...)
+                    replacement_tag = next((tag for cls, tag in span_class_map.items() if cls in classes), None)
+                    if not replacement_tag:
                         # Filter semantic classes (c + digits)
                         sem_classes = [c for c in classes if c.startswith('c') and c[1:].isdigit()]
                         
@@ -647,28 +1487,23 @@ class VLPParser:
             # Clean up any remaining empty spans
             result = re.sub(r'<span[^>]*>\s*</span>', '', result)
             
-            # Normalize <ol> start attributes and handle nested lists
+            # Nest flat Google Docs list levels and keep their numbering
             soup = BeautifulSoup(result, 'html.parser')
-            for ol_tag in soup.find_all('ol'):
-                if ol_tag.has_attr('start'):
-                    del ol_tag['start']
-                
-                # Handle nested list types based on Google Docs classes (lst-kix_...-N)
-                # Level 0 is default (1, 2, 3...)
-                # Level 1 should be alpha (a, b, c...)
-                # Level 2 should be roman (i, ii, iii...)
-                classes = ol_tag.get('class')
-                if classes:
-                    for cls in classes:
-                        if 'lst-kix_' in cls:
-                            if cls.endswith('-1'):
-                                ol_tag['type'] = 'a'
-                                # Add margin to simulate nesting since they are often separate blocks
-                                # Force list style type via CSS as HTML attribute is often overridden
-                                ol_tag['style'] = 'margin-left: 40px; list-style-type: lower-latin;'
-                            elif cls.endswith('-2'):
-                                ol_tag['type'] = 'i'
-                                ol_tag['style'] = 'margin-left: 80px; list-style-type: upper-latin;'
+            self._restructure_lists(soup)
+            
+            # Turn <pre> sections and command paragraphs into copyable code blocks
+            if self.options.get('code_blocks', True):
+                self._convert_code_blocks(soup)
+            
+            # Give images without alt text their title or figure caption
+            self._apply_alt_text(soup)
+            
+            # Keep the link of clickable screenshots on the image itself
+            self._convert_image_links(soup)
+            
+            # Set lab credentials tables apart as Lab Credentials blocks
+            if self.options.get('credentials_block', 'styled') != 'keep':
+                self._convert_credentials_tables(soup)
             result = str(soup)
 
             return result
@@ -681,20 +1516,373 @@ class VLPParser:
                 self.logger.warning(f"Traceback: {traceback.format_exc()}")
             return html
     
+    def _apply_alt_text(self, soup) -> None:
+        """Fill missing image alt text from the title attribute or figure caption
+        
+        The uploader carries alt text into the alt_tag of ImageContentBlocks.
+        Existing alt text and inline icons are left alone.
+        """
+        for img in soup.find_all('img'):
+            if 'inline-icon' in (img.get('class') or []) or (img.get('alt') or '').strip():
+                continue
+            text = (img.get('title') or '').strip()
+            if not text:
+                figure = img.find_parent('figure')
+                caption = figure.find('figcaption') if figure else None
+                text = caption.get_text(' ', strip=True) if caption else ''
+            if text:
+                img['alt'] = re.sub(r'\s+', ' ', text)
+    
+    def _convert_image_links(self, soup) -> None:
+        """Move the link of a linked screenshot (<a href><img></a>) onto the image
+        
+        The uploader turns each screenshot into its own image block, which
+        would tear the surrounding anchor apart. The anchor is unwrapped and its
+        href kept as data-href on the image; the uploader sets it as the image
+        block's URL. Links to a file in the export's images (the full-size
+        version) are dropped, as uploaded images already open full size.
+        Links to other lessons stay anchors so they can be resolved.
+        """
+        for a_tag in soup.find_all('a', href=True):
+            images = a_tag.find_all('img')
+            if len(images) != 1 or 'inline-icon' in (images[0].get('class') or []) \
+                    or a_tag.get_text(strip=True):
+                continue
+            href = str(a_tag['href']).strip()
+            external = re.match(r'^(https?:)?//', href, re.IGNORECASE)
+            export_image = not external and re.search(r'\.(png|jpe?g|gif|svg|bmp|webp)(\?.*)?$', href, re.IGNORECASE)
+            if not (external or export_image):
+                continue
+            if external:
+                images[0]['data-href'] = href
+            a_tag.unwrap()
+            self.image_links += 1
+    
+    def _convert_credentials_tables(self, soup: BeautifulSoup) -> None:
+        """Wrap lab credentials tables in a warning-styled Lab Credentials block
+        
+        A table is a credentials table when its first row names both a user
+        (User, Username, Login, Account) and a password column. The block's
+        data-credentials attribute carries the --credentials-block mode: the
+        uploader folds 'foldable' blocks into a collapsed sub-step, and
+        'article' blocks are moved into the restricted Lab Credentials article.
+        """
+        mode = self.options.get('credentials_block', 'styled')
+        for table in soup.find_all('table'):
+            if table.find_parent('div', class_='screensteps-styled-block'):
+                continue
+            first_row = table.find('tr')
+            headers = [cell.get_text(' ', strip=True) for cell in first_row.find_all(['th', 'td'])] if first_row else []
+            if not (any(CREDENTIALS_USER_REGEX.search(h) for h in headers) and
+                    any(CREDENTIALS_PASSWORD_REGEX.search(h) for h in headers)):
+                continue
+            block = soup.new_tag('div', attrs={'class': 'screensteps-styled-block', 'data-style': 'warning',
+                                               'data-credentials': mode})
+            title = soup.new_tag('p')
+            title_text = soup.new_tag('strong')
+            title_text.string = CREDENTIALS_TITLE
+            title.append(title_text)
+            table.replace_with(block)
+            block.append(title)
+            block.append(table)
+            self.credentials_tables += 1
+    
+    def _convert_widgets(self, soup: BeautifulSoup) -> None:
+        """Turn VLP knowledge-check and checklist widgets into plain ScreenSteps content
+        
+        A widget is an element with a quiz/knowledge-check or checklist class
+        (or data-widget value), or a list whose items all start with a
+        checkbox. A question's answer options become a numbered list and its
+        correct options and feedback a foldable Answer block, which the
+        uploader folds into a collapsed sub-step; checklists become numbered
+        lists. Questions without recognizable options, and every widget with
+        --widgets alert, become an alert block holding the widget's text.
+        """
+        mode = self.options.get('widgets', 'convert')
+        
+        def classes_match(tag: Tag, regex) -> bool:
+            return any(regex.match(c) for c in (tag.get('class') or [])) or bool(regex.match(str(tag.get('data-widget', ''))))
+        
+        def kind_of(tag: Tag) -> Optional[str]:
+            if classes_match(tag, QUIZ_CLASS_REGEX):
+                return 'quiz'
+            if classes_match(tag, CHECKLIST_CLASS_REGEX):
+                return 'checklist'
+            items = tag.find_all('li', recursive=False) if tag.name in ('ul', 'ol') else []
+            if items and all(checkbox_first(li) for li in items):
+                return 'checklist'
+            return None
+        
+        def checkbox_first(li: Tag) -> bool:
+            first = next((c for c in li.contents if not (isinstance(c, str) and not c.strip())), None)
+            return isinstance(first, Tag) and first.name == 'input' and first.get('type') == 'checkbox'
+        
+        def text_of(tag: Tag) -> str:
+            return re.sub(r'\s+', ' ', tag.get_text(' ', strip=True))
+        
+        def paragraph(text: str, bold: bool = False) -> Tag:
+            p = soup.new_tag('p')
+            if bold:
+                p.append(soup.new_tag('strong'))
+                p.strong.string = text
+            else:
+                p.string = text
+            return p
+        
+        def styled_block(style: str, title: str, texts: List[str], foldable: bool = False) -> Tag:
+            attrs = {'class': 'screensteps-styled-block', 'data-style': style}
+            if foldable:
+                attrs['data-foldable'] = 'true'
+            block = soup.new_tag('div', attrs=attrs)
+            block.append(paragraph(title, bold=True))
+            for text in texts:
+                block.append(paragraph(text))
+            return block
+        
+        def numbered_list(texts: List[str]) -> Tag:
+            ol = soup.new_tag('ol')
+            for text in texts:
+                li = soup.new_tag('li')
+                li.string = text
+                ol.append(li)
+            return ol
+        
+        def is_correct(tag: Tag) -> bool:
+            return classes_match(tag, QUIZ_CORRECT_CLASS_REGEX) or \
+                str(tag.get('data-correct', '')).lower() in ('true', '1', 'yes', 'correct')
+        
+        def question_parts(question: Tag):
+            """Prompt, options as (text, correct) and feedback texts of a question"""
+            feedback = [tag for tag in question.find_all(True) if classes_match(tag, QUIZ_FEEDBACK_CLASS_REGEX)]
+            feedback_texts = [text_of(tag) for tag in feedback if text_of(tag)]
+            for tag in feedback:
+                tag.extract()
+            prompt = next((tag for tag in question.find_all(True) if classes_match(tag, QUIZ_PROMPT_CLASS_REGEX)), None)
+            option_tags = [tag for tag in question.find_all(True) if classes_match(tag, QUIZ_OPTION_CLASS_REGEX)]
+            if not option_tags:
+                inputs = question.find_all('input', type=['radio', 'checkbox'])
+                option_tags = [label for label in question.find_all('label')
+                               if label.find('input') or any(label.get('for') == i.get('id') for i in inputs if i.get('id'))]
+            if not option_tags and question.find(['ul', 'ol']):
+                option_tags = question.find(['ul', 'ol']).find_all('li', recursive=False)
+            answer = str(question.get('data-answer') or question.get('data-correct') or '').strip().lower()
+            options = []
+            for number, tag in enumerate(option_tags, 1):
+                inputs = tag.find_all('input')
+                correct = is_correct(tag) or any(is_correct(i) for i in inputs) or bool(answer) and answer in (
+                    str(number), chr(ord('a') + number - 1), *(str(i.get('value', '')).lower() for i in inputs))
+                for i in inputs:
+                    i.decompose()
+                options.append((text_of(tag), correct))
+            options = [option for option in options if option[0]]
+            if prompt is None:
+                # The question's text around the options
+                for tag in option_tags:
+                    tag.extract()
+                prompt = question
+            return text_of(prompt), options, feedback_texts
+        
+        def convert_quiz(widget: Tag, title: str) -> Tuple[List[Tag], int, bool]:
+            questions = [widget] if classes_match(widget, QUIZ_QUESTION_CLASS_REGEX) else \
+                [q for q in widget.find_all(True) if classes_match(q, QUIZ_QUESTION_CLASS_REGEX)
+                 and not any(classes_match(p, QUIZ_QUESTION_CLASS_REGEX) for p in q.parents if p is not widget)] or [widget]
+            parsed = [question_parts(q) for q in questions]
+            if not all(options for _, options, _ in parsed):
+                return [], len(questions), False
+            tags = [paragraph(title, bold=True)]
+            for number, (prompt, options, feedback) in enumerate(parsed, 1):
+                if prompt:
+                    tags.append(paragraph(f"{number}. {prompt}" if len(parsed) > 1 else prompt))
+                tags.append(numbered_list([text for text, _ in options]))
+                correct = [f"{i}. {text}" for i, (text, is_right) in enumerate(options, 1) if is_right]
+                if correct or feedback:
+                    answer_title = f"{QUIZ_ANSWER_TITLE} {number}" if len(parsed) > 1 else QUIZ_ANSWER_TITLE
+                    tags.append(styled_block('info', answer_title, correct + feedback, foldable=True))
+            return tags, len(questions), True
+        
+        def convert_checklist(widget: Tag, title: str) -> Tuple[List[Tag], int, bool]:
+            lists = [widget] if widget.name in ('ul', 'ol') else widget.find_all(['ul', 'ol'])
+            items = [li for lst in lists for li in lst.find_all('li', recursive=False)]
+            if not items:
+                items = [label for label in widget.find_all('label') if label.find('input', type='checkbox')]
+            for tag in items:
+                for checkbox in tag.find_all('input'):
+                    checkbox.decompose()
+            texts = [text_of(tag) for tag in items if text_of(tag)]
+            if not texts:
+                return [], 0, False
+            return ([paragraph(title, bold=True)] if title else []) + [numbered_list(texts)], len(texts), True
+        
+        widgets = [tag for tag in soup.find_all(True) if kind_of(tag) and not tag.find_parent('table')]
+        widget_ids = {id(widget) for widget in widgets}
+        for widget in widgets:
+            if widget.parent is None or any(id(p) in widget_ids for p in widget.parents):
+                continue
+            kind = kind_of(widget)
+            # The widget's heading (or legend) becomes its title
+            heading = widget.find(['h1', 'h2', 'h3', 'h4', 'h5', 'h6', 'legend']) if widget.name not in ('ul', 'ol') else None
+            title = text_of(heading) if heading else ''
+            if heading:
+                heading.extract()
+            text = text_of(widget)
+            # A widget alone in a paragraph replaces the paragraph, blocks cannot go inside a <p>
+            target = widget.parent if widget.parent.name == 'p' and text_of(widget.parent) == text else widget
+            if kind == 'quiz':
+                title = title or QUIZ_TITLE
+            tags, count, converted = (convert_quiz if kind == 'quiz' else convert_checklist)(widget, title) \
+                if mode == 'convert' else ([], 0, False)
+            if not converted:
+                tags = [styled_block('alert', title or 'Checklist', [text] if text else [])]
+                if mode == 'convert':
+                    self.logger.warning(f"{'Knowledge check' if kind == 'quiz' else 'Checklist'} could not be "
+                                        f"converted, kept as an alert block: {text[:80]}")
+            for tag in tags:
+                target.insert_before(tag)
+            target.decompose()
+            record = {'kind': kind, 'title': title, 'action': 'converted' if converted else 'alert'}
+            if converted:
+                record['questions' if kind == 'quiz' else 'items'] = count
+            self.widgets.append(record)
+            self.logger.substep(f"Converted {'knowledge check' if kind == 'quiz' else 'checklist'} "
+                                f"({record['action']}): {title or text[:40]}")
+        
+    def _apply_transforms(self, chapters: List[Dict], manual_title: str) -> None:
+        """Run the --transform hooks over every step's final HTML
+        
+        Last of the passes, so hooks see what the uploader will split into
+        content blocks (links converted, credentials moved).
+        """
+        for chapter in chapters:
+            for article in chapter['articles']:
+                for step in article['steps']:
+                    context = {'manual': manual_title, 'chapter': chapter['title'], 'article': article['title'],
+                               'step': step['title'], 'article_id': article['id'], 'step_id': step['id'],
+                               'vlp_id': step.get('vlp_id')}
+                    content = apply_transforms(self.options['transforms'], step['content'] or '', context)
+                    if content != (step['content'] or ''):
+                        step['content'] = content
+                        self.transformed_steps += 1
+        if self.transformed_steps:
+            self.logger.substep(f"Transforms changed {self.transformed_steps} steps")
+    
+    def _externalize_credentials(self, chapters: List[Dict]) -> None:
+        """Move Lab Credentials blocks into a restricted article (--credentials-block article)
+        
+        Each step that showed credentials gets a link to its section of a
+        Lab Credentials article, in a chapter of its own appended to the
+        manual. Both are marked restricted, so the uploader never publishes
+        them and only ScreenSteps editors can see the credentials.
+        """
+        article_id = self._make_id('article', 'lab-credentials')
+        credentials_article = {
+            'id': article_id,
+            'vlp_id': None,
+            'title': CREDENTIALS_TITLE,
+            'description': '',
+            'vlp_order': 0,
+            'position': 1,
+            'duration_minutes': None,
+            'restricted': True,
+            'steps': []
+        }
+        for chapter in chapters:
+            for article in chapter['articles']:
+                for step in article['steps']:
+                    blocks = CREDENTIALS_BLOCK_REGEX.findall(step['content'] or '')
+                    if not blocks:
+                        continue
+                    title = f"{article['title']}: {step['title']}" if step['title'] else article['title']
+                    anchor = slugify(title)
+                    credentials_article['steps'].append({
+                        'id': self._make_id('step', 'lab-credentials', step['id']),
+                        'title': title,
+                        'anchor': anchor,
+                        'order': len(credentials_article['steps']) + 1,
+                        'content': ''.join(blocks),
+                        'images': []
+                    })
+                    link = (f'<p>The credentials for this step are in the <a href="#{anchor}" '
+                            f'data-ss-article="{article_id}">{CREDENTIALS_TITLE}</a> article (restricted).</p>')
+                    step['content'] = CREDENTIALS_BLOCK_REGEX.sub(lambda m: link, step['content'], count=1)
+                    step['content'] = CREDENTIALS_BLOCK_REGEX.sub('', step['content'])
+        
+        if not credentials_article['steps']:
+            return
+        chapters.append({
+            'id': self._make_id('chapter', 'lab-credentials'),
+            'vlp_id': None,
+            'title': CREDENTIALS_TITLE,
+            'order': max((c['order'] for c in chapters), default=0) + 1,
+            'description': '',
+            'duration_minutes': None,
+            'restricted': True,
+            'articles': [credentials_article]
+        })
+        self.logger.info(f"Moved lab credentials of {len(credentials_article['steps'])} steps "
+                         f"into the restricted '{CREDENTIALS_TITLE}' article")
+    
+    def _add_overview_article(self, chapters: List[Dict]) -> None:
+        """Add an Overview chapter and article listing every chapter and article (--overview-article)
+        
+        Mirrors the navigation page of VLP manuals: one step per chapter with
+        links to its articles, marked like other internal links so the
+        uploader points them at the articles it creates. Restricted content
+        is left out, since readers cannot open it.
+        """
+        title = self.options['overview_article']
+        article = {
+            'id': self._make_id('article', 'overview'),
+            'vlp_id': None,
+            'title': title,
+            'description': '',
+            'vlp_order': 0,
+            'position': 1,
+            'duration_minutes': None,
+            'steps': []
+        }
+        for chapter in chapters:
+            if chapter.get('restricted'):
+                continue
+            items = []
+            for listed in chapter['articles']:
+                if listed.get('restricted'):
+                    continue
+                duration = f" ({format_duration(listed['duration_minutes'])})" if listed.get('duration_minutes') else ''
+                items.append(f'<li><a href="#" data-ss-article="{listed["id"]}">{escape(listed["title"])}</a>{duration}</li>')
+            if not items:
+                continue
+            description = f"<p>{escape(chapter['description'])}</p>" if chapter.get('description') else ''
+            article['steps'].append({
+                'id': self._make_id('step', 'overview', chapter['id']),
+                'title': chapter['title'],
+                'anchor': slugify(chapter['title']),
+                'order': len(article['steps']) + 1,
+                'content': f"{description}<ul>{''.join(items)}</ul>",
+                'images': []
+            })
+        
+        if not article['steps']:
+            self.logger.warning("Nothing to list in an overview article - skipped")
+            return
+        for chapter in chapters:
+            chapter['order'] += 1
+        chapters.insert(0, {
+            'id': self._make_id('chapter', 'overview'),
+            'vlp_id': None,
+            'title': title,
+            'order': 1,
+            'description': '',
+            'duration_minutes': None,
+            'articles': [article]
+        })
+        self.logger.info(f"Added an '{title}' article listing {len(article['steps'])} chapters")
+    
     def _convert_vlp_paragraph_styles(self, html_content: str) -> str:
         """Convert VLP paragraph classes to ScreenSteps formatted blocks."""
         if not html_content:
             return ""
 
-        p_class_to_style_map = {
-            # NOTE: CSS classes like c10, c44, c48, etc. are document-specific and NOT reliable
-            # indicators of styled blocks. They are used for regular paragraph formatting (indentation,
-            # alignment, etc.) and should NOT be mapped to ScreenSteps styled blocks.
-            # 
-            # Styled blocks should only be applied based on semantic HTML structure or explicit
-            # content patterns, not generic CSS classes from Google Docs exports.
-            # Add mappings here ONLY if you identify a truly consistent semantic pattern.
-        }
+        p_class_to_style_map = self.options.get('paragraph_style_map', DEFAULT_PARAGRAPH_STYLE_MAP)
 
         soup = BeautifulSoup(html_content, 'html.parser')
         
@@ -769,71 +1957,650 @@ class VLPParser:
                 
         return str(soup)
     
-    def _convert_youtube_embeds(self, soup: BeautifulSoup) -> None:
-        """Convert VLP YouTube embed divs to ScreenSteps iframe format"""
-        # Find all YouTube embed divs
-        youtube_divs = soup.find_all('div', class_='mediatag-thumb youtube-thumb')
+    def _restructure_lists(self, soup: BeautifulSoup) -> None:
+        """Rebuild nested lists from the flat lists of Google Docs exports
+        
+        Each nesting level is exported as a separate sibling <ol>/<ul> with a
+        lst-kix_<list>-<level> class. A deeper list that directly follows its
+        parent list is moved into the parent's last <li>; a list at the same
+        level of the same run is merged into it. Lists that stay separate (e.g.
+        interrupted by a screenshot) keep counting through a start attribute.
+        Sub-lists restart under each new parent item; ordered levels cycle
+        through 1, a, i numbering.
+        """
+        def list_level(tag: Tag) -> Optional[Tuple[str, int]]:
+            for cls in tag.get('class') or []:
+                match = LIST_LEVEL_CLASS_REGEX.match(cls)
+                if match:
+                    return match.group(1), int(match.group(2))
+            return None
+        
+        counters = {}  # (list ID, level) -> items numbered so far
+        run = []  # (level, tag) of the nested lists built from the current run of sibling lists
+        
+        for tag in soup.find_all(['ol', 'ul']):
+            info = list_level(tag)
+            if not info or not tag.parent or tag.find_parent('table'):
+                continue
+            list_id, level = info
+            items = tag.find_all('li', recursive=False)
+            
+            previous = tag.find_previous_sibling()
+            if not (run and previous is run[0][1] and list_level(previous)[0] == list_id):
+                run = []
+            while run and run[-1][0] > level:
+                run.pop()
+            
+            # Sub-list numbering restarts under each new item of a higher level
+            for key in [k for k in counters if k[0] == list_id and k[1] > level]:
+                del counters[key]
+            key = (list_id, level)
+            
+            if run and run[-1][0] == level and run[-1][1].name == tag.name:
+                # Same level of the same list: continue it
+                run[-1][1].extend(items)
+                tag.decompose()
+                counters[key] = counters.get(key, 0) + len(items)
+                continue
+            
+            style = []
+            if tag.name == 'ol':
+                if tag.has_attr('start') and str(tag['start']).isdigit():
+                    counters[key] = int(tag['start']) - 1
+                elif counters.get(key):
+                    tag['start'] = str(counters[key] + 1)
+                if level % 3:
+                    # The type attribute alone is often overridden by the site stylesheet
+                    tag['type'] = LIST_LEVEL_TYPES[level % 3]
+                    style.append(f"list-style-type: {'lower-latin' if level % 3 == 1 else 'lower-roman'};")
+            counters[key] = counters.get(key, 0) + len(items)
+            
+            parent_items = run[-1][1].find_all('li', recursive=False) if run else []
+            if parent_items:
+                parent_items[-1].append(tag.extract())
+            elif level:
+                # No parent list to nest into - indent it instead
+                style.insert(0, f"margin-left: {40 * level}px;")
+            if style:
+                tag['style'] = ' '.join(style)
+            elif parent_items:
+                tag.attrs.pop('style', None)
+            run.append((level, tag))
+    
+    def _convert_code_blocks(self, soup: BeautifulSoup) -> None:
+        """Wrap <pre> sections and VLP command paragraphs in 'code' styled blocks
         
-        for youtube_div in youtube_divs:
-            # Extract the YouTube video ID from data-media-id attribute
-            video_id = youtube_div.get('data-media-id')
+        A command paragraph carries one of the command classes or holds nothing
+        but a single <code> element (a c6 span in the default export theme).
+        Consecutive command paragraphs form one block, one line each. The
+        uploader sends code blocks with the copy-to-clipboard button enabled.
+        """
+        command_classes = set(self.options.get('command_classes', DEFAULT_COMMAND_CLASSES))
+        
+        def skip(tag: Tag) -> bool:
+            return bool(tag.find_parent('table') or tag.find_parent('div', class_='screensteps-styled-block'))
+        
+        def code_block(before: Tag) -> Tag:
+            block = soup.new_tag('div')
+            block['class'] = 'screensteps-styled-block'
+            block['data-style'] = 'code'
+            before.insert_before(block)
+            self.code_blocks += 1
+            return block
+        
+        def command_text(p: Tag) -> Optional[str]:
+            if not set(p.get('class') or []) & command_classes:
+                children = [c for c in p.contents if not (isinstance(c, str) and not c.strip())]
+                if len(children) != 1 or not isinstance(children[0], Tag) or children[0].name != 'code':
+                    return None
+            for br in p.find_all('br'):
+                br.replace_with('\n')
+            text = p.get_text().replace('\xa0', ' ').strip('\n')
+            return text if text.strip() else None
+        
+        for pre in soup.find_all('pre'):
+            if not skip(pre):
+                code_block(pre).append(pre.extract())
+        
+        for p in soup.find_all('p'):
+            if not p.parent or skip(p):
+                continue
+            text = command_text(p)
+            if text is None:
+                continue
+            lines = [text]
+            group = [p]
+            sibling = p.find_next_sibling()
+            while sibling is not None and sibling.name == 'p' and (line := command_text(sibling)) is not None:
+                lines.append(line)
+                group.append(sibling)
+                sibling = sibling.find_next_sibling()
+            pre = soup.new_tag('pre')
+            code = soup.new_tag('code')
+            code.string = '\n'.join(lines)
+            pre.append(code)
+            code_block(p).append(pre)
+            for tag in group:
+                tag.decompose()
+    
+    def _convert_font_icons(self, soup: BeautifulSoup) -> None:
+        """Replace VLP font-icon elements (e.g. <span class="icon-warning">) using the icon map
+        
+        Font icons depend on the VLP stylesheet and render as empty boxes in
+        ScreenSteps, so each known icon class is swapped for text/emoji, inline
+        SVG, or an <img> (for URL values). Unknown icon classes are reported.
+        """
+        icon_map = self.options.get('icon_map', DEFAULT_ICON_MAP)
+        
+        for icon in soup.find_all(['span', 'i'], class_=re.compile(r'^icon-')):
+            if not icon.parent:
+                continue
             
-            if not video_id:
-                # Try to extract from data-thumb-url as fallback
-                thumb_url = youtube_div.get('data-thumb-url')
-                if thumb_url:
-                    thumb_url_str = str(thumb_url) if not isinstance(thumb_url, str) else thumb_url
-                    match = re.search(r'youtube\.com/vi/([^/]+)/', thumb_url_str)
-                    if match:
-                        video_id = match.group(1)
+            classes = icon.get('class') or []
+            replacement = next((icon_map[cls] for cls in classes if cls in icon_map), None)
+            if replacement is None:
+                self.logger.warning(f"Unmapped font icon class: {' '.join(classes)}")
+                continue
             
-            if video_id:
-                # Create the ScreenSteps-compatible HTML embed structure
-                # Outer div with html-embed class (no tabindex, no wrapper div)
-                outer_div = soup.new_tag('div')
-                outer_div['class'] = 'html-embed'
-                
-                # Create the iframe directly inside html-embed div
+            if replacement.lstrip().startswith('<svg'):
+                new_node = BeautifulSoup(replacement, 'html.parser')
+            elif re.match(r'^(https?:)?//', replacement) or re.search(r'\.(png|jpe?g|gif|svg)$', replacement, re.IGNORECASE):
+                new_node = soup.new_tag('img')
+                new_node['src'] = replacement
+                new_node['alt'] = classes[0][len('icon-'):]
+                new_node['class'] = 'inline-icon'
+            else:
+                new_node = replacement
+            
+            icon.replace_with(new_node)
+            self.logger.substep(f"Converted font icon: {' '.join(classes)}")
+    
+    def _convert_keyboard_keys(self, soup: BeautifulSoup) -> None:
+        """Replace VLP key markup with <kbd> tags
+        
+        Keys are spans (or <i>/<b>) with a key/kbd/keycap class, whose text is
+        the key or, for class-only spans such as <span class="key-enter">, the
+        class suffix; and images with such a class or a key file name
+        (key_ctrl.png), named by their alt text. VLP also highlights key terms
+        with a bare key class (<span class="key">Note</span>), so those only
+        count when their text is a key name, a single character, an F key or
+        a combination of them. Key combinations (shortcut, key-combo, keys
+        classes, or a key whose text is 'Ctrl+C') become one <kbd> per key
+        joined by '+'. Keys inside tables are left alone.
+        """
+        def key_name(raw: str) -> str:
+            name = raw.strip()
+            if name.lower() in KEY_NAMES:
+                return KEY_NAMES[name.lower()]
+            return name.upper() if len(name) == 1 or re.match(r'^f\d{1,2}$', name, re.IGNORECASE) else name.capitalize()
+        
+        def key_tags(text: str) -> List:
+            """<kbd> tags for 'Ctrl+Alt+Del' (or a single key), with the '+' between them"""
+            parts = [part.strip() for part in re.split(r'\s*\+\s*(?=\S)', text.strip())]
+            nodes = []
+            for part in parts:
+                if nodes:
+                    nodes.append('+')
+                kbd = soup.new_tag('kbd')
+                kbd.string = part
+                nodes.append(kbd)
+            self.keyboard_keys += len(parts)
+            return nodes
+        
+        def replace(tag: Tag, nodes: List) -> None:
+            for node in nodes:
+                tag.insert_before(node)
+            tag.decompose()
+        
+        def known_key(name: str) -> bool:
+            return name.lower() in KEY_NAMES or len(name) == 1 or bool(re.match(r'^f\d{1,2}$', name, re.IGNORECASE))
+        
+        def known_keys(text: str) -> bool:
+            """Whether text is a key or a combination of keys ('Ctrl+C'), not just a word"""
+            parts = [part.strip() for part in re.split(r'\s*\+\s*(?=\S)', text.strip())]
+            return all(part and known_key(part) for part in parts)
+        
+        def class_key(tag: Tag, text: str) -> Optional[re.Match]:
+            for match in (KEY_CLASS_REGEX.match(c) for c in tag.get('class') or []):
+                if not match:
+                    continue
+                if match.group(1) is not None:
+                    if known_key(match.group(1)):
+                        return match
+                elif match.group(0).lower() != 'key' or known_keys(text):
+                    return match  # A bare key class is also VLP's key-term highlight
+            return None
+        
+        for img in soup.find_all('img'):
+            if img.find_parent('table'):
+                continue
+            match = class_key(img, (img.get('alt') or '').strip())
+            file_match = KEY_IMAGE_REGEX.search(str(img.get('src', '')).split('?')[0])
+            if file_match and not known_key(file_match.group(1)):
+                file_match = None
+            if not (match or file_match):
+                continue
+            name = (img.get('alt') or '').strip() or key_name((match and match.group(1)) or
+                                                               (file_match.group(1) if file_match else ''))
+            if name and len(name) <= KEY_MAX_LENGTH:
+                replace(img, key_tags(name))
+        
+        for tag in soup.find_all(['span', 'i', 'b']):
+            if tag.parent is None or tag.find_parent('kbd') or tag.find_parent('table'):
+                continue
+            text = tag.get_text(' ', strip=True)
+            match = class_key(tag, text)
+            if not match:
+                continue
+            if not text and match.group(1):
+                text = key_name(match.group(1))
+            if text and len(text) <= KEY_MAX_LENGTH:
+                replace(tag, key_tags(text))
+        
+        for combo in soup.find_all(['span', 'i', 'b']):
+            if combo.parent is None or not any(KEY_COMBO_CLASS_REGEX.match(c) for c in combo.get('class') or []):
+                continue
+            text = combo.get_text(' ', strip=True)
+            if combo.find('kbd'):
+                combo.unwrap()  # Its keys are already converted
+            elif text and len(text) <= KEY_MAX_LENGTH:
+                replace(combo, key_tags(text))
+    
+    def _convert_copy_widgets(self, soup: BeautifulSoup) -> None:
+        """Turn VLP copy-text widgets (console paste boxes) into 'code' styled blocks
+        
+        A copy widget has a copy-text, copyable or send-text class (see
+        COPY_WIDGET_CLASS_REGEX) or carries its text in a data-copy-text or
+        data-clipboard-text attribute; its Copy/Send buttons are dropped. A
+        widget on its own line becomes a code block, which the uploader sends
+        with the copy-to-clipboard button, one inside a sentence or list item
+        inline <code>. Applies with --no-code-blocks too, as the text was
+        copyable in VLP.
+        """
+        def is_button(tag: Tag) -> bool:
+            return tag.name == 'button' or any(COPY_BUTTON_CLASS_REGEX.match(c) for c in tag.get('class') or [])
+        
+        def text_of(tag: Tag) -> str:
+            for button in [t for t in tag.find_all(True) if is_button(t)]:
+                button.decompose()
+            for br in tag.find_all('br'):
+                br.replace_with('\n')
+            for block in tag.find_all(['p', 'div', 'li', 'pre']):
+                block.append('\n')
+            return tag.get_text().replace('\xa0', ' ').strip('\n')
+        
+        widgets = [tag for tag in soup.find_all(True)
+                   if (any(COPY_WIDGET_CLASS_REGEX.match(c) for c in tag.get('class') or [])
+                       or any(tag.get(attribute) for attribute in COPY_WIDGET_ATTRIBUTES))
+                   and not tag.find_parent('table') and not tag.find_parent('div', class_='screensteps-styled-block')]
+        widget_ids = {id(widget) for widget in widgets}
+        for widget in widgets:
+            if widget.parent is None or any(id(p) in widget_ids for p in widget.parents):
+                continue
+            text = next((str(widget[a]) for a in COPY_WIDGET_ATTRIBUTES if widget.get(a)), None)
+            if text is not None and is_button(widget):
+                # A Copy button next to the command it copies: the command's element is the widget
+                parent = widget.parent
+                widget.decompose()
+                if parent.name in ('[document]', 'body') or \
+                        parent.get_text().replace('\xa0', ' ').strip() != text.strip():
+                    continue
+                widget = parent
+            if text is None:
+                text = text_of(widget)
+            text = text.strip('\n')
+            if not text.strip():
+                widget.decompose()
+                continue
+            # The element the widget stands for: itself, or a paragraph holding nothing else
+            target = widget
+            if widget.name not in ('div', 'pre', 'p') and widget.parent.name in ('p', 'div') \
+                    and widget.parent.get_text().replace('\xa0', ' ').strip() == widget.get_text().replace('\xa0', ' ').strip():
+                target = widget.parent
+            if target.name in ('div', 'pre', 'p') and not target.find_parent('li'):
+                block = soup.new_tag('div', attrs={'class': 'screensteps-styled-block', 'data-style': 'code'})
+                pre = soup.new_tag('pre')
+                code = soup.new_tag('code')
+                code.string = text
+                pre.append(code)
+                block.append(pre)
+                target.replace_with(block)
+            else:
+                code = soup.new_tag('code')
+                code.string = text.replace('\n', ' ')
+                target.replace_with(code)
+            self.copy_widgets += 1
+    
+    def _convert_media_embeds(self, soup: BeautifulSoup) -> None:
+        """Convert VLP media tags and <video> elements to ScreenSteps embeds
+        
+        VLP media tags (<div class="mediatag-thumb <provider>-thumb"
+        data-media-id="...">) become an html-embed iframe built from the
+        provider's embed URL template (YouTube, Vimeo and the VMware video
+        portal built in, more with --media-provider). <video> elements and
+        links to video files become html-embed <video> players; videos that
+        are part of the export are copied with the article's images and
+        uploaded as file assets. Media that cannot be converted is turned into
+        a link (or removed) with a warning instead of silently dropped. Every
+        media element is recorded in media_embeds.
+        """
+        providers = self.options.get('media_providers', DEFAULT_MEDIA_PROVIDERS)
+        
+        def embed(node: Tag, child: Tag) -> None:
+            wrapper = soup.new_tag('div')
+            wrapper['class'] = 'html-embed'
+            wrapper.append(child)
+            # A paragraph holding only the media is replaced by the embed
+            parent = node.parent
+            if parent is not None and parent.name == 'p' and not parent.get_text(strip=True) \
+                    and len(parent.find_all(True)) == len(node.find_all(True)) + 1:
+                node = parent
+            node.replace_with(wrapper)
+        
+        def record(provider: str, media_id: str, action: str) -> None:
+            self.media_embeds.append({'provider': provider, 'id': media_id, 'action': action})
+            if self.verbose:
+                self.logger.substep(f"Media {action}: {provider} {media_id}")
+        
+        for media_div in soup.find_all('div', class_='mediatag-thumb'):
+            classes = media_div.get('class') or []
+            provider = next((cls[:-len('-thumb')] for cls in classes if cls.endswith('-thumb') and cls != 'mediatag-thumb'), '')
+            media_id = str(media_div.get('data-media-id') or '')
+            thumb_url = str(media_div.get('data-thumb-url') or '')
+            if not media_id and provider == 'youtube':
+                # Fall back to the ID in the thumbnail URL
+                match = re.search(r'youtube\.com/vi/([^/]+)/', thumb_url)
+                media_id = match.group(1) if match else ''
+            
+            if provider in providers and media_id:
                 iframe = soup.new_tag('iframe')
                 iframe['width'] = '560'
                 iframe['height'] = '315'
-                iframe['src'] = f'https://www.youtube.com/embed/{video_id}'
-                iframe['title'] = 'YouTube video player'
+                iframe['src'] = providers[provider].format(id=media_id)
+                iframe['title'] = f"{MEDIA_PROVIDER_TITLES.get(provider, provider.capitalize())} video player"
                 iframe['frameborder'] = '0'
                 iframe['allow'] = 'accelerometer; autoplay; clipboard-write; encrypted-media; gyroscope; picture-in-picture; web-share'
                 iframe['referrerpolicy'] = 'strict-origin-when-cross-origin'
                 iframe['allowfullscreen'] = ''
-                
-                # Assemble the structure
-                outer_div.append(iframe)
-                
-                # Replace the VLP YouTube div with the ScreenSteps format
-                youtube_div.replace_with(outer_div)
-                
-                self.logger.substep(f"Converted YouTube embed: {video_id}")
+                embed(media_div, iframe)
+                record(provider, media_id, 'embedded')
+                continue
+            
+            url = str(media_div.get('data-media-url') or '')
+            reason = f"unknown media provider '{provider}'" if media_id else f"{provider or 'media'} tag without a media ID"
+            if url:
+                link = soup.new_tag('a', href=url)
+                link.string = url
+                paragraph = soup.new_tag('p')
+                paragraph.append(link)
+                media_div.replace_with(paragraph)
+                record(provider, media_id, 'linked')
+                self.logger.warning(f"Replaced media tag with a link ({reason}): {url}")
             else:
-                self.logger.warning("Found YouTube embed div but could not extract video ID")
+                media_div.decompose()
+                record(provider, media_id, 'removed')
+                self.logger.warning(f"Removed media tag ({reason}; add it with --media-provider)")
+        
+        # <video src> / <video><source src></video>, and links to video files in the export
+        videos = [(video, video.get('src') or (video.find('source') or {}).get('src'))
+                  for video in soup.find_all('video')]
+        videos += [(a_tag, a_tag['href']) for a_tag in soup.find_all('a', href=True)
+                   if not re.match(r'^[a-z][a-z0-9+.-]*:|^//', a_tag['href'], re.IGNORECASE)
+                   and Path(urlparse(a_tag['href']).path).suffix.lower() in VIDEO_EXTENSIONS]
+        for node, src in videos:
+            src = str(src or '').strip()
+            if not src:
+                node.decompose()
+                record('video', '', 'removed')
+                self.logger.warning("Removed <video> element without a source")
+                continue
+            local = not re.match(r'^[a-z][a-z0-9+.-]*:|^//', src, re.IGNORECASE)
+            player = soup.new_tag('video')
+            player['controls'] = ''
+            player['src'] = re.sub(r'^\./', '', src)
+            if node.name == 'video' and node.get('poster'):
+                player['poster'] = node['poster']
+            embed(node, player)
+            record('video', src, 'uploaded' if local else 'embedded')
     
-    def _extract_description(self, html: str, max_length: int = 200) -> str:
-        """Extract plain text description from HTML"""
-        if not html:
-            return ""
+    def _convert_iframes(self, soup: BeautifulSoup) -> None:
+        """Apply the iframe policy to (non-YouTube) iframes
+        
+        Iframes from allow-listed domains are wrapped in an html-embed div so the
+        uploader sends them as embed blocks; all others are replaced with a link
+        to their source. Every iframe is recorded in iframe_sources.
+        """
+        allowlist = self.options.get('iframe_allow', DEFAULT_IFRAME_ALLOWLIST)
+        
+        for iframe in soup.find_all('iframe'):
+            src = str(iframe.get('src', '')).strip()
+            host = (urlparse(src).hostname or '').lower()
+            allowed = '*' in allowlist or any(host == domain or host.endswith('.' + domain)
+                                              for domain in allowlist)
+            
+            if not src:
+                action = 'removed'
+                iframe.decompose()
+            elif allowed:
+                action = 'embedded'
+                wrapper = iframe.parent
+                if not (wrapper and wrapper.name == 'div' and 'html-embed' in (wrapper.get('class') or [])):
+                    # A paragraph holding only the iframe is replaced by the embed
+                    if wrapper and wrapper.name == 'p' and not wrapper.get_text(strip=True):
+                        wrapper.replace_with(iframe)
+                    embed = soup.new_tag('div')
+                    embed['class'] = 'html-embed'
+                    iframe.wrap(embed)
+            else:
+                action = 'linked'
+                link = soup.new_tag('a', href=src)
+                link.string = str(iframe.get('title') or '').strip() or host or src
+                paragraph = soup.new_tag('p')
+                paragraph.append(link)
+                target = iframe.parent if iframe.parent and iframe.parent.name == 'p' \
+                    and not iframe.parent.get_text(strip=True) else iframe
+                target.replace_with(paragraph)
+            
+            self.iframe_sources.append({'src': src, 'host': host, 'action': action})
+            if self.verbose:
+                self.logger.substep(f"Iframe {action}: {src or '(no src)'}")
+    
+    @staticmethod
+    def _parse_style(tag: Tag) -> Dict[str, str]:
+        """Parse an inline style attribute into a property -> value dict"""
+        style = {}
+        for declaration in str(tag.get('style', '')).split(';'):
+            if ':' in declaration:
+                prop, value = declaration.split(':', 1)
+                style[prop.strip().lower()] = value.strip().lower()
+        return style
+    
+    @staticmethod
+    def _strip_layout(tag: Tag, class_regex: re.Pattern) -> None:
+        """Remove layout-only inline styles and classes from an element"""
+        def is_layout(declaration: str) -> bool:
+            name, value = (part.strip().lower() for part in declaration.split(':', 1))
+            return name in LAYOUT_STYLE_PROPERTIES or (name == 'display' and value in LAYOUT_DISPLAY_VALUES)
+        
+        if tag.has_attr('style'):
+            kept = [d for d in str(tag['style']).split(';') if ':' in d and not is_layout(d)]
+            if kept:
+                tag['style'] = ';'.join(kept)
+            else:
+                del tag['style']
+        if tag.has_attr('class'):
+            classes = [c for c in tag.get('class', []) if not class_regex.match(c)]
+            if classes:
+                tag['class'] = classes
+            else:
+                del tag['class']
+    
+    def _flatten_layout(self, soup: BeautifulSoup) -> None:
+        """Linearize VLP layout markup that collapses badly in ScreenSteps
+        
+        - Absolutely positioned siblings are reordered top-to-bottom, then
+          left-to-right, and their positioning is removed
+        - Multi-column containers (CSS columns, flex/grid, column classes) are
+          unwrapped so the columns follow each other
+        - Floated images are moved below the paragraph they floated beside;
+          other floated elements just lose the float
+        Tables are left alone. Every applied heuristic is reported as a warning.
+        """
+        def px(value: str) -> float:
+            match = re.match(r'-?\d+(\.\d+)?', value or '')
+            return float(match.group(0)) if match else 0.0
+        
+        # 1. Absolutely positioned elements, grouped by parent
+        positioned = {}
+        for tag in soup.find_all(style=True):
+            if tag.find_parent('table'):
+                continue
+            if self._parse_style(tag).get('position') in ('absolute', 'fixed'):
+                positioned.setdefault(id(tag.parent), []).append(tag)
+        positioned_count = 0
+        for group in positioned.values():
+            ordered = sorted(group, key=lambda t: (px(self._parse_style(t).get('top')),
+                                                   px(self._parse_style(t).get('left'))))
+            marker = soup.new_string('')
+            group[0].insert_before(marker)
+            for tag in ordered:
+                marker.insert_before(tag.extract())
+            marker.extract()
+            for tag in ordered:
+                self._strip_layout(tag, LAYOUT_COLUMN_CLASS_REGEX)
+            positioned_count += len(group)
+        
+        # 2. Multi-column containers
+        column_count = 0
+        for tag in soup.find_all(['div', 'section']):
+            if not tag.parent or tag.find_parent('table'):
+                continue
+            style = self._parse_style(tag)
+            is_columns = (
+                'column-count' in style or 'columns' in style
+                or style.get('display') in ('flex', 'inline-flex', 'grid', 'inline-grid')
+                or any(LAYOUT_COLUMN_CLASS_REGEX.match(c) for c in tag.get('class', []))
+            )
+            if not is_columns:
+                continue
+            for child in tag.find_all(['div', 'section'], recursive=False):
+                self._strip_layout(child, LAYOUT_COLUMN_CLASS_REGEX)
+                if not child.attrs:
+                    child.unwrap()
+            self._strip_layout(tag, LAYOUT_COLUMN_CLASS_REGEX)
+            if not tag.attrs:
+                tag.unwrap()
+            column_count += 1
+        
+        # 3. Floats
+        float_count = 0
+        for tag in soup.find_all(True):
+            if not tag.parent or tag.find_parent('table'):
+                continue
+            floated = (self._parse_style(tag).get('float') in ('left', 'right')
+                       or any(LAYOUT_FLOAT_CLASS_REGEX.match(c) for c in tag.get('class', [])))
+            if not floated:
+                continue
+            self._strip_layout(tag, LAYOUT_FLOAT_CLASS_REGEX)
+            paragraph = tag.find_parent('p')
+            if tag.name in ('img', 'figure') and paragraph:
+                paragraph.insert_after(tag.extract())
+            float_count += 1
+        
+        applied = []
+        if positioned_count:
+            applied.append(f"{positioned_count} positioned elements reordered")
+        if column_count:
+            applied.append(f"{column_count} multi-column containers linearized")
+        if float_count:
+            applied.append(f"{float_count} floats removed")
+        if applied:
+            self.logger.warning(f"Flattened layout markup: {', '.join(applied)}")
+    
+    def _apply_link_policy(self, soup: BeautifulSoup) -> None:
+        """Set target/rel attributes on external links per the configured policy
+        
+        VLP emits links with whatever target/rel the author's editor produced.
+        External links (absolute http/https URLs) are normalized to the site
+        conventions; relative links, anchors and mailto: links are left alone.
+        """
+        if not self.options.get('link_policy', True):
+            return
         
-        # Remove HTML tags
-        text = re.sub(r'<[^>]+>', '', html)
-        # Remove extra whitespace
-        text = re.sub(r'\s+', ' ', text).strip()
+        target = self.options.get('link_target', '_blank')
+        rel = self.options.get('link_rel', 'noopener noreferrer')
         
-        if len(text) > max_length:
-            text = text[:max_length] + "..."
+        updated = 0
+        for a_tag in soup.find_all('a', href=True):
+            href = str(a_tag.get('href', '')).strip()
+            if not re.match(r'^(https?:)?//', href, re.IGNORECASE):
+                continue
+            
+            if target:
+                a_tag['target'] = target
+            elif a_tag.has_attr('target'):
+                del a_tag['target']
+            
+            if rel:
+                a_tag['rel'] = rel
+            elif a_tag.has_attr('rel'):
+                del a_tag['rel']
+            
+            updated += 1
         
-        return text
+        if updated:
+            self.logger.substep(f"Applied link policy to {updated} external links")
+    
+    def _extract_description(self, html: str, max_length: int = DESCRIPTION_MAX_LENGTH) -> str:
+        """Extract a plain text description from HTML
+        
+        Prefers the first paragraph with text. Longer text is cut after the
+        last complete sentence that fits, else at a word boundary, and marked
+        with an ellipsis; the result (ellipsis included) never exceeds
+        max_length characters. Entities are decoded before measuring and the
+        cut never separates a character from its combining marks, so no
+        broken entities or half characters end up in the description.
+        """
+        if not html:
+            return ""
+        
+        text = ""
+        for block in DESCRIPTION_BLOCK_REGEX.split(html):
+            text = re.sub(r'\s+', ' ', unescape(re.sub(r'<[^>]+>', ' ', block))).strip()
+            if text:
+                break
+        if len(text) <= max_length:
+            return text
+        
+        limit = max_length - 1  # Room for the ellipsis
+        sentence_end = max((m.end() for m in re.finditer(r'[.!?](?=\s)', text[:limit + 1])), default=0)
+        if sentence_end >= limit // 2:
+            return text[:sentence_end]
+        
+        cut = limit
+        # Keep combining marks, variation selectors and joiners with their base character
+        while cut > 0 and (unicodedata.combining(text[cut]) or text[cut] in '\u200d\ufe0e\ufe0f'
+                           or text[cut - 1] == '\u200d'):
+            cut -= 1
+        word_end = text.rfind(' ', 0, cut + 1)
+        if word_end >= limit // 2:
+            cut = word_end
+        return text[:cut].rstrip(' ,;:-') + "\u2026"
 
 class ScreenStepsConverter:
     """Converter from VLP to ScreenSteps format"""
     
-    def __init__(self, logger: ProgressLogger):
+    def __init__(self, logger: ProgressLogger, options: Optional[Dict] = None):
         self.logger = logger
+        self.options = options or {}
+        self.fuzzy_image_matches = []  # Images resolved by fallback rules in the last write_output
+        self.missing_images = 0  # Images referenced by the last write_output but not in the export
+        self.stale_images = []  # Screenshots older than --stale-before in the last write_output
+        self.videos_copied = 0  # Local videos copied next to the article images in the last write_output
+        self.attachments_copied = 0  # Linked attachments copied next to the article images in the last write_output
+        self.rejected_images = []  # Images left out by their format policy (e.g. GIFs over the size limit)
+        self.rasterized_images = 0  # SVGs converted to PNG in the last write_output
+        self.annotations = []  # Screenshots with callouts and what became of them (summary.json, --annotations)
     
     def convert(self, vlp_data: Dict, chapters: List[Dict], 
                 output_dir: Path, images_dir: Path) -> Dict:
@@ -843,10 +2610,18 @@ class ScreenStepsConverter:
         
         # Create ScreenSteps manual structure
         manual = {
+            'generator': build_info(),
             'manual': {
-                'id': vlp_data['id'],
+                'id': stable_id(vlp_data['id'] or vlp_data['name'], 'manual'),
+                'vlp_id': vlp_data['id'],
                 'title': vlp_data['name'],
+                'description': vlp_data.get('description', ''),
                 'language': vlp_data['language'],
+                'metadata': {  # Traces the migrated content back to its lab (uploader --metadata-tags, article_snippets)
+                    'sku': vlp_data.get('sku'),
+                    'data_format': vlp_data.get('format'),
+                    'export_date': vlp_data.get('export_date'),
+                },
                 'created_at': datetime.now().isoformat(),
                 'updated_at': datetime.now().isoformat(),
                 'chapters': []
@@ -857,9 +2632,12 @@ class ScreenStepsConverter:
         for chapter in chapters:
             ss_chapter = {
                 'id': chapter['id'],
+                'vlp_id': chapter.get('vlp_id'),
                 'title': chapter['title'],
                 'order': chapter['order'],
                 'description': chapter.get('description', ''),
+                'duration_minutes': chapter.get('duration_minutes'),
+                'restricted': chapter.get('restricted', False),  # Never published by the uploader
                 'articles': []
             }
             
@@ -867,9 +2645,13 @@ class ScreenStepsConverter:
             for article in chapter['articles']:
                 ss_article = {
                     'id': article['id'],
+                    'vlp_id': article.get('vlp_id'),
                     'title': article['title'],
+                    'description': article.get('description', ''),
                     'position': article['position'],  # Sequential position for ScreenSteps
                     'vlp_order': article.get('vlp_order'),  # Keep VLP order for reference
+                    'duration_minutes': article.get('duration_minutes'),
+                    'restricted': article.get('restricted', False),
                     'steps': article.get('steps', [])  # Include steps
                 }
                 ss_chapter['articles'].append(ss_article)
@@ -881,8 +2663,14 @@ class ScreenStepsConverter:
         return manual
     
     def write_output(self, manual: Dict, chapters: List[Dict], 
-                     output_dir: Path, images_source: Path) -> Tuple[int, int]:
-        """Write ScreenSteps formatted output and return counts"""
+                     output_dir: Path, images_source: Path,
+                     archive: Optional[ExportArchive] = None) -> Tuple[int, int]:
+        """Write ScreenSteps formatted output and return counts
+        
+        Images, videos and attachments are copied from images_source and the
+        export directory above it, or from archive when the ZIP is read in
+        place (--in-memory).
+        """
         
         self.logger.info("Writing ScreenSteps output files...")
         
@@ -892,63 +2680,215 @@ class ScreenStepsConverter:
         articles_dir.mkdir(parents=True, exist_ok=True)
         images_dir.mkdir(parents=True, exist_ok=True)
         
-        # Write table of contents
-        toc_file = output_dir / f"{manual['manual']['id']}.json"
-        with open(toc_file, 'w', encoding='utf-8') as f:
-            json.dump(manual, f, indent=2, ensure_ascii=False)
-        self.logger.substep(f"Created TOC: {toc_file.name}")
+        # Index the source images once instead of checking every reference
+        if archive:
+            image_index = ArchiveIndex(archive, 'images', self.options.get('image_ignore', []))
+        else:
+            image_index = ImageIndex(images_source, self.options.get('image_ignore', []))
+        self.logger.substep(f"Indexed {len(image_index)} source images ({image_index.ignored} ignored)")
+        file_index = None  # Videos and attachments anywhere in the export, built on first use
+        self.videos_copied = 0
+        self.attachments_copied = 0
         
         # Write individual articles and count images
         article_count = 0
         image_count = 0
+        self.missing_images = 0
+        self.rejected_images = []
+        self.rasterized_images = 0
+        self.annotations = []
+        self.stale_images = []
+        stale_before = self.options.get('stale_before')
         for chapter in manual['manual']['chapters']:
             for article in chapter['articles']:
                 article_id = article['id']
                 
-                # Write article JSON (with steps)
-                article_file = articles_dir / f"{article_id}.json"
-                with open(article_file, 'w', encoding='utf-8') as f:
-                    json.dump(article, f, indent=2, ensure_ascii=False)
-                
-                # Copy article images from steps
+                # Copy article images from steps (before the JSON, which may point at rasterized copies)
                 article_images_dir = images_dir / article_id
                 article_images_dir.mkdir(exist_ok=True)
                 
                 for step in article.get('steps', []):
                     for img_info in step.get('images', []):
-                        src_image = images_source / img_info['filename']
-                        if src_image.exists():
-                            dst_image = article_images_dir / src_image.name
-                            shutil.copy2(src_image, dst_image)
+                        src_image = image_index.lookup(img_info['filename'])
+                        if src_image:
+                            # Keep the referenced name so the HTML still points at the copy
+                            dst_image = image_index.copy_to(src_image,
+                                                            article_images_dir / Path(img_info['filename']).name)
+                            modified = datetime.fromtimestamp(dst_image.stat().st_mtime)
+                            try:
+                                used_image = apply_format_policy(dst_image, self.options.get('image_formats'),
+                                                                 article_images_dir)
+                            except ImageFormatError as e:
+                                dst_image.unlink()
+                                self.logger.warning(f"Image left out: {e}")
+                                self.rejected_images.append({'article': article['title'], 'step': step.get('title'),
+                                                             'file': img_info['filename'], 'reason': str(e)})
+                                continue
+                            if used_image != dst_image:
+                                # Rasterized SVG: point the step at the PNG
+                                dst_image.unlink()
+                                old_name, new_name = Path(img_info['filename']).name, used_image.name
+                                step['content'] = re.sub(r'(src="[^"]*?)' + re.escape(escape(old_name)) + '"',
+                                                         lambda m: f'{m.group(1)}{escape(new_name)}"', step.get('content') or '')
+                                img_info['filename'] = str(Path(img_info['filename']).with_name(new_name))
+                                self.rasterized_images += 1
+                                dst_image = used_image
                             image_count += 1
+                            if stale_before:
+                                if modified < stale_before:
+                                    self.stale_images.append({
+                                        'article_id': article_id, 'article': article['title'],
+                                        'step': step.get('title'), 'file': dst_image.name,
+                                        'modified': modified.date().isoformat()
+                                    })
+                        else:
+                            self.logger.warning(f"Image not found in export: {img_info['filename']}")
+                            self.missing_images += 1
+                    
+                    if ANNOTATION_ATTRIBUTE in (step.get('content') or ''):
+                        self._apply_annotations(article, step, article_images_dir)
+                    
+                    # Local videos and attachments are not listed in the XML; find them anywhere in the export
+                    content = step.get('content') or ''
+                    local_files = [('video', src) for src in re.findall(r'<video\b[^>]*\bsrc="([^"]+)"', content)]
+                    local_files += [('attachment', href) for href in re.findall(r'<a\b[^>]*\bhref="([^"#?]+)', content)
+                                    if Path(unescape(href)).suffix.lower() in ATTACHMENT_EXTENSIONS]
+                    for kind, src in local_files:
+                        src = unescape(src)
+                        if re.match(r'^[a-z][a-z0-9+.-]*:|^//', src, re.IGNORECASE):
+                            continue
+                        if file_index is None:
+                            extensions = VIDEO_EXTENSIONS | ATTACHMENT_EXTENSIONS
+                            if archive:
+                                file_index = ArchiveIndex(archive, '', self.options.get('image_ignore', []),
+                                                          extensions=extensions)
+                            else:
+                                file_index = ImageIndex(images_source.parent, self.options.get('image_ignore', []),
+                                                        extensions=extensions)
+                        src_file = file_index.lookup(src) or file_index.lookup(src.split('/')[-1])
+                        if not src_file:
+                            self.logger.warning(f"{kind.capitalize()} not found in export: {src}")
+                            self.missing_images += 1
+                            continue
+                        file_index.copy_to(src_file, article_images_dir / src.split('/')[-1])
+                        if kind == 'video':
+                            self.videos_copied += 1
+                        else:
+                            self.attachments_copied += 1
                 
+                # Write article JSON (with steps)
+                article_file = articles_dir / f"{article_id}.json"
+                with open(article_file, 'w', encoding='utf-8') as f:
+                    json.dump(article, f, indent=2, ensure_ascii=False)
                 article_count += 1
         
+        # Write table of contents (after the images, so it has any rasterized image names)
+        toc_file = output_dir / f"{manual['manual']['id']}.json"
+        with open(toc_file, 'w', encoding='utf-8') as f:
+            json.dump(manual, f, indent=2, ensure_ascii=False)
+        self.logger.substep(f"Created TOC: {toc_file.name}")
+        
+        if self.rejected_images:
+            self.logger.warning(f"{len(self.rejected_images)} images were left out by their format policy "
+                                f"(listed under rejected_images in {SUMMARY_FILE})")
+        
+        self.fuzzy_image_matches = image_index.fuzzy_matches
+        for match in self.fuzzy_image_matches:
+            self.logger.warning(f"Image {match['reference']} matched {match['matched']} by {match['rule']}")
+        
+        burned = sum(1 for a in self.annotations if a['action'] == 'burned')
+        if self.annotations:
+            self.logger.substep(f"Screenshot callouts: {burned} of {len(self.annotations)} images annotated "
+                                f"(--annotations {self.options.get('annotations', 'burn')})")
+        
+        if self.stale_images:
+            self.logger.info(f"{len(self.stale_images)} screenshots are older than "
+                             f"{stale_before.date().isoformat()} (listed under stale_images in {SUMMARY_FILE})")
+        
         self.logger.substep(f"Created {article_count} article files with {image_count} images")
         self.logger.success(f"Output written to: {output_dir}")
         
         return article_count, image_count
 
+    def _apply_annotations(self, article: Dict, step: Dict, article_images_dir: Path) -> None:
+        """Burn, keep or drop the screenshot callouts the parser put on a step's images (--annotations)
+        
+        Burned callouts go into a copy of the copied image (see annotated_name),
+        which the step then points at; callouts that cannot be burned are kept
+        as data-annotations.
+        """
+        mode = self.options.get('annotations', 'burn')
+        soup = BeautifulSoup(step['content'], 'html.parser')
+        for img in soup.find_all('img', attrs={ANNOTATION_ATTRIBUTE: True}):
+            src = img.get('src', '')
+            name = unquote(src.split('?')[0]).split('/')[-1]
+            record = {'article': article['title'], 'step': step.get('title'), 'file': name}
+            try:
+                annotations = load_annotations(img[ANNOTATION_ATTRIBUTE])
+                record['shapes'] = len(annotations['shapes'])
+                if mode == 'burn':
+                    if not (article_images_dir / name).is_file():
+                        raise AnnotationError(f"{name} is not in the output")
+                    burned = burn_annotations(article_images_dir / name, annotations,
+                                              article_images_dir / annotated_name(name, annotations))
+                    img['src'] = src[:len(src) - len(src.split('/')[-1])] + burned.name
+                    record['annotated_file'] = burned.name
+            except AnnotationError as e:
+                self.logger.warning(f"Screenshot callouts kept as data: {e}")
+                self.annotations.append({**record, 'action': 'kept', 'reason': str(e)})
+                continue
+            if mode != 'keep':
+                del img[ANNOTATION_ATTRIBUTE]
+            self.annotations.append({**record, 'action': {'burn': 'burned', 'keep': 'kept', 'drop': 'dropped'}[mode]})
+        step['content'] = str(soup)
+
+class PhaseTimer:
+    """Measures the duration of consecutive conversion phases"""
+    
+    def __init__(self):
+        self.timings = {}
+        self._mark = time.time()
+    
+    def lap(self, phase: str):
+        """Record the time since the previous lap as the duration of phase"""
+        now = time.time()
+        self.timings[phase] = round(now - self._mark, 2)
+        self._mark = now
+
 class VLPToScreenStepsConverter:
     """Main converter class"""
     
-    def __init__(self, verbose: bool = False):
+    def __init__(self, verbose: bool = False, options: Optional[Dict] = None):
         self.verbose = verbose
-        self.logger = ProgressLogger(verbose)
-        self.parser = VLPParser(self.logger)
-        self.converter = ScreenStepsConverter(self.logger)
+        self.options = options or {}
+        self.logger = ProgressLogger(verbose, quiet=self.options.get('quiet', False),
+                                     log_dir=self.options.get('log_dir', DEFAULT_LOG_DIR),
+                                     log_keep=self.options.get('log_keep', DEFAULT_LOG_KEEP))
+        self.stats = {}  # Counts of the last conversion (--quiet result line)
+        self.toc_file = None  # Table of contents written by the last conversion
+        self.parser = VLPParser(self.logger, self.options)
+        self.converter = ScreenStepsConverter(self.logger, self.options)
     
     def convert_zip(self, zip_path: Path, output_dir: Path, 
                     cleanup: bool = True) -> Path:
         """Convert a VLP ZIP export to ScreenSteps format"""
         
+        if self.options.get('in_memory'):
+            # Read content.xml and the images straight from the ZIP; nothing goes to temp/
+            with self._open_archive(zip_path) as archive:
+                return self.convert_directory(zip_path, output_dir, source=zip_path, archive=archive)
+        
         self.logger.header("VLP to ScreenSteps Converter")
         self.logger.info(f"Input: {zip_path}")
         self.logger.info(f"Output: {output_dir}")
         
+        started_at = datetime.now()
+        timer = PhaseTimer()
+        
         # Step 1: Extract ZIP
         self.logger.step(1, 5, "Extracting VLP ZIP file")
         temp_dir = self._extract_zip(zip_path)
+        timer.lap('extract')
         
         # Step 2: Parse VLP XML
         self.logger.step(2, 5, "Parsing VLP content")
@@ -957,6 +2897,7 @@ class VLPToScreenStepsConverter:
             raise FileNotFoundError(f"content.xml not found in {temp_dir}")
         
         vlp_data = self.parser.parse_xml(xml_file)
+        timer.lap('parse')
         
         # Step 3: Flatten structure
         self.logger.step(3, 5, "Flattening content structure")
@@ -964,24 +2905,35 @@ class VLPToScreenStepsConverter:
         self.logger.substep(f"Created {len(chapters)} chapters")
         total_articles = sum(len(ch['articles']) for ch in chapters)
         self.logger.substep(f"Created {total_articles} articles")
+        timer.lap('flatten')
         
         # Step 4: Convert to ScreenSteps format
         self.logger.step(4, 5, "Converting to ScreenSteps format")
         manual = self.converter.convert(vlp_data, chapters, output_dir, 
                                        temp_dir / "images")
+        timer.lap('convert')
         
         # Step 5: Write output
         self.logger.step(5, 5, "Writing output files")
         output_path = output_dir / vlp_data['name']
         output_path.mkdir(parents=True, exist_ok=True)
+        self._track_artifact('output', output_path, zip_path)
         
         images_source = temp_dir / "images"
         article_count, image_count = self.converter.write_output(manual, chapters, output_path, images_source)
-        
-        # Cleanup
+        self.stats = {'chapters': len(chapters), 'articles': article_count, 'images': image_count,
+                      'missing_images': self.converter.missing_images, 'warnings': len(self.logger.warnings),
+                      'missing_alt_text': len(self.parser.missing_alt_text)}
+        timer.lap('write')
+        self._write_summary(output_path, zip_path, manual, started_at, timer.timings)
+        self._write_qa_report(output_path, manual)
+        self._finish_artifact(output_path)
+        
+        # Cleanup (a kept temp directory is only removed by vlp2ss_clean.py --include-kept)
         if cleanup:
             self.logger.info("Cleaning up temporary files...")
             shutil.rmtree(temp_dir)
+        self._finish_artifact(temp_dir, removed=cleanup)
         
         self.logger.header("Conversion Complete!")
         self.logger.success(f"ScreenSteps content created at: {output_path}")
@@ -990,20 +2942,36 @@ class VLPToScreenStepsConverter:
         
         return output_path
     
-    def convert_directory(self, dir_path: Path, output_dir: Path) -> Path:
-        """Convert an extracted VLP directory to ScreenSteps format"""
+    def convert_directory(self, dir_path: Path, output_dir: Path, language: Optional[str] = None,
+                          source: Optional[Path] = None, archive: Optional[ExportArchive] = None) -> Path:
+        """Convert an extracted VLP directory to ScreenSteps format
+        
+        With a language, only that language's text is converted, into
+        <output>/<manual>/<language>/ (see convert_languages); source is the
+        input recorded in the summary when dir_path is an extracted ZIP. With
+        an archive, the export is read from it instead of dir_path.
+        """
         
         self.logger.header("VLP to ScreenSteps Converter")
         self.logger.info(f"Input: {dir_path}")
         self.logger.info(f"Output: {output_dir}")
+        if archive:
+            self.logger.info("Reading the ZIP in place (--in-memory)")
+        
+        started_at = datetime.now()
+        timer = PhaseTimer()
         
         # Parse VLP XML
         self.logger.step(1, 4, "Parsing VLP content")
-        xml_file = dir_path / "content.xml"
-        if not xml_file.exists():
-            raise FileNotFoundError(f"content.xml not found in {dir_path}")
-        
-        vlp_data = self.parser.parse_xml(xml_file)
+        if archive:
+            with archive.open("content.xml") as xml_file:
+                vlp_data = self.parser.parse_xml(xml_file, language)
+        else:
+            xml_file = dir_path / "content.xml"
+            if not xml_file.exists():
+                raise FileNotFoundError(f"content.xml not found in {dir_path}")
+            vlp_data = self.parser.parse_xml(xml_file, language)
+        timer.lap('parse')
         
         # Flatten structure
         self.logger.step(2, 4, "Flattening content structure")
@@ -1011,19 +2979,36 @@ class VLPToScreenStepsConverter:
         self.logger.substep(f"Created {len(chapters)} chapters")
         total_articles = sum(len(ch['articles']) for ch in chapters)
         self.logger.substep(f"Created {total_articles} articles")
+        timer.lap('flatten')
         
         # Convert to ScreenSteps format
         self.logger.step(3, 4, "Converting to ScreenSteps format")
         manual = self.converter.convert(vlp_data, chapters, output_dir, 
                                        dir_path / "images")
+        if language and language != self.parser.default_language:
+            # Each language is uploaded as a manual of its own
+            manual['manual']['title'] = f"{vlp_data['name']} ({language})"
+        timer.lap('convert')
         
         # Write output
         self.logger.step(4, 4, "Writing output files")
         output_path = output_dir / vlp_data['name']
+        if language:
+            output_path = output_path / language
         output_path.mkdir(parents=True, exist_ok=True)
+        self._track_artifact('output', output_path, source or dir_path)
         
         images_source = dir_path / "images"
-        article_count, image_count = self.converter.write_output(manual, chapters, output_path, images_source)
+        article_count, image_count = self.converter.write_output(manual, chapters, output_path, images_source,
+                                                                 archive=archive)
+        self.stats = {'chapters': len(chapters), 'articles': article_count, 'images': image_count,
+                      'missing_images': self.converter.missing_images, 'warnings': len(self.logger.warnings),
+                      'missing_alt_text': len(self.parser.missing_alt_text)}
+        timer.lap('write')
+        self.toc_file = output_path / f"{manual['manual']['id']}.json"
+        self._write_summary(output_path, source or dir_path, manual, started_at, timer.timings)
+        self._write_qa_report(output_path, manual)
+        self._finish_artifact(output_path)
         
         self.logger.header("Conversion Complete!")
         self.logger.success(f"ScreenSteps content created at: {output_path}")
@@ -1032,32 +3017,393 @@ class VLPToScreenStepsConverter:
         
         return output_path
     
-    def _extract_zip(self, zip_path: Path) -> Path:
-        """Extract ZIP file to temporary directory"""
-        temp_dir = Path("temp") / zip_path.stem
-        temp_dir.mkdir(parents=True, exist_ok=True)
+    def convert_languages(self, input_path: Path, output_dir: Path, languages: List[str],
+                          cleanup: bool = True) -> Path:
+        """Convert each listed language (or 'all') of a VLP ZIP or directory into its own content directory
         
-        self.logger.substep(f"Extracting to: {temp_dir}")
+        Writes <output>/<manual>/<language>/ per language and a languages.json
+        index next to them, and returns the manual directory. stats add up the
+        counts of all languages.
+        """
+        is_zip = input_path.is_file()
+        if is_zip and self.options.get('in_memory'):
+            with self._open_archive(input_path) as archive:
+                return self._convert_languages(input_path, input_path, output_dir, languages, archive=archive)
+        content_dir = self._extract_zip(input_path) if is_zip else input_path
+        manual_path = self._convert_languages(input_path, content_dir, output_dir, languages)
+        if is_zip:
+            if cleanup:
+                self.logger.info("Cleaning up temporary files...")
+                shutil.rmtree(content_dir)
+            self._finish_artifact(content_dir, removed=cleanup)
+        return manual_path
+    
+    def _convert_languages(self, input_path: Path, content_dir: Path, output_dir: Path, languages: List[str],
+                           archive: Optional[ExportArchive] = None) -> Path:
+        """convert_languages for an extracted directory, or an archive read in place"""
+        if archive:
+            with archive.open("content.xml") as xml_file:
+                available = VLPParser.list_languages(xml_file)
+        else:
+            xml_file = content_dir / "content.xml"
+            if not xml_file.exists():
+                raise FileNotFoundError(f"content.xml not found in {content_dir}")
+            available = VLPParser.list_languages(xml_file)
+        if 'all' in languages:
+            languages = available
+        unknown = [code for code in languages if code not in available]
+        if unknown:
+            raise ValueError(f"Languages not in the export: {', '.join(unknown)} (available: {', '.join(available)})")
+        
+        totals = {}
+        entries = []
+        for language in languages:
+            # Fresh parser and converter per language, so their counters describe one manual each
+            self.parser = VLPParser(self.logger, self.options)
+            self.converter = ScreenStepsConverter(self.logger, self.options)
+            output_path = self.convert_directory(content_dir, output_dir, language=language, source=input_path,
+                                                 archive=archive)
+            entries.append({'code': language, 'default': language == self.parser.default_language,
+                            'directory': language, 'toc': f"{language}/{self.toc_file.name}"})
+            for key, value in self.stats.items():
+                totals[key] = totals.get(key, 0) + value
+        totals['warnings'] = len(self.logger.warnings)
+        self.stats = dict(totals, languages=len(languages))
+        
+        manual_path = output_path.parent
+        index = {'generator': build_info(), 'manual': manual_path.name,
+                 'default_language': self.parser.default_language, 'languages': entries}
+        with open(manual_path / LANGUAGES_FILE, 'w', encoding='utf-8') as f:
+            json.dump(index, f, indent=2, ensure_ascii=False)
+        self.logger.success(f"Converted {len(languages)} languages into {manual_path} (index: {LANGUAGES_FILE})")
+        return manual_path
+    
+    def preview_replace(self, input_path: Path, rules) -> int:
+        """Show a colored diff of what the replacement rules would change
         
-        with zipfile.ZipFile(zip_path, 'r') as zip_ref:
-            zip_ref.extractall(temp_dir)
+        Reads content.xml straight from the ZIP (or directory) and converts it
+        in memory without the rules, then applies them to every title, article
+        and step and prints the differences with the match counts of each rule
+        per scope. Nothing is written. Returns the number of changed articles.
+        """
+        self.logger.header("Replacement Rules Preview")
+        self.logger.info(f"Input: {input_path}")
+        self.logger.info(f"Rules: {len(rules.rules)}")
+        
+        vlp_data = self._parse_input(input_path)
+        totals = {}
+        
+        def count(counts):
+            for key, value in counts.items():
+                totals[key] = totals.get(key, 0) + value
+        
+        # Title rules apply to the parsed nodes, before anything is derived from the titles
+        titles = []
+        def collect_titles(nodes):
+            for node in nodes:
+                titles.append(node['title'])
+                collect_titles(node.get('children', []))
+        collect_titles(vlp_data['chapters'])
+        changed_titles = 0
+        for title in [vlp_data['name']] + titles:
+            after, counts = rules.apply_title(title)
+            if after == title:
+                continue
+            if not changed_titles:
+                print(f"\n{Colors.BOLD}Titles{Colors.ENDC}")
+            print(f"{Colors.FAIL}  -{title}{Colors.ENDC}")
+            print(f"{Colors.OKGREEN}  +{after}{Colors.ENDC}")
+            count(counts)
+            changed_titles += 1
         
-        # Find the actual content directory (may be nested)
-        content_xml = None
-        for root, dirs, files in os.walk(temp_dir):
-            if 'content.xml' in files:
-                content_xml = Path(root)
-                break
+        chapters = self.parser.flatten_structure(vlp_data)
+        changed_articles = 0
+        for chapter in chapters:
+            for article in chapter['articles']:
+                article_changed = False
+                for step in article['steps']:
+                    before = step.get('content', '')
+                    after, counts = rules.apply(before)
+                    if after == before:
+                        continue
+                    count(counts)
+                    
+                    if not article_changed:
+                        print(f"\n{Colors.BOLD}{chapter['title']} / {article['title']}{Colors.ENDC}")
+                        article_changed = True
+                    self._print_diff(before, after, step.get('title') or 'Introduction')
+                if article_changed:
+                    changed_articles += 1
+        
+        self.logger.header("Preview Complete")
+        if not totals:
+            self.logger.info("No rule matched any content")
+        scope_labels = {'body': 'bodies', 'title': 'titles', 'image_src': 'image sources'}
+        for rule in rules.rules:
+            per_scope = ', '.join(f"{totals.get((rule.name, scope), 0)} in {scope_labels[scope]}" for scope in rule.scopes)
+            self.logger.info(f"{rule.name}: {per_scope}")
+        self.logger.info(f"Titles that would change: {changed_titles}")
+        self.logger.info(f"Articles that would change: {changed_articles}")
+        self.logger.info("No output was written")
+        self.stats = {'changed_articles': changed_articles, 'changed_titles': changed_titles,
+                      'replacements': sum(totals.values())}
+        return changed_articles
+    
+    def print_structure(self, input_path: Path, include_steps: bool = False) -> int:
+        """Print the planned ScreenSteps structure without writing output
         
-        if content_xml and content_xml != temp_dir:
-            # Move contents up if nested
-            for item in content_xml.iterdir():
-                shutil.move(str(item), str(temp_dir / item.name))
+        Shows ScreenSteps positions next to the VLP OrderIndex values so that
+        ordering problems are visible before upload. Returns the number of
+        items with a duplicated or out-of-sequence VLP order.
+        """
+        self.logger.header("Planned ScreenSteps Structure")
+        self.logger.info(f"Input: {input_path}")
         
-        self.logger.substep(f"Extracted {len(list(temp_dir.rglob('*')))} files")
+        vlp_data = self._parse_input(input_path)
+        chapters = self.parser.flatten_structure(vlp_data)
+        manual = self.converter.convert(vlp_data, chapters, None, None)
+        
+        lines, flagged = format_structure(manual, include_steps)
+        print()
+        for line in lines:
+            print(f"{Colors.WARNING}{line}{Colors.ENDC}" if '   ! ' in line else line)
+        print()
+        
+        if flagged:
+            self.logger.warning(f"{flagged} item(s) have a duplicated or out-of-sequence VLP order")
+        self.logger.info("No output was written")
+        self.stats = {'chapters': len(chapters),
+                      'articles': sum(len(ch['articles']) for ch in chapters),
+                      'order_warnings': flagged}
+        return flagged
+    
+    def _parse_input(self, input_path: Path) -> Dict:
+        """Parse content.xml from a ZIP export (read in memory) or an extracted directory"""
+        if input_path.is_file():
+            with zipfile.ZipFile(input_path, 'r') as zip_ref:
+                members = [n for n in zip_ref.namelist() if n.endswith('content.xml')]
+                if not members:
+                    raise FileNotFoundError(f"content.xml not found in {input_path}")
+                with zip_ref.open(min(members, key=len)) as xml_file:
+                    return self.parser.parse_xml(xml_file)
+        xml_file = input_path / "content.xml"
+        if not xml_file.exists():
+            raise FileNotFoundError(f"content.xml not found in {input_path}")
+        return self.parser.parse_xml(xml_file)
+    
+    def _print_diff(self, before: str, after: str, label: str):
+        """Print a colored unified diff of two HTML fragments, one tag per line"""
+        def split(html):
+            return re.sub(r'>\s*<', '>\n<', html).splitlines()
+        
+        for line in difflib.unified_diff(split(before), split(after), 'before', 'after', n=1, lineterm=''):
+            if line.startswith('---') or line.startswith('+++'):
+                continue
+            if line.startswith('@@'):
+                print(f"{Colors.OKCYAN}  {label} {line}{Colors.ENDC}")
+            elif line.startswith('-'):
+                print(f"{Colors.FAIL}  {line}{Colors.ENDC}")
+            elif line.startswith('+'):
+                print(f"{Colors.OKGREEN}  {line}{Colors.ENDC}")
+            else:
+                print(f"  {line}")
+    
+    def _write_summary(self, output_path: Path, input_path: Path, manual: Dict,
+                       started_at: datetime, timings: Dict[str, float]):
+        """Write summary.json with counts, timings, warnings and per-article status"""
+        articles = []
+        for chapter in manual['manual']['chapters']:
+            for article in chapter['articles']:
+                steps = article.get('steps', [])
+                has_content = any((step.get('content') or '').strip() for step in steps)
+                articles.append({
+                    'id': article['id'],
+                    'vlp_id': article.get('vlp_id'),
+                    'title': article['title'],
+                    'chapter': chapter['title'],
+                    'steps': len(steps),
+                    'images': sum(len(step.get('images', [])) for step in steps),
+                    'status': 'converted' if has_content else 'empty'
+                })
+        
+        summary = {
+            'generator': build_info(),
+            'conversion': {
+                'input': str(input_path),
+                'manual_id': manual['manual']['id'],
+                'manual_title': manual['manual']['title'],
+                'schema_version': self.parser.schema_version,
+                'export_type': 'module' if self.parser.module_export else 'manual',
+                'started_at': started_at.isoformat(),
+                'finished_at': datetime.now().isoformat(),
+                'duration_seconds': round(sum(timings.values()), 2),
+                'timings': timings,
+                'counts': {
+                    'chapters': self.stats.get('chapters', 0),
+                    'articles': self.stats.get('articles', 0),
+                    'images': self.stats.get('images', 0),
+                    'empty_articles': sum(1 for a in articles if a['status'] == 'empty'),
+                    'warnings': len(self.logger.warnings),
+                    'fuzzy_image_matches': len(self.converter.fuzzy_image_matches),
+                    'iframes_embedded': sum(1 for i in self.parser.iframe_sources if i['action'] == 'embedded'),
+                    'iframes_linked': sum(1 for i in self.parser.iframe_sources if i['action'] == 'linked'),
+                    'media_embeds': sum(1 for m in self.parser.media_embeds if m['action'] != 'removed'),
+                    'media_removed': sum(1 for m in self.parser.media_embeds if m['action'] == 'removed'),
+                    'videos_copied': self.converter.videos_copied,
+                    'attachments_copied': self.converter.attachments_copied,
+                    'chapter_merges': len(self.parser.chapter_merges),
+                    'filtered': len(self.parser.filtered),
+                    'code_blocks': self.parser.code_blocks,
+                    'copy_widgets': self.parser.copy_widgets,
+                    'keyboard_keys': self.parser.keyboard_keys,
+                    'credentials_tables': self.parser.credentials_tables,
+                    'widgets': sum(1 for w in self.parser.widgets if w['action'] == 'converted'),
+                    'nested_steps': self.parser.nested_steps,
+                    'transformed_steps': self.parser.transformed_steps,
+                    'image_links': self.parser.image_links,
+                    'unresolved_links': len(self.parser.unresolved_links),
+                    'missing_alt_text': len(self.parser.missing_alt_text),
+                    'stale_images': len(self.converter.stale_images),
+                    'rejected_images': len(self.converter.rejected_images),
+                    'rasterized_images': self.converter.rasterized_images,
+                    'annotated_images': sum(1 for a in self.converter.annotations if a['action'] == 'burned'),
+                    'findings': len(self.parser.findings),
+                },
+                'warnings': self.logger.warnings,
+                'fuzzy_image_matches': self.converter.fuzzy_image_matches,
+                'iframes': self.parser.iframe_sources,
+                'media': self.parser.media_embeds,
+                'widgets': self.parser.widgets,
+                'chapter_merges': self.parser.chapter_merges,
+                'filtered': self.parser.filtered,
+                'intro_node': self.parser.intro_node,
+                'unresolved_links': self.parser.unresolved_links,
+                'missing_alt_text': self.parser.missing_alt_text,
+                'stale_images': self.converter.stale_images,
+                'rejected_images': self.converter.rejected_images,
+                'annotations': self.converter.annotations,
+                'findings': self.parser.findings,
+                'articles': articles,
+            }
+        }
+        summary_file = output_path / SUMMARY_FILE
+        with open(summary_file, 'w', encoding='utf-8') as f:
+            json.dump(summary, f, indent=2, ensure_ascii=False)
+        self.logger.substep(f"Wrote summary report: {summary_file}")
+    
+    def _write_qa_report(self, output_path: Path, manual: Dict):
+        """Write the HTML QA report for reviewers (unless --no-qa-report)"""
+        if not self.options.get('qa_report', True):
+            return
+        report_file = write_qa_report(output_path, manual)
+        if report_file:
+            self.logger.substep(f"Wrote QA report: {report_file}")
+    
+    def _track_artifact(self, kind: str, path: Path, source: Path):
+        """Record a directory this run creates, so vlp2ss_clean.py can find it if the run is interrupted"""
+        error = register_artifact(kind, path, source)
+        if error:
+            self.logger.warning(f"Could not record {kind} directory {path} in the artifact state: {error}")
+    
+    def _finish_artifact(self, path: Path, removed: bool = False):
+        """Mark a recorded directory as finished (or forget it once removed)"""
+        error = finish_artifact(path, removed=removed)
+        if error:
+            self.logger.warning(f"Could not update the artifact state for {path}: {error}")
+    
+    def _open_archive(self, zip_path: Path) -> ExportArchive:
+        """Open a ZIP export to read in place (--in-memory)"""
+        archive = ExportArchive(zip_path, max_total_size=self.options.get('max_extract_size', DEFAULT_MAX_TOTAL_SIZE))
+        for skipped in archive.skipped:
+            self.logger.warning(f"Skipped ZIP entry {skipped['name']} ({skipped['reason']})")
+        if not archive.exists("content.xml"):
+            archive.close()
+            raise FileNotFoundError(f"content.xml not found in {zip_path}")
+        return archive
+    
+    def _extract_zip(self, zip_path: Path) -> Path:
+        """Extract ZIP file to a temporary directory of its own"""
+        # One directory per run, so concurrent or repeated runs of the same ZIP never share files
+        temp_root = Path(self.options.get('temp_dir') or DEFAULT_TEMP_ROOT)
+        temp_root.mkdir(parents=True, exist_ok=True)
+        temp_dir = Path(tempfile.mkdtemp(prefix=f"{zip_path.stem}-", dir=temp_root))
+        self._track_artifact('temp', temp_dir, zip_path)
+        
+        self.logger.substep(f"Extracting to: {temp_dir}")
+        
+        # The directory holding content.xml (may be nested) becomes the top level
+        try:
+            extracted = safe_extract(zip_path, temp_dir,
+                                     max_total_size=self.options.get('max_extract_size', DEFAULT_MAX_TOTAL_SIZE))
+        except ExtractionError:
+            shutil.rmtree(temp_dir, ignore_errors=True)
+            self._finish_artifact(temp_dir, removed=True)
+            raise
+        for skipped in extracted.skipped:
+            self.logger.warning(f"Skipped ZIP entry {skipped['name']} ({skipped['reason']})")
+        
+        self.logger.substep(f"Extracted {extracted.files} files")
         
         return temp_dir
 
+def parse_media_provider(value: str) -> Tuple[str, str]:
+    """argparse type for NAME=https://host/embed/{id} media provider templates"""
+    name, _, template = value.partition('=')
+    if not name.strip() or not template.startswith('https://') or '{id}' not in template:
+        raise argparse.ArgumentTypeError(f"invalid media provider '{value}' (expected NAME=https://.../{{id}})")
+    return name.strip().lower(), template
+
+def parse_date(value: str) -> datetime:
+    """argparse type for YYYY-MM-DD dates"""
+    try:
+        return datetime.strptime(value, '%Y-%m-%d')
+    except ValueError:
+        raise argparse.ArgumentTypeError(f"invalid date '{value}' (expected YYYY-MM-DD)")
+
+def load_icon_map(path: Path) -> Dict[str, str]:
+    """Load a JSON icon map and merge it over the built-in defaults"""
+    with open(path, 'r', encoding='utf-8') as f:
+        custom_map = json.load(f)
+    if not isinstance(custom_map, dict):
+        raise ValueError(f"Icon map must be a JSON object of class -> replacement: {path}")
+    
+    icon_map = dict(DEFAULT_ICON_MAP)
+    icon_map.update({str(k): str(v) for k, v in custom_map.items()})
+    return icon_map
+
+def check_class_map(class_map, source: str) -> Dict[str, Dict[str, str]]:
+    """Validate a class map with 'span' and/or 'paragraph' sections and normalize its keys and values"""
+    if not isinstance(class_map, dict):
+        raise ConfigError(f"Class map {source} must have 'span' and/or 'paragraph' sections")
+    unknown = set(class_map) - {'span', 'paragraph'}
+    if unknown:
+        raise ConfigError(f"Class map {source} has unknown sections: {', '.join(sorted(unknown))} (expected span, paragraph)")
+    for section, mapping in class_map.items():
+        if not isinstance(mapping, dict):
+            raise ConfigError(f"Class map section '{section}' in {source} must be a mapping of VLP class -> formatting")
+    return {section: {str(k): str(v if v is not None else CLASS_MAP_NONE) for k, v in mapping.items()}
+            for section, mapping in class_map.items()}
+
+def load_class_map(path: Path) -> Dict[str, Dict[str, str]]:
+    """Load a YAML/JSON class map with 'span' and/or 'paragraph' sections (same format as a profile's class_map)"""
+    return check_class_map(load_config(path), str(path))
+
+def merge_class_maps(*class_maps: Dict[str, Dict[str, str]]) -> Dict[str, Dict[str, str]]:
+    """The built-in span/paragraph maps extended by each class map in turn
+    
+    Later maps win for the same class; mapping a class to 'none' (or null)
+    removes it, including a built-in mapping.
+    """
+    merged = {'span': dict(DEFAULT_SPAN_CLASS_MAP), 'paragraph': dict(DEFAULT_PARAGRAPH_STYLE_MAP)}
+    for class_map in class_maps:
+        for section, mapping in class_map.items():
+            for vlp_class, formatting in mapping.items():
+                if formatting.lower() == CLASS_MAP_NONE:
+                    merged[section].pop(vlp_class, None)
+                else:
+                    merged[section][vlp_class] = formatting
+    return merged
+
 def print_usage_examples():
     """Print detailed usage examples"""
     examples = """
@@ -1082,6 +3428,12 @@ def print_usage_examples():
        python vlp_converter.py -i "$file" -o output/
    done
 
+6. Preview what a replacement rules file would change (writes nothing):
+   python vlp_converter.py -i input.zip --rules rules.yaml --preview-replace
+
+7. Check chapter/article ordering before converting (add -v to list steps):
+   python vlp_converter.py -i input.zip --print-structure
+
 ╔══════════════════════════════════════════════════════════════════════════╗
 ║                         OUTPUT STRUCTURE                                 ║
 ╚══════════════════════════════════════════════════════════════════════════╝
@@ -1126,12 +3478,134 @@ def main():
                        help='Output directory (default: output)')
     parser.add_argument('-v', '--verbose', action='store_true',
                        help='Enable verbose logging')
+    parser.add_argument('--lang', type=lambda value: [code.strip() for code in value.split(',') if code.strip()],
+                       metavar='CODE[,CODE...]',
+                       help='Convert these languages of a multi-language export, each into <output>/<manual>/<CODE>/ '
+                            f'with a {LANGUAGES_FILE} index (default: only the first localization of each node)')
+    parser.add_argument('--all-languages', action='store_true',
+                       help='Like --lang, for every language present in the export')
     parser.add_argument('--no-cleanup', action='store_true',
                        help='Keep temporary files after conversion')
-    parser.add_argument('--version', action='version',
-                       version=f'vlp2ss-py v{APP_VERSION}')
+    parser.add_argument('--temp-dir', type=Path, default=DEFAULT_TEMP_ROOT, metavar='DIR',
+                       help=f'Parent directory of the per-run ZIP extraction directories (default: {DEFAULT_TEMP_ROOT})')
+    parser.add_argument('--no-clean-output', action='store_true',
+                       help='Keep what is already in the output directory instead of emptying it first')
+    parser.add_argument('--force', action='store_true',
+                       help='Empty the output directory even if it was not created by the converter')
+    parser.add_argument('--in-memory', action='store_true',
+                       help='Read ZIP exports in place instead of extracting them to temp/ '
+                            '(for read-only working directories)')
+    parser.add_argument('--max-extract-size', type=int, default=DEFAULT_MAX_TOTAL_SIZE // 1024 ** 2, metavar='MB',
+                       help=f'Refuse ZIP exports that expand to more than MB megabytes '
+                            f'(default: {DEFAULT_MAX_TOTAL_SIZE // 1024 ** 2})')
+    parser.add_argument('--link-target', type=str, default='_blank',
+                       help='target attribute for external links (default: _blank, "" to remove)')
+    parser.add_argument('--link-rel', type=str, default='noopener noreferrer',
+                       help='rel attribute for external links (default: "noopener noreferrer", "" to remove)')
+    parser.add_argument('--no-link-policy', action='store_true',
+                       help='Keep link target/rel attributes exactly as exported by VLP')
+    parser.add_argument('--iframe-allow', action='append', default=[], metavar='DOMAIN',
+                       help='Keep iframes from DOMAIN (and its subdomains) as embeds; others become links '
+                            f'(repeatable, "*" allows all; always allowed: {", ".join(DEFAULT_IFRAME_ALLOWLIST)})')
+    parser.add_argument('--media-provider', type=parse_media_provider, action='append', default=[], metavar='NAME=TEMPLATE',
+                       help='Embed URL template for VLP media tags of class NAME-thumb, with {id} for the media ID '
+                            f'(repeatable; built in: {", ".join(DEFAULT_MEDIA_PROVIDERS)})')
+    parser.add_argument('--duration-template', type=str, default=DEFAULT_DURATION_TEMPLATE,
+                       help='Intro block text for chapters/articles with an estimated duration '
+                            '({duration}, {minutes}, {title}; "" disables)')
+    parser.add_argument('--merge-singleton-chapters', action='store_true',
+                       help='Fold chapters that contain exactly one article into --singleton-parent, or rename them after their article')
+    parser.add_argument('--singleton-parent', type=str, metavar='TITLE',
+                       help='Chapter that receives the articles of single-article chapters (created if missing)')
+    parser.add_argument('--append-orphan-images', action='store_true',
+                       help='Append images listed in the XML but never used in the content to the end of their step')
+    parser.add_argument('--orphan-caption', type=str, default='Additional screenshot: {name}',
+                       help='Caption template for appended orphan images ({name}, {filename}, {step}, {article})')
+    parser.add_argument('--chapter-depth', type=int, default=1, metavar='LEVEL',
+                       help='VLP level whose nodes become chapters (default: 1; e.g. 2 for manuals grouped into modules)')
+    parser.add_argument('--article-depth', type=int, metavar='LEVEL',
+                       help='VLP level whose nodes become articles (default: chapter depth + 1; equal to the chapter '
+                            'depth makes each chapter a single article); the level below becomes steps')
+    parser.add_argument('--intro-node', choices=['keep', 'article', 'skip'], default='keep',
+                       help='First top-level node without children (lab intro, copyright page): keep it as a chapter (default), '
+                            'make it the first article of the next chapter (article), or leave it out (skip)')
+    parser.add_argument('--stable-anchors', action='store_true',
+                       help='Use VLP node IDs instead of slugified step titles as step anchors, so existing deep links keep working')
+    parser.add_argument('--missing-alt', choices=['ignore', 'warn', 'fail'], default='ignore',
+                       help='Images without alt text (after title/caption fallback): only list them in the reports (ignore), '
+                            'also log warnings (warn), or fail the run (fail)')
+    parser.add_argument('--strict', action='store_true',
+                       help='Fail on export issues the conversion otherwise works around (unknown XML elements, missing '
+                            'localizations, unbalanced spans, images without a filename), naming the node path; '
+                            f'by default they are listed under findings in {SUMMARY_FILE}')
+    parser.add_argument('--credentials-block', choices=['styled', 'foldable', 'article', 'keep'], default='styled',
+                       help='Lab credentials tables: a styled Lab Credentials block (default), a folded one, '
+                            'a restricted Lab Credentials article, or keep the table as is')
+    parser.add_argument('--widgets', choices=['convert', 'alert', 'keep'], default='convert',
+                       help='Knowledge-check and checklist widgets: numbered lists with folded answers (default), '
+                            'alert blocks holding their text for authors to rebuild, or keep the markup as is')
+    parser.add_argument('--overview-article', nargs='?', const=OVERVIEW_TITLE, metavar='TITLE',
+                       help=f'Add a first chapter with an article linking to every chapter and article, '
+                            f'like the VLP navigation page (title: {OVERVIEW_TITLE} unless given)')
+    parser.add_argument('--no-code-blocks', action='store_true',
+                       help='Keep <pre> sections and command paragraphs as plain text instead of copyable code blocks')
+    parser.add_argument('--no-flatten-layout', action='store_true',
+                       help='Keep VLP layout markup (columns, absolute positioning, floats) as exported')
+    parser.add_argument('--no-qa-report', action='store_true',
+                       help='Do not write qa_report.html (HTML review report) into the output directory')
+    parser.add_argument('--annotations', choices=['burn', 'keep', 'drop'], default='burn',
+                       help='Screenshot callouts (numbered markers, boxes, arrows) overlaid in VLP: draw them into a copy '
+                            'of the image (default, needs Pillow), keep them as data-annotations JSON on the image, '
+                            'or drop them')
+    parser.add_argument('--stale-before', type=parse_date, metavar='YYYY-MM-DD',
+                       help='Flag screenshots whose file date in the export is older than this date in the reports')
+    parser.add_argument('--image-ignore', action='append', default=[], metavar='PATTERN',
+                       help='Ignore images matching PATTERN when indexing the export (e.g. "thumbnails/", "*_small.png"; repeatable)')
+    parser.add_argument('--gif-max-size', type=float, metavar='MB',
+                       help='Leave out GIFs larger than MB megabytes (default: 10, or the profile image_formats; 0 = no limit)')
+    parser.add_argument('--rasterize-svg', type=int, metavar='WIDTH',
+                       help='Convert SVG images to PNGs WIDTH pixels wide (needs CairoSVG; 0 keeps SVGs)')
+    parser.add_argument('--class-map', type=str, metavar='FILE|URL',
+                       help='YAML/JSON file or HTTPS URL mapping VLP span/paragraph classes to formatting (overrides the profile class_map)')
+    parser.add_argument('--icon-map', type=str, metavar='FILE|URL',
+                       help='JSON file or HTTPS URL mapping font-icon classes to emoji/text, inline SVG, or image URLs')
+    parser.add_argument('--rules', type=str, metavar='FILE|URL',
+                       help='YAML/JSON file or HTTPS URL of ordered regex find/replace rules applied to converted HTML, '
+                            'titles or image sources (per-rule scope)')
+    parser.add_argument('--transform', action='append', default=[], metavar='SPEC',
+                       help='Hook rewriting each step\'s HTML before output: file.py:function, module:function, or '
+                            'exec:COMMAND (HTML on stdin and stdout); repeatable, run in order')
+    parser.add_argument('--preview-replace', action='store_true',
+                       help='Show a colored diff of what --rules would change, without writing output')
+    parser.add_argument('--print-structure', action='store_true',
+                       help='Print the planned ScreenSteps structure with VLP order values (-v adds steps) and exit without writing output')
+    parser.add_argument('--notify-url', type=str, default=os.environ.get('VLP2SS_NOTIFY_URL'), metavar='URL',
+                       help='POST the run result (status, counts, output path) to this webhook when done (or VLP2SS_NOTIFY_URL env var)')
+    parser.add_argument('--email-report', type=str, default=os.environ.get('VLP2SS_EMAIL_REPORT'), metavar='ADDRESS',
+                       help='Email an HTML report of the run to these comma-separated addresses when done, using the '
+                            'profile smtp settings (or VLP2SS_EMAIL_REPORT env var)')
+    parser.add_argument('--metrics-file', type=str, default=str(DEFAULT_METRICS_FILE), metavar='FILE',
+                       help='History file that run metrics are appended to (see vlp2ss_metrics.py; or VLP2SS_METRICS_FILE env var)')
+    parser.add_argument('--no-metrics', action='store_true',
+                       help='Do not record this run in the metrics history')
+    parser.add_argument('--log-dir', type=str, default=DEFAULT_LOG_DIR, metavar='DIR',
+                       help='Directory for log files (default: logs, or VLP2SS_LOG_DIR env var)')
+    parser.add_argument('--log-keep', type=int, default=DEFAULT_LOG_KEEP, metavar='N',
+                       help=f'Previous converter logs to keep in --log-dir; older ones are deleted (default: {DEFAULT_LOG_KEEP}, 0 keeps all)')
+    parser.add_argument('-q', '--quiet', action='store_true',
+                       help='Suppress headers and progress; print only a final JSON result line (errors go to stderr)')
+    parser.add_argument('--version', action=VersionAction,
+                       tool_name='VLP2SS - The VLP to ScreenSteps Converter')
     parser.add_argument('--examples', action='store_true',
                        help='Show detailed usage examples')
+    add_filter_arguments(parser)
+    add_config_arguments(parser)
+    
+    try:
+        profile_name, profile = apply_profile(parser)
+    except ConfigError as e:
+        print(f"{Colors.FAIL}Error: {e}{Colors.ENDC}")
+        return 1
     
     args = parser.parse_args()
     
@@ -1145,6 +3619,49 @@ def main():
         print_usage_examples()
         return 0
     
+    result = {}
+    if args.quiet:
+        # --quiet: silence all decorative output and print one JSON result line
+        with open(os.devnull, 'w') as devnull, contextlib.redirect_stdout(devnull):
+            exit_code = run_conversion(args, profile_name, profile, result)
+    else:
+        exit_code = run_conversion(args, profile_name, profile, result)
+    
+    run_result = {'status': exit_status(exit_code), 'exit_code': exit_code, **result,
+                  'version': APP_VERSION, 'git_sha': build_info()['git_sha']}
+    if args.notify_url:
+        error = notify_webhook(args.notify_url, 'conversion', run_result)
+        if error:
+            print(f"Warning: webhook notification failed: {error}", file=sys.stderr)
+    if args.email_report:
+        recipients = [address.strip() for address in args.email_report.split(',') if address.strip()]
+        error = email_report(recipients, 'conversion', run_result, smtp_settings(profile),
+                             report_dir=Path(result.get('output')) if result.get('output') else None)
+        if error:
+            print(f"Warning: email report failed: {error}", file=sys.stderr)
+    if not (args.no_metrics or args.print_structure or args.preview_replace):
+        error = record_run('conversion', run_result, Path(args.metrics_file))
+        if error:
+            print(f"Warning: could not record run metrics: {error}", file=sys.stderr)
+    
+    if args.quiet:
+        if result.get('error'):
+            print(f"Error: {result['error']}", file=sys.stderr)
+        print(json.dumps(run_result))
+    return exit_code
+
+def report_error(result: Dict, message: str, exit_code: int = EXIT_ERROR) -> int:
+    """Print an error, record it for the --quiet result line, and return the exit code"""
+    result['error'] = message
+    print(f"{Colors.FAIL}Error: {message}{Colors.ENDC}")
+    return exit_code
+
+def run_conversion(args, profile_name: Optional[str], profile: Dict, result: Dict) -> int:
+    """Run the conversion (or rules preview) selected on the command line
+    
+    Fills result with the fields of the --quiet result line and returns the
+    exit code.
+    """
     # --- Print Header ---
     print(f"{Colors.BOLD}{Colors.HEADER}{'='*70}{Colors.ENDC}")
     print(f"{Colors.BOLD}{Colors.HEADER}{'VLP to ScreenSteps Converter'.center(70)}{Colors.ENDC}")
@@ -1158,43 +3675,113 @@ def main():
         output_dir = Path(args.output)
         
         if not input_path.exists():
-            print(f"{Colors.FAIL}Error: Input path does not exist: {input_path}{Colors.ENDC}")
-            return 1
-        
-        # Clean logs and output directories at startup
-        logs_dir = Path("logs")
-        if logs_dir.exists():
-            shutil.rmtree(logs_dir)
-        logs_dir.mkdir(exist_ok=True)
-        
-        if output_dir.exists():
-            shutil.rmtree(output_dir)
-        output_dir.mkdir(parents=True, exist_ok=True)
-        
-        converter = VLPToScreenStepsConverter(verbose=args.verbose)
+            return report_error(result, f"Input path does not exist: {input_path}")
+        
+        # --class-map, --icon-map and --rules may name HTTPS URLs (cached, optionally pinned)
+        def warn(message: str):
+            print(f"{Colors.WARNING}⚠ {message}{Colors.ENDC}")
+        rules = load_rules(fetch_file(args.rules, warn=warn)) if args.rules else None
+        transforms = load_transforms(args.transform)
+        class_map = merge_class_maps(
+            check_class_map(profile.get('class_map') or {}, f"of profile {profile_name}"),
+            load_class_map(fetch_file(args.class_map, warn=warn)) if args.class_map else {})
+        
+        if args.preview_replace and not rules:
+            return report_error(result, "--preview-replace requires --rules")
+        if args.all_languages:
+            args.lang = ['all']
+        if args.chapter_depth < 1 or (args.article_depth is not None and args.article_depth < args.chapter_depth):
+            return report_error(result, "--chapter-depth must be at least 1 and --article-depth at least the chapter depth")
+        
+        # Clean the output directory at startup, if a converter created it (logs are rotated, never wiped)
+        if not (args.preview_replace or args.print_structure):
+            prepare_output_dir(output_dir, clean=not args.no_clean_output, force=args.force,
+                               protected=[input_path, args.temp_dir, args.log_dir])
+        
+        options = {
+            'link_policy': not args.no_link_policy,
+            'link_target': args.link_target,
+            'link_rel': args.link_rel,
+            'iframe_allow': DEFAULT_IFRAME_ALLOWLIST + tuple(d.lower().lstrip('.') for d in args.iframe_allow),
+            'media_providers': {**DEFAULT_MEDIA_PROVIDERS, **dict(args.media_provider)},
+            'icon_map': load_icon_map(fetch_file(args.icon_map, warn=warn)) if args.icon_map else DEFAULT_ICON_MAP,
+            'duration_template': args.duration_template,
+            'append_orphan_images': args.append_orphan_images,
+            'merge_singleton_chapters': args.merge_singleton_chapters,
+            'singleton_parent': args.singleton_parent,
+            'orphan_caption': args.orphan_caption,
+            'flatten_layout': not args.no_flatten_layout,
+            'code_blocks': not args.no_code_blocks,
+            'credentials_block': args.credentials_block,
+            'widgets': args.widgets,
+            # A profile can set overview_article: true for the default title
+            'overview_article': OVERVIEW_TITLE if args.overview_article is True else args.overview_article,
+            'stable_anchors': args.stable_anchors,
+            'chapter_depth': args.chapter_depth,
+            'intro_node': args.intro_node,
+            'article_depth': args.article_depth,
+            'missing_alt': args.missing_alt,
+            'stale_before': args.stale_before,
+            'image_ignore': args.image_ignore,
+            'image_formats': load_format_policies(profile.get('image_formats'), args.gif_max_size, args.rasterize_svg),
+            'qa_report': not args.no_qa_report,
+            'annotations': args.annotations,
+            'strict': args.strict,
+            'span_class_map': class_map['span'],
+            'paragraph_style_map': class_map['paragraph'],
+            # The preview converts without the rules so it can diff their effect
+            'rules': None if args.preview_replace else rules,
+            'transforms': transforms,
+            'content_filter': filter_from_args(args),
+            'max_extract_size': args.max_extract_size * 1024 ** 2,
+            'in_memory': args.in_memory,
+            'temp_dir': args.temp_dir,
+            'quiet': args.quiet,
+            'log_dir': args.log_dir,
+            'log_keep': args.log_keep,
+        }
         
-        if input_path.is_file() and input_path.suffix == '.zip':
-            converter.convert_zip(input_path, output_dir, 
-                                 cleanup=not args.no_cleanup)
+        if profile_name:
+            print(f"{Colors.OKCYAN}ℹ Using profile: {profile_name}{Colors.ENDC}")
+        
+        converter = VLPToScreenStepsConverter(verbose=args.verbose, options=options)
+        
+        if args.print_structure:
+            converter.print_structure(input_path, include_steps=args.verbose)
+        elif args.preview_replace:
+            converter.preview_replace(input_path, rules)
+        elif args.lang and (input_path.is_dir() or input_path.suffix == '.zip'):
+            result['output'] = str(converter.convert_languages(input_path, output_dir, args.lang,
+                                                               cleanup=not args.no_cleanup))
+        elif input_path.is_file() and input_path.suffix == '.zip':
+            result['output'] = str(converter.convert_zip(input_path, output_dir, 
+                                                         cleanup=not args.no_cleanup))
         elif input_path.is_dir():
-            converter.convert_directory(input_path, output_dir)
+            result['output'] = str(converter.convert_directory(input_path, output_dir))
         else:
-            print(f"{Colors.FAIL}Error: Input must be a ZIP file or directory{Colors.ENDC}")
-            return 1
+            return report_error(result, "Input must be a ZIP file or directory")
         
         elapsed = time.time() - start_time
+        result.update(converter.stats)
+        exit_code = result_exit_code(converter.stats)
+        if args.missing_alt == 'fail' and converter.stats.get('missing_alt_text'):
+            exit_code = report_error(result, f"{converter.stats['missing_alt_text']} images have no alt text "
+                                             f"(--missing-alt fail, listed in {SUMMARY_FILE})")
+        result['elapsed_seconds'] = round(elapsed, 1)
         minutes, seconds = divmod(int(elapsed), 60)
         if minutes > 0:
             print(f"{Colors.OKCYAN}ℹ Total execution time: {minutes}m {seconds}s{Colors.ENDC}")
         else:
             print(f"{Colors.OKCYAN}ℹ Total execution time: {seconds}s{Colors.ENDC}")
         
-        return 0
+        return exit_code
         
+    except (RulesError, ConfigError, RemoteFileError, ImageFormatError, TransformError, FilterError,
+            ExtractionError, OutputDirError, StrictModeError, SchemaVersionError) as e:
+        return report_error(result, str(e))
     except Exception as e:
-        print(f"{Colors.FAIL}Error: {e}{Colors.ENDC}")
         logging.exception("Conversion failed")
-        return 1
+        return report_error(result, str(e))
 
 if __name__ == "__main__":
     sys.exit(main())
@@ -0,0 +1 @@
+Write-Host "VLP2SS sample attachment"
@@ -0,0 +1,81 @@
+"""Cassette tests: recording exchanges and playing them back without a network"""
+import io
+import json
+import sys
+from pathlib import Path
+
+import pytest
+
+requests = pytest.importorskip('requests')
+
+sys.path.insert(0, str(Path(__file__).resolve().parent.parent / 'python'))
+
+from requests.structures import CaseInsensitiveDict  # noqa: E402
+from vlp2ss_cassette import SITE_PLACEHOLDER, Cassette, CassetteError, recorded_request  # noqa: E402
+
+SITE_URL = 'https://acme.screenstepslive.com/api/v2'
+
+
+def response(status, body, content_type='application/json', **headers):
+    sent = requests.Response()
+    sent.status_code = status
+    sent.headers = CaseInsensitiveDict(dict(headers, **{'Content-Type': content_type}))
+    sent._content = body
+    return sent
+
+
+def record(path, exchanges):
+    """Record (method, url, kwargs, response) exchanges through a cassette's transport"""
+    cassette = Cassette(path, 'record')
+    pending = [exchange[3] for exchange in exchanges]
+    transport = cassette.wrap(lambda method, url, **kwargs: pending.pop(0), SITE_URL)
+    for method, url, kwargs, _ in exchanges:
+        transport(method, url, **kwargs)
+    return cassette
+
+
+def test_recorded_request_hides_site_and_files():
+    request = recorded_request('post', f'{SITE_URL}/sites/1/files', {
+        'params': {'page': 2}, 'files': {'file': ('shot.png', io.BytesIO(b'png'), 'image/png')},
+        'auth': ('user', 'token')}, SITE_URL)
+    assert request['method'] == 'POST'
+    assert request['url'] == f'{SITE_PLACEHOLDER}/sites/1/files'
+    assert request['params'] == {'page': '2'}
+    assert request['files']['file']['size'] == 3
+    assert 'token' not in json.dumps(request)
+
+
+def test_replay_answers_in_recorded_order(tmp_path):
+    path = tmp_path / 'run.cassette'
+    record(path, [
+        ('GET', f'{SITE_URL}/sites', {}, response(429, b'', **{'Retry-After': '1'})),
+        ('GET', f'{SITE_URL}/sites', {}, response(200, b'{"sites": []}')),
+        ('GET', f'{SITE_URL}/files/1', {}, response(200, b'\x89PNG\xff', 'image/png')),
+    ])
+    cassette = Cassette(path, 'replay')
+    # Another account's site URL plays back the same cassette
+    transport = cassette.wrap(None, 'https://other.screenstepslive.com/api/v2')
+    first = transport('GET', 'https://other.screenstepslive.com/api/v2/sites')
+    assert (first.status_code, first.headers['Retry-After']) == (429, '1')
+    assert transport('GET', 'https://other.screenstepslive.com/api/v2/sites').json() == {'sites': []}
+    assert cassette.unplayed == 1
+    assert transport('GET', 'https://other.screenstepslive.com/api/v2/files/1').content == b'\x89PNG\xff'
+    with pytest.raises(CassetteError):
+        transport('GET', 'https://other.screenstepslive.com/api/v2/sites')
+
+
+def test_replay_matches_query_parameters(tmp_path):
+    path = tmp_path / 'run.cassette'
+    record(path, [('GET', f'{SITE_URL}/sites', {'params': {'page': 1}}, response(200, b'{"page": 1}'))])
+    transport = Cassette(path, 'replay').wrap(None, SITE_URL)
+    with pytest.raises(CassetteError):
+        transport('GET', f'{SITE_URL}/sites', params={'page': 2})
+    assert transport('GET', f'{SITE_URL}/sites', params={'page': 1}).json() == {'page': 1}
+
+
+@pytest.mark.parametrize('text', ['', '{"cassette": 99}\n', '{"cassette": 1}\nnot json\n', '{"cassette": 1}\n{}\n'])
+def test_invalid_cassette_is_an_error(tmp_path, text):
+    path = tmp_path / 'run.cassette'
+    path.write_text(text, encoding='utf-8')
+    with pytest.raises(CassetteError):
+        Cassette(path, 'replay')
@@ -0,0 +1,83 @@
+"""Export ZIP extraction tests: unsafe member names, symlinks and size limits"""
+import stat
+import sys
+import zipfile
+from pathlib import Path
+
+import pytest
+
+sys.path.insert(0, str(Path(__file__).resolve().parent.parent / 'python'))
+
+from vlp2ss_extract import ExportArchive, ExtractionError, normalize_member, safe_extract  # noqa: E402
+
+
+def make_zip(path, members):
+    """Write a ZIP of (name, data) members; a data of ('symlink', target) archives a symlink"""
+    with zipfile.ZipFile(path, 'w', zipfile.ZIP_DEFLATED) as archive:
+        for name, data in members:
+            if isinstance(data, tuple):
+                info = zipfile.ZipInfo(name)
+                info.external_attr = (stat.S_IFLNK | 0o777) << 16
+                archive.writestr(info, data[1])
+            else:
+                archive.writestr(name, data)
+    return path
+
+
+@pytest.mark.parametrize('name, expected', [
+    ('export/content.xml', 'export/content.xml'),
+    ('export\\images\\a.png', 'export/images/a.png'),
+    ('./export//a.png', 'export/a.png'),
+    ('../evil.txt', None),
+    ('export/../../evil.txt', None),
+    ('/etc/passwd', None),
+    ('C:\\evil.txt', None),
+])
+def test_normalize_member_refuses_paths_outside_target(name, expected):
+    assert normalize_member(name) == expected
+
+
+def test_extract_moves_content_root_up_and_skips_traversal(tmp_path):
+    zip_path = make_zip(tmp_path / 'lab.zip', [('export/content.xml', '<xml/>'), ('export/images/a.png', 'png'),
+                                               ('../evil.txt', 'x'), ('/abs.txt', 'x')])
+    target = tmp_path / 'out'
+    result = safe_extract(zip_path, target)
+    assert (result.root, result.files) == ('export', 2)
+    assert (target / 'content.xml').read_text() == '<xml/>'
+    assert (target / 'images' / 'a.png').exists()
+    assert not (tmp_path / 'evil.txt').exists()
+    assert [(item['name'], item['reason']) for item in result.skipped] == \
+        [('../evil.txt', 'outside target directory'), ('/abs.txt', 'outside target directory')]
+
+
+def test_extract_skips_symlinks(tmp_path):
+    zip_path = make_zip(tmp_path / 'lab.zip', [('content.xml', '<xml/>'), ('images', ('symlink', '/etc'))])
+    target = tmp_path / 'out'
+    result = safe_extract(zip_path, target)
+    assert not (target / 'images').exists()
+    assert result.skipped == [{'name': 'images', 'reason': 'symlink'}]
+
+
+def test_extract_refuses_highly_compressed_members(tmp_path):
+    zip_path = make_zip(tmp_path / 'bomb.zip', [('content.xml', '<xml/>'), ('zeros.bin', b'\0' * (4 * 1024 ** 2))])
+    with pytest.raises(ExtractionError, match='possible zip bomb'):
+        safe_extract(zip_path, tmp_path / 'out', max_ratio=10)
+    assert not (tmp_path / 'out' / 'zeros.bin').exists()
+
+
+def test_extract_refuses_archives_over_size_and_file_limits(tmp_path):
+    zip_path = make_zip(tmp_path / 'lab.zip', [('content.xml', '<xml/>'), ('a.txt', 'a' * 100)])
+    with pytest.raises(ExtractionError):
+        safe_extract(zip_path, tmp_path / 'out', max_total_size=50)
+    with pytest.raises(ExtractionError):
+        safe_extract(zip_path, tmp_path / 'out', max_files=1)
+
+
+def test_export_archive_applies_the_same_rules(tmp_path):
+    zip_path = make_zip(tmp_path / 'lab.zip', [('export/content.xml', '<xml/>'), ('export/../evil.txt', 'x'),
+                                               ('export/link', ('symlink', '/etc/passwd'))])
+    with ExportArchive(zip_path) as archive:
+        assert archive.exists('content.xml')
+        assert not archive.exists('link')
+        assert archive.open('content.xml').read() == b'<xml/>'
+        assert [item['reason'] for item in archive.skipped] == ['outside target directory', 'symlink']
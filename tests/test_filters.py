@@ -0,0 +1,68 @@
+"""Content filter tests: include/exclude patterns and article selection (--only, --start-from)"""
+import sys
+from pathlib import Path
+
+import pytest
+
+sys.path.insert(0, str(Path(__file__).resolve().parent.parent / 'python'))
+
+from vlp2ss_filters import ContentFilter, FilterError, compile_pattern, select_articles  # noqa: E402
+
+
+def chapters():
+    return [
+        {'id': 'ch1', 'title': 'Introduction', 'articles': [
+            {'id': 'a1', 'vlp_id': 'vlp-1', 'title': 'Welcome', 'position': 1},
+            {'id': 'a2', 'vlp_id': 'vlp-2', 'title': 'Lab Setup', 'position': 2}]},
+        {'id': 'ch2', 'title': 'Module 1', 'articles': [
+            {'id': 'a3', 'vlp_id': 'vlp-3', 'title': 'Deploy', 'position': 1},
+            {'id': 'a4', 'vlp_id': 'vlp-4', 'title': 'Appendix: Notes', 'position': 2}]},
+    ]
+
+
+def test_glob_is_case_insensitive_and_regex_is_searched():
+    assert compile_pattern('intro*')('Introduction')
+    assert not compile_pattern('intro')('Introduction')
+    assert compile_pattern('re:^Mod')('Module 1')
+    assert not compile_pattern('re:^mod')('Module 1')
+
+
+def test_invalid_regex_is_an_error():
+    with pytest.raises(FilterError):
+        compile_pattern('re:(')
+
+
+def test_empty_filter_is_false():
+    assert not ContentFilter()
+    assert ContentFilter(exclude_articles=['x'])
+
+
+def test_exclude_wins_over_include_and_positions_are_renumbered():
+    kept, dropped = ContentFilter(include_articles=['*'], exclude_articles=['Welcome', 'vlp-4']).apply(chapters())
+    assert [[(a['id'], a['position']) for a in chapter['articles']] for chapter in kept] == \
+        [[('a2', 1)], [('a3', 1)]]
+    assert [(item['id'], item['reason']) for item in dropped] == [('a1', 'excluded'), ('a4', 'excluded')]
+
+
+def test_chapter_without_articles_left_is_dropped():
+    kept, dropped = ContentFilter(include_articles=['Deploy']).apply(chapters())
+    assert [chapter['id'] for chapter in kept] == ['ch2']
+    assert {'type': 'chapter', 'id': 'ch1', 'title': 'Introduction', 'reason': 'no articles left'} in dropped
+
+
+def test_apply_does_not_modify_its_input():
+    original = chapters()
+    ContentFilter(exclude_chapters=['ch1'], exclude_articles=['a3']).apply(original)
+    assert original == chapters()
+
+
+def test_select_articles_by_name_and_start():
+    kept, dropped = select_articles(chapters(), only=['vlp-2', 'Deploy', 'ss-44'], start_from='Lab Setup',
+                                    screensteps_ids={'a4': 'ss-44'})
+    assert [[a['id'] for a in chapter['articles']] for chapter in kept] == [['a2'], ['a3', 'a4']]
+    assert [(item['id'], item['reason']) for item in dropped] == [('a1', 'before start')]
+
+
+def test_select_articles_unknown_name_is_an_error():
+    with pytest.raises(FilterError):
+        select_articles(chapters(), only=['Missing'])
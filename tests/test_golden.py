@@ -0,0 +1,24 @@
+"""Golden-file regression tests: converted sample exports must match tests/golden
+
+After an intended output change, review the differences and refresh the golden files:
+
+    python3 python/vlp2ss_golden.py --golden-update
+"""
+import sys
+from pathlib import Path
+
+import pytest
+
+# The converter needs BeautifulSoup; without it there is no output to compare
+pytest.importorskip('bs4')
+
+sys.path.insert(0, str(Path(__file__).resolve().parent.parent / 'python'))
+
+from vlp2ss_golden import check_sample, list_samples  # noqa: E402
+
+
+@pytest.mark.parametrize('sample', list_samples(), ids=lambda sample: sample.name)
+def test_conversion_matches_golden_files(sample):
+    """Converting a sample export reproduces its golden JSON, HTML and file hashes."""
+    differences = check_sample(sample)
+    assert not differences, '\n'.join(differences)
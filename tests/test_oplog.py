@@ -0,0 +1,101 @@
+"""Operation log tests: which logged operations --replay sends, and replaying a log more than once"""
+import sys
+from pathlib import Path
+
+import pytest
+
+# The operation log reports replay failures with requests' exceptions
+requests = pytest.importorskip('requests')
+
+sys.path.insert(0, str(Path(__file__).resolve().parent.parent / 'python'))
+
+from vlp2ss_oplog import OperationLog, OperationLogError, load_operations, replay_operations, select_replay  # noqa: E402
+
+
+def operation(op, ok=True, run='run1', replay_of=None, **fields):
+    entry = {'op': op, 'run': run, 'account': 'acme', 'method': 'POST', 'endpoint': 'sites/1/manuals', 'ok': ok}
+    if replay_of is not None:
+        entry['replay_of'] = replay_of
+    entry.update(fields)
+    return entry
+
+
+class FakeResponse:
+    def __init__(self, result):
+        self.result = result
+        self.content = b'{}' if result is not None else b''
+
+    def json(self):
+        return self.result
+
+
+class FakeClient:
+    """Logs each call like ScreenStepsClient; calls to endpoints in failing raise a connection error"""
+
+    def __init__(self, log, failing=(), created=None):
+        self.account = 'acme'
+        self.log = log
+        self.failing = set(failing)
+        self.created = created or {}
+        self.sent = []
+
+    def send(self, method, endpoint, replay_of=None, **kwargs):
+        self.sent.append((method, endpoint, kwargs))
+        if endpoint in self.failing:
+            self.log.record(method, endpoint, kwargs, None, error='connection refused', replay_of=replay_of)
+            raise requests.exceptions.ConnectionError('connection refused')
+        result = self.created.get(endpoint)
+        self.log.record(method, endpoint, kwargs, 200, result=result, replay_of=replay_of)
+        return FakeResponse(result)
+
+
+def test_default_replay_sends_only_failed_operations_of_last_failed_run():
+    operations = [operation(1, ok=False), operation(2), operation(3, run='run2', ok=False),
+                  operation(4, run='run2'), operation(5, run='run2', ok=False)]
+    assert [entry['op'] for entry in select_replay(operations)] == [3, 5]
+
+
+def test_default_replay_skips_operations_a_later_replay_completed():
+    operations = [operation(1, ok=False), operation(2, ok=False),
+                  operation(3, run='replay1', replay_of=1), operation(4, run='replay1', replay_of=2, ok=False),
+                  operation(5, run='replay2', replay_of=4)]
+    assert select_replay(operations) == []
+
+
+def test_replay_from_resends_operations_that_succeeded():
+    operations = [operation(1), operation(2, ok=False), operation(3), operation(4, run='run2')]
+    assert [entry['op'] for entry in select_replay(operations, 1)] == [1, 2, 3]
+
+
+def test_replay_from_unknown_operation_is_an_error():
+    with pytest.raises(OperationLogError):
+        select_replay([operation(1)], 7)
+
+
+def test_replaying_twice_resumes_where_the_first_replay_stopped(tmp_path):
+    path = tmp_path / 'api_operations.ndjson'
+    log = OperationLog(path, 'acme')
+    # The manual was created as 10; an outage stopped both of its chapters
+    log.record('POST', 'sites/1/manuals', {'json': {'title': 'Lab'}}, 201, result={'manual': {'id': 10}})
+    log.record('POST', 'manuals/10/chapters', {'json': {'manual_id': 10, 'title': 'One'}}, None, error='timed out')
+    log.record('POST', 'manuals/10/chapters', {'json': {'manual_id': 10, 'title': 'Two'}}, None, error='timed out')
+
+    # First replay, into a fresh site from op 1: the manual is re-created as 20, its chapters fail again
+    log = OperationLog(path, 'acme')
+    client = FakeClient(log, failing={'manuals/20/chapters'}, created={'sites/1/manuals': {'manual': {'id': 20}}})
+    history = load_operations(path)
+    outcome = replay_operations(client, select_replay(history, 1), history=history)
+    assert outcome == {'replayed': 1, 'failed_op': 2, 'error': 'connection refused'}
+
+    # Second replay: only the chapters are sent, to the manual the first replay created
+    client = FakeClient(log)
+    history = load_operations(path)
+    selected = select_replay(history)
+    assert [entry['op'] for entry in selected] == [2, 3]
+    outcome = replay_operations(client, selected, history=history)
+    assert outcome == {'replayed': 2, 'failed_op': None}
+    assert [(endpoint, kwargs['json']['manual_id']) for _, endpoint, kwargs in client.sent] == \
+        [('manuals/20/chapters', 20), ('manuals/20/chapters', 20)]
+
+    # Nothing is left to replay
+    assert select_replay(load_operations(path)) == []